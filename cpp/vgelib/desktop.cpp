@@ -4,12 +4,14 @@
 #include <GLFW/glfw3.h>
 #include <chrono>
 #include <atomic>
+#include <cmath>
 
 void vge::Desktop::runThread(vge::Desktop* vDesktop) {
 	while (!vDesktop->stopped) {
 		auto found = vDesktop->runActions();
 		if (!found) {
 			glfwPollEvents();
+			vDesktop->pollGamepads();
 			std::this_thread::sleep_for(std::chrono::milliseconds(1));
 		}
 	}
@@ -160,6 +162,38 @@ bool vge::Desktop::runActions()
 	return false;
 }
 
+// pollGamepads pushes events for changes in gamepad states. GLFW has no callbacks for gamepad input
+void vge::Desktop::pollGamepads()
+{
+	for (int jid = 0; jid <= GLFW_JOYSTICK_LAST; jid++) {
+		GamepadState& prev = gamepads[jid];
+		GLFWgamepadstate state;
+		bool connected = glfwJoystickIsGamepad(jid) && glfwGetGamepadState(jid, &state);
+		if (connected != prev.connected) {
+			pushEvent(RawEvent{ connected ? EventType::GamepadConnected : EventType::GamepadDisconnected, jid });
+			prev = GamepadState{};
+			prev.connected = connected;
+		}
+		if (!connected) {
+			continue;
+		}
+		for (int button = 0; button <= GLFW_GAMEPAD_BUTTON_LAST; button++) {
+			if (state.buttons[button] != prev.buttons[button]) {
+				pushEvent(RawEvent{ state.buttons[button] == GLFW_PRESS ? EventType::GamepadButtonDown : EventType::GamepadButtonUp,
+					jid, button });
+				prev.buttons[button] = state.buttons[button];
+			}
+		}
+		for (int axis = 0; axis <= GLFW_GAMEPAD_AXIS_LAST; axis++) {
+			if (std::abs(state.axes[axis] - prev.axes[axis]) >= 0.01f) {
+				// Axis value is scaled to -32767 ... 32767
+				pushEvent(RawEvent{ EventType::GamepadAxis, jid, axis, static_cast<int32_t>(state.axes[axis] * 32767) });
+				prev.axes[axis] = state.axes[axis];
+			}
+		}
+	}
+}
+
 void vge::Window::PrepareSwapchain(Device* dev, ImageDescription* imageDesc, int32_t& imageCount)
 {
 	if (_win == nullptr) {
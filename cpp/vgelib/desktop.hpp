@@ -8,6 +8,13 @@ struct GLFWwindow;
 
 namespace vge {
 	
+	// Last polled state of gamepad
+	struct GamepadState {
+		bool connected;
+		uint8_t buttons[15];
+		float axes[6];
+	};
+
 	class Desktop : public External, public InstanceExtension, public DeviceExtension {
 		friend struct Static;
 	public:
@@ -29,6 +36,7 @@ namespace vge {
 
 		static void runThread(Desktop* desktop);
 		bool runActions();
+		void pollGamepads();
 		// Instance extension
 		virtual void dispose() override;
 		virtual void prepare(vk::InstanceCreateInfo& ici, std::vector<const char*>& layers, std::vector<const char*>& extensions) override;
@@ -48,6 +56,7 @@ namespace vge {
 		Application* const _app;
 		vk::ImageUsageFlags _flags;
 		bool stopped = false;
+		GamepadState gamepads[16] = {};
 	};
 
 	class PresentInfo : SubmitInfo {
@@ -64,6 +64,11 @@ namespace vge {
         MouseDown = 301,
         MouseMove = 302,
         MouseScroll = 303,
+        GamepadButtonUp = 400,
+        GamepadButtonDown = 401,
+        GamepadAxis = 402,
+        GamepadConnected = 403,
+        GamepadDisconnected = 404,
     };
 
     // Raw event from desktop
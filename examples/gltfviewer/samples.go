@@ -186,24 +186,53 @@ func buildScene(sample *Sample, model *vscene.Node) {
 	})
 }
 
+type animator interface {
+	SetAnimationIndex(fromTime float64, animIndex int)
+}
+
 // Check if we have animates nodes
 func checkAnim(modelRool *vscene.Node) {
-	for _, n := range modelRool.Children {
-		an, ok := n.Ctrl.(*vscene.AnimatedNodeControl)
-		if ok && len(an.Skin.Animations) > 1 {
-			ai := 0
-			app.extraUi.Visible = true
-			app.extraUi.Content = vui.NewMenuButton("Toggle animation").SetOnClick(func() {
-				ai++
-				if ai >= len(an.Skin.Animations) {
-					ai = 0
-				}
-				an.SetAnimationIndex(app.rw.GetSceneTime(), ai)
-			})
+	var animators []animator
+	animCount := 0
+	collectAnim(modelRool, func(a animator, count int) {
+		animators = append(animators, a)
+		if count > animCount {
+			animCount = count
 		}
-		checkAnim(n)
+	})
+	if animCount <= 1 {
+		return
 	}
+	ai := 0
+	app.extraUi.Visible = true
+	app.extraUi.Content = vui.NewMenuButton("Toggle animation").SetOnClick(func() {
+		ai++
+		if ai >= animCount {
+			ai = 0
+		}
+		for _, an := range animators {
+			an.SetAnimationIndex(app.rw.GetSceneTime(), ai)
+		}
+	})
+}
 
+func collectAnim(n *vscene.Node, found func(a animator, count int)) {
+	ctrls := []vscene.NodeControl{n.Ctrl}
+	mc, ok := n.Ctrl.(*vscene.MultiControl)
+	if ok {
+		ctrls = mc.Controls
+	}
+	for _, ctrl := range ctrls {
+		switch an := ctrl.(type) {
+		case *vscene.AnimatedNodeControl:
+			found(an, len(an.Skin.Animations))
+		case *vscene.NodeAnimationControl:
+			found(an, len(an.Animation.Tracks))
+		}
+	}
+	for _, ch := range n.Children {
+		collectAnim(ch, found)
+	}
 }
 
 func newPlacedLight(s *Sample, position mgl32.Vec3) *vscene.Node {
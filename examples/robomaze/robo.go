@@ -2,6 +2,8 @@ package main
 
 import (
 	"github.com/lakal3/vge/vge/materials/shadow"
+	"github.com/lakal3/vge/vge/vanimation"
+	"github.com/lakal3/vge/vge/vmodel"
 	"math"
 	"math/rand"
 
@@ -24,8 +26,12 @@ type robo struct {
 	walkStart float64
 	lastDrop  float64
 	done      bool
+	sm        *vanimation.StateMachine
 }
 
+// turnPause is time robot stands idle before it turns to new direction
+const turnPause = 0.8
+
 func (rp roboPos) nextPoint() roboPos {
 	switch rp.dir {
 	case Left:
@@ -113,13 +119,28 @@ func (r *robo) Process(pi *vscene.ProcessInfo) {
 	wd := pi.Time - r.walkStart
 	if wd > 1 {
 		r.nextDir()
+		wd = pi.Time - r.walkStart
+	}
+	if wd < 0 {
+		// Waiting before turn
+		r.setState(pi, "idle")
 		wd = 0
+	} else {
+		r.setState(pi, "walk")
 	}
 	ag := lerpAngle(wd, r.current.getRot(), r.next.getRot())
 	pi.World = pi.World.Mul4(mgl32.Translate3D(lerpFloat(wd, r.current.x, r.next.x), 0, -lerpFloat(wd, r.current.y, r.next.y))).
 		Mul4(mgl32.HomogRotate3DY(ag)).Mul4(roboScale)
 }
 
+// setState changes animation state. State machine is only updated in animate phase that is processed before robots skin
+func (r *robo) setState(pi *vscene.ProcessInfo, state string) {
+	_, ok := pi.Phase.(*vscene.AnimatePhase)
+	if ok && r.sm != nil {
+		_ = r.sm.SetState(pi.Time, state)
+	}
+}
+
 func lerpAngle(wd float64, a1 float32, a2 float32) float32 {
 	ag := a1*(1-float32(wd)) + a2*float32(wd)
 	if ag > math.Pi {
@@ -144,6 +165,9 @@ func (rb *robo) nextDir() {
 		return
 	}
 	rb.walkStart = app.mainWnd.GetSceneTime()
+	if turning {
+		rb.walkStart += turnPause
+	}
 	if rb.current.canMoveTo(rb.m, rb.current.dir) {
 		mustTurn = false
 	}
@@ -189,6 +213,8 @@ func addRobo(m *maze, at float64) {
 
 	m.robos = append(m.robos, rb)
 	rb.n = vscene.NodeFromModel(app.robotModel, app.robotModel.FindNode("droid"), true)
+	rb.sm = vanimation.NewStateMachine(0.5)
+	setRoboAnimation(rb.n, rb.sm, at)
 	app.mainWnd.Scene.Update(func() {
 		if app.slowShadows {
 			// We turn off shadow casting from robots when shadows don't update every frame. Animated objects look terrible on slow shadows
@@ -199,6 +225,35 @@ func addRobo(m *maze, at float64) {
 	})
 }
 
+// setRoboAnimation replaces skins animation with state machine that starts from rest pose. Robot cross fades
+// between idle (rest pose) and walking when it stops before turns
+func setRoboAnimation(n *vscene.Node, sm *vanimation.StateMachine, at float64) {
+	an, ok := n.Ctrl.(*vscene.AnimatedNodeControl)
+	if !ok {
+		mc, isMulti := n.Ctrl.(*vscene.MultiControl)
+		if isMulti {
+			for _, ctrl := range mc.Controls {
+				an, ok = ctrl.(*vscene.AnimatedNodeControl)
+				if ok {
+					break
+				}
+			}
+		}
+	}
+	if ok && len(an.Skin.Animations) > 0 && sm.Current() == "" {
+		sm.AddState("idle", vmodel.Animation{}, true)
+		sm.AddState("walk", an.Skin.Animations[0], true)
+		sm.AddTransition("walk", "idle", 0.3)
+		_ = sm.SetState(at, "idle")
+	}
+	if ok && sm.Current() != "" {
+		an.Source = sm
+	}
+	for _, ch := range n.Children {
+		setRoboAnimation(ch, sm, at)
+	}
+}
+
 type newRoboCtrl struct {
 	prevAdded float64
 	m         *maze
@@ -69,6 +69,10 @@ func (r *robo) checkNewStain(time float64) {
 	}
 	r.lastDrop = time
 	wd := time - r.walkStart
+	if wd < 0 {
+		// Robot is waiting before turn
+		wd = 0
+	}
 	// Add stain if possible
 	if len(r.m.stains) >= 20 {
 		// Failed, adjust minStainDelta
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lakal3/vge/vge/vasset"
+	"github.com/lakal3/vge/vge/vasset/pngloader"
+	"github.com/lakal3/vge/vge/vglyph"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+	"github.com/lakal3/vge/vge/vmodel/gltf2loader"
+	"github.com/lakal3/vge/vge/vmodel/objloader"
+	"github.com/lakal3/vge/vge/vpack"
+)
+
+type buildContext struct {
+}
+
+func (b buildContext) SetError(err error) {
+	log.Fatal("Pack failed: ", err)
+}
+
+func (b buildContext) IsValid() bool {
+	return true
+}
+
+func (b buildContext) Begin(callName string) (atEnd func()) {
+	return nil
+}
+
+var ctx buildContext
+
+func main() {
+	var outPath string
+	var mipLevels uint
+	var list bool
+	flag.StringVar(&outPath, "out", "assets.vpack", "Output path")
+	flag.UintVar(&mipLevels, "mips", 6, "Mip levels generated for images and models")
+	flag.BoolVar(&list, "list", false, "List content of existing asset pack")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+	}
+	if list {
+		listPack(flag.Arg(0))
+		return
+	}
+
+	app := vk.NewApplication(ctx, "vpack")
+	app.Init(ctx)
+	defer app.Dispose()
+	dev := app.NewDevice(ctx, 0)
+	pngloader.RegisterPngLoader()
+	vasset.RegisterNativeImageLoader(ctx, app)
+
+	b := &vpack.Builder{}
+	for _, arg := range flag.Args() {
+		name, fPath := arg, arg
+		idx := strings.Index(arg, "=")
+		if idx > 0 {
+			name, fPath = arg[:idx], arg[idx+1:]
+		}
+		addAsset(b, dev, name, fPath, uint32(mipLevels))
+	}
+	fl, err := os.Create(outPath)
+	if err != nil {
+		log.Fatal("Create failed: ", err)
+	}
+	defer fl.Close()
+	err = b.Write(fl)
+	if err != nil {
+		log.Fatal("Write failed: ", err)
+	}
+	fmt.Println("Packed to ", outPath)
+}
+
+func addAsset(b *vpack.Builder, dev *vk.Device, name string, fPath string, mipLevels uint32) {
+	ext := strings.ToLower(filepath.Ext(fPath))
+	dir, file := filepath.Split(fPath)
+	l := vasset.DirectoryLoader{Directory: dir}
+	switch ext {
+	case ".gltf", ".glb":
+		mb := &vmodel.ModelBuilder{MipLevels: mipLevels}
+		gl := &gltf2loader.GLTF2Loader{Builder: mb, Loader: l}
+		var err error
+		if ext == ".glb" {
+			err = gl.LoadGLB(file)
+		} else {
+			err = gl.LoadGltf(file)
+		}
+		if err == nil {
+			err = gl.Convert(0)
+		}
+		if err != nil {
+			log.Fatal("Load ", fPath, " failed: ", err)
+		}
+		b.AddModel(ctx, dev, name, mb)
+	case ".obj":
+		mb := &vmodel.ModelBuilder{MipLevels: mipLevels}
+		ol := &objloader.ObjLoader{Builder: mb, Loader: l}
+		err := ol.LoadFile(file)
+		if err != nil {
+			log.Fatal("Load ", fPath, " failed: ", err)
+		}
+		b.AddModel(ctx, dev, name, mb)
+	case ".ttf", ".otf":
+		vsb := &vglyph.VectorSetBuilder{}
+		vsb.AddFont(ctx, readFile(fPath), vglyph.Range{From: 32, To: 255})
+		gs := vsb.Build(ctx, dev)
+		defer gs.Dispose()
+		b.AddGlyphSet(ctx, dev, name, gs)
+	case ".png", ".jpg", ".jpeg", ".dds", ".hdr":
+		b.AddImage(ctx, dev, name, ext[1:], readFile(fPath), mipLevels)
+	default:
+		b.AddFile(ctx, name, readFile(fPath))
+	}
+	fmt.Println("Added ", name)
+}
+
+func readFile(fPath string) []byte {
+	content, err := ioutil.ReadFile(fPath)
+	if err != nil {
+		log.Fatal("Read failed: ", err)
+	}
+	return content
+}
+
+func listPack(fPath string) {
+	p, err := vpack.Parse(readFile(fPath))
+	if err != nil {
+		log.Fatal("Parse failed: ", err)
+	}
+	for _, e := range p.Entries() {
+		fmt.Printf("%-40s %-10s %d\n", e.Name, kindName(e.Kind), e.Size)
+	}
+}
+
+func kindName(kind vpack.EntryKind) string {
+	switch kind {
+	case vpack.KindFile:
+		return "file"
+	case vpack.KindImage:
+		return "image"
+	case vpack.KindModel:
+		return "model"
+	case vpack.KindGlyphSet:
+		return "glyphset"
+	}
+	return fmt.Sprintf("kind %d", kind)
+}
+
+func usage() {
+	fmt.Println("vpack {flags} [name=]file...")
+	fmt.Println("  Packs files to VGE asset pack. Asset name defaults to file path.")
+	fmt.Println("  .gltf, .glb and .obj files are added as models, .ttf and .otf fonts as glyph sets,")
+	fmt.Println("  .png, .jpg, .dds and .hdr files as images and all other files as plain files")
+	fmt.Println("vpack -list file")
+	fmt.Println("  Lists content of asset pack")
+	flag.PrintDefaults()
+	os.Exit(1)
+}
@@ -27,13 +27,8 @@ type DeferredLayout interface {
 	BindDeferredFrame() *vk.DescriptorSet
 }
 
-type Probe struct {
-	SPH      [9]mgl32.Vec4
-	EnvImage float32
-	Filler1  float32
-	Filler2  float32
-	Filler3  float32
-}
+// Probe is environment probe in lights frame
+type Probe = vscene.ShaderProbe
 
 type LightsFrame struct {
 	NoProbes      float32
@@ -46,6 +41,7 @@ type LightsFrame struct {
 	EyePos        mgl32.Vec4
 	Probes        [MAX_PROBES]Probe
 	Lights        [MAX_LIGHTS]vscene.Light
+	ProbeVolumes  [MAX_PROBES]vscene.ShaderProbeVolume
 }
 
 type DeferredFrame struct {
@@ -72,6 +68,10 @@ func (d *DeferredFrame) GetCache() *vk.RenderCache {
 	return d.cache
 }
 
+func (d *DeferredFrame) ViewProjection() (projection, view mgl32.Mat4) {
+	return d.DrawPhase.Projection, d.DrawPhase.View
+}
+
 func (d *DeferredFrame) BindDeferredFrame() *vk.DescriptorSet {
 	if !d.drawUpdated {
 		d.writeDrawFrame()
@@ -100,13 +100,18 @@ func (d *DeferredFrame) AddFrameImage(view *vk.ImageView, sampler *vk.Sampler) (
 
 var _ vscene.ImageFrame = &DeferredFrame{}
 
+// AddEnvironment adds global environment probe
 func (d *DeferredFrame) AddEnvironment(SPH [9]mgl32.Vec4, ubfImage vmodel.ImageIndex, pi *vscene.ProcessInfo) {
+	d.AddProbe(vscene.NewEnvProbe(SPH, ubfImage, mgl32.Ident4(), vscene.ProbeVolume{}), pi)
+}
+
+// AddProbe adds environment probe with influence volume. Lights pass blends two best probes for each pixel
+func (d *DeferredFrame) AddProbe(probe vscene.EnvProbe, pi *vscene.ProcessInfo) {
 	if d.probesUsed >= MAX_PROBES {
 		return
 	}
-	d.LightsFrame.Probes[d.probesUsed] = Probe{SPH: SPH, EnvImage: float32(ubfImage)}
+	d.LightsFrame.Probes[d.probesUsed], d.LightsFrame.ProbeVolumes[d.probesUsed] = probe.ShaderLayout()
 	d.probesUsed++
-	return
 }
 
 func (f *DeferredFrame) GetSimpleFrame() *vscene.SimpleFrame {
@@ -8,6 +8,7 @@
 #define TX_NORMAL 2
 #define TX_MATERIAL 3
 #define TX_DEPTH 4
+#define TX_AO 5
 
 #define MAX_PROBES 16
 #define MAX_LIGHTS 64
@@ -35,6 +36,7 @@ struct LIGHT {
 
 };
 
+// Environment probe, see vscene.ShaderProbe
 struct PROBE {
     vec4 sph[9];
     float envImage;
@@ -43,6 +45,14 @@ struct PROBE {
     float filler3;
 };
 
+// Influence volume of environment probe, see vscene.ShaderProbeVolume
+struct PROBEVOLUME {
+    vec4 position;  // Capture position of probe
+    vec4 boxMin;    // w = radius of influence sphere
+    vec4 boxMax;    // w = blend distance
+    vec4 flags;     // x = kind of influence volume, y = box projection
+};
+
 layout(set=0, binding=0) uniform FRAME {
     float noProbes;
     float noLights;
@@ -54,6 +64,7 @@ layout(set=0, binding=0) uniform FRAME {
     vec4 eyePos;
     PROBE probes[MAX_PROBES];
     LIGHT lights[MAX_LIGHTS];
+    PROBEVOLUME probeVolumes[MAX_PROBES];
 } frame;
 
 layout(set=0, binding=1) uniform sampler2D frameImages2D[];
@@ -65,29 +76,10 @@ layout(set=0, binding=1) uniform sampler2DArray frameImagesArray[];
 layout(set=0, binding=1) uniform usampler2D frameImagesU2D[];
 
 /**************** Helpers *****************************************************/
+#define SHADOW_EYE_POS frame.eyePos.xyz
 #include "../vscene/shadowfactor.glsl"
 
-// IBL color from spherical harmonics
-vec3 sh(vec3 normal, PROBE p) {
-    float x = normal.x;
-    float y = normal.y;
-    float z = normal.z;
-    vec4 result = (
-        p.sph[0] +
-
-        p.sph[1] * -y +
-        p.sph[2] * z +
-        p.sph[3] * -x +
-
-        p.sph[4] * x * y +
-        p.sph[5] * -y * z +
-        p.sph[6] * (3.0 * z * z - 1.0) +
-        p.sph[7] * -x * z +
-        p.sph[8] * (x*x - y*y)
-    );
-
-    return max(vec3(result), vec3(0.0));
-}
+#include "../vscene/probes.glsl"
 
 // NOTE: this approximation is not valid if the energy compensation term
 // for multiscattering is applied. We use the DFG LUT solution to implement
@@ -104,27 +96,15 @@ vec2 prefilteredDFG(float normalDView, float roughness) {
 }
 
 
-vec3 specularIBL(vec3 reflectDir, float roughness, uint tx_envMap) {
-    if (tx_envMap == 0) {
-        return vec3(0.5);
-    }
-    int lods = textureQueryLevels(frameImagesCube[tx_envMap]);
-    float lod1 = floor(lods * roughness);
-    float lod2 = ceil(lods * roughness);
-    vec4 tx1 = textureLod(frameImagesCube[tx_envMap], reflectDir, lod1);
-    vec4 tx2 = textureLod(frameImagesCube[tx_envMap], reflectDir, lod2);
-    return mix(tx1.rgb, tx2.rgb, fract(roughness));
-}
-
-vec3 ibl(vec3 normal, vec3 viewDir, vec3 diffuseColor, vec3 f0, float roughness, vec2 dfg, uint probe) {
+vec3 ibl(vec3 worldPos, vec3 normal, vec3 viewDir, vec3 diffuseColor, vec3 f0, float roughness, vec2 dfg, uint probe) {
     if (probe == 0) {
         return diffuseColor;
     }
-    PROBE p = frame.probes[probe - 1];
+    PROBESELECTION ps = selectProbes(worldPos);
     vec3 r = reflect(-viewDir, normal);
     vec3 f90 = vec3(1);
-    vec3 Ld = sh(normal, p) * diffuseColor;
-    vec3 Lld = specularIBL(r, roughness, int(p.envImage));
+    vec3 Ld = blendSH(ps, normal) * diffuseColor;
+    vec3 Lld = blendSpecular(ps, worldPos, r, roughness);
     vec3 Lr =  (f0 * dfg.x + f90 * dfg.y) * Lld;
     return (Ld + Lr);
 }
@@ -212,21 +192,19 @@ vec3 calcLight(LIGHT light, vec3 worldPos, vec3 normal, vec3 viewDir, vec3 diffu
 }
 
 // Debug helpers
-vec3 debugSH(vec3 normal, uint probe) {
+vec3 debugSH(vec3 worldPos, vec3 normal, uint probe) {
     if (probe == 0) {
         return vec3(0.2);
     }
-    PROBE p = frame.probes[probe - 1];
-    return sh(normal, p);
+    return blendSH(selectProbes(worldPos), normal);
 }
 
-vec3 debugSpecularIBL(float roughness, vec3 normal, vec3 viewDir, uint probe) {
+vec3 debugSpecularIBL(vec3 worldPos, float roughness, vec3 normal, vec3 viewDir, uint probe) {
     if (probe == 0) {
         return vec3(0.2);
     }
-    PROBE p = frame.probes[probe - 1];
     vec3 reflectDir = reflect(-viewDir, normal);
-    return specularIBL(reflectDir, roughness, uint(p.envImage));
+    return blendSpecular(selectProbes(worldPos), worldPos, reflectDir, roughness);
 }
 
 /**************** main *****************************************************/
@@ -289,11 +267,11 @@ void main() {
     }
 
     if (frame.debugMode == 7) {
-        o_Color = vec4(debugSH(normal, probe), 1);
+        o_Color = vec4(debugSH(worldPosition, normal, probe), 1);
         return;
     }
     if (frame.debugMode == 8) {
-        o_Color = vec4(debugSpecularIBL(roughness, normal, viewDir, probe), 1);
+        o_Color = vec4(debugSpecularIBL(worldPosition, roughness, normal, viewDir, probe), 1);
         return;
     }
 #endif
@@ -301,7 +279,16 @@ void main() {
     vec3 diffuseColor = (1.0 - metalness) * albedo.rgb;
     vec3 f0 = 0.16 * reflectance * reflectance * (1.0 - metalness) + albedo.rgb * metalness;
     vec2 dfg = prefilteredDFG(roughness, normalDView);
-    vec3 iblColor = ibl(normal, viewDir, diffuseColor, f0, roughness, dfg, probe);
+    float occlusion = 1.0 - float(i_material.a) / 255;
+    // Screen space ambient occlusion. White image if SSAO is not enabled
+    occlusion = occlusion * texelFetch(frameImages2D[TX_AO], texelPosition, 0).r;
+#ifdef INCL_DEBUG
+    if (frame.debugMode == 12) {
+        o_Color = vec4(vec3(occlusion), 1);
+        return;
+    }
+#endif
+    vec3 iblColor = ibl(worldPosition, normal, viewDir, diffuseColor, f0, roughness, dfg, probe) * occlusion;
 
     // Calculate lights
     vec3 lightColors = vec3(0);
@@ -6,6 +6,8 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/lakal3/vge/vge/occlusion"
+	"github.com/lakal3/vge/vge/postprocess"
 	"github.com/lakal3/vge/vge/vk"
 	"github.com/lakal3/vge/vge/vmodel"
 	"github.com/lakal3/vge/vge/vscene"
@@ -37,6 +39,7 @@ type Renderer struct {
 	imColor    []*vk.Image
 	imNormal   []*vk.Image
 	imMaterial []*vk.Image
+	imAO       []*vk.Image
 
 	imViews      []*vk.ImageView
 	frameBuffers []*vk.Buffer
@@ -50,8 +53,17 @@ type Renderer struct {
 	size         image.Point
 	joinPipeline *vk.GraphicsPipeline
 
-	debugMode  int
-	debugIndex int
+	debugMode    int
+	debugIndex   int
+	post         *postprocess.Chain
+	transparency vscene.TransparencyMode
+	hiz          *occlusion.HiZ
+
+	ssao    *SSAO
+	aoViews []*vk.ImageView
+	bfSSAO  *vk.Buffer
+	dpSSAO  *vk.DescriptorPool
+	dsSSAO  []*vk.DescriptorSet
 }
 
 func (r *Renderer) GetPerRenderer(key vk.Key, ctor func(ctx vk.APIContext) interface{}) interface{} {
@@ -76,10 +88,7 @@ var kCmd = vk.NewKey()
 var kFrameLayout = vk.NewKey()
 
 func (f *Renderer) Dispose() {
-	for _, v := range f.imViews {
-		v.Dispose()
-	}
-	f.imViews = nil
+	f.disposeSSAO()
 	if f.mpImages != nil {
 		f.mpImages.Dispose()
 		f.mpImages = nil
@@ -96,13 +105,41 @@ func (f *Renderer) Dispose() {
 		f.rpSplit.Dispose()
 		f.rpSplit = nil
 	}
-
+	if f.post != nil {
+		f.post.Dispose()
+		f.post = nil
+	}
+	if f.hiz != nil {
+		f.hiz.Dispose()
+		f.hiz = nil
+	}
 }
 
 func (f *Renderer) GetRenderPass() vk.RenderPass {
 	return f.rpFinal
 }
 
+// SetTransparency selects how transparent (LAYERTransparent) draw items are ordered. Transparent items are drawn
+// with forward shading after lights have been calculated. Default is TRANSPARENCYUnsorted
+func (f *Renderer) SetTransparency(mode vscene.TransparencyMode) *Renderer {
+	f.transparency = mode
+	return f
+}
+
+// AddOcclusionCulling skips drawing of meshes to G-buffer if they were hidden behind other objects in depth buffer of
+// previous frame (see occlusion package)
+func (f *Renderer) AddOcclusionCulling() *Renderer {
+	f.hiz = occlusion.NewHiZ()
+	return f
+}
+
+// AddPostProcessing renders lights and transparent objects to HDR image and applies post processing chain to it
+// before drawing UI layer. Post processing must be added before renderer is set up. Renderer will dispose chain
+func (f *Renderer) AddPostProcessing(chain *postprocess.Chain) *Renderer {
+	f.post = chain
+	return f
+}
+
 func (f *Renderer) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDescription, images int) {
 	if vscene.FrameMaxDynamicSamplers == 0 {
 		ctx.SetError(errors.New("you must enable DynamicDescriptor and set vscene.FrameMaxDynamicSamplers for DeferredRenderer"))
@@ -117,6 +154,7 @@ func (f *Renderer) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDe
 	materialDesc := mainImage
 	materialDesc.Format = vk.FORMATR8g8b8a8Uint
 	if f.rpFinal != nil {
+		f.disposeSSAO()
 		f.mpImages.Dispose()
 		f.imDepth, f.imColor, f.imMaterial, f.imNormal, f.frameBuffers = nil, nil, nil, nil, nil
 	} else {
@@ -128,9 +166,15 @@ func (f *Renderer) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDe
 			{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: vk.IMAGELayoutGeneral, Format: depthDesc.Format,
 				ClearColor: [4]float32{1, 0, 0, 0}},
 		})
-		f.rpFinal = vk.NewGeneralRenderPass(ctx, dev, false, []vk.AttachmentInfo{
-			{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: vk.IMAGELayoutPresentSrcKhr, Format: mainImage.Format},
-		})
+		if f.post != nil {
+			f.rpFinal = vk.NewGeneralRenderPass(ctx, dev, false, []vk.AttachmentInfo{
+				{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: vk.IMAGELayoutShaderReadOnlyOptimal, Format: postprocess.HDRFormat},
+			})
+		} else {
+			f.rpFinal = vk.NewGeneralRenderPass(ctx, dev, false, []vk.AttachmentInfo{
+				{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: vk.IMAGELayoutPresentSrcKhr, Format: mainImage.Format},
+			})
+		}
 		f.rpBG = vk.NewGeneralRenderPass(ctx, dev, false, []vk.AttachmentInfo{
 			{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: vk.IMAGELayoutColorAttachmentOptimal, Format: colorDesc.Format},
 		})
@@ -147,6 +191,9 @@ func (f *Renderer) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDe
 		}
 		f.joinPipeline = f.newLightsPipeline(ctx, dev)
 	}
+	if f.post != nil {
+		f.post.Setup(ctx, dev, mainImage)
+	}
 	f.mpImages = vk.NewMemoryPool(dev)
 	for idx := 0; idx < images; idx++ {
 		f.imDepth = append(f.imDepth, f.mpImages.ReserveImage(ctx, depthDesc,
@@ -162,6 +209,9 @@ func (f *Renderer) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDe
 		f.imMaterial = append(f.imMaterial, f.mpImages.ReserveImage(ctx, materialDesc, vk.IMAGEUsageColorAttachmentBit|vk.IMAGEUsageTransferSrcBit|vk.IMAGEUsageSampledBit))
 	}
 
+	if f.ssao != nil {
+		f.ssaoImages(ctx, mainImage, images)
+	}
 	for idx := 0; idx < images*2; idx++ {
 		f.frameBuffers = append(f.frameBuffers, f.mpImages.ReserveBuffer(ctx, 32768, true, vk.BUFFERUsageUniformBufferBit))
 	}
@@ -178,6 +228,9 @@ func (f *Renderer) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDe
 		f.dsLights[idx].WriteImage(ctx, 1, 3, f.imMaterial[idx].DefaultView(ctx), sampler)
 		f.dsLights[idx].WriteImage(ctx, 1, 4, f.imDepth[idx].DefaultView(ctx), sampler)
 	}
+	if f.ssao != nil {
+		f.ssaoDescriptors(ctx, images)
+	}
 
 }
 
@@ -210,6 +263,9 @@ func (f *Renderer) RenderView(camera vscene.Camera, sc *vscene.Scene, rc *vk.Ren
 		return vk.NewFramebuffer(ctx, f.rpSplit, []*vk.ImageView{colorView, normalView, materialView, depthView})
 	}).(*vk.Framebuffer)
 	fbFinal := rc.Get(kFpFinal, func(ctx vk.APIContext) interface{} {
+		if f.post != nil {
+			return vk.NewFramebuffer(ctx, f.rpFinal, []*vk.ImageView{f.post.HDRView(rc)})
+		}
 		return vk.NewFramebuffer(ctx, f.rpFinal, []*vk.ImageView{mainView})
 	}).(*vk.Framebuffer)
 	fbBG := rc.Get(kFpBG, func(ctx vk.APIContext) interface{} {
@@ -234,7 +290,8 @@ func (f *Renderer) RenderView(camera vscene.Camera, sc *vscene.Scene, rc *vk.Ren
 	if f.timedOutput != nil {
 		cmd.WriteTimer(tp, 1, vk.PIPELINEStageTopOfPipeBit)
 	}
-	frame := &DeferredFrame{dsLight: dsLight, dsDraw: f.dsDraw[imageIndex], imagesUsed: 4, cache: rc, renderer: f}
+	ssaoActive := f.bindOcclusion(rc.Ctx, dsLight, imageIndex)
+	frame := &DeferredFrame{dsLight: dsLight, dsDraw: f.dsDraw[imageIndex], imagesUsed: 5, cache: rc, renderer: f}
 	frame.bfLightsFrame = f.frameBuffers[imageIndex*2]
 	frame.bfDrawFrame = f.frameBuffers[imageIndex*2+1]
 	frame.LightsFrame.Debug = float32(f.debugMode)
@@ -249,19 +306,34 @@ func (f *Renderer) RenderView(camera vscene.Camera, sc *vscene.Scene, rc *vk.Ren
 		cmd.BeginRenderPass(f.rpSplit, fbSplit)
 	}, func() {
 		cmd.EndRenderPass()
+		if ssaoActive {
+			f.renderSSAO(cmd, rc, imageIndex, frame)
+		}
 	})
+	if f.hiz != nil {
+		splitPhase.(*vscene.BasicDrawPhase).Occluder = f.hiz.Occluder()
+	}
 	frame.DrawPhase.Projection, frame.DrawPhase.View = camera.CameraProjection(f.size)
 	frame.DrawPhase.EyePos = frame.DrawPhase.View.Inv().Col(3)
 	frame.LightsFrame.View = frame.DrawPhase.View
 
 	join := &DrawLights{ds: dsLight, fb: fbFinal, rp: f.rpFinal, cache: rc, cmd: cmd, frame: frame, pipeline: f.joinPipeline}
 	// splitPhase := vscene.NewDrawPhase(rc, f.rpFinal, vscene.LAYER3D, cmd, nil, nil)
-	transparent := vscene.NewDrawPhase(frame, f.rpFinal, vscene.LAYERTransparent, cmd, nil, nil)
+	transparent := vscene.NewTransparentPhase(frame, f.rpFinal, cmd, f.transparency, frame.DrawPhase.View, nil, nil)
 	ui := vscene.NewDrawPhase(frame, f.rpFinal, vscene.LAYERUI, cmd, func() {
 	}, func() {
 
 		cmd.EndRenderPass()
 	})
+	if f.post != nil {
+		transparent = vscene.NewTransparentPhase(frame, f.rpFinal, cmd, f.transparency, frame.DrawPhase.View, nil, func() {
+			cmd.EndRenderPass()
+			f.post.Render(cmd, rc, mainView, depthView, frame.DrawPhase.Projection, frame.DrawPhase.View)
+		})
+		ui = vscene.NewDrawPhase(frame, f.post.FinalPass(), vscene.LAYERUI, cmd, nil, func() {
+			cmd.EndRenderPass()
+		})
+	}
 	ppPhase := &vscene.PredrawPhase{Scene: sc, Cmd: cmd}
 
 	sc.Process(sc.Time, frame, &vscene.AnimatePhase{}, ppPhase, bgPhase, splitPhase, join, transparent, ui)
@@ -271,6 +343,9 @@ func (f *Renderer) RenderView(camera vscene.Camera, sc *vscene.Scene, rc *vk.Ren
 		pd()
 	}
 	infos = append(infos, ppPhase.Needeed...)
+	if f.hiz != nil {
+		f.hiz.Record(cmd, rc, depthView, frame.DrawPhase.Projection, frame.DrawPhase.View)
+	}
 	if tp != nil {
 		cmd.WriteTimer(tp, 2, vk.PIPELINEStageAllCommandsBit)
 	}
@@ -4,9 +4,17 @@ import _ "embed"
 
 //go:generate glslangValidator -V lights.vert.glsl -o lights.vert.spv
 //go:generate glslangValidator -V lights.frag.glsl -o lights.frag.spv
+//go:generate glslangValidator -V ssao.comp.glsl -o ssao.comp.spv
+//go:generate glslangValidator -V ssaoblur.comp.glsl -o ssaoblur.comp.spv
 
 //go:embed lights.vert.spv
 var lights_vert_spv []byte
 
 //go:embed lights.frag.spv
 var lights_frag_spv []byte
+
+//go:embed ssao.comp.spv
+var ssao_comp_spv []byte
+
+//go:embed ssaoblur.comp.spv
+var ssaoblur_comp_spv []byte
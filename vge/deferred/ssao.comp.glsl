@@ -0,0 +1,79 @@
+#version 450
+
+// Screen space ambient occlusion. Samples hemisphere around each pixel in view space and checks how many
+// samples are behind depth buffer
+
+layout (local_size_x = 8, local_size_y = 8, local_size_z = 1) in;
+
+#define TX_DEPTH 0
+#define TX_NORMAL 1
+#define TX_INPUT 2
+
+#define MAX_SAMPLES 32
+
+layout(set=0, binding=0) uniform SSAO {
+    mat4 projection;
+    mat4 invProjection;
+    mat4 view;
+    vec4 settings; // radius, intensity, bias, samples
+    vec4 blur;     // direction x, direction y, sharpness
+    vec4 size;     // width, height
+    vec4 kernel[MAX_SAMPLES];
+} ssao;
+
+layout(set=0, binding=1) uniform sampler2D images[3];
+
+layout(set=0, binding=2, r32f) uniform writeonly image2D outputImage;
+
+vec3 getViewPosition(vec2 uv, float z) {
+    vec4 viewPos = ssao.invProjection * vec4(uv * 2.0 - 1.0, z, 1);
+    return viewPos.xyz / viewPos.w;
+}
+
+// Small per pixel rotation to trade banding for noise. Blur pass removes most of noise
+vec3 randomVec(ivec2 pos) {
+    float a = fract(sin(dot(vec2(pos), vec2(12.9898, 78.233))) * 43758.5453) * 6.2831853;
+    return vec3(cos(a), sin(a), 0);
+}
+
+void main() {
+    ivec2 pos = ivec2(gl_GlobalInvocationID.xy);
+    if (pos.x >= int(ssao.size.x) || pos.y >= int(ssao.size.y)) {
+        return;
+    }
+    float z = texelFetch(images[TX_DEPTH], pos, 0).x;
+    vec4 rawNormal = texelFetch(images[TX_NORMAL], pos, 0);
+    if (z >= 1.0 || rawNormal.a < 0.5) {
+        // Background or emissive surface
+        imageStore(outputImage, pos, vec4(1));
+        return;
+    }
+    vec2 uv = (vec2(pos) + vec2(0.5)) / ssao.size.xy;
+    vec3 viewPos = getViewPosition(uv, z);
+    vec3 normal = normalize(mat3(ssao.view) * (rawNormal.xyz * 2.0 - 1.0));
+    vec3 rv = randomVec(pos);
+    vec3 tangent = normalize(rv - normal * dot(rv, normal));
+    vec3 bitangent = cross(normal, tangent);
+    mat3 tbn = mat3(tangent, bitangent, normal);
+
+    float radius = ssao.settings.x;
+    float bias = ssao.settings.z;
+    int samples = int(ssao.settings.w);
+    float occlusion = 0;
+    for (int idx = 0; idx < samples; idx++) {
+        vec3 samplePos = viewPos + tbn * ssao.kernel[idx].xyz * radius;
+        vec4 offset = ssao.projection * vec4(samplePos, 1);
+        offset.xyz = offset.xyz / offset.w;
+        vec2 sampleUV = offset.xy * 0.5 + 0.5;
+        if (sampleUV.x < 0 || sampleUV.y < 0 || sampleUV.x > 1 || sampleUV.y > 1) {
+            continue;
+        }
+        float sampleZ = textureLod(images[TX_DEPTH], sampleUV, 0).x;
+        vec3 surfacePos = getViewPosition(sampleUV, sampleZ);
+        // View space looks towards negative z
+        float rangeCheck = smoothstep(0.0, 1.0, radius / abs(viewPos.z - surfacePos.z));
+        occlusion += (surfacePos.z >= samplePos.z + bias ? 1.0 : 0.0) * rangeCheck;
+    }
+    float ao = 1.0 - occlusion / max(samples, 1);
+    imageStore(outputImage, pos, vec4(pow(ao, ssao.settings.y)));
+}
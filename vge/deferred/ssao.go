@@ -0,0 +1,223 @@
+package deferred
+
+import (
+	"math"
+	"math/rand"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// MaxSSAOSamples is maximum number of samples in SSAO kernel
+const MaxSSAOSamples = 32
+
+// SSAO is screen space ambient occlusion computed from G-buffer depth and normals. Ambient occlusion
+// darkens ambient light and probe lighting in corners and near other objects.
+type SSAO struct {
+	// Radius of sampled hemisphere in world units
+	Radius float32
+	// Intensity is exponent applied to occlusion. Values above 1 make occlusion stronger
+	Intensity float32
+	// Bias prevents self occlusion of flat surfaces
+	Bias float32
+	// Samples is number of samples per pixel (max MaxSSAOSamples)
+	Samples int
+	// BlurSharpness controls how much depth differences stop bilateral blur. 0 disables blur
+	BlurSharpness float32
+}
+
+// NewSSAO creates SSAO with default settings
+func NewSSAO() *SSAO {
+	return &SSAO{Radius: 0.5, Intensity: 1.5, Bias: 0.025, Samples: 16, BlurSharpness: 8}
+}
+
+// SetSSAO enables screen space ambient occlusion. SSAO must be set before renderer is set up to allocate occlusion
+// images. Setting SSAO to nil disables it
+func (f *Renderer) SetSSAO(ssao *SSAO) {
+	f.ssao = ssao
+}
+
+// ssaoFrame is uniform buffer of SSAO compute shaders. See ssao.comp.glsl
+type ssaoFrame struct {
+	Projection    mgl32.Mat4
+	InvProjection mgl32.Mat4
+	View          mgl32.Mat4
+	// radius, intensity, bias, samples
+	Settings mgl32.Vec4
+	// direction x, direction y, sharpness
+	Blur mgl32.Vec4
+	// width, height
+	Size   mgl32.Vec4
+	Kernel [MaxSSAOSamples]mgl32.Vec4
+}
+
+// ssaoSliceSize is size of one ssaoFrame slice aligned to uniform buffer alignment
+const ssaoSliceSize = (uint64(unsafe.Sizeof(ssaoFrame{})) + vk.MinUniformBufferOffsetAlignment - 1) /
+	vk.MinUniformBufferOffsetAlignment * vk.MinUniformBufferOffsetAlignment
+
+// ssaoPasses are occlusion pass, horizontal blur and vertical blur
+const ssaoPasses = 3
+
+// ssaoKernel returns samples in hemisphere oriented along positive z axis. Samples are more dense near center
+func ssaoKernel(samples int) (kernel [MaxSSAOSamples]mgl32.Vec4) {
+	rnd := rand.New(rand.NewSource(1))
+	for idx := 0; idx < samples && idx < MaxSSAOSamples; idx++ {
+		v := mgl32.Vec3{rnd.Float32()*2 - 1, rnd.Float32()*2 - 1, rnd.Float32()}
+		if v.Len() < 0.001 {
+			v = mgl32.Vec3{0, 0, 1}
+		}
+		v = v.Normalize().Mul(rnd.Float32())
+		scale := float32(idx) / float32(samples)
+		scale = 0.1 + 0.9*scale*scale
+		kernel[idx] = v.Mul(scale).Vec4(0)
+	}
+	return kernel
+}
+
+// ssaoImages reserves occlusion images for each frame. Occlusion is computed to first image and blurred through second image
+// back to first one.
+func (f *Renderer) ssaoImages(ctx vk.APIContext, mainImage vk.ImageDescription, images int) {
+	aoDesc := mainImage
+	aoDesc.Format = vk.FORMATR32Sfloat
+	for idx := 0; idx < images*2; idx++ {
+		f.imAO = append(f.imAO, f.mpImages.ReserveImage(ctx, aoDesc, vk.IMAGEUsageStorageBit|vk.IMAGEUsageSampledBit))
+	}
+	f.bfSSAO = f.mpImages.ReserveBuffer(ctx, ssaoSliceSize*ssaoPasses*uint64(images), true, vk.BUFFERUsageUniformBufferBit)
+}
+
+// ssaoDescriptors writes descriptors for SSAO passes after images have been allocated
+func (f *Renderer) ssaoDescriptors(ctx vk.APIContext, images int) {
+	la := getSSAOLayout(ctx, f.dev)
+	f.dpSSAO = vk.NewDescriptorPool(ctx, la, images*ssaoPasses)
+	sampler := vmodel.GetDefaultSampler(ctx, f.dev)
+	for idx := 0; idx < images; idx++ {
+		ao, tmp := f.newAOViews(ctx, idx)
+		inputs := []*vk.ImageView{nil, ao, tmp}
+		outputs := []*vk.ImageView{ao, tmp, ao}
+		for pass := 0; pass < ssaoPasses; pass++ {
+			ds := f.dpSSAO.Alloc(ctx)
+			from := ssaoSliceSize * uint64(idx*ssaoPasses+pass)
+			ds.WriteSlice(ctx, 0, 0, f.bfSSAO.Slice(ctx, from, from+ssaoSliceSize))
+			ds.WriteImage(ctx, 1, 0, f.imDepth[idx].DefaultView(ctx), sampler)
+			ds.WriteImage(ctx, 1, 1, f.imNormal[idx].DefaultView(ctx), sampler)
+			if inputs[pass] != nil {
+				ds.WriteImage(ctx, 1, 2, inputs[pass], sampler)
+			} else {
+				ds.WriteImage(ctx, 1, 2, f.imNormal[idx].DefaultView(ctx), sampler)
+			}
+			ds.WriteImage(ctx, 2, 0, outputs[pass], nil)
+			f.dsSSAO = append(f.dsSSAO, ds)
+		}
+	}
+}
+
+// newAOViews creates views of occlusion images in general layout
+func (f *Renderer) newAOViews(ctx vk.APIContext, imageIndex int) (ao *vk.ImageView, tmp *vk.ImageView) {
+	r := vk.ImageRange{LayerCount: 1, LevelCount: 1, Layout: vk.IMAGELayoutGeneral}
+	ao = vk.NewImageView(ctx, f.imAO[imageIndex*2], &r)
+	tmp = vk.NewImageView(ctx, f.imAO[imageIndex*2+1], &r)
+	f.imViews = append(f.imViews, ao, tmp)
+	f.aoViews = append(f.aoViews, ao)
+	return
+}
+
+// disposeSSAO releases SSAO views and descriptors. Images are released with other G-buffer images
+func (f *Renderer) disposeSSAO() {
+	for _, v := range f.imViews {
+		v.Dispose()
+	}
+	f.imViews, f.aoViews, f.imAO = nil, nil, nil
+	if f.dpSSAO != nil {
+		f.dpSSAO.Dispose()
+		f.dpSSAO, f.dsSSAO = nil, nil
+	}
+}
+
+// bindOcclusion binds occlusion image or white image if SSAO is not active to lights pass
+func (f *Renderer) bindOcclusion(ctx vk.APIContext, ds *vk.DescriptorSet, imageIndex int) bool {
+	sampler := vmodel.GetDefaultSampler(ctx, f.dev)
+	if f.ssao == nil || len(f.aoViews) <= imageIndex {
+		ds.WriteImage(ctx, 1, 5, f.whiteImage.DefaultView(ctx), sampler)
+		return false
+	}
+	ds.WriteImage(ctx, 1, 5, f.aoViews[imageIndex], sampler)
+	return true
+}
+
+// renderSSAO computes ambient occlusion after G-buffer has been rendered. Occlusion image is left in general layout
+// for lights pass
+func (f *Renderer) renderSSAO(cmd *vk.Command, rc *vk.RenderCache, imageIndex int, frame *DeferredFrame) {
+	ctx := rc.Ctx
+	pl, plBlur := f.getSSAOPipelines(ctx)
+	s := f.ssao
+	samples := s.Samples
+	if samples > MaxSSAOSamples {
+		samples = MaxSSAOSamples
+	}
+	sf := ssaoFrame{Projection: frame.DrawPhase.Projection, InvProjection: frame.DrawPhase.Projection.Inv(),
+		View: frame.DrawPhase.View, Settings: mgl32.Vec4{s.Radius, s.Intensity, s.Bias, float32(samples)},
+		Size: mgl32.Vec4{float32(f.size.X), float32(f.size.Y)}, Kernel: ssaoKernel(samples)}
+	r := vk.ImageRange{LayerCount: 1, LevelCount: 1, Layout: vk.IMAGELayoutUndefined}
+	for idx := 0; idx < 2; idx++ {
+		cmd.SetLayout(f.imAO[imageIndex*2+idx], &r, vk.IMAGELayoutGeneral)
+	}
+	gx, gy := uint32(math.Ceil(float64(f.size.X)/8)), uint32(math.Ceil(float64(f.size.Y)/8))
+	passes := 1
+	if s.BlurSharpness > 0 {
+		passes = ssaoPasses
+	}
+	for pass := 0; pass < passes; pass++ {
+		switch pass {
+		case 1:
+			sf.Blur = mgl32.Vec4{1, 0, s.BlurSharpness}
+		case 2:
+			sf.Blur = mgl32.Vec4{0, 1, s.BlurSharpness}
+		}
+		from := ssaoSliceSize * uint64(imageIndex*ssaoPasses+pass)
+		b := *(*[unsafe.Sizeof(ssaoFrame{})]byte)(unsafe.Pointer(&sf))
+		copy(f.bfSSAO.Bytes(ctx)[from:from+ssaoSliceSize], b[:])
+		ds := f.dsSSAO[imageIndex*ssaoPasses+pass]
+		if pass == 0 {
+			cmd.Compute(pl, gx, gy, 1, ds)
+		} else {
+			r.Layout = vk.IMAGELayoutGeneral
+			// Wait for previous pass
+			cmd.SetLayout(f.imAO[imageIndex*2+pass-1], &r, vk.IMAGELayoutGeneral)
+			cmd.Compute(plBlur, gx, gy, 1, ds)
+		}
+	}
+	r.Layout = vk.IMAGELayoutGeneral
+	cmd.SetLayout(f.imAO[imageIndex*2], &r, vk.IMAGELayoutGeneral)
+}
+
+var kSSAOLayout = vk.NewKey()
+var kSSAOPipelines = vk.NewKeys(2)
+
+func getSSAOLayout(ctx vk.APIContext, dev *vk.Device) *vk.DescriptorLayout {
+	return dev.Get(ctx, kSSAOLayout, func(ctx vk.APIContext) interface{} {
+		la := vk.NewDescriptorLayout(ctx, dev, vk.DESCRIPTORTypeUniformBuffer, vk.SHADERStageComputeBit, 1)
+		la2 := la.AddBinding(ctx, vk.DESCRIPTORTypeCombinedImageSampler, vk.SHADERStageComputeBit, 3)
+		return la2.AddBinding(ctx, vk.DESCRIPTORTypeStorageImage, vk.SHADERStageComputeBit, 1)
+	}).(*vk.DescriptorLayout)
+}
+
+func (f *Renderer) getSSAOPipelines(ctx vk.APIContext) (pl *vk.ComputePipeline, plBlur *vk.ComputePipeline) {
+	la := getSSAOLayout(ctx, f.dev)
+	for idx, code := range [][]byte{ssao_comp_spv, ssaoblur_comp_spv} {
+		cp := f.dev.Get(ctx, kSSAOPipelines+vk.Key(idx), func(ctx vk.APIContext) interface{} {
+			cp := vk.NewComputePipeline(ctx, f.dev)
+			cp.AddLayout(ctx, la)
+			cp.AddShader(ctx, code)
+			cp.Create(ctx)
+			return cp
+		}).(*vk.ComputePipeline)
+		if idx == 0 {
+			pl = cp
+		} else {
+			plBlur = cp
+		}
+	}
+	return
+}
@@ -0,0 +1,18 @@
+package deferred
+
+import "testing"
+
+func TestSSAOKernel(t *testing.T) {
+	kernel := ssaoKernel(16)
+	for idx, k := range kernel {
+		if idx >= 16 {
+			if k.Len() != 0 {
+				t.Error("Unused sample ", idx, " not zero")
+			}
+			continue
+		}
+		if k.Z() < 0 || k.Len() > 1 {
+			t.Error("Sample ", idx, " outside of hemisphere ", k)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+#version 450
+
+// Depth aware separable blur of ambient occlusion. Samples with large depth difference to center pixel are weighted down
+// so that occlusion don't bleed over object edges
+
+layout (local_size_x = 8, local_size_y = 8, local_size_z = 1) in;
+
+#define TX_DEPTH 0
+#define TX_NORMAL 1
+#define TX_INPUT 2
+
+#define MAX_SAMPLES 32
+#define BLUR_RADIUS 4
+
+layout(set=0, binding=0) uniform SSAO {
+    mat4 projection;
+    mat4 invProjection;
+    mat4 view;
+    vec4 settings; // radius, intensity, bias, samples
+    vec4 blur;     // direction x, direction y, sharpness
+    vec4 size;     // width, height
+    vec4 kernel[MAX_SAMPLES];
+} ssao;
+
+layout(set=0, binding=1) uniform sampler2D images[3];
+
+layout(set=0, binding=2, r32f) uniform writeonly image2D outputImage;
+
+float linearDepth(ivec2 pos) {
+    float z = texelFetch(images[TX_DEPTH], pos, 0).x;
+    vec4 viewPos = ssao.invProjection * vec4(0, 0, z, 1);
+    return -viewPos.z / viewPos.w;
+}
+
+void main() {
+    ivec2 pos = ivec2(gl_GlobalInvocationID.xy);
+    ivec2 size = ivec2(ssao.size.xy);
+    if (pos.x >= size.x || pos.y >= size.y) {
+        return;
+    }
+    ivec2 dir = ivec2(ssao.blur.xy);
+    float sharpness = ssao.blur.z;
+    float centerDepth = linearDepth(pos);
+    float total = 0;
+    float weights = 0;
+    for (int idx = -BLUR_RADIUS; idx <= BLUR_RADIUS; idx++) {
+        ivec2 p = clamp(pos + dir * idx, ivec2(0), size - ivec2(1));
+        float d = linearDepth(p);
+        float w = exp(-float(idx * idx) / (2.0 * BLUR_RADIUS)) *
+            exp(-abs(d - centerDepth) * sharpness / max(centerDepth, 0.001));
+        total += texelFetch(images[TX_INPUT], p, 0).r * w;
+        weights += w;
+    }
+    imageStore(outputImage, pos, vec4(total / max(weights, 0.0001)));
+}
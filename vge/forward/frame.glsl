@@ -16,6 +16,25 @@ struct LIGHT {
     float shadowMapIndex;
 };
 
+// Environment probe, see vscene.ShaderProbe
+struct PROBE {
+    vec4 sph[9];
+    float envImage;
+    float filler1;
+    float filler2;
+    float filler3;
+};
+
+// Influence volume of environment probe, see vscene.ShaderProbeVolume
+struct PROBEVOLUME {
+    vec4 position;  // Capture position of probe
+    vec4 boxMin;    // w = radius of influence sphere
+    vec4 boxMax;    // w = blend distance
+    vec4 flags;     // x = kind of influence volume, y = box projection
+};
+
+#define MAX_PROBES 16
+
 layout(set=0, binding=0) uniform FRAME {
     mat4 projection;
     mat4 view;
@@ -27,6 +46,9 @@ layout(set=0, binding=0) uniform FRAME {
     float envLoDs; // Level of details in envmap
     float far;
     LIGHT[MAX_LIGHTS] lights;
+    float noProbes;
+    PROBE probes[MAX_PROBES];
+    PROBEVOLUME probeVolumes[MAX_PROBES];
 } frame;
 
 #ifdef DYNAMIC_DESCRIPTORS
@@ -10,6 +10,7 @@ import (
 
 const MAX_LIGHTS = 64
 const MAX_IMAGES = 48
+const MAX_PROBES = 16
 
 type ShaderFrame struct {
 	Projection mgl32.Mat4
@@ -21,6 +22,10 @@ type ShaderFrame struct {
 	EnvLods    float32
 	Far        float32
 	Lights     [MAX_LIGHTS]vscene.Light
+	NoProbes   float32
+	filler     [3]float32
+	Probes     [MAX_PROBES]vscene.ShaderProbe
+	Volumes    [MAX_PROBES]vscene.ShaderProbeVolume
 }
 
 type ForwardFrame interface {
@@ -62,12 +67,23 @@ func (f *Frame) GetCache() *vk.RenderCache {
 	return f.cache
 }
 
+// AddEnvironment adds global environment probe. First probe is also used by shaders that support only one probe
 func (f *Frame) AddEnvironment(SPH [9]mgl32.Vec4, ubfImage vmodel.ImageIndex, pi *vscene.ProcessInfo) {
-	if f.SF.EnvLods > 0 || f.ds != nil {
-		return // Currently only one probe that must be added at prepare phase
+	f.AddProbe(vscene.NewEnvProbe(SPH, ubfImage, mgl32.Ident4(), vscene.ProbeVolume{}), pi)
+}
+
+// AddProbe adds environment probe with influence volume. Std and Pbr shaders blend two best probes for each pixel.
+// Probes must be added in predraw phase
+func (f *Frame) AddProbe(probe vscene.EnvProbe, pi *vscene.ProcessInfo) {
+	if f.ds != nil || int(f.SF.NoProbes) >= MAX_PROBES {
+		return
+	}
+	f.SF.Probes[int(f.SF.NoProbes)], f.SF.Volumes[int(f.SF.NoProbes)] = probe.ShaderLayout()
+	f.SF.NoProbes++
+	if f.SF.EnvLods == 0 {
+		f.SF.EnvMap, f.SF.EnvLods = probe.Position[3], 6
+		f.SF.SPH = probe.SPH
 	}
-	f.SF.EnvMap, f.SF.EnvLods = float32(ubfImage), 6
-	f.SF.SPH = SPH
 }
 
 func (f *Frame) AddFrameImage(view *vk.ImageView, sampler *vk.Sampler) (imageIndex vmodel.ImageIndex) {
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/lakal3/vge/vge/materials/predepth"
+	"github.com/lakal3/vge/vge/occlusion"
+	"github.com/lakal3/vge/vge/postprocess"
 	"github.com/lakal3/vge/vge/vk"
 	"github.com/lakal3/vge/vge/vmodel"
 	"github.com/lakal3/vge/vge/vscene"
@@ -33,6 +35,9 @@ type Renderer struct {
 	mpDepth      *vk.MemoryPool
 	imDepth      []*vk.Image
 	depthPrePass bool
+	post         *postprocess.Chain
+	transparency vscene.TransparencyMode
+	hiz          *occlusion.HiZ
 }
 
 func (f *Renderer) GetPerRenderer(key vk.Key, ctor func(ctx vk.APIContext) interface{}) interface{} {
@@ -65,7 +70,14 @@ func (f *Renderer) Dispose() {
 		f.frp.Dispose()
 		f.frp = nil
 	}
-
+	if f.post != nil {
+		f.post.Dispose()
+		f.post = nil
+	}
+	if f.hiz != nil {
+		f.hiz.Dispose()
+		f.hiz = nil
+	}
 }
 
 func (f *Renderer) GetRenderPass() vk.RenderPass {
@@ -81,6 +93,28 @@ func (f *Renderer) AddDepthPrePass() *Renderer {
 	return f
 }
 
+// SetTransparency selects how transparent (LAYERTransparent) draw items are ordered. Default is TRANSPARENCYUnsorted
+func (f *Renderer) SetTransparency(mode vscene.TransparencyMode) *Renderer {
+	f.transparency = mode
+	return f
+}
+
+// AddOcclusionCulling skips drawing of meshes that were hidden behind other objects in depth buffer of previous frame
+// (see occlusion package). Occlusion culling requires depth buffer and it must be added before renderer is set up
+func (f *Renderer) AddOcclusionCulling() *Renderer {
+	if f.depth {
+		f.hiz = occlusion.NewHiZ()
+	}
+	return f
+}
+
+// AddPostProcessing renders scene to HDR image and applies post processing chain to it before drawing UI layer.
+// Post processing must be added before renderer is set up. Renderer will dispose chain
+func (f *Renderer) AddPostProcessing(chain *postprocess.Chain) *Renderer {
+	f.post = chain
+	return f
+}
+
 func (f *Renderer) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDescription, images int) {
 	fDepth := vk.FORMATUndefined
 	f.size.X, f.size.Y = int(mainImage.Width), int(mainImage.Height)
@@ -94,19 +128,46 @@ func (f *Renderer) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDe
 		}
 	} else {
 		f.Ctx, f.dev = ctx, dev
-		f.frp = vk.NewForwardRenderPass(ctx, dev, mainImage.Format, vk.IMAGELayoutPresentSrcKhr, fDepth)
+		if f.post != nil {
+			f.frp = f.newHDRRenderPass(ctx, dev, fDepth)
+		} else if f.hiz != nil {
+			f.frp = newDepthKeepingRenderPass(ctx, dev, mainImage.Format, vk.IMAGELayoutPresentSrcKhr, fDepth)
+		} else {
+			f.frp = vk.NewForwardRenderPass(ctx, dev, mainImage.Format, vk.IMAGELayoutPresentSrcKhr, fDepth)
+		}
+	}
+	if f.post != nil {
+		f.post.Setup(ctx, dev, mainImage)
 	}
 	if f.depth {
 		depthDesc := mainImage
 		depthDesc.Format = vk.FORMATD32Sfloat
 		f.mpDepth = vk.NewMemoryPool(dev)
 		for idx := 0; idx < images; idx++ {
-			f.imDepth = append(f.imDepth, f.mpDepth.ReserveImage(ctx, depthDesc, vk.IMAGEUsageDepthStencilAttachmentBit|vk.IMAGEUsageTransferSrcBit))
+			f.imDepth = append(f.imDepth, f.mpDepth.ReserveImage(ctx, depthDesc,
+				vk.IMAGEUsageDepthStencilAttachmentBit|vk.IMAGEUsageTransferSrcBit|vk.IMAGEUsageSampledBit))
 		}
 		f.mpDepth.Allocate(ctx)
 	}
 }
 
+// newHDRRenderPass creates scene render pass for post processing. Depth image is kept for effects like fog
+func (f *Renderer) newHDRRenderPass(ctx vk.APIContext, dev *vk.Device, fDepth vk.Format) *vk.GeneralRenderPass {
+	return newDepthKeepingRenderPass(ctx, dev, postprocess.HDRFormat, vk.IMAGELayoutShaderReadOnlyOptimal, fDepth)
+}
+
+// newDepthKeepingRenderPass creates scene render pass that leaves depth image to general layout
+func newDepthKeepingRenderPass(ctx vk.APIContext, dev *vk.Device, format vk.Format, finalLayout vk.ImageLayout,
+	fDepth vk.Format) *vk.GeneralRenderPass {
+	ai := []vk.AttachmentInfo{{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: finalLayout,
+		Format: format, ClearColor: [4]float32{0.2, 0.2, 0.2, 1}}}
+	if fDepth != vk.FORMATUndefined {
+		ai = append(ai, vk.AttachmentInfo{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: vk.IMAGELayoutGeneral,
+			Format: fDepth, ClearColor: [4]float32{1, 0, 0, 0}})
+	}
+	return vk.NewGeneralRenderPass(ctx, dev, fDepth != vk.FORMATUndefined, ai)
+}
+
 func (f *Renderer) Render(camera vscene.Camera, sc *vscene.Scene, rc *vk.RenderCache, mainImage *vk.Image, imageIndex int, infos []vk.SubmitInfo) {
 	mainView := rc.Get(kImageViews+vk.Key(imageIndex), func(ctx vk.APIContext) interface{} {
 		return mainImage.NewView(ctx, 0, 0)
@@ -123,10 +184,14 @@ var kTimerCmd = vk.NewKey()
 
 func (f *Renderer) RenderView(camera vscene.Camera, sc *vscene.Scene, rc *vk.RenderCache, mainView *vk.ImageView, depthView *vk.ImageView, infos []vk.SubmitInfo) {
 	fb := rc.Get(kFp, func(ctx vk.APIContext) interface{} {
+		targetView := mainView
+		if f.post != nil {
+			targetView = f.post.HDRView(rc)
+		}
 		if f.depth {
-			return vk.NewFramebuffer(ctx, f.frp, []*vk.ImageView{mainView, depthView})
+			return vk.NewFramebuffer(ctx, f.frp, []*vk.ImageView{targetView, depthView})
 		}
-		return vk.NewFramebuffer(ctx, f.frp, []*vk.ImageView{mainView})
+		return vk.NewFramebuffer(ctx, f.frp, []*vk.ImageView{targetView})
 	}).(*vk.Framebuffer)
 	start := time.Now()
 	var tp *vk.TimerPool
@@ -155,10 +220,22 @@ func (f *Renderer) RenderView(camera vscene.Camera, sc *vscene.Scene, rc *vk.Ren
 		}
 	}, nil)
 	dp := vscene.NewDrawPhase(frame, f.frp, vscene.LAYER3D, cmd, nil, nil)
-	dt := vscene.NewDrawPhase(frame, f.frp, vscene.LAYERTransparent, cmd, nil, nil)
+	if f.hiz != nil {
+		dp.(*vscene.BasicDrawPhase).Occluder = f.hiz.Occluder()
+	}
+	dt := vscene.NewTransparentPhase(frame, f.frp, cmd, f.transparency, frame.SF.View, nil, nil)
 	ui := vscene.NewDrawPhase(frame, f.frp, vscene.LAYERUI, cmd, nil, func() {
 		cmd.EndRenderPass()
 	})
+	if f.post != nil {
+		dt = vscene.NewTransparentPhase(frame, f.frp, cmd, f.transparency, frame.SF.View, nil, func() {
+			cmd.EndRenderPass()
+			f.post.Render(cmd, rc, mainView, depthView, frame.SF.Projection, frame.SF.View)
+		})
+		ui = vscene.NewDrawPhase(frame, f.post.FinalPass(), vscene.LAYERUI, cmd, nil, func() {
+			cmd.EndRenderPass()
+		})
+	}
 	ppPhase := &vscene.PredrawPhase{Scene: sc, Cmd: cmd}
 	lightPhase := FrameLightPhase{F: frame, Cache: rc}
 	if f.depthPrePass {
@@ -178,6 +255,9 @@ func (f *Renderer) RenderView(camera vscene.Camera, sc *vscene.Scene, rc *vk.Ren
 		pd()
 	}
 	infos = append(infos, ppPhase.Needeed...)
+	if f.hiz != nil && depthView != nil {
+		f.hiz.Record(cmd, rc, depthView, frame.SF.Projection, frame.SF.View)
+	}
 	if tp != nil {
 		cmd.WriteTimer(tp, 2, vk.PIPELINEStageAllCommandsBit)
 	}
@@ -0,0 +1,116 @@
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vasset"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+// BakedProbeVersion is version of baked probe sidecar format
+const BakedProbeVersion = 1
+
+// BakedProbe is sidecar of baked probe. Prefiltered environment cube is stored separately as DDS image
+type BakedProbe struct {
+	Version int
+	// SPH are spherical harmonics coefficients of probe
+	SPH [9]mgl32.Vec4
+	// Volume is influence volume of probe
+	Volume vscene.ProbeVolume
+}
+
+// SidecarPath returns path of sidecar file for baked probe image
+func SidecarPath(imagePath string) string {
+	return imagePath + ".json"
+}
+
+// Bake writes prefiltered environment cube with all mip levels as DDS image and SPH coefficients and influence volume
+// of probe as JSON sidecar. Probe must be rendered at least once before it can be baked
+func (p *Probe) Bake(ctx vk.APIContext, dev *vk.Device, image io.Writer, sidecar io.Writer) error {
+	if p.currentImg < 0 {
+		return errors.New("Probe not rendered")
+	}
+	cp := vmodel.NewCopier(ctx, dev)
+	defer cp.Dispose()
+	img := p.imgs[p.currentImg]
+	content := cp.CopyFromImage(img, img.FullRange(), "dds", vk.IMAGELayoutShaderReadOnlyOptimal)
+	if !ctx.IsValid() {
+		return errors.New("Failed to copy probe image")
+	}
+	_, err := image.Write(content)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(sidecar)
+	enc.SetIndent("", "  ")
+	return enc.Encode(BakedProbe{Version: BakedProbeVersion, SPH: p.SPH, Volume: p.Volume})
+}
+
+// BakeToFile bakes probe to DDS image at path and sidecar at SidecarPath(path)
+func (p *Probe) BakeToFile(ctx vk.APIContext, dev *vk.Device, path string) error {
+	wImage := &bytes.Buffer{}
+	wSidecar := &bytes.Buffer{}
+	err := p.Bake(ctx, dev, wImage, wSidecar)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path, wImage.Bytes(), 0660)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(SidecarPath(path), wSidecar.Bytes(), 0660)
+}
+
+// LoadProbe reconstitutes baked probe from DDS image and sidecar content. Loaded probe is not rendered again unless
+// Update is called, so scene is fully lit already on first frame
+func LoadProbe(ctx vk.APIContext, dev *vk.Device, image []byte, sidecar []byte) (*Probe, error) {
+	var bp BakedProbe
+	err := json.Unmarshal(sidecar, &bp)
+	if err != nil {
+		return nil, err
+	}
+	if bp.Version != BakedProbeVersion {
+		return nil, fmt.Errorf("Unsupported baked probe version %d", bp.Version)
+	}
+	p := NewProbe(ctx, dev)
+	var desc vk.ImageDescription
+	vasset.DdsImageLoader{}.DescribeImage(ctx, "dds", &desc, image)
+	if !ctx.IsValid() {
+		p.Dispose()
+		return nil, errors.New("Invalid baked probe image")
+	}
+	if desc.Width != p.desc.Width || desc.Height != p.desc.Height || desc.Format != p.desc.Format ||
+		desc.Layers != p.desc.Layers || desc.MipLevels != p.desc.MipLevels {
+		p.Dispose()
+		return nil, fmt.Errorf("Baked probe image %dx%d, %d layers, %d mips don't match probe",
+			desc.Width, desc.Height, desc.Layers, desc.MipLevels)
+	}
+	cp := vmodel.NewCopier(ctx, dev)
+	defer cp.Dispose()
+	cp.CopyToImage(p.imgs[0], "dds", image, p.imgs[0].FullRange(), vk.IMAGELayoutShaderReadOnlyOptimal)
+	p.SPH, p.Volume = bp.SPH, bp.Volume
+	p.currentImg, p.needUpdate = 0, false
+	return p, nil
+}
+
+// LoadBakedProbe loads baked probe image from path and sidecar from SidecarPath(path) using given loader.
+// If loader is nil, vasset.DefaultLoader is used
+func LoadBakedProbe(ctx vk.APIContext, dev *vk.Device, path string, l vasset.Loader) (*Probe, error) {
+	image, err := vasset.Load(path, l)
+	if err != nil {
+		return nil, err
+	}
+	sidecar, err := vasset.Load(SidecarPath(path), l)
+	if err != nil {
+		return nil, err
+	}
+	return LoadProbe(ctx, dev, image, sidecar)
+}
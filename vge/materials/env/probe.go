@@ -1,13 +1,11 @@
 package env
 
 import (
-	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lakal3/vge/vge/vk"
 	"github.com/lakal3/vge/vge/vmodel"
 	"github.com/lakal3/vge/vge/vscene"
 	"image"
-	"io/ioutil"
 	"math"
 )
 
@@ -16,25 +14,45 @@ type EnvFrame interface {
 	AddEnvironment(SPH [9]mgl32.Vec4, ubfImage vmodel.ImageIndex, pi *vscene.ProcessInfo)
 }
 
+// ProbeFrame is implemented by frames that support multiple probes with influence volumes.
+// Frames that only implement EnvFrame will use first probe for whole scene
+type ProbeFrame interface {
+	AddProbe(probe vscene.EnvProbe, pi *vscene.ProcessInfo)
+}
+
 type Probe struct {
+	// Volume is influence volume of probe. Default volume covers whole scene
+	Volume vscene.ProbeVolume
+
+	// StaticUpdate will render probe again each time static nodes of scene change (see vscene.Static)
+	StaticUpdate bool
+
 	pool       *vk.MemoryPool
 	desc       vk.ImageDescription
 	imgs       []*vk.Image
 	frp        *vk.ForwardRenderPass
 	currentImg int
 
-	SPH        [9]mgl32.Vec4
-	needUpdate bool
-	indexKey   vk.Key
+	SPH           [9]mgl32.Vec4
+	needUpdate    bool
+	indexKey      vk.Key
+	staticVersion int
 }
 
 // KProbe can be used to access current probe from Phase
 var KProbe = vk.NewKey()
 
+// Update renders probe again before next frame. Update can also be called from vscene.Scene.Update
 func (p *Probe) Update() {
 	p.needUpdate = true
 }
 
+// SetVolume sets influence volume of probe
+func (p *Probe) SetVolume(volume vscene.ProbeVolume) *Probe {
+	p.Volume = volume
+	return p
+}
+
 func (p *Probe) Dispose() {
 
 	if p.pool != nil {
@@ -61,24 +79,30 @@ func (p *Probe) Process(pi *vscene.ProcessInfo) {
 			return
 		}
 		cache := pi.Frame.GetCache()
+		if p.StaticUpdate && p.staticVersion != pre.Scene.StaticVersion() {
+			p.needUpdate = true
+		}
 		if p.needUpdate {
+			p.staticVersion = pre.Scene.StaticVersion()
 			p.renderProbe(cache.Ctx, cache, pre.Scene, pi.World.Col(3).Vec3())
-			// p.saveImg(pre.Cache, "d:/temp/prope.dds")
 		}
 		sampler := getEnvSampler(cache.Ctx, cache.Device)
+		var idx vmodel.ImageIndex
 		imFrame, ok := pi.Frame.(vscene.ImageFrame)
 		if ok {
-			idx := imFrame.AddFrameImage(p.imgs[p.currentImg].NewCubeView(cache.Ctx, -1), sampler)
-			ev.AddEnvironment(p.SPH, idx, pi)
+			idx = imFrame.AddFrameImage(p.imgs[p.currentImg].NewCubeView(cache.Ctx, -1), sampler)
+		}
+		pf, ok := pi.Frame.(ProbeFrame)
+		if ok {
+			pf.AddProbe(vscene.NewEnvProbe(p.SPH, idx, pi.World, p.Volume), pi)
 		} else {
-			ev.AddEnvironment(p.SPH, 0, pi)
+			ev.AddEnvironment(p.SPH, idx, pi)
 		}
 	}
 	dp, ok := pi.Phase.(*drawProbe)
 	if ok && dp.p == p {
 		pi.Visible = false
 	}
-	// TODO: Add multi probe support
 }
 
 type probeSettings struct {
@@ -137,7 +161,7 @@ func (p *Probe) setup(ctx vk.APIContext, dev *vk.Device) {
 	}
 	for idx := 0; idx < 2; idx++ {
 		img := p.pool.ReserveImage(ctx, p.desc, vk.IMAGEUsageColorAttachmentBit|vk.IMAGEUsageSampledBit|
-			vk.IMAGEUsageTransferSrcBit|vk.IMAGEUsageStorageBit)
+			vk.IMAGEUsageTransferSrcBit|vk.IMAGEUsageTransferDstBit|vk.IMAGEUsageStorageBit)
 		p.imgs = append(p.imgs, img)
 	}
 	p.pool.Allocate(ctx)
@@ -308,17 +332,6 @@ func (p *probeRender) renderMainLayer(cmd *vk.Command, layer int32, depthImage *
 
 }
 
-func (p *Probe) saveImg(rc *vk.RenderCache, path string) {
-	cp := vmodel.NewCopier(rc.Ctx, rc.Device)
-	content := cp.CopyFromImage(p.imgs[p.currentImg], p.imgs[p.currentImg].FullRange(), "dds", vk.IMAGELayoutShaderReadOnlyOptimal)
-	err := ioutil.WriteFile(path, content, 0660)
-	if err != nil {
-		fmt.Println("Failed to save ", path, ": ", err)
-	} else {
-		fmt.Println("Saved probe to ", path)
-	}
-}
-
 var kProbeLayouts = vk.NewKeys(4)
 
 func (p *probeRender) renderSubimage(siIndex int, cmd *vk.Command, layer int32, mip uint32) {
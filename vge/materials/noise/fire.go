@@ -23,7 +23,7 @@ func NewFire(sizeX, sizeY float32) *Fire {
 func (f *Fire) Process(pi *vscene.ProcessInfo) {
 	pd, ok := pi.Phase.(vscene.DrawPhase)
 	if ok {
-		dc := pd.GetDC(vscene.LAYERTransparent)
+		dc := vscene.GetDrawContext(pd, vscene.LAYERTransparent, pi.World.Col(3).Vec3())
 		if dc != nil {
 			w := pi.World.Mul4(mgl32.Scale3D(f.Size[0], f.Size[1], f.Size[0]))
 			f.draw(dc, w, pi.Time)
@@ -0,0 +1,32 @@
+// Clearcoat layer (KHR_materials_clearcoat) on top of base material.
+// Include after ndfGGX, gaSchlickGGX and F_Schlick have been defined
+
+// clearcoatLight adds clearcoat specular of a light on top of base light color
+vec3 clearcoatLight(LIGHT light, vec3 position, vec3 normal, vec3 viewDir, vec3 baseColor, float clearcoat,
+  float roughness, float shadowFactor) {
+    vec3 lightToPos = light.position.xyz - position;
+    float lenToPos = length(lightToPos);
+    vec3 lightDir = length(light.direction.xyz) > 0.5 ? -light.direction.xyz : normalize(lightToPos);
+    vec3 halfDir = normalize(viewDir + lightDir);
+    float lenToPos2 = max(0.01, light.attenuation.x + lenToPos * light.attenuation.y +
+      lenToPos * lenToPos * light.attenuation.z);
+    vec3 radiance = light.intensity.rgb * shadowFactor / lenToPos2;
+
+    float normalDView = abs(dot(normal, viewDir)) + 1e-5;
+    float normalDHalf = clamp(dot(normal, halfDir), 0, 1);
+    float normalDLight = clamp(dot(normal, lightDir), 0, 1);
+    float viewDHalf = clamp(dot(viewDir, halfDir), 0, 1);
+
+    float Fc = F_Schlick(viewDHalf, vec3(0.04)).x * clearcoat;
+    float D = ndfGGX(normalDHalf, roughness);
+    float V = gaSchlickGGX(normalDView, normalDLight, roughness);
+    float Ks = (D * V * Fc) / (4.0 * normalDView * normalDLight + 0.01);
+    return baseColor * (1.0 - Fc) + Ks * radiance * normalDLight;
+}
+
+// clearcoatIBL adds clearcoat reflection of environment on top of base color
+vec3 clearcoatIBL(vec3 normal, vec3 viewDir, vec3 baseColor, vec3 envColor, float clearcoat) {
+    float normalDView = max(dot(normal, viewDir), 0.0);
+    float Fc = F_Schlick(normalDView, vec3(0.04)).x * clearcoat;
+    return baseColor * (1.0 - Fc) + envColor * Fc;
+}
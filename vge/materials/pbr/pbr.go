@@ -7,6 +7,7 @@ import (
 	"unsafe"
 
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/materials/unlit"
 	"github.com/lakal3/vge/vge/vk"
 	"github.com/lakal3/vge/vge/vmodel"
 	"github.com/lakal3/vge/vge/vscene"
@@ -14,10 +15,14 @@ import (
 
 func PbrFactory(ctx vk.APIContext, dev *vk.Device, props vmodel.MaterialProperties) (
 	sh vmodel.Shader, layout *vk.DescriptorLayout, ubf []byte, images []vmodel.ImageIndex) {
+	if props.GetFactor(vmodel.FUnlit, 0) > 0 {
+		return unlit.UnlitFactory(ctx, dev, props)
+	}
 	tx_diffuse := props.GetImage(vmodel.TxAlbedo)
 	tx_metalRoughness := props.GetImage(vmodel.TxMetallicRoughness)
 	tx_emissive := props.GetImage(vmodel.TxEmissive)
 	tx_normal := props.GetImage(vmodel.TxBump)
+	tx_occlusion := props.GetImage(vmodel.TxOcclusion)
 	mf := float32(0)
 	if tx_metalRoughness != 0 {
 		mf = 1
@@ -27,13 +32,22 @@ func PbrFactory(ctx vk.APIContext, dev *vk.Device, props vmodel.MaterialProperti
 		emissiveColor:   props.GetColor(vmodel.CEmissive, getColorFactor(tx_emissive)),
 		metalnessFactor: props.GetFactor(vmodel.FMetalness, mf),
 		roughnessFactor: props.GetFactor(vmodel.FRoughness, 1),
+		clearcoat:       props.GetFactor(vmodel.FClearcoat, 0),
+		clearcoatRough:  props.GetFactor(vmodel.FClearcoatRoughness, 0),
 	}
 	if tx_normal > 0 {
 		ub.normalMap = 1
 	}
+	if tx_occlusion > 0 {
+		ub.occlusionStrength = props.GetFactor(vmodel.FOcclusionStrength, 1)
+	}
+	es := props.GetFactor(vmodel.FEmissiveStrength, 1)
+	ub.emissiveColor = mgl32.Vec4{ub.emissiveColor[0] * es, ub.emissiveColor[1] * es, ub.emissiveColor[2] * es, ub.emissiveColor[3]}
+	tr := props.GetTransform(vmodel.XUVTransform)
+	ub.uvTransform0, ub.uvTransform1 = tr.Row(0).Vec4(0), tr.Row(1).Vec4(0)
 
 	b := *(*[unsafe.Sizeof(pbrMaterial{})]byte)(unsafe.Pointer(&ub))
-	return &PbrMaterial{}, getPbrLayout(ctx, dev), b[:], []vmodel.ImageIndex{tx_diffuse, tx_normal, tx_metalRoughness, tx_emissive}
+	return &PbrMaterial{blend: props.GetFactor(vmodel.FAlphaBlend, 0) > 0}, getPbrLayout(ctx, dev), b[:], []vmodel.ImageIndex{tx_diffuse, tx_normal, tx_metalRoughness, tx_emissive, tx_occlusion}
 }
 
 func getColorFactor(imIndex vmodel.ImageIndex) mgl32.Vec4 {
@@ -45,6 +59,20 @@ func getColorFactor(imIndex vmodel.ImageIndex) mgl32.Vec4 {
 
 type PbrMaterial struct {
 	dsMat *vk.DescriptorSet
+	blend bool
+}
+
+// Transparent returns true if material is alpha blended (see vmodel.FAlphaBlend)
+func (u *PbrMaterial) Transparent(frame vmodel.Frame) bool {
+	return u.blend
+}
+
+// pipelineKeys returns keys of normal and skinned pipeline of material
+func (u *PbrMaterial) pipelineKeys() (normal vk.Key, skinned vk.Key) {
+	if u.blend {
+		return kPbrBlendPipeline, kPbrSkinnedBlendPipeline
+	}
+	return kPbrPipeline, kPbrSkinnedPipeline
 }
 
 func (u *PbrMaterial) SetDescriptor(dsMat *vk.DescriptorSet) {
@@ -59,7 +87,8 @@ func (u *PbrMaterial) DrawSkinned(dc *vmodel.DrawContext, mesh vmodel.Mesh, worl
 		return
 	}
 	rc := ff.GetCache()
-	gp := dc.Pass.Get(rc.Ctx, kPbrSkinnedPipeline, func(ctx vk.APIContext) interface{} {
+	_, key := u.pipelineKeys()
+	gp := dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
 		return u.NewPipeline(ctx, dc, true)
 	}).(*vk.GraphicsPipeline)
 	uc := vscene.GetUniformCache(rc)
@@ -68,11 +97,12 @@ func (u *PbrMaterial) DrawSkinned(dc *vmodel.DrawContext, mesh vmodel.Mesh, worl
 		ds, sl := uc.Alloc(ctx)
 		return &pbrInstance{ds: ds, sl: sl}
 	}).(*pbrInstance)
-	copy(uli.sl.Content[uli.count*64:uli.count*64+64], vk.Float32ToBytes(world[:]))
+	uli.writeInstance(world, noTint)
 	dsMesh, slMesh := uc.Alloc(rc.Ctx)
 	copy(slMesh.Content, vscene.Mat4ToBytes(aniMatrix))
+	vmodel.WriteMorph(slMesh.Content, mesh, vmodel.GetMorphWeights(extra))
 	dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindSkinned)...).
-		AddDescriptors(dsFrame, uli.ds, u.dsMat, dsMesh).SetInstances(uli.count, 1)
+		AddDescriptors(dsFrame, uli.ds, u.dsMat, dsMesh, mesh.Model.MorphDescriptor()).SetInstances(uli.count, 1)
 	uli.count++
 	if uli.count >= 200 {
 		rc.SetPerFrame(kPbrSkinnedInstances, nil)
@@ -86,7 +116,8 @@ func (u *PbrMaterial) Draw(dc *vmodel.DrawContext, mesh vmodel.Mesh, world mgl32
 		return
 	}
 	rc := ff.GetCache()
-	gp := dc.Pass.Get(rc.Ctx, kPbrPipeline, func(ctx vk.APIContext) interface{} {
+	key, _ := u.pipelineKeys()
+	gp := dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
 		return u.NewPipeline(ctx, dc, false)
 	}).(*vk.GraphicsPipeline)
 	uc := vscene.GetUniformCache(rc)
@@ -95,7 +126,7 @@ func (u *PbrMaterial) Draw(dc *vmodel.DrawContext, mesh vmodel.Mesh, world mgl32
 		ds, sl := uc.Alloc(ctx)
 		return &pbrInstance{ds: ds, sl: sl}
 	}).(*pbrInstance)
-	copy(uli.sl.Content[uli.count*64:uli.count*64+64], vk.Float32ToBytes(world[:]))
+	uli.writeInstance(world, noTint)
 	dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
 		AddDescriptors(dsFrame, uli.ds, u.dsMat).SetInstances(uli.count, 1)
 	uli.count++
@@ -104,6 +135,40 @@ func (u *PbrMaterial) Draw(dc *vmodel.DrawContext, mesh vmodel.Mesh, world mgl32
 	}
 }
 
+// DrawInstanced draws all instances of mesh with as few draw calls as possible
+func (u *PbrMaterial) DrawInstanced(dc *vmodel.DrawContext, mesh vmodel.Mesh, instances []vmodel.Instance,
+	extra vmodel.ShaderExtra) {
+	ff, ok := dc.Frame.(forward.ForwardFrame)
+	if !ok {
+		// Unsupported
+		return
+	}
+	rc := ff.GetCache()
+	key, _ := u.pipelineKeys()
+	gp := dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
+		return u.NewPipeline(ctx, dc, false)
+	}).(*vk.GraphicsPipeline)
+	uc := vscene.GetUniformCache(rc)
+	dsFrame := ff.BindForwardFrame()
+	for len(instances) > 0 {
+		uli := rc.GetPerFrame(kPbrInstances, func(ctx vk.APIContext) interface{} {
+			ds, sl := uc.Alloc(ctx)
+			return &pbrInstance{ds: ds, sl: sl}
+		}).(*pbrInstance)
+		first := uli.count
+		for len(instances) > 0 && uli.count < 200 {
+			uli.writeInstance(instances[0].World, instances[0].Color)
+			uli.count++
+			instances = instances[1:]
+		}
+		dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
+			AddDescriptors(dsFrame, uli.ds, u.dsMat).SetInstances(first, uli.count-first)
+		if uli.count >= 200 {
+			rc.SetPerFrame(kPbrInstances, nil)
+		}
+	}
+}
+
 func (u *PbrMaterial) NewPipeline(ctx vk.APIContext, dc *vmodel.DrawContext, skinned bool) *vk.GraphicsPipeline {
 	rc := dc.Frame.GetCache()
 	gp := vk.NewGraphicsPipeline(ctx, rc.Device)
@@ -123,20 +188,33 @@ func (u *PbrMaterial) NewPipeline(ctx vk.APIContext, dc *vmodel.DrawContext, ski
 	gp.AddLayout(ctx, la)
 	gp.AddLayout(ctx, la2)
 	if skinned {
-		gp.AddLayout(ctx, laUBF) // Transform matrix
+		gp.AddLayout(ctx, laUBF)                                 // Transform matrix
+		gp.AddLayout(ctx, vmodel.GetMorphLayout(ctx, rc.Device)) // Morph targets
 	}
 	gp.AddShader(ctx, vk.SHADERStageFragmentBit, pbr_frag_spv)
-	gp.AddDepth(ctx, true, true)
+	if u.blend {
+		gp.AddAlphaBlend(ctx)
+		gp.AddDepth(ctx, false, true)
+	} else {
+		gp.AddDepth(ctx, true, true)
+	}
 	gp.Create(ctx, dc.Pass)
 	return gp
 }
 
 type pbrMaterial struct {
-	albedoColor     mgl32.Vec4
-	emissiveColor   mgl32.Vec4
-	metalnessFactor float32
-	roughnessFactor float32
-	normalMap       float32
+	albedoColor       mgl32.Vec4
+	emissiveColor     mgl32.Vec4
+	metalnessFactor   float32
+	roughnessFactor   float32
+	normalMap         float32
+	dummy1            float32
+	uvTransform0      mgl32.Vec4
+	uvTransform1      mgl32.Vec4
+	occlusionStrength float32
+	clearcoat         float32
+	clearcoatRough    float32
+	dummy2            float32
 }
 
 type pbrInstance struct {
@@ -145,15 +223,28 @@ type pbrInstance struct {
 	count uint32
 }
 
+// colorOffset is offset of instance colors after world matrices. See pbr.vert.glsl
+const colorOffset = 256 * 64
+
+// noTint is instance color of meshes drawn without instancing
+var noTint = mgl32.Vec4{1, 1, 1, 1}
+
+func (i *pbrInstance) writeInstance(world mgl32.Mat4, color mgl32.Vec4) {
+	copy(i.sl.Content[i.count*64:i.count*64+64], vk.Float32ToBytes(world[:]))
+	copy(i.sl.Content[colorOffset+i.count*16:colorOffset+i.count*16+16], vk.Float32ToBytes(color[:]))
+}
+
 var kPbrLayout = vk.NewKey()
 var kPbrPipeline = vk.NewKey()
 var kPbrSkinnedPipeline = vk.NewKey()
+var kPbrBlendPipeline = vk.NewKey()
+var kPbrSkinnedBlendPipeline = vk.NewKey()
 var kPbrInstances = vk.NewKey()
 var kPbrSkinnedInstances = vk.NewKey()
 
 func getPbrLayout(ctx vk.APIContext, dev *vk.Device) *vk.DescriptorLayout {
 	la := vscene.GetUniformLayout(ctx, dev)
 	return dev.Get(ctx, kPbrLayout, func(ctx vk.APIContext) interface{} {
-		return la.AddBinding(ctx, vk.DESCRIPTORTypeCombinedImageSampler, vk.SHADERStageFragmentBit, 4)
+		return la.AddBinding(ctx, vk.DESCRIPTORTypeCombinedImageSampler, vk.SHADERStageFragmentBit, 5)
 	}).(*vk.DescriptorLayout)
 }
@@ -6,15 +6,18 @@
 
 layout(set = 1, binding = 0) uniform INSTANCE {
     mat4 world[256];
+    vec4 color[256];
 } instance;
 
 #ifdef SKINNED
+#define MORPH_SET 4
 #include "../../vscene/skin.glsl"
 #endif
 
 layout(location = 0) out vec3 o_position;
 layout(location = 1) out vec2 o_UV0;
 layout(location = 2) out mat3 o_normalSpace;
+layout(location = 5) out vec4 o_color;
 
 out gl_PerVertex
 {
@@ -24,10 +27,16 @@ out gl_PerVertex
 void main() {
     mat4 world = instance.world[gl_InstanceIndex];
     #ifdef SKINNED
+    vec3 position, normal, tangent;
+    morphVertex(position, normal, tangent);
     world = world * skinMatrix();
+    o_normalSpace = calcNormalSpaceOf(world, normal, tangent);
+    #else
+    vec3 position = i_position;
+    o_normalSpace = calcNormalSpace(world);
     #endif
     o_UV0 = i_uv0;
-    gl_Position = frame.projection * frame.view * world * vec4(i_position, 1.0);
-    o_position = vec3(world * vec4(i_position, 1.0));
-    o_normalSpace = calcNormalSpace(world);
+    o_color = instance.color[gl_InstanceIndex];
+    gl_Position = frame.projection * frame.view * world * vec4(position, 1.0);
+    o_position = vec3(world * vec4(position, 1.0));
 }
@@ -14,12 +14,18 @@ type PreDepthPass struct {
 	Cmd       *vk.Command
 	BindFrame func() *vk.DescriptorSet
 	OnBegin   func()
+	culler    vscene.ViewCuller
 }
 
 func (p *PreDepthPass) GetCache() *vk.RenderCache {
 	return p.DC.Frame.GetCache()
 }
 
+// Visible culls objects outside of camera view
+func (p *PreDepthPass) Visible(aabb vmodel.AABB) bool {
+	return p.culler.Visible(p.DC.Frame, aabb)
+}
+
 func (p *PreDepthPass) Begin() (atEnd func()) {
 	if p.OnBegin != nil {
 		p.OnBegin()
@@ -51,6 +57,34 @@ func (p *PreDepthPass) DrawShadow(mesh vmodel.Mesh, world mgl32.Mat4, material v
 	}
 }
 
+// DrawShadowInstanced draws depth of all instances with as few draw calls as possible
+func (p *PreDepthPass) DrawShadowInstanced(mesh vmodel.Mesh, instances []vmodel.Instance, material vmodel.Shader) {
+	rc := p.DC.Frame.GetCache()
+	gp := p.DC.Pass.Get(rc.Ctx, kPreDepthPipeline, func(ctx vk.APIContext) interface{} {
+		return p.newPipeline(ctx, false)
+	}).(*vk.GraphicsPipeline)
+	uc := vscene.GetUniformCache(rc)
+	dsWorld := p.BindFrame()
+	for len(instances) > 0 {
+		uli := rc.GetPerFrame(kPreDepthInstances, func(ctx vk.APIContext) interface{} {
+			ds, sl := uc.Alloc(ctx)
+			return &preDepthInstances{ds: ds, sl: sl}
+		}).(*preDepthInstances)
+		first := uli.count
+		for len(instances) > 0 && uli.count < 200 {
+			world := instances[0].World
+			copy(uli.sl.Content[uli.count*64:uli.count*64+64], vk.Float32ToBytes(world[:]))
+			uli.count++
+			instances = instances[1:]
+		}
+		p.DC.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
+			AddDescriptors(dsWorld, uli.ds).SetInstances(first, uli.count-first)
+		if uli.count >= 200 {
+			rc.SetPerFrame(kPreDepthInstances, nil)
+		}
+	}
+}
+
 func (p *PreDepthPass) DrawSkinnedShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader, aniMatrix []mgl32.Mat4) {
 	rc := p.DC.Frame.GetCache()
 	gp := p.DC.Pass.Get(rc.Ctx, kPreDepthSkinnedPipeline, func(ctx vk.APIContext) interface{} {
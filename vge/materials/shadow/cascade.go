@@ -0,0 +1,299 @@
+package shadow
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+// cascade is placement of one cascade shadow map
+type cascade struct {
+	// Origin of cascades orthographic projection. Origin is on light side of cascade area
+	origin mgl32.Vec3
+	// Half size of area (in world units) covered by cascade
+	area float32
+	// Depth range of projection
+	depth float32
+	// Distances from eye to splits where cascade starts and ends
+	near float32
+	far  float32
+}
+
+// Per renderer resources of cascaded shadow maps. Images, framebuffers and descriptors are double buffered
+type cascadeResources struct {
+	pool        *vk.MemoryPool
+	rp          *vk.GeneralRenderPass
+	sampler     *vk.Sampler
+	dpFrame     *vk.DescriptorPool
+	views       [2][]*vk.ImageView
+	fbs         [2][]*vk.Framebuffer
+	dsFrame     [2][]*vk.DescriptorSet
+	slFrame     [2][]*vk.Slice
+	cascades    [2][]cascade
+	lastImage   int
+	updateCount int
+}
+
+func (cr *cascadeResources) Dispose() {
+	for idx := 0; idx < 2; idx++ {
+		for _, fb := range cr.fbs[idx] {
+			fb.Dispose()
+		}
+		for _, v := range cr.views[idx] {
+			v.Dispose()
+		}
+		cr.fbs[idx], cr.views[idx], cr.dsFrame[idx], cr.slFrame[idx] = nil, nil, nil, nil
+	}
+	if cr.pool != nil {
+		cr.pool.Dispose()
+		cr.pool = nil
+	}
+	if cr.dpFrame != nil {
+		cr.dpFrame.Dispose()
+		cr.dpFrame = nil
+	}
+}
+
+// SetCascades enables cascaded shadow maps. Count is number of cascades and splitLambda blends split distances between
+// uniform (0) and logarithmic (1) splits. Optional mapSizes sets size of each cascade map. If mapSizes are not given,
+// all cascades use map size given in NewDirectionalLight. Cascades must be set before light is rendered first time.
+func (pl *DirectionalLight) SetCascades(count int, splitLambda float32, mapSizes ...uint32) *DirectionalLight {
+	pl.Cascades, pl.SplitLambda, pl.CascadeMapSizes = count, splitLambda, mapSizes
+	return pl
+}
+
+// SetCascadeBlend sets CascadeBlend
+func (pl *DirectionalLight) SetCascadeBlend(blend float32) *DirectionalLight {
+	pl.CascadeBlend = blend
+	return pl
+}
+
+func (pl *DirectionalLight) cascadeMapSize(index int) uint32 {
+	if index < len(pl.CascadeMapSizes) && pl.CascadeMapSizes[index] > 0 {
+		return pl.CascadeMapSizes[index]
+	}
+	return pl.mapSize
+}
+
+func (pl *DirectionalLight) cascadeBlend() float32 {
+	if pl.CascadeBlend < 0.001 {
+		return 0.001
+	}
+	if pl.CascadeBlend > 0.5 {
+		return 0.5
+	}
+	return pl.CascadeBlend
+}
+
+func (pl *DirectionalLight) processCascades(pi *vscene.ProcessInfo) {
+	pd, ok := pi.Phase.(*vscene.PredrawPhase)
+	if ok {
+		_, ok := pi.Frame.(vscene.ImageFrame)
+		if !ok {
+			return
+		}
+		crs := pi.Frame.GetRenderer().GetPerRenderer(pl.key, func(ctx vk.APIContext) interface{} {
+			return pl.makeCascadeResources(ctx, pi.Frame.GetCache().Device)
+		}).(*cascadeResources)
+		if crs.updateCount > 0 {
+			crs.updateCount--
+		} else {
+			pl.renderCascades(pd, pi, crs)
+		}
+	}
+
+	lp, ok := pi.Phase.(vscene.LightPhase)
+	if ok {
+		crs := pi.Frame.GetRenderer().GetPerRenderer(pl.key, func(ctx vk.APIContext) interface{} {
+			return pl.makeCascadeResources(ctx, pi.Frame.GetCache().Device)
+		}).(*cascadeResources)
+		lights := pl.cascadeLights(pi.Frame, crs)
+		if lights == nil {
+			// No shadow maps available. Add light without shadows
+			lights = []vscene.Light{{Intensity: pl.Intensity.Vec4(1), Direction: pl.Direction.Vec4(0), Attenuation: mgl32.Vec4{1, 0, 0, 0}}}
+		}
+		for _, l := range lights {
+			lp.AddLight(l, lp)
+		}
+	}
+}
+
+// cascadeLights returns one light for each cascade. Each light will only lit area covered by it's cascade
+// so that together cascade lights lit whole scene once
+func (pl *DirectionalLight) cascadeLights(f vmodel.Frame, crs *cascadeResources) []vscene.Light {
+	imFrame, ok := f.(vscene.ImageFrame)
+	if !ok || crs.lastImage < 0 {
+		return nil
+	}
+	dir := pl.Direction.Normalize()
+	plane := QuoternionFromYUp(dir)
+	blend := pl.cascadeBlend()
+	cascades := crs.cascades[crs.lastImage]
+	var lights []vscene.Light
+	for idx, c := range cascades {
+		imIndex := imFrame.AddFrameImage(crs.views[crs.lastImage][idx], crs.sampler)
+		if imIndex <= 0 {
+			return nil
+		}
+		l := vscene.Light{Intensity: pl.Intensity.Vec4(blend), Direction: dir.Vec4(c.depth),
+			Attenuation: mgl32.Vec4{1, 0, 0, c.area}, Position: c.origin.Vec4(0),
+			InnerAngle: c.near, OuterAngle: c.far, ShadowPlane: plane, ShadowMapIndex: float32(imIndex)}
+		l.SetShadowMethod(6, pl.Filter.Mode, pl.Filter.Size)
+		if idx == len(cascades)-1 {
+			// Last cascade fades to fully lit
+			l.Intensity[3] = -blend
+		}
+		lights = append(lights, l)
+	}
+	return lights
+}
+
+func (pl *DirectionalLight) renderCascades(pd *vscene.PredrawPhase, pi *vscene.ProcessInfo, crs *cascadeResources) {
+	cascades := pl.calcCascades(pi.Frame, pl.Cascades)
+	if len(cascades) == 0 {
+		return
+	}
+	cache := pi.Frame.GetCache()
+	sr := cache.Get(pl.key, func(ctx vk.APIContext) interface{} {
+		return makeDirResources(ctx, cache.Device, crs.rp)
+	}).(*dirResources)
+	gpl := crs.rp.Get(cache.Ctx, kDirDepthPipeline, func(ctx vk.APIContext) interface{} {
+		return pl.makeShadowPipeline(ctx, cache.Device, crs.rp)
+	}).(*vk.GraphicsPipeline)
+	gSkinnedPl := crs.rp.Get(cache.Ctx, kDirSkinnedDepthPipeline, func(ctx vk.APIContext) interface{} {
+		return pl.makeSkinnedShadowPipeline(ctx, cache.Device, crs.rp)
+	}).(*vk.GraphicsPipeline)
+	imageIndex := crs.lastImage + 1
+	if imageIndex >= 2 {
+		imageIndex = 0
+	}
+	cmd := sr.cmd
+	cmd.Begin()
+	for idx, c := range cascades {
+		cmd.BeginRenderPass(crs.rp, crs.fbs[imageIndex][idx])
+		sp := &shadowPass{ctx: cache.Ctx, cmd: cmd, dl: &vk.DrawList{}, rc: cache, renderer: pi.Frame.GetRenderer(),
+			pl: gpl, plSkin: gSkinnedPl, maxDistance: c.depth, areaSize: c.area, sampler: crs.sampler,
+			dsFrame: crs.dsFrame[imageIndex][idx], slFrame: crs.slFrame[imageIndex][idx], directional: true}
+		sp.dir = pl.Direction.Normalize()
+		sp.pos = c.origin.Vec4(0)
+		pd.Scene.Process(pi.Time, sp, sp)
+		sp.flush()
+		cmd.EndRenderPass()
+	}
+	waitFor := cmd.SubmitForWait(1, vk.PIPELINEStageFragmentShaderBit)
+	pd.Pending = append(pd.Pending, func() {
+		pd.Needeed = append(pd.Needeed, waitFor)
+	})
+	crs.cascades[imageIndex] = cascades
+	crs.lastImage, crs.updateCount = imageIndex, pl.UpdateDelay
+}
+
+// calcCascades splits view frustum from near plane to MaxShadowDistance and fits a sphere around each split.
+// Cascade size only depends on projection so that cascades don't change size when camera moves or rotates.
+// Cascade centers are snapped to shadow map texels in light space to prevent shadow edges from shimmering.
+func (pl *DirectionalLight) calcCascades(f vmodel.Frame, count int) []cascade {
+	sf := vscene.GetSimpleFrame(f)
+	if sf == nil {
+		return nil
+	}
+	proj := sf.SSF.Projection
+	if proj[11] == 0 {
+		// Cascades are only supported with perspective projection
+		return nil
+	}
+	near, far := proj[14]/(proj[10]-1), proj[14]/(proj[10]+1)
+	if pl.MaxShadowDistance < far {
+		far = pl.MaxShadowDistance
+	}
+	if far <= near {
+		return nil
+	}
+	// Squared length of frustum diagonal at distance 1
+	diag2 := 1/(proj[0]*proj[0]) + 1/(proj[5]*proj[5])
+	inv := sf.SSF.View.Inv()
+	eye, forward := inv.Col(3).Vec3(), inv.Col(2).Vec3().Mul(-1).Normalize()
+	dir := pl.Direction.Normalize()
+	q := mgl32.QuatBetweenVectors(mgl32.Vec3{0, 1, 0}, dir).Normalize()
+	qInv := q.Conjugate()
+	cascades := make([]cascade, count)
+	prev := near
+	for idx := range cascades {
+		split := SplitDistance(near, far, pl.SplitLambda, idx+1, count)
+		// Sphere around frustum slice from prev to split
+		n, s := prev, split
+		center := (1 + diag2) * (s + n) / 2
+		if center > s {
+			center = s
+		}
+		radius := float32(math.Sqrt(float64((center-n)*(center-n) + n*n*diag2)))
+		rFar := float32(math.Sqrt(float64((s-center)*(s-center) + s*s*diag2)))
+		if rFar > radius {
+			radius = rFar
+		}
+		// Round radius up a bit so that small changes in projection don't change texel size
+		radius = float32(math.Ceil(float64(radius)*16)) / 16
+		texel := 2 * radius / float32(pl.cascadeMapSize(idx))
+		cp := qInv.Rotate(eye.Add(forward.Mul(center)))
+		cp[0] = float32(math.Floor(float64(cp[0]/texel))) * texel
+		cp[2] = float32(math.Floor(float64(cp[2]/texel))) * texel
+		front := radius
+		if front < pl.MaxShadowDistance*0.5 {
+			front = pl.MaxShadowDistance * 0.5
+		}
+		cascades[idx] = cascade{origin: q.Rotate(cp).Sub(dir.Mul(front)), area: radius, depth: front + radius,
+			near: prev, far: split}
+		if idx == 0 {
+			cascades[idx].near = 0
+		}
+		prev = split
+	}
+	return cascades
+}
+
+// SplitDistance calculates distance of cascade split using practical split scheme. Lambda blends between
+// uniform (0) and logarithmic (1) splits.
+func SplitDistance(near, far float32, lambda float32, index int, count int) float32 {
+	f := float64(index) / float64(count)
+	log := float64(near) * math.Pow(float64(far/near), f)
+	uniform := float64(near) + float64(far-near)*f
+	return float32(float64(lambda)*log + float64(1-lambda)*uniform)
+}
+
+func (pl *DirectionalLight) makeCascadeResources(ctx vk.APIContext, dev *vk.Device) *cascadeResources {
+	crs := &cascadeResources{lastImage: -1}
+	crs.rp = makeRenderPass(ctx, dev)
+	crs.pool = vk.NewMemoryPool(dev)
+	var images [2][]*vk.Image
+	var buffers [2][]*vk.Buffer
+	for imIdx := 0; imIdx < 2; imIdx++ {
+		for idx := 0; idx < pl.Cascades; idx++ {
+			size := pl.cascadeMapSize(idx)
+			desc := vk.ImageDescription{Width: size, Height: size, Depth: 1, Layers: 1, MipLevels: 1,
+				Format: ShadowFormat}
+			images[imIdx] = append(images[imIdx], crs.pool.ReserveImage(ctx, desc, vk.IMAGEUsageDepthStencilAttachmentBit|
+				vk.IMAGEUsageSampledBit))
+			buffers[imIdx] = append(buffers[imIdx], crs.pool.ReserveBuffer(ctx, vk.MinUniformBufferOffsetAlignment, true,
+				vk.BUFFERUsageUniformBufferBit))
+		}
+	}
+	crs.dpFrame = vk.NewDescriptorPool(ctx, getShadowFrameLayout(ctx, dev), 2*pl.Cascades)
+	crs.pool.Allocate(ctx)
+	crs.sampler = vmodel.GetDefaultSampler(ctx, dev)
+	for imIdx := 0; imIdx < 2; imIdx++ {
+		for idx := 0; idx < pl.Cascades; idx++ {
+			ds := crs.dpFrame.Alloc(ctx)
+			sl := buffers[imIdx][idx].Slice(ctx, 0, vk.MinUniformBufferOffsetAlignment)
+			ds.WriteSlice(ctx, 0, 0, sl)
+			crs.dsFrame[imIdx] = append(crs.dsFrame[imIdx], ds)
+			crs.slFrame[imIdx] = append(crs.slFrame[imIdx], sl)
+			rg := vk.ImageRange{FirstLayer: 0, LayerCount: 1, LevelCount: 1}
+			view := vk.NewImageView(ctx, images[imIdx][idx], &rg)
+			crs.views[imIdx] = append(crs.views[imIdx], view)
+			crs.fbs[imIdx] = append(crs.fbs[imIdx], vk.NewFramebuffer(ctx, crs.rp, []*vk.ImageView{view}))
+		}
+	}
+	return crs
+}
@@ -0,0 +1,56 @@
+package shadow
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+func TestSplitDistance(t *testing.T) {
+	if d := SplitDistance(1, 100, 0, 1, 2); math.Abs(float64(d-50.5)) > 0.001 {
+		t.Error("Uniform split ", d)
+	}
+	if d := SplitDistance(1, 100, 1, 1, 2); math.Abs(float64(d-10)) > 0.001 {
+		t.Error("Logarithmic split ", d)
+	}
+	if d := SplitDistance(1, 100, 0.5, 2, 2); math.Abs(float64(d-100)) > 0.001 {
+		t.Error("Last split ", d)
+	}
+}
+
+func TestCalcCascades(t *testing.T) {
+	pl := NewDirectionalLight(vscene.DirectionalLight{Direction: mgl32.Vec3{0.3, -1, 0.2}}, 1024).SetCascades(3, 0.7)
+	sf := &vscene.SimpleFrame{}
+	sf.SSF.Projection = mgl32.Perspective(1, 1.5, 0.1, 1000)
+	sf.SSF.View = mgl32.LookAtV(mgl32.Vec3{1, 2, 3}, mgl32.Vec3{5, 1, 0}, mgl32.Vec3{0, 1, 0})
+	cs := pl.calcCascades(sf, pl.Cascades)
+	if len(cs) != 3 {
+		t.Fatal("Expected 3 cascades, got ", len(cs))
+	}
+	if cs[0].near != 0 || cs[2].far != pl.MaxShadowDistance || cs[1].near != cs[0].far {
+		t.Error("Invalid splits ", cs)
+	}
+	if cs[0].area >= cs[1].area || cs[1].area >= cs[2].area {
+		t.Error("Cascade areas should grow ", cs)
+	}
+
+	// Move and rotate camera. Cascade sizes must not change and origins must stay on texel grid
+	sf.SSF.View = mgl32.LookAtV(mgl32.Vec3{1.37, 2, 3.11}, mgl32.Vec3{-5, 1, 2}, mgl32.Vec3{0, 1, 0})
+	cs2 := pl.calcCascades(sf, pl.Cascades)
+	q := mgl32.QuatBetweenVectors(mgl32.Vec3{0, 1, 0}, pl.Direction.Normalize()).Normalize().Conjugate()
+	for idx := range cs {
+		if cs[idx].area != cs2[idx].area {
+			t.Error("Cascade size changed ", cs[idx].area, cs2[idx].area)
+		}
+		texel := 2 * cs[idx].area / 1024
+		d := q.Rotate(cs2[idx].origin.Sub(cs[idx].origin))
+		for _, v := range []float32{d[0], d[2]} {
+			f := float64(v / texel)
+			if math.Abs(f-math.Round(f)) > 0.01 {
+				t.Errorf("Cascade %d moved %f texels", idx, f)
+			}
+		}
+	}
+}
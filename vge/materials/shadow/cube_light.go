@@ -99,13 +99,38 @@ func (s *cubeShadowPass) DrawShadow(mesh vmodel.Mesh, world mgl32.Mat4, material
 	}
 }
 
+// DrawShadowInstanced draws all instances with as few draw calls as possible
+func (s *cubeShadowPass) DrawShadowInstanced(mesh vmodel.Mesh, instances []vmodel.Instance, material vmodel.Shader) {
+	for len(instances) > 0 {
+		s.BindFrame()
+		first := s.siCount
+		for len(instances) > 0 && s.siCount < maxInstances {
+			s.si.instances[s.siCount] = instances[0].World
+			s.siCount++
+			instances = instances[1:]
+		}
+		s.dl.DrawIndexed(s.pl, mesh.From, mesh.Count).AddDescriptors(s.ds).
+			AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).SetInstances(uint32(first), uint32(s.siCount-first))
+		if s.siCount >= maxInstances {
+			s.flush()
+		}
+	}
+}
+
 func (s *cubeShadowPass) DrawSkinnedShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader, aniMatrix []mgl32.Mat4) {
+	s.DrawMorphedShadow(mesh, world, material, aniMatrix, nil)
+}
+
+// DrawMorphedShadow draws skinned mesh with morph target weights. Nil weights uses default weights of mesh
+func (s *cubeShadowPass) DrawMorphedShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader, aniMatrix []mgl32.Mat4,
+	weights []float32) {
 	s.BindFrame()
 	uc := vscene.GetUniformCache(s.rc)
 	s.si.instances[s.siCount] = world
 	dsMesh, slMesh := uc.Alloc(s.ctx)
 	copy(slMesh.Content, vscene.Mat4ToBytes(aniMatrix))
-	s.dl.DrawIndexed(s.plSkin, mesh.From, mesh.Count).AddDescriptors(s.ds, dsMesh).
+	vmodel.WriteMorph(slMesh.Content, mesh, weights)
+	s.dl.DrawIndexed(s.plSkin, mesh.From, mesh.Count).AddDescriptors(s.ds, dsMesh, mesh.Model.MorphDescriptor()).
 		AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindSkinned)...).SetInstances(uint32(s.siCount), 1)
 	s.siCount++
 	if s.siCount >= maxInstances {
@@ -260,6 +285,7 @@ func (pl *CubePointLight) makeSkinnedShadowPipeline(ctx vk.APIContext, dev *vk.D
 	gp.AddDepth(ctx, true, true)
 	gp.AddLayout(ctx, vscene.GetUniformLayout(ctx, dev))
 	gp.AddLayout(ctx, vscene.GetUniformLayout(ctx, dev))
+	gp.AddLayout(ctx, vmodel.GetMorphLayout(ctx, dev))
 	gp.AddShader(ctx, vk.SHADERStageVertexBit, shadow_vert_skin_spv)
 	gp.AddShader(ctx, vk.SHADERStageGeometryBit, shadow_geom_spv)
 	gp.AddShader(ctx, vk.SHADERStageFragmentBit, shadow_frag_spv)
@@ -0,0 +1,85 @@
+package shadow
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// Visible culls objects outside of shadow map. Directional lights use box aligned to light direction and other lights
+// sphere of max shadow distance around light
+func (s *shadowPass) Visible(aabb vmodel.AABB) bool {
+	if s.directional {
+		return s.visibleDirectional(aabb)
+	}
+	return sphereIntersects(s.pos.Vec3(), s.maxDistance, aabb)
+}
+
+// visibleDirectional transforms corners of box to shadow map space like dir_shadow.vert.glsl. Objects between light and
+// shadow area are not culled because they may still cast shadow to area
+func (s *shadowPass) visibleDirectional(aabb vmodel.AABB) bool {
+	plane := QuoternionFromYUp(s.dir)
+	q := mgl32.Quat{W: plane[3], V: plane.Vec3()}.Conjugate()
+	var sp vmodel.AABB
+	for idx := 0; idx < 8; idx++ {
+		v := aabb.Min
+		if idx&1 == 1 {
+			v[0] = aabb.Max[0]
+		}
+		if idx&2 == 2 {
+			v[1] = aabb.Max[1]
+		}
+		if idx&4 == 4 {
+			v[2] = aabb.Max[2]
+		}
+		sp.Add(idx == 0, q.Rotate(v.Sub(s.pos.Vec3())))
+	}
+	area := s.areaSize
+	if area <= 0 {
+		area = s.maxDistance
+	}
+	return sp.Max[0] >= -area && sp.Min[0] <= area && sp.Max[2] >= -area && sp.Min[2] <= area && sp.Min[1] <= s.maxDistance
+}
+
+func (s staticOnly) Visible(aabb vmodel.AABB) bool {
+	return s.sp.Visible(aabb)
+}
+
+func (s *cubeShadowPass) Visible(aabb vmodel.AABB) bool {
+	return sphereIntersects(s.pos, s.maxDistance, aabb)
+}
+
+// sphereIntersects tests if closest point of box is inside sphere
+func sphereIntersects(center mgl32.Vec3, radius float32, aabb vmodel.AABB) bool {
+	var d2 float32
+	for idx := 0; idx < 3; idx++ {
+		if center[idx] < aabb.Min[idx] {
+			d := aabb.Min[idx] - center[idx]
+			d2 += d * d
+		} else if center[idx] > aabb.Max[idx] {
+			d := center[idx] - aabb.Max[idx]
+			d2 += d * d
+		}
+	}
+	return d2 <= radius*radius
+}
+
+// Cullable marks lights as controls that don't draw anything. Static subtrees containing lights can be culled
+func (pl *DirectionalLight) Cullable() bool {
+	return true
+}
+
+func (pl *PointLight) Cullable() bool {
+	return true
+}
+
+func (pl *SpotLight) Cullable() bool {
+	return true
+}
+
+func (pl *CubePointLight) Cullable() bool {
+	return true
+}
+
+func (n NoShadow) Cullable() bool {
+	return true
+}
@@ -21,7 +21,7 @@ type DirectionalLight struct {
 
 	// MaxShadowDistance determines how large area shadow map will cover. Everything outside if will be fully lit.
 	// Default size is 100 but should match to size of your scene.
-	// Currently VGE dont support multiple resolutions for depth map, but this may change in future.
+	// Use cascades to have multiple resolutions for depth map.
 	MaxShadowDistance float32
 
 	// CenterPoint tells where to place light shadow map. Even though directional lights don't have any place we must
@@ -35,6 +35,24 @@ type DirectionalLight struct {
 	// Number of frames to keep same shadow map
 	UpdateDelay int
 
+	// Filter used to sample shadow map
+	Filter Filter
+
+	// Cascades is number of cascaded shadow maps. If Cascades is 0, a single shadow map placed using CenterPoint is used.
+	// Cascades split view frustum from near plane to MaxShadowDistance so that closest cascade covers smallest area.
+	// Cascades are only supported with perspective projections.
+	Cascades int
+
+	// SplitLambda blends cascade split distances between uniform (0) and logarithmic (1) splits. Values around 0.5 - 0.9 works
+	// well for most scenes
+	SplitLambda float32
+
+	// CascadeMapSizes are optional map sizes for each cascade. Cascades without size use map size given in NewDirectionalLight
+	CascadeMapSizes []uint32
+
+	// CascadeBlend is fraction of split distance where cascade is blended with next one. Default is 0.1 (10%)
+	CascadeBlend float32
+
 	key     vk.Key
 	mapSize uint32
 }
@@ -51,6 +69,12 @@ func (pl *DirectionalLight) SetCenterPoint(centerPoint float32) *DirectionalLigh
 	return pl
 }
 
+// SetFilter sets filter mode and size used to sample shadow map
+func (pl *DirectionalLight) SetFilter(mode vscene.ShadowFilter, size float32) *DirectionalLight {
+	pl.Filter = Filter{Mode: mode, Size: size}
+	return pl
+}
+
 // SetUpdateDelay set delay between shadowmap updates. 0 - each frame, 1 - every second frame, 2 - every third frame...
 func (pl *DirectionalLight) SetUpdateDelay(delayFrames int) *DirectionalLight {
 	pl.UpdateDelay = delayFrames
@@ -61,10 +85,16 @@ func (pl *DirectionalLight) SetUpdateDelay(delayFrames int) *DirectionalLight {
 // Map size is size of shadow map used to sample lights distance to closest occluder
 // Higher resolutions give better quality of shadows but greatly increases memory usage in GPU
 func NewDirectionalLight(baseLight vscene.DirectionalLight, mapSize uint32) *DirectionalLight {
-	return &DirectionalLight{key: vk.NewKey(), DirectionalLight: baseLight, mapSize: mapSize, MaxShadowDistance: 100, CenterPoint: 0.5}
+	return &DirectionalLight{key: vk.NewKey(), DirectionalLight: baseLight, mapSize: mapSize, MaxShadowDistance: 100, CenterPoint: 0.5,
+		SplitLambda: 0.7, CascadeBlend: 0.1, Filter: DefaultFilter}
 }
 
 func (pl *DirectionalLight) Process(pi *vscene.ProcessInfo) {
+	pl.UpdateDirection(pi.World)
+	if pl.Cascades > 0 && pl.MaxShadowDistance > 0 {
+		pl.processCascades(pi)
+		return
+	}
 	pd, ok := pi.Phase.(*vscene.PredrawPhase)
 	if ok {
 		if pl.MaxShadowDistance == 0 {
@@ -80,10 +110,23 @@ func (pl *DirectionalLight) Process(pi *vscene.ProcessInfo) {
 		rsr := pi.Frame.GetRenderer().GetPerRenderer(pl.key, func(ctx vk.APIContext) interface{} {
 			return pl.makeRenderResources(ctx, pi.Frame.GetCache().Device)
 		}).(*renderResources)
+		casters := castAll
+		st := rsr.getStatic(pd.Scene, func() *renderResources {
+			return pl.makeRenderResources(pi.Frame.GetCache().Ctx, pi.Frame.GetCache().Device)
+		})
+		if st != nil {
+			// Shadow map follows camera. Static map is valid only as long as shadow map position remains same
+			casters = castDynamic
+			placement := lightPlacement(pl.GetShadowmapPos(pi.Frame), pl.Direction.Vec4(0), pl.MaxShadowDistance)
+			if !st.valid(pd.Scene.StaticVersion(), placement) {
+				pl.renderShadowMap(pd, pi, st.rsr, st.key, castStatic, eyePos)
+				st.version, st.placement = pd.Scene.StaticVersion(), placement
+			}
+		}
 		if rsr.updateCount > 0 {
 			rsr.updateCount--
 		} else {
-			pl.renderShadowMap(pd, pi, rsr, eyePos)
+			pl.renderShadowMap(pd, pi, rsr, pl.key, casters, eyePos)
 		}
 	}
 
@@ -95,14 +138,9 @@ func (pl *DirectionalLight) Process(pi *vscene.ProcessInfo) {
 		imFrame, ok := pi.Frame.(vscene.ImageFrame)
 		l := vscene.Light{Intensity: pl.Intensity.Vec4(1), Direction: pl.Direction.Vec4(0), Attenuation: mgl32.Vec4{1, 0, 0, pl.MaxShadowDistance}}
 		l.Position = pl.GetShadowmapPos(pi.Frame)
-		var imIndex vmodel.ImageIndex
-		if ok && rsr.lastImage >= 0 {
-			imIndex = imFrame.AddFrameImage(rsr.shadowImages[rsr.lastImage].DefaultView(pi.Frame.GetCache().Ctx), rsr.sampler)
-		}
-		if imIndex > 0 {
-			l.ShadowMapMethod = 5
+		if ok && rsr.addShadowMaps(imFrame, &l, shadowImageView(pi.Frame.GetCache().Ctx)) {
+			l.SetShadowMethod(5, pl.Filter.Mode, pl.Filter.Size)
 			l.ShadowPlane = QuoternionFromYUp(l.Direction.Vec3())
-			l.ShadowMapIndex = float32(imIndex)
 		}
 		lp.AddLight(l, lp)
 	}
@@ -137,6 +175,7 @@ type shadowPass struct {
 	imCount     uint32
 	renderer    vmodel.Renderer
 	maxDistance float32
+	areaSize    float32
 	pos         mgl32.Vec4
 	dir         mgl32.Vec3
 	yFactor     float32
@@ -146,6 +185,8 @@ type shadowPass struct {
 	dsInst      *vk.DescriptorSet
 	slInst      *vk.Slice
 	imMap       map[uintptr]uint32
+	casters     casterMode
+	directional bool
 }
 
 func (s *shadowPass) GetRenderer() vmodel.Renderer {
@@ -155,7 +196,7 @@ func (s *shadowPass) GetRenderer() vmodel.Renderer {
 func (s *shadowPass) BindFrame() *vk.DescriptorSet {
 	if s.siFrame == nil {
 		s.siFrame = &shaderFrame{lightPos: s.pos,
-			maxShadow: s.maxDistance, minShadow: 0}
+			maxShadow: s.maxDistance, minShadow: 0, areaSize: s.areaSize}
 		s.imCount = 1
 		if s.yFactor != 0 {
 			s.siFrame.yFactor = s.yFactor
@@ -192,13 +233,39 @@ func (s *shadowPass) DrawShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmo
 	}
 }
 
+// DrawShadowInstanced draws all instances with as few draw calls as possible
+func (s *shadowPass) DrawShadowInstanced(mesh vmodel.Mesh, instances []vmodel.Instance, material vmodel.Shader) {
+	for len(instances) > 0 {
+		inst := s.makeInstance(instances[0].World, mesh, material)
+		first := s.siCount
+		for len(instances) > 0 && s.siCount < maxInstances {
+			inst.world = instances[0].World
+			s.siInstance.instances[s.siCount] = inst
+			s.siCount++
+			instances = instances[1:]
+		}
+		s.dl.DrawIndexed(s.pl, mesh.From, mesh.Count).AddDescriptors(s.dsFrame, s.dsInst).
+			AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).SetInstances(uint32(first), uint32(s.siCount-first))
+		if s.siCount >= maxInstances {
+			s.flush()
+		}
+	}
+}
+
 func (s *shadowPass) DrawSkinnedShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader, aniMatrix []mgl32.Mat4) {
+	s.DrawMorphedShadow(mesh, world, material, aniMatrix, nil)
+}
+
+// DrawMorphedShadow draws skinned mesh with morph target weights. Nil weights uses default weights of mesh
+func (s *shadowPass) DrawMorphedShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader, aniMatrix []mgl32.Mat4,
+	weights []float32) {
 	_ = s.BindFrame()
 	uc := vscene.GetUniformCache(s.rc)
 	s.siInstance.instances[s.siCount] = s.makeInstance(world, mesh, material)
 	dsMesh, slMesh := uc.Alloc(s.ctx)
 	copy(slMesh.Content, vscene.Mat4ToBytes(aniMatrix))
-	s.dl.DrawIndexed(s.plSkin, mesh.From, mesh.Count).AddDescriptors(s.dsFrame, s.dsInst, dsMesh).
+	vmodel.WriteMorph(slMesh.Content, mesh, weights)
+	s.dl.DrawIndexed(s.plSkin, mesh.From, mesh.Count).AddDescriptors(s.dsFrame, s.dsInst, dsMesh, mesh.Model.MorphDescriptor()).
 		AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindSkinned)...).SetInstances(uint32(s.siCount), 1)
 	s.siCount++
 	if s.siCount >= maxInstances {
@@ -260,15 +327,16 @@ var kDirDepthPass = vk.NewKey()
 var kDirDepthPipeline = vk.NewKey()
 var kDirSkinnedDepthPipeline = vk.NewKey()
 
-func (pl *DirectionalLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.ProcessInfo, rsr *renderResources, eyePos mgl32.Vec3) {
+func (pl *DirectionalLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.ProcessInfo, rsr *renderResources,
+	key vk.Key, casters casterMode, eyePos mgl32.Vec3) {
 	cache := pi.Frame.GetCache()
-	sr := cache.Get(pl.key, func(ctx vk.APIContext) interface{} {
+	sr := cache.Get(key, func(ctx vk.APIContext) interface{} {
 		return makeDirResources(ctx, cache.Device, rsr.rp)
 	}).(*dirResources)
-	gpl := rsr.rp.Get(cache.Ctx, kDepthPipeline, func(ctx vk.APIContext) interface{} {
+	gpl := rsr.rp.Get(cache.Ctx, kDirDepthPipeline, func(ctx vk.APIContext) interface{} {
 		return pl.makeShadowPipeline(ctx, cache.Device, rsr.rp)
 	}).(*vk.GraphicsPipeline)
-	gSkinnedPl := rsr.rp.Get(cache.Ctx, kSkinnedDepthPipeline, func(ctx vk.APIContext) interface{} {
+	gSkinnedPl := rsr.rp.Get(cache.Ctx, kDirSkinnedDepthPipeline, func(ctx vk.APIContext) interface{} {
 		return pl.makeSkinnedShadowPipeline(ctx, cache.Device, rsr.rp)
 	}).(*vk.GraphicsPipeline)
 	cmd := sr.cmd
@@ -277,18 +345,18 @@ func (pl *DirectionalLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.
 	if imageIndex >= 2 {
 		imageIndex = 0
 	}
-	fbs := cache.GetPerFrame(pl.key, func(ctx vk.APIContext) interface{} {
+	fbs := cache.GetPerFrame(key, func(ctx vk.APIContext) interface{} {
 		return makeDirFrameResource(cache, rsr, imageIndex)
 	}).(*dirFrameResources)
 	fb := fbs.fb
 	cmd.BeginRenderPass(rsr.rp, fb)
 	sp := &shadowPass{ctx: cache.Ctx, cmd: cmd, dl: &vk.DrawList{}, rc: cache, renderer: pi.Frame.GetRenderer(),
 		pl: gpl, plSkin: gSkinnedPl, maxDistance: pl.MaxShadowDistance, sampler: rsr.sampler,
-		dsFrame: rsr.dsFrame[imageIndex], slFrame: rsr.slFrame[imageIndex]}
+		dsFrame: rsr.dsFrame[imageIndex], slFrame: rsr.slFrame[imageIndex], casters: casters, directional: true}
 	sp.dir = pl.Direction
 	sp.pos = pl.GetShadowmapPos(pi.Frame)
 
-	pd.Scene.Process(pi.Time, sp, sp)
+	pd.Scene.Process(pi.Time, sp, sp.phase())
 	sp.flush()
 	cmd.EndRenderPass()
 	waitFor := cmd.SubmitForWait(1, vk.PIPELINEStageFragmentShaderBit)
@@ -338,6 +406,7 @@ func (pl *DirectionalLight) makeSkinnedShadowPipeline(ctx vk.APIContext, dev *vk
 	gp.AddLayout(ctx, getShadowFrameLayout(ctx, dev))
 	gp.AddLayout(ctx, vscene.GetUniformLayout(ctx, dev))
 	gp.AddLayout(ctx, vscene.GetUniformLayout(ctx, dev))
+	gp.AddLayout(ctx, vmodel.GetMorphLayout(ctx, dev))
 	gp.AddShader(ctx, vk.SHADERStageVertexBit, dir_shadow_vert_skin_spv)
 	if vscene.FrameMaxDynamicSamplers > 0 {
 		gp.AddShader(ctx, vk.SHADERStageFragmentBit, point_shadow_dyn_frag_spv)
@@ -11,7 +11,8 @@ layout(location = 2) out flat int o_index;
 #include "shadowframe.glsl"
 
 #ifdef SKINNED
-#define SKIN_SET 1
+#define SKIN_SET 2
+#define MORPH_SET 3
 #include "../../vscene/skin.glsl"
 #endif
 
@@ -28,12 +29,17 @@ void main() {
     o_index = gl_InstanceIndex;
     mat4 world = instances.instances[gl_InstanceIndex].world;
     #ifdef SKINNED
+    vec3 position, normal, tangent;
+    morphVertex(position, normal, tangent);
     world = world * skinMatrix();
+    #else
+    vec3 position = i_position;
     #endif
     o_uv0 = i_uv0;
-    vec4 worldPos = world * vec4(i_position, 1);
+    vec4 worldPos = world * vec4(position, 1);
     vec3 samplePos = worldPos.xyz - frame.lightPos.xyz;
     o_position = qtransform(frame.plane, samplePos);
-    vec3 pos = o_position / vec3(frame.maxShadow);
+    float area = frame.areaSize > 0 ? frame.areaSize : frame.maxShadow;
+    vec3 pos = o_position / vec3(area, frame.maxShadow, area);
     gl_Position = vec4(centerAdjust(pos.x), centerAdjust(pos.z), pos.y, 1);
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+package shadow
+
+import "github.com/lakal3/vge/vge/vscene"
+
+// Filter selects how shadow map of light is sampled
+type Filter struct {
+	Mode vscene.ShadowFilter
+	// Size of filter. Meaning of size depends on filter mode, see vscene.ShadowFilter
+	Size float32
+}
+
+// DefaultFilter is filter used in shadow casting lights unless changed with SetFilter.
+// Default filter keeps shadow map method unchanged so lights render same way as before filters were added
+var DefaultFilter = Filter{Mode: vscene.ShadowFilterHard}
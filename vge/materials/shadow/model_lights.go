@@ -0,0 +1,31 @@
+package shadow
+
+import "github.com/lakal3/vge/vge/vscene"
+
+// AddShadows replaces all plain vscene.PointLight, vscene.SpotLight and vscene.DirectionalLight controls in node and its children
+// with shadow casting lights. Use AddShadows for example on scene nodes created from model with vscene.NodeFromModel.
+// Map size is size of shadow maps. Directional lights will have default MaxShadowDistance and CenterPoint.
+//
+// AddShadows should be called before node is added to live scene.
+func AddShadows(n *vscene.Node, mapSize uint32) {
+	n.Ctrl = shadowControl(n.Ctrl, mapSize)
+	for _, ch := range n.Children {
+		AddShadows(ch, mapSize)
+	}
+}
+
+func shadowControl(ctrl vscene.NodeControl, mapSize uint32) vscene.NodeControl {
+	switch c := ctrl.(type) {
+	case *vscene.PointLight:
+		return NewPointLight(*c, mapSize)
+	case *vscene.SpotLight:
+		return NewSpotLight(*c, mapSize)
+	case *vscene.DirectionalLight:
+		return NewDirectionalLight(*c, mapSize)
+	case *vscene.MultiControl:
+		for idx, mc := range c.Controls {
+			c.Controls[idx] = shadowControl(mc, mapSize)
+		}
+	}
+	return ctrl
+}
@@ -17,10 +17,19 @@ type PointLight struct {
 	// Number of frames to keep same shadow map
 	UpdateDelay int
 
+	// Filter used to sample shadow map
+	Filter Filter
+
 	key     vk.Key
 	mapSize uint32
 }
 
+// SetFilter sets filter mode and size used to sample shadow map
+func (pl *PointLight) SetFilter(mode vscene.ShadowFilter, size float32) *PointLight {
+	pl.Filter = Filter{Mode: mode, Size: size}
+	return pl
+}
+
 // SetUpdateDelay set delay between shadowmap updates. 0 - each frame, 1 - every second frame, 2 - every third frame...
 func (pl *PointLight) SetUpdateDelay(delayFrames int) *PointLight {
 	pl.UpdateDelay = delayFrames
@@ -39,6 +48,8 @@ type renderResources struct {
 	dpFrame      *vk.DescriptorPool
 	dsFrame      []*vk.DescriptorSet
 	slFrame      []*vk.Slice
+	// Static shadow map. Nil if scene has never had static nodes
+	static *staticCache
 }
 
 type plFrameResources struct {
@@ -65,6 +76,10 @@ func (r *renderResources) Dispose() {
 		r.dpFrame.Dispose()
 		r.dsFrame, r.dpFrame, r.slFrame = nil, nil, nil
 	}
+	if r.static != nil {
+		r.static.rsr.Dispose()
+		r.static = nil
+	}
 }
 
 type plResources struct {
@@ -93,7 +108,8 @@ type shaderFrame struct {
 	minShadow float32
 	maxShadow float32
 	yFactor   float32
-	dummy2    float32
+	// Half size of directional light shadow map area. If 0, maxShadow is used
+	areaSize float32
 }
 
 type shaderInstances struct {
@@ -130,7 +146,7 @@ var kFrameBuffer = vk.NewKeys(2)
 // NewPointLight will construct a point light that has a shadow. MapSize parameter sets size of parabloid shadow map.
 // Higher resolution will produce more accurate shadow but will have higher memory and GPU rendering cost.
 func NewPointLight(baseLight vscene.PointLight, mapSize uint32) *PointLight {
-	return &PointLight{key: vk.NewKey(), PointLight: baseLight, mapSize: mapSize}
+	return &PointLight{key: vk.NewKey(), PointLight: baseLight, mapSize: mapSize, Filter: DefaultFilter}
 }
 
 func (pl *PointLight) Process(pi *vscene.ProcessInfo) {
@@ -159,11 +175,24 @@ func (pl *PointLight) Process(pi *vscene.ProcessInfo) {
 			return pl.makeRenderResources(ctx, pi.Frame.GetCache().Device)
 		}).(*renderResources)
 
+		casters := castAll
+		st := rsr.getStatic(pd.Scene, func() *renderResources {
+			return pl.makeRenderResources(pi.Frame.GetCache().Ctx, pi.Frame.GetCache().Device)
+		})
+		if st != nil {
+			casters = castDynamic
+			placement := lightPlacement(pos, mgl32.Vec4{}, pl.MaxDistance)
+			if !st.valid(pd.Scene.StaticVersion(), placement) {
+				pl.renderShadowMap(pd, pi, st.rsr, st.key, castStatic, 0)
+				pl.renderShadowMap(pd, pi, st.rsr, st.key, castStatic, 1)
+				st.version, st.placement = pd.Scene.StaticVersion(), placement
+			}
+		}
 		if rsr.updateCount > 0 {
 			rsr.updateCount--
 		} else {
-			pl.renderShadowMap(pd, pi, rsr, 0)
-			pl.renderShadowMap(pd, pi, rsr, 1)
+			pl.renderShadowMap(pd, pi, rsr, pl.key, casters, 0)
+			pl.renderShadowMap(pd, pi, rsr, pl.key, casters, 1)
 		}
 	}
 
@@ -176,23 +205,22 @@ func (pl *PointLight) Process(pi *vscene.ProcessInfo) {
 		imFrame, ok := pi.Frame.(vscene.ImageFrame)
 		l := vscene.Light{Intensity: pl.Intensity.Vec4(1),
 			Position: pos, Attenuation: pl.Attenuation.Vec4(pl.MaxDistance)}
-		var imIndex vmodel.ImageIndex
-		var imIndex2 vmodel.ImageIndex
-		if ok && rsr.lastImage >= 0 {
-			imIndex = imFrame.AddFrameImage(rsr.shadowViews[rsr.lastImage*2+4], rsr.sampler)
-			imIndex2 = imFrame.AddFrameImage(rsr.shadowViews[rsr.lastImage*2+5], rsr.sampler)
-		}
-		if imIndex2 > 0 {
-			l.ShadowMapMethod = 3
-			l.ShadowMapIndex = float32(imIndex)
+		if ok && rsr.addShadowMaps(imFrame, &l, pl.shadowViews) {
+			l.SetShadowMethod(3, pl.Filter.Mode, pl.Filter.Size)
 		}
 		lp.AddLight(l, lp)
 	}
 }
 
-func (pl *PointLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.ProcessInfo, rsr *renderResources, side int) *plResources {
+// Both paraboloid maps of last rendered image
+func (pl *PointLight) shadowViews(rsr *renderResources) []*vk.ImageView {
+	return rsr.shadowViews[rsr.lastImage*2+4 : rsr.lastImage*2+6]
+}
+
+func (pl *PointLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.ProcessInfo, rsr *renderResources,
+	key vk.Key, casters casterMode, side int) *plResources {
 	cache := pi.Frame.GetCache()
-	sr := cache.Get(pl.key, func(ctx vk.APIContext) interface{} {
+	sr := cache.Get(key, func(ctx vk.APIContext) interface{} {
 		return pl.makeResources(ctx, cache.Device, rsr.rp)
 	}).(*plResources)
 	gpl := rsr.rp.Get(cache.Ctx, kDepthPipeline, func(ctx vk.APIContext) interface{} {
@@ -207,14 +235,14 @@ func (pl *PointLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.Proces
 	if imageIndex >= 2 {
 		imageIndex = 0
 	}
-	fbs := cache.GetPerFrame(pl.key, func(ctx vk.APIContext) interface{} {
+	fbs := cache.GetPerFrame(key, func(ctx vk.APIContext) interface{} {
 		return pl.makeFrameResource(cache, rsr, imageIndex)
 	}).(*plFrameResources)
 	fb := fbs.fbs[side]
 	cmd.BeginRenderPass(rsr.rp, fb)
 	sp := &shadowPass{ctx: cache.Ctx, cmd: cmd, dl: &vk.DrawList{}, maxDistance: pl.MaxDistance,
 		rc: cache, renderer: pi.Frame.GetRenderer(), pl: gpl, plSkin: gSkinnedPl, sampler: rsr.sampler,
-		dsFrame: rsr.dsFrame[imageIndex*2+side], slFrame: rsr.slFrame[2*imageIndex+side]}
+		dsFrame: rsr.dsFrame[imageIndex*2+side], slFrame: rsr.slFrame[2*imageIndex+side], casters: casters}
 	lightPos := pi.World.Mul4x1(mgl32.Vec4{0, 0, 0, 1})
 	sp.pos = lightPos
 	sp.yFactor = -1
@@ -222,7 +250,7 @@ func (pl *PointLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.Proces
 		sp.yFactor = 1
 	}
 
-	pd.Scene.Process(pi.Time, sp, sp)
+	pd.Scene.Process(pi.Time, sp, sp.phase())
 	sp.flush()
 	cmd.EndRenderPass()
 	waitFor := cmd.SubmitForWait(1, vk.PIPELINEStageFragmentShaderBit)
@@ -276,6 +304,7 @@ func makePlSkinnedShadowPipeline(ctx vk.APIContext, dev *vk.Device, rp *vk.Depth
 	gp.AddLayout(ctx, getShadowFrameLayout(ctx, dev))
 	gp.AddLayout(ctx, vscene.GetUniformLayout(ctx, dev))
 	gp.AddLayout(ctx, vscene.GetUniformLayout(ctx, dev))
+	gp.AddLayout(ctx, vmodel.GetMorphLayout(ctx, dev))
 	gp.AddShader(ctx, vk.SHADERStageVertexBit, point_shadow_vert_skin_spv)
 	if vscene.FrameMaxDynamicSamplers > 0 {
 		gp.AddShader(ctx, vk.SHADERStageFragmentBit, point_shadow_dyn_frag_spv)
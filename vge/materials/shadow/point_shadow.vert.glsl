@@ -12,6 +12,7 @@ layout(location = 2) out flat int o_index;
 
 #ifdef SKINNED
 #define SKIN_SET 2
+#define MORPH_SET 3
 #include "../../vscene/skin.glsl"
 #endif
 
@@ -23,10 +24,14 @@ void main() {
     o_index = gl_InstanceIndex;
     mat4 world = instances.instances[gl_InstanceIndex].world;
     #ifdef SKINNED
+    vec3 position, normal, tangent;
+    morphVertex(position, normal, tangent);
     world = world * skinMatrix();
+    #else
+    vec3 position = i_position;
     #endif
     o_uv0 = i_uv0;
-    vec4 worldPos = world * vec4(i_position, 1);
+    vec4 worldPos = world * vec4(position, 1);
     vec3 samplePos = worldPos.xyz - frame.lightPos.xyz;
     if (frame.yFactor != 0) {
         o_position = samplePos * vec3(1, frame.yFactor, 1);
@@ -12,6 +12,7 @@ layout(location = 1) out vec2 o_uv0;
 
 #ifdef SKINNED
 #define SKIN_SET 1
+#define MORPH_SET 2
 #include "../../vscene/skin.glsl"
 #endif
 
@@ -19,8 +20,12 @@ void main() {
     o_index = gl_InstanceIndex;
     mat4 world = frame.instances[gl_InstanceIndex];
     #ifdef SKINNED
+    vec3 position, normal, tangent;
+    morphVertex(position, normal, tangent);
     world = world * skinMatrix();
+    #else
+    vec3 position = i_position;
     #endif
     o_uv0 = i_uv0;
-    gl_Position = world * vec4(i_position, 1);
+    gl_Position = world * vec4(position, 1);
 }
\ No newline at end of file
@@ -16,7 +16,7 @@ layout(set=0, binding=0) uniform FRAME {
     float minShadow;
     float maxShadow;
     float yFactor;
-    float dummy2;
+    float areaSize; // Half size of directional light shadow area. If 0, maxShadow is used
 } frame;
 
 #ifdef DYNAMIC_DESCRIPTORS
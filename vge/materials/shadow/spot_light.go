@@ -11,7 +11,7 @@ import (
 // NewSpotLight will construct a spot light that has a shadow. MapSize parameter sets size of parabloid shadow map.
 // Higher resolution will produce more accurate shadow but will have higher memory and GPU rendering cost.
 func NewSpotLight(base vscene.SpotLight, mapSize uint32) *SpotLight {
-	return &SpotLight{SpotLight: base, key: vk.NewKey(), mapSize: mapSize}
+	return &SpotLight{SpotLight: base, key: vk.NewKey(), mapSize: mapSize, Filter: DefaultFilter}
 }
 
 type SpotLight struct {
@@ -20,10 +20,19 @@ type SpotLight struct {
 	// Number of frames to keep same shadow map
 	UpdateDelay int
 
+	// Filter used to sample shadow map
+	Filter Filter
+
 	key     vk.Key
 	mapSize uint32
 }
 
+// SetFilter sets filter mode and size used to sample shadow map
+func (pl *SpotLight) SetFilter(mode vscene.ShadowFilter, size float32) *SpotLight {
+	pl.Filter = Filter{Mode: mode, Size: size}
+	return pl
+}
+
 // SetUpdateDelay set delay between shadowmap updates. 0 - each frame, 1 - every second frame, 2 - every third frame...
 func (pl *SpotLight) SetUpdateDelay(delayFrames int) *SpotLight {
 	pl.UpdateDelay = delayFrames
@@ -55,10 +64,23 @@ func (pl *SpotLight) Process(pi *vscene.ProcessInfo) {
 		rsr := pi.Frame.GetRenderer().GetPerRenderer(pl.key, func(ctx vk.APIContext) interface{} {
 			return pl.makeRenderResources(ctx, pi.Frame.GetCache().Device)
 		}).(*renderResources)
+		casters := castAll
+		st := rsr.getStatic(pd.Scene, func() *renderResources {
+			return pl.makeRenderResources(pi.Frame.GetCache().Ctx, pi.Frame.GetCache().Device)
+		})
+		if st != nil {
+			casters = castDynamic
+			l := pl.AsStdLight(pi.World)
+			placement := lightPlacement(l.Position, l.Direction, pl.MaxDistance)
+			if !st.valid(pd.Scene.StaticVersion(), placement) {
+				pl.renderShadowMap(pd, pi, st.rsr, st.key, castStatic)
+				st.version, st.placement = pd.Scene.StaticVersion(), placement
+			}
+		}
 		if rsr.updateCount > 0 {
 			rsr.updateCount--
 		} else {
-			pl.renderShadowMap(pd, pi, rsr)
+			pl.renderShadowMap(pd, pi, rsr, pl.key, casters)
 		}
 	}
 
@@ -69,22 +91,18 @@ func (pl *SpotLight) Process(pi *vscene.ProcessInfo) {
 		}).(*renderResources)
 		imFrame, ok := pi.Frame.(vscene.ImageFrame)
 		l := pl.AsStdLight(pi.World)
-		var imIndex vmodel.ImageIndex
-		if ok && rsr.lastImage >= 0 {
-			imIndex = imFrame.AddFrameImage(rsr.shadowImages[rsr.lastImage].DefaultView(pi.Frame.GetCache().Ctx), rsr.sampler)
-		}
-		if imIndex >= 0 {
-			l.ShadowMapMethod = 4
-			l.ShadowMapIndex = float32(imIndex)
+		if ok && rsr.addShadowMaps(imFrame, &l, shadowImageView(pi.Frame.GetCache().Ctx)) {
+			l.SetShadowMethod(4, pl.Filter.Mode, pl.Filter.Size)
 			l.ShadowPlane = QuoternionFromYUp(l.Direction.Vec3())
 		}
 		lp.AddLight(l, lp)
 	}
 }
 
-func (pl *SpotLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.ProcessInfo, rsr *renderResources) *dirResources {
+func (pl *SpotLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.ProcessInfo, rsr *renderResources,
+	key vk.Key, casters casterMode) *dirResources {
 	cache := pi.Frame.GetCache()
-	sr := cache.Get(pl.key, func(ctx vk.APIContext) interface{} {
+	sr := cache.Get(key, func(ctx vk.APIContext) interface{} {
 		return makeDirResources(ctx, cache.Device, rsr.rp)
 	}).(*dirResources)
 	gpl := rsr.rp.Get(cache.Ctx, kDepthPipeline, func(ctx vk.APIContext) interface{} {
@@ -99,19 +117,19 @@ func (pl *SpotLight) renderShadowMap(pd *vscene.PredrawPhase, pi *vscene.Process
 	if imageIndex >= 2 {
 		imageIndex = 0
 	}
-	fbs := cache.GetPerFrame(pl.key, func(ctx vk.APIContext) interface{} {
+	fbs := cache.GetPerFrame(key, func(ctx vk.APIContext) interface{} {
 		return makeDirFrameResource(cache, rsr, imageIndex)
 	}).(*dirFrameResources)
 	fb := fbs.fb
 	cmd.BeginRenderPass(rsr.rp, fb)
 	sp := &shadowPass{ctx: cache.Ctx, cmd: cmd, dl: &vk.DrawList{}, maxDistance: pl.MaxDistance,
 		pl: gpl, plSkin: gSkinnedPl, rc: cache, renderer: pi.Frame.GetRenderer(), sampler: rsr.sampler,
-		dsFrame: rsr.dsFrame[imageIndex], slFrame: rsr.slFrame[imageIndex]}
+		dsFrame: rsr.dsFrame[imageIndex], slFrame: rsr.slFrame[imageIndex], casters: casters}
 	l := pl.AsStdLight(pi.World)
 	sp.pos = l.Position
 	sp.dir = l.Direction.Vec3()
 
-	pd.Scene.Process(pi.Time, sp, sp)
+	pd.Scene.Process(pi.Time, sp, sp.phase())
 	sp.flush()
 	cmd.EndRenderPass()
 	waitFor := cmd.SubmitForWait(1, vk.PIPELINEStageFragmentShaderBit)
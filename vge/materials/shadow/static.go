@@ -0,0 +1,129 @@
+package shadow
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+// casterMode selects shadow casters rendered by shadow pass
+type casterMode int
+
+const (
+	// All nodes cast shadow
+	castAll = casterMode(iota)
+	// Only dynamic nodes cast shadow. Static casters are in static shadow map
+	castDynamic
+	// Only static nodes (see vscene.Static) cast shadow
+	castStatic
+)
+
+// staticCache holds static shadow map of light. Static shadow map is rendered again only when static nodes of scene
+// change or light moves
+type staticCache struct {
+	key       vk.Key
+	rsr       *renderResources
+	active    bool
+	version   int
+	placement mgl32.Mat4
+}
+
+func (s *shadowPass) StaticPhase() vscene.Phase {
+	if s.casters == castDynamic {
+		return nil
+	}
+	return s
+}
+
+// phase returns phase used to process scene. Static shadow pass is hidden from dynamic nodes
+func (s *shadowPass) phase() vscene.Phase {
+	if s.casters == castStatic {
+		return staticOnly{sp: s}
+	}
+	return s
+}
+
+type staticOnly struct {
+	sp *shadowPass
+}
+
+func (s staticOnly) Begin() (atEnd func()) {
+	return nil
+}
+
+func (s staticOnly) StaticPhase() vscene.Phase {
+	return s.sp
+}
+
+// getStatic returns static shadow map cache or nil if scene has no static nodes
+func (r *renderResources) getStatic(sc *vscene.Scene, makeResources func() *renderResources) *staticCache {
+	active := sc.StaticNodes() > 0
+	if r.static == nil {
+		if !active {
+			return nil
+		}
+		r.static = &staticCache{key: vk.NewKey(), rsr: makeResources(), version: -1}
+	}
+	if r.static.active != active {
+		// Dynamic map must be rendered again with new set of shadow casters
+		r.static.active, r.updateCount = active, 0
+	}
+	if !active {
+		return nil
+	}
+	return r.static
+}
+
+// valid checks if static shadow map was rendered for current static version and light placement
+func (s *staticCache) valid(version int, placement mgl32.Mat4) bool {
+	return s.rsr.lastImage >= 0 && s.version == version && s.placement == placement
+}
+
+// lightPlacement combines light position, direction and range that determine content of shadow map
+func lightPlacement(pos mgl32.Vec4, dir mgl32.Vec4, maxDistance float32) mgl32.Mat4 {
+	return mgl32.Mat4FromCols(pos, dir, mgl32.Vec4{maxDistance, 0, 0, 0}, mgl32.Vec4{})
+}
+
+// addShadowMaps adds shadow map views and static shadow map views (if used) to frame and sets indexes to light.
+// Views of multi map lights must get consecutive indexes. Returns false if frame has no image slots for all maps
+func (r *renderResources) addShadowMaps(imFrame vscene.ImageFrame, l *vscene.Light,
+	views func(rsr *renderResources) []*vk.ImageView) bool {
+	index := addViews(imFrame, r, views)
+	if index <= 0 {
+		return false
+	}
+	staticIndex := vmodel.ImageIndex(-1)
+	if r.static != nil && r.static.active {
+		staticIndex = addViews(imFrame, r.static.rsr, views)
+		if staticIndex <= 0 {
+			// Shadow map without static casters is worse than no shadow at all
+			return false
+		}
+	}
+	return l.SetShadowMaps(index, staticIndex)
+}
+
+// shadowImageView returns default view of last rendered shadow image
+func shadowImageView(ctx vk.APIContext) func(rsr *renderResources) []*vk.ImageView {
+	return func(rsr *renderResources) []*vk.ImageView {
+		return []*vk.ImageView{rsr.shadowImages[rsr.lastImage].DefaultView(ctx)}
+	}
+}
+
+func addViews(imFrame vscene.ImageFrame, rsr *renderResources, views func(rsr *renderResources) []*vk.ImageView) vmodel.ImageIndex {
+	if rsr.lastImage < 0 {
+		return -1
+	}
+	first := vmodel.ImageIndex(-1)
+	for idx, view := range views(rsr) {
+		imIndex := imFrame.AddFrameImage(view, rsr.sampler)
+		if imIndex <= 0 {
+			return -1
+		}
+		if idx == 0 {
+			first = imIndex
+		}
+	}
+	return first
+}
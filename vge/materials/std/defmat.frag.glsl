@@ -9,6 +9,7 @@ layout(location = 2) out uvec4 o_Material;
 layout(location = 0) in vec3 i_position;
 layout(location = 1) in vec2 i_UV0;
 layout(location = 2) in mat3 i_normalSpace;
+layout(location = 5) in vec4 i_color;
 
 #include "../../deferred/frame.glsl"
 
@@ -19,23 +20,35 @@ layout(set = 2, binding = 0) uniform MATERIAL {
     float roughnessFactor;
     float normalMap;
     float alphaCutoff;
+    vec4 uvTransform0;
+    vec4 uvTransform1;
+    float occlusionStrength;
+    float clearcoat;          // Forward only, G-buffers have no room for clearcoat
+    float clearcoatRoughness;
 } material;
 
 #define TX_ALBEDO 0
 #define TX_NORMAL 1
 #define TX_METAL_ROUGHNESS 2
 #define TX_EMISSIVE 3
+#define TX_OCCLUSION 4
 
 
-layout(set=2, binding=1) uniform sampler2D textures[4];
+layout(set=2, binding=1) uniform sampler2D textures[5];
+
+// Texture coordinates with KHR_texture_transform
+vec2 getUV() {
+    vec3 uv = vec3(i_UV0, 1);
+    return vec2(dot(material.uvTransform0.xyz, uv), dot(material.uvTransform1.xyz, uv));
+}
 
 #include "../decal/decal.glsl"
 
 const float PI = 3.14159265;
-vec3 calcNormal() {
+vec3 calcNormal(vec2 uv) {
     vec3 bNormal = vec3(0, 0, 1);
     if (material.normalMap > 0.5) {
-        bNormal = (2 * texture( textures[TX_NORMAL], i_UV0).xyz) - vec3(1.0, 1.0, 1.0);
+        bNormal = (2 * texture( textures[TX_NORMAL], uv).xyz) - vec3(1.0, 1.0, 1.0);
     }
     return normalize(i_normalSpace * bNormal) * vec3(0.5, 0.5, 0.5) + vec3(0.5, 0.5, 0.5);
 }
@@ -43,13 +56,18 @@ vec3 calcNormal() {
 
 
 void main() {
-    vec4 albedoColor = material.albedoColor * texture( textures[TX_ALBEDO], i_UV0);
+    vec2 uv = getUV();
+    vec4 albedoColor = material.albedoColor * i_color * texture( textures[TX_ALBEDO], uv);
 
-    vec4 emissiveColor = vec4(material.emissiveColor * texture( textures[TX_EMISSIVE], i_UV0));
-    vec3 normal = calcNormal();
+    vec4 emissiveColor = vec4(material.emissiveColor * texture( textures[TX_EMISSIVE], uv));
+    vec3 normal = calcNormal(uv);
     vec3 refNormal = i_normalSpace * vec3(0,0,1.0);
     vec3 albedo = albedoColor.rgb;
-    vec3 mrColor = texture( textures[TX_METAL_ROUGHNESS], i_UV0).rgb;
+    vec3 mrColor = texture( textures[TX_METAL_ROUGHNESS], uv).rgb;
+    float occlusion = 1;
+    if (material.occlusionStrength > 0) {
+        occlusion = 1.0 + material.occlusionStrength * (texture(textures[TX_OCCLUSION], uv).r - 1.0);
+    }
     float metallic = mrColor.b * material.metallicFactor;
     float roughness = mrColor.g * material.roughnessFactor;
 
@@ -70,6 +88,7 @@ void main() {
         }
         o_Color = vec4(albedo, 1);
         o_Normal = vec4(normal, 1.0);
-        o_Material = uvec4(metallic * 255, roughness * 255, 1, 0);
+        // Alpha channel of material is ambient occlusion. 0 - no occlusion
+        o_Material = uvec4(metallic * 255, roughness * 255, 1, (1 - occlusion) * 255);
     }
 }
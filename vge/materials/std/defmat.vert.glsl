@@ -6,7 +6,7 @@
 
 #include "../../deferred/frame.glsl"
 
-#define MAX_INSTANCES 1000
+#define MAX_INSTANCES 680
 
 struct INSTANCE {
     mat4 world;
@@ -16,18 +16,21 @@ struct INSTANCE {
 
 layout(set = 1, binding = 0) uniform INSTANCES {
     INSTANCE inst[MAX_INSTANCES];
+    vec4 color[MAX_INSTANCES];
 } instances;
 
 #include "../decal/decal.glsl"
 
 #ifdef SKINNED
 #define SKIN_SET 4
+#define MORPH_SET 5
 #include "../../vscene/skin.glsl"
 #endif
 
 layout(location = 0) out vec3 o_position;
 layout(location = 1) out vec2 o_UV0;
 layout(location = 2) out mat3 o_normalSpace;
+layout(location = 5) out vec4 o_color;
 
 out gl_PerVertex
 {
@@ -37,10 +40,16 @@ out gl_PerVertex
 void main() {
     mat4 world = instances.inst[gl_InstanceIndex].world;
     #ifdef SKINNED
+    vec3 position, normal, tangent;
+    morphVertex(position, normal, tangent);
     world = world * skinMatrix();
+    o_normalSpace = calcNormalSpaceOf(world, normal, tangent);
+    #else
+    vec3 position = i_position;
+    o_normalSpace = calcNormalSpace(world);
     #endif
     o_UV0 = i_uv0;
-    gl_Position = frame.projection * frame.view * world * vec4(i_position, 1.0);
-    o_position = vec3(world * vec4(i_position, 1.0));
-    o_normalSpace = calcNormalSpace(world);
+    o_color = instances.color[gl_InstanceIndex];
+    gl_Position = frame.projection * frame.view * world * vec4(position, 1.0);
+    o_position = vec3(world * vec4(position, 1.0));
 }
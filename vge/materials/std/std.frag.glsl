@@ -18,6 +18,7 @@ layout(location = 0) out vec4 o_Color;
 layout(location = 0) in vec3 i_position;
 layout(location = 1) in vec2 i_UV0;
 layout(location = 2) in mat3 i_normalSpace;
+layout(location = 5) in vec4 i_color;
 
 layout(set = 2, binding = 0) uniform MATERIAL {
     vec4 albedoColor;
@@ -26,14 +27,26 @@ layout(set = 2, binding = 0) uniform MATERIAL {
     float roughnessFactor;
     float normalMap;
     float alphaCutoff;
+    vec4 uvTransform0;
+    vec4 uvTransform1;
+    float occlusionStrength;
+    float clearcoat;
+    float clearcoatRoughness;
 } material;
 
 #define TX_ALBEDO 0
 #define TX_NORMAL 1
 #define TX_METAL_ROUGHNESS 2
 #define TX_EMISSIVE 3
+#define TX_OCCLUSION 4
 
-layout(set=2, binding=1) uniform sampler2D textures[4];
+layout(set=2, binding=1) uniform sampler2D textures[5];
+
+// Texture coordinates with KHR_texture_transform
+vec2 getUV() {
+    vec3 uv = vec3(i_UV0, 1);
+    return vec2(dot(material.uvTransform0.xyz, uv), dot(material.uvTransform1.xyz, uv));
+}
 
 #include "../decal/decal.glsl"
 
@@ -43,10 +56,10 @@ const float PI = 3.14159265;
 
 
 
-vec3 calcNormal() {
+vec3 calcNormal(vec2 uv) {
     vec3 bNormal = vec3(0, 0, 1);
     if (material.normalMap > 0.5) {
-        bNormal = (2 * texture( textures[TX_NORMAL], i_UV0).xyz) - vec3(1.0, 1.0, 1.0);
+        bNormal = (2 * texture( textures[TX_NORMAL], uv).xyz) - vec3(1.0, 1.0, 1.0);
     }
     return normalize(i_normalSpace * bNormal);
 }
@@ -54,6 +67,7 @@ vec3 calcNormal() {
 #include "../../vscene/shadowfactor.glsl"
 
 #include "../pbr/sh_helper.glsl"
+#include "../../vscene/probes.glsl"
 
 
 
@@ -139,6 +153,9 @@ vec3 calcLight(LIGHT light, vec3 normal, vec3 viewDir, vec3 diffuseColor, vec3 f
 }
 
 vec3 specularIBL(vec3 r, float roughness) {
+    if (frame.noProbes > 0) {
+        return blendSpecular(selectProbes(i_position), i_position, r, roughness);
+    }
     float lod1 = floor(frame.envLoDs * roughness);
     float lod2 = ceil(frame.envLoDs * roughness);
     int tx_preEnv = int(frame.envMap);
@@ -150,28 +167,43 @@ vec3 specularIBL(vec3 r, float roughness) {
     return mix(tx1.rgb, tx2.rgb, fract(roughness));
 }
 
+#include "../pbr/clearcoat.glsl"
+
 vec3 ibl(vec3 normal, vec3 viewDir, vec3 diffuseColor, vec3 f0, float roughness, vec2 dfg) {
     vec3 r = reflect(-viewDir, normal);
     vec3 f90 = vec3(1);
-    vec3 Ld = sh(normal) * diffuseColor;
-    vec3 Lld = specularIBL(r, roughness);
+    vec3 Ld;
+    vec3 Lld;
+    if (frame.noProbes > 0) {
+        PROBESELECTION ps = selectProbes(i_position);
+        Ld = blendSH(ps, normal) * diffuseColor;
+        Lld = blendSpecular(ps, i_position, r, roughness);
+    } else {
+        Ld = sh(normal) * diffuseColor;
+        Lld = specularIBL(r, roughness);
+    }
     vec3 Lr =  (f0 * dfg.x + f90 * dfg.y) * Lld;
     return (Ld + Lr);
 }
 
 
 void main() {
-    vec4 diffuseAlphaColor = material.albedoColor * texture( textures[TX_ALBEDO], i_UV0);
-    vec3 emissiveColor = vec3(material.emissiveColor * texture( textures[TX_EMISSIVE], i_UV0));
+    vec2 uv = getUV();
+    vec4 diffuseAlphaColor = material.albedoColor * i_color * texture( textures[TX_ALBEDO], uv);
+    vec3 emissiveColor = vec3(material.emissiveColor * texture( textures[TX_EMISSIVE], uv));
     float alpha =  diffuseAlphaColor.a;
     if (alpha < 0.1) {
         discard;
     }
 
-    vec3 normal = calcNormal();
+    vec3 normal = calcNormal(uv);
     vec3 refNormal = i_normalSpace * vec3(0,0,1.0);
     vec3 albedo = diffuseAlphaColor.rgb;
-    vec3 mrColor = texture( textures[TX_METAL_ROUGHNESS], i_UV0).rgb;
+    vec3 mrColor = texture( textures[TX_METAL_ROUGHNESS], uv).rgb;
+    float occlusion = 1;
+    if (material.occlusionStrength > 0) {
+        occlusion = 1.0 + material.occlusionStrength * (texture(textures[TX_OCCLUSION], uv).r - 1.0);
+    }
     float metallic = mrColor.b * material.metallicFactor;
     float roughness = mrColor.g * material.roughnessFactor;
 
@@ -199,9 +231,18 @@ void main() {
         // o_Color = vec4(shadowFactor, 0, 0, 1);
         vec3 lightOut = shadowFactor > 0.1 ? calcLight(l, normal, viewDir, diffuseColor * shadowFactor,
         f0  * shadowFactor, metallic, roughness, dfg) : vec3(0);
+        if (material.clearcoat > 0) {
+            lightOut = clearcoatLight(l, i_position, normalize(refNormal), viewDir, lightOut, material.clearcoat,
+                material.clearcoatRoughness, shadowFactor);
+        }
         lightColors += lightOut;
     }
-    vec3 iblColor = ibl(normal, viewDir, diffuseColor, f0, roughness, dfg);
+    vec3 iblColor = ibl(normal, viewDir, diffuseColor, f0, roughness, dfg) * occlusion;
+    if (material.clearcoat > 0) {
+        vec3 ccNormal = normalize(refNormal);
+        iblColor = clearcoatIBL(ccNormal, viewDir, iblColor, specularIBL(reflect(-viewDir, ccNormal),
+            material.clearcoatRoughness) * occlusion, material.clearcoat);
+    }
     o_Color = vec4(lightColors + iblColor + emissiveColor, alpha);
 }
 
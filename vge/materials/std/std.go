@@ -10,22 +10,27 @@ import (
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lakal3/vge/vge/materials/decal"
+	"github.com/lakal3/vge/vge/materials/unlit"
 	"github.com/lakal3/vge/vge/vk"
 	"github.com/lakal3/vge/vge/vmodel"
 	"github.com/lakal3/vge/vge/vscene"
 )
 
-const maxInstances = 1000 // Must match shader value
+const maxInstances = 680 // Must match shader value. Instances and their colors must fit into 64k uniform buffer
 
 // MinEmission is minimum emission to interpret that material is emissive. Otherwise it is non emissive
 const MinEmission = 0.01
 
 func Factory(ctx vk.APIContext, dev *vk.Device, props vmodel.MaterialProperties) (
 	sh vmodel.Shader, layout *vk.DescriptorLayout, ubf []byte, images []vmodel.ImageIndex) {
+	if props.GetFactor(vmodel.FUnlit, 0) > 0 {
+		return unlit.UnlitFactory(ctx, dev, props)
+	}
 	tx_diffuse := props.GetImage(vmodel.TxAlbedo)
 	tx_metalRoughness := props.GetImage(vmodel.TxMetallicRoughness)
 	tx_emissive := props.GetImage(vmodel.TxEmissive)
 	tx_normal := props.GetImage(vmodel.TxBump)
+	tx_occlusion := props.GetImage(vmodel.TxOcclusion)
 	mf := float32(0)
 	if tx_metalRoughness != 0 {
 		mf = 1
@@ -35,16 +40,25 @@ func Factory(ctx vk.APIContext, dev *vk.Device, props vmodel.MaterialProperties)
 		emissiveColor:   props.GetColor(vmodel.CEmissive, getColorFactor(tx_emissive)),
 		metalnessFactor: props.GetFactor(vmodel.FMetalness, mf),
 		roughnessFactor: props.GetFactor(vmodel.FRoughness, 1),
+		clearcoat:       props.GetFactor(vmodel.FClearcoat, 0),
+		clearcoatRough:  props.GetFactor(vmodel.FClearcoatRoughness, 0),
 		alphaCut:        props.GetFactor(vmodel.FAlphaCutoff, 0),
 	}
 	if tx_normal > 0 {
 		ub.normalMap = 1
 	}
+	if tx_occlusion > 0 {
+		ub.occlusionStrength = props.GetFactor(vmodel.FOcclusionStrength, 1)
+	}
+	es := props.GetFactor(vmodel.FEmissiveStrength, 1)
+	ub.emissiveColor = mgl32.Vec4{ub.emissiveColor[0] * es, ub.emissiveColor[1] * es, ub.emissiveColor[2] * es, ub.emissiveColor[3]}
+	tr := props.GetTransform(vmodel.XUVTransform)
+	ub.uvTransform0, ub.uvTransform1 = tr.Row(0).Vec4(0), tr.Row(1).Vec4(0)
 	if ub.emissiveColor.Vec3().Len() < MinEmission {
 		ub.emissiveColor = mgl32.Vec4{}
 	}
 	b := *(*[unsafe.Sizeof(stdMaterial{})]byte)(unsafe.Pointer(&ub))
-	return &Material{alphaCut: ub.alphaCut, albedoTexture: tx_diffuse}, getStdLayout(ctx, dev), b[:], []vmodel.ImageIndex{tx_diffuse, tx_normal, tx_metalRoughness, tx_emissive}
+	return &Material{alphaCut: ub.alphaCut, albedoTexture: tx_diffuse, blend: props.GetFactor(vmodel.FAlphaBlend, 0) > 0}, getStdLayout(ctx, dev), b[:], []vmodel.ImageIndex{tx_diffuse, tx_normal, tx_metalRoughness, tx_emissive, tx_occlusion}
 }
 
 func getColorFactor(imIndex vmodel.ImageIndex) mgl32.Vec4 {
@@ -59,6 +73,22 @@ type Material struct {
 	alphaCut      float32
 	albedoTexture vmodel.ImageIndex
 	model         *vmodel.Model
+	blend         bool
+}
+
+// Transparent returns true if material is alpha blended (see vmodel.FAlphaBlend). Deferred renderer draws
+// blended materials as opaque
+func (u *Material) Transparent(frame vmodel.Frame) bool {
+	_, ok := frame.(forward.ForwardFrame)
+	return u.blend && ok
+}
+
+// pipelineKeys returns keys of normal and skinned forward pipeline of material
+func (u *Material) pipelineKeys() (normal vk.Key, skinned vk.Key) {
+	if u.blend {
+		return kStdBlendPipeline, kStdSkinnedBlendPipeline
+	}
+	return kStdPipeline, kStdSkinnedPipeline
 }
 
 func (u *Material) GetAlphaTexture() (cutoff float32, view *vk.ImageView, sampler *vk.Sampler) {
@@ -87,7 +117,8 @@ func (u *Material) DrawSkinned(dc *vmodel.DrawContext, mesh vmodel.Mesh, world m
 		return
 	}
 	rc := ff.GetCache()
-	gp := dc.Pass.Get(rc.Ctx, kStdSkinnedPipeline, func(ctx vk.APIContext) interface{} {
+	_, key := u.pipelineKeys()
+	gp := dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
 		return u.NewPipeline(ctx, dc, true)
 	}).(*vk.GraphicsPipeline)
 	uc := vscene.GetUniformCache(rc)
@@ -101,12 +132,13 @@ func (u *Material) DrawSkinned(dc *vmodel.DrawContext, mesh vmodel.Mesh, world m
 		return &stdInstance{ds: ds, sl: sl}
 	}).(*stdInstance)
 	dsDecal := decal.BindPainter(rc, extra)
-	dm := stdMatInstance{world: world}
+	dm := stdMatInstance{world: world, color: noTint}
 	dsMesh, slMesh := uc.Alloc(rc.Ctx)
 	copy(slMesh.Content, vscene.Mat4ToBytes(aniMatrix))
+	vmodel.WriteMorph(slMesh.Content, mesh, vmodel.GetMorphWeights(extra))
 	uli.writeInstance(dm)
 	dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindSkinned)...).
-		AddDescriptors(dsFrame, uli.ds, u.dsMat, dsDecal, dsMesh).SetInstances(uli.count, 1)
+		AddDescriptors(dsFrame, uli.ds, u.dsMat, dsDecal, dsMesh, mesh.Model.MorphDescriptor()).SetInstances(uli.count, 1)
 	uli.count++
 	if uli.count >= maxInstances {
 		rc.SetPerFrame(kStdSkinnedInstances, nil)
@@ -120,7 +152,8 @@ func (u *Material) Draw(dc *vmodel.DrawContext, mesh vmodel.Mesh, world mgl32.Ma
 		return
 	}
 	rc := ff.GetCache()
-	gp := dc.Pass.Get(rc.Ctx, kStdPipeline, func(ctx vk.APIContext) interface{} {
+	key, _ := u.pipelineKeys()
+	gp := dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
 		return u.NewPipeline(ctx, dc, false)
 	}).(*vk.GraphicsPipeline)
 	uc := vscene.GetUniformCache(rc)
@@ -134,7 +167,7 @@ func (u *Material) Draw(dc *vmodel.DrawContext, mesh vmodel.Mesh, world mgl32.Ma
 		return &stdInstance{ds: ds, sl: sl}
 	}).(*stdInstance)
 	dsDecal := decal.BindPainter(rc, extra)
-	dm := stdMatInstance{world: world}
+	dm := stdMatInstance{world: world, color: noTint}
 	uli.writeInstance(dm)
 	dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
 		AddDescriptors(dsFrame, uli.ds, u.dsMat, dsDecal).SetInstances(uli.count, 1)
@@ -159,7 +192,7 @@ func (u *Material) drawDeferred(dc *vmodel.DrawContext, mesh vmodel.Mesh, world
 		ds, sl := uc.Alloc(ctx)
 		return &stdInstance{ds: ds, sl: sl}
 	}).(*stdInstance)
-	uli.writeInstance(stdMatInstance{world: world})
+	uli.writeInstance(stdMatInstance{world: world, color: noTint})
 	dsDecal := decal.BindPainter(rc, extra)
 	dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
 		AddDescriptors(dsFrame, uli.ds, u.dsMat, dsDecal).SetInstances(uli.count, 1)
@@ -185,25 +218,84 @@ func (u *Material) drawSkinnedDeferred(dc *vmodel.DrawContext, mesh vmodel.Mesh,
 		ds, sl := uc.Alloc(ctx)
 		return &stdInstance{ds: ds, sl: sl}
 	}).(*stdInstance)
-	uli.writeInstance(stdMatInstance{world: world})
+	uli.writeInstance(stdMatInstance{world: world, color: noTint})
 	dsMesh, slMesh := uc.Alloc(rc.Ctx)
 	dsDecal := decal.BindPainter(rc, extra)
 
 	copy(slMesh.Content, vscene.Mat4ToBytes(aniMatrix))
+	vmodel.WriteMorph(slMesh.Content, mesh, vmodel.GetMorphWeights(extra))
 	dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindSkinned)...).
-		AddDescriptors(dsFrame, uli.ds, u.dsMat, dsDecal, dsMesh).SetInstances(uli.count, 1)
+		AddDescriptors(dsFrame, uli.ds, u.dsMat, dsDecal, dsMesh, mesh.Model.MorphDescriptor()).SetInstances(uli.count, 1)
 	uli.count++
 	if uli.count >= maxInstances {
 		rc.SetPerFrame(kStdInstances, nil)
 	}
 }
 
+// DrawInstanced draws all instances of mesh with as few draw calls as possible
+func (u *Material) DrawInstanced(dc *vmodel.DrawContext, mesh vmodel.Mesh, instances []vmodel.Instance,
+	extra vmodel.ShaderExtra) {
+	rc := dc.Frame.GetCache()
+	var gp *vk.GraphicsPipeline
+	var dsFrame *vk.DescriptorSet
+	ff, ok := dc.Frame.(forward.ForwardFrame)
+	if ok {
+		key, _ := u.pipelineKeys()
+		gp = dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
+			return u.NewPipeline(ctx, dc, false)
+		}).(*vk.GraphicsPipeline)
+		dsFrame = ff.BindDynamicFrame()
+	} else {
+		frame, ok := dc.Frame.(deferred.DeferredLayout)
+		if !ok {
+			return
+		}
+		gp = dc.Pass.Get(rc.Ctx, kDefPipeline, func(ctx vk.APIContext) interface{} {
+			return u.NewDeferredPipeline(ctx, dc, false)
+		}).(*vk.GraphicsPipeline)
+		dsFrame = frame.BindDeferredFrame()
+	}
+	if dsFrame == nil {
+		return // Not supported
+	}
+	uc := vscene.GetUniformCache(rc)
+	dsDecal := decal.BindPainter(rc, extra)
+	for len(instances) > 0 {
+		uli := rc.GetPerFrame(kStdInstances, func(ctx vk.APIContext) interface{} {
+			ds, sl := uc.Alloc(ctx)
+			return &stdInstance{ds: ds, sl: sl}
+		}).(*stdInstance)
+		first := uli.count
+		for len(instances) > 0 && uli.count < maxInstances {
+			uli.writeInstance(stdMatInstance{world: instances[0].World, color: instances[0].Color})
+			uli.count++
+			instances = instances[1:]
+		}
+		dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
+			AddDescriptors(dsFrame, uli.ds, u.dsMat, dsDecal).SetInstances(first, uli.count-first)
+		if uli.count >= maxInstances {
+			rc.SetPerFrame(kStdInstances, nil)
+		}
+	}
+}
+
 type stdMatInstance struct {
 	world      mgl32.Mat4
 	decalIndex mgl32.Vec2
 	dummy      mgl32.Vec2
+	color      mgl32.Vec4
 }
 
+// instanceSize is size of INSTANCE in std.vert.glsl. Color is not part of INSTANCE, colors are stored
+// after all instances at colorOffset
+const instanceSize = uint32(unsafe.Offsetof(stdMatInstance{}.color))
+
+// colorOffset is offset of instance colors after instances. See std.vert.glsl
+const colorOffset = maxInstances * instanceSize
+
+// noTint is instance color of meshes drawn without instancing
+var noTint = mgl32.Vec4{1, 1, 1, 1}
+
 func (u *Material) NewPipeline(ctx vk.APIContext, dc *vmodel.DrawContext, skinned bool) *vk.GraphicsPipeline {
 	rc := dc.Frame.GetCache()
 	gp := vk.NewGraphicsPipeline(ctx, rc.Device)
@@ -228,10 +320,16 @@ func (u *Material) NewPipeline(ctx vk.APIContext, dc *vmodel.DrawContext, skinne
 	gp.AddLayout(ctx, la2)
 	gp.AddLayout(ctx, la) // Decals
 	if skinned {
-		gp.AddLayout(ctx, laUBF) // Transform & decal matrix
+		gp.AddLayout(ctx, laUBF)                                 // Transform & decal matrix
+		gp.AddLayout(ctx, vmodel.GetMorphLayout(ctx, rc.Device)) // Morph targets
 	}
 	gp.AddShader(ctx, vk.SHADERStageFragmentBit, std_frag_spv)
-	gp.AddDepth(ctx, true, true)
+	if u.blend {
+		gp.AddAlphaBlend(ctx)
+		gp.AddDepth(ctx, false, true)
+	} else {
+		gp.AddDepth(ctx, true, true)
+	}
 	gp.Create(ctx, dc.Pass)
 	return gp
 }
@@ -258,6 +356,9 @@ func (u *Material) NewDeferredPipeline(ctx vk.APIContext, dc *vmodel.DrawContext
 		gp.AddLayout(ctx, laUBF) // Transform matrix
 	}
 	gp.AddLayout(ctx, la) // Decals
+	if skinned {
+		gp.AddLayout(ctx, vmodel.GetMorphLayout(ctx, rc.Device)) // Morph targets
+	}
 	gp.AddShader(ctx, vk.SHADERStageFragmentBit, defmat_frag_spv)
 	gp.AddDepth(ctx, true, true)
 	gp.Create(ctx, dc.Pass)
@@ -265,12 +366,18 @@ func (u *Material) NewDeferredPipeline(ctx vk.APIContext, dc *vmodel.DrawContext
 }
 
 type stdMaterial struct {
-	albedoColor     mgl32.Vec4
-	emissiveColor   mgl32.Vec4
-	metalnessFactor float32
-	roughnessFactor float32
-	normalMap       float32
-	alphaCut        float32
+	albedoColor       mgl32.Vec4
+	emissiveColor     mgl32.Vec4
+	metalnessFactor   float32
+	roughnessFactor   float32
+	normalMap         float32
+	alphaCut          float32
+	uvTransform0      mgl32.Vec4
+	uvTransform1      mgl32.Vec4
+	occlusionStrength float32
+	clearcoat         float32
+	clearcoatRough    float32
+	dummy             float32
 }
 
 type stdInstance struct {
@@ -280,14 +387,16 @@ type stdInstance struct {
 }
 
 func (i stdInstance) writeInstance(dm stdMatInstance) {
-	lInst := uint32(unsafe.Sizeof(stdMatInstance{}))
 	b := *(*[unsafe.Sizeof(stdMatInstance{})]byte)(unsafe.Pointer(&dm))
-	copy(i.sl.Content[i.count*lInst:(i.count+1)*lInst], b[:])
+	copy(i.sl.Content[i.count*instanceSize:(i.count+1)*instanceSize], b[:instanceSize])
+	copy(i.sl.Content[colorOffset+i.count*16:colorOffset+i.count*16+16], b[instanceSize:])
 }
 
 var kStdLayout = vk.NewKey()
 var kStdPipeline = vk.NewKey()
 var kStdSkinnedPipeline = vk.NewKey()
+var kStdBlendPipeline = vk.NewKey()
+var kStdSkinnedBlendPipeline = vk.NewKey()
 var kStdInstances = vk.NewKey()
 var kStdSkinnedInstances = vk.NewKey()
 var kDefPipeline = vk.NewKey()
@@ -296,6 +405,6 @@ var kDefSkinnedPipeline = vk.NewKey()
 func getStdLayout(ctx vk.APIContext, dev *vk.Device) *vk.DescriptorLayout {
 	la := vscene.GetUniformLayout(ctx, dev)
 	return dev.Get(ctx, kStdLayout, func(ctx vk.APIContext) interface{} {
-		return la.AddBinding(ctx, vk.DESCRIPTORTypeCombinedImageSampler, vk.SHADERStageFragmentBit, 4)
+		return la.AddBinding(ctx, vk.DESCRIPTORTypeCombinedImageSampler, vk.SHADERStageFragmentBit, 5)
 	}).(*vk.DescriptorLayout)
 }
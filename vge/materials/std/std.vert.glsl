@@ -4,7 +4,7 @@
 
 #define DYNAMIC_DESCRIPTORS 1
 
-#define MAX_INSTANCES 1000
+#define MAX_INSTANCES 680
 
 #include "../../vscene/input.glsl"
 #include "../../forward/frame.glsl"
@@ -17,16 +17,19 @@ struct INSTANCE {
 
 layout(set = 1, binding = 0) uniform INSTANCES {
     INSTANCE inst[MAX_INSTANCES];
+    vec4 color[MAX_INSTANCES];
 } instances;
 
 #ifdef SKINNED
 #define SKIN_SET 4
+#define MORPH_SET 5
 #include "../../vscene/skin.glsl"
 #endif
 
 layout(location = 0) out vec3 o_position;
 layout(location = 1) out vec2 o_UV0;
 layout(location = 2) out mat3 o_normalSpace;
+layout(location = 5) out vec4 o_color;
 
 out gl_PerVertex
 {
@@ -38,10 +41,16 @@ void main() {
     vec2 decalIndex = instances.inst[gl_InstanceIndex].decalIndex;
 
     #ifdef SKINNED
+    vec3 position, normal, tangent;
+    morphVertex(position, normal, tangent);
     world = world * skinMatrix();
+    o_normalSpace = calcNormalSpaceOf(world, normal, tangent);
+    #else
+    vec3 position = i_position;
+    o_normalSpace = calcNormalSpace(world);
     #endif
     o_UV0 = i_uv0;
-    gl_Position = frame.projection * frame.view * world * vec4(i_position, 1.0);
-    o_position = vec3(world * vec4(i_position, 1.0));
-    o_normalSpace = calcNormalSpace(world);
+    o_color = instances.color[gl_InstanceIndex];
+    gl_Position = frame.projection * frame.view * world * vec4(position, 1.0);
+    o_position = vec3(world * vec4(position, 1.0));
 }
@@ -2,6 +2,7 @@
 
 layout(location = 0) out vec4 outColor;
 layout(location = 0) in vec2 i_uv;
+layout(location = 1) in vec4 i_color;
 
 layout(set = 2, binding = 0) uniform MaterialUBF {
     vec4 color;
@@ -12,8 +13,8 @@ layout(set = 2, binding = 1) uniform sampler2D textures[1];
 
 void main() {
    if (Material.textured > 0) {
-       outColor = texture(textures[0], i_uv);
+       outColor = texture(textures[0], i_uv) * i_color;
    } else {
-       outColor = Material.color;
+       outColor = Material.color * i_color;
    }
 }
\ No newline at end of file
@@ -21,11 +21,26 @@ func UnlitFactory(ctx vk.APIContext, dev *vk.Device, props vmodel.MaterialProper
 		ub.textured = 1
 	}
 	b := *(*[unsafe.Sizeof(unlitMaterial{})]byte)(unsafe.Pointer(&ub))
-	return &UnlitMaterial{}, getUnlitLayout(ctx, dev), b[:], []vmodel.ImageIndex{tx_albedo}
+	return &UnlitMaterial{blend: props.GetFactor(vmodel.FAlphaBlend, 0) > 0}, getUnlitLayout(ctx, dev), b[:],
+		[]vmodel.ImageIndex{tx_albedo}
 }
 
 type UnlitMaterial struct {
 	dsMat *vk.DescriptorSet
+	blend bool
+}
+
+// Transparent returns true if material is alpha blended (see vmodel.FAlphaBlend)
+func (u *UnlitMaterial) Transparent(frame vmodel.Frame) bool {
+	return u.blend
+}
+
+// pipelineKeys returns keys of normal and skinned pipeline of material
+func (u *UnlitMaterial) pipelineKeys() (normal vk.Key, skinned vk.Key) {
+	if u.blend {
+		return kUnlitBlendPipeline, kUnlitSkinnedBlendPipeline
+	}
+	return kUnlitPipeline, kUnlitSkinnedPipeline
 }
 
 func (u *UnlitMaterial) SetDescriptor(dsMat *vk.DescriptorSet) {
@@ -38,7 +53,8 @@ func (u *UnlitMaterial) Draw(dc *vmodel.DrawContext, mesh vmodel.Mesh, world mgl
 		return // Simple frame not supported
 	}
 	rc := scf.GetCache()
-	gp := dc.Pass.Get(rc.Ctx, kUnlitPipeline, func(ctx vk.APIContext) interface{} {
+	key, _ := u.pipelineKeys()
+	gp := dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
 		return u.NewPipeline(ctx, dc, false)
 	}).(*vk.GraphicsPipeline)
 	uc := vscene.GetUniformCache(rc)
@@ -47,7 +63,7 @@ func (u *UnlitMaterial) Draw(dc *vmodel.DrawContext, mesh vmodel.Mesh, world mgl
 		ds, sl := uc.Alloc(ctx)
 		return &unlitInstances{ds: ds, sl: sl}
 	}).(*unlitInstances)
-	copy(uli.sl.Content[uli.count*64:uli.count*64+64], vk.Float32ToBytes(world[:]))
+	uli.writeInstance(world, noTint)
 	dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
 		AddDescriptors(dsWorld, uli.ds, u.dsMat).SetInstances(uli.count, 1)
 	uli.count++
@@ -63,7 +79,8 @@ func (u *UnlitMaterial) DrawSkinned(dc *vmodel.DrawContext, mesh vmodel.Mesh, wo
 		return // Simple frame not supported
 	}
 	rc := scf.GetCache()
-	gp := dc.Pass.Get(rc.Ctx, kUnlitSkinnedPipeline, func(ctx vk.APIContext) interface{} {
+	_, key := u.pipelineKeys()
+	gp := dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
 		return u.NewPipeline(ctx, dc, true)
 	}).(*vk.GraphicsPipeline)
 	uc := vscene.GetUniformCache(rc)
@@ -72,7 +89,7 @@ func (u *UnlitMaterial) DrawSkinned(dc *vmodel.DrawContext, mesh vmodel.Mesh, wo
 		ds, sl := uc.Alloc(ctx)
 		return &unlitInstances{ds: ds, sl: sl}
 	}).(*unlitInstances)
-	copy(uli.sl.Content[uli.count*64:uli.count*64+64], vk.Float32ToBytes(world[:]))
+	uli.writeInstance(world, noTint)
 	dsMesh, slMesh := uc.Alloc(rc.Ctx)
 	copy(slMesh.Content, vscene.Mat4ToBytes(aniMatrix))
 	dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
@@ -83,6 +100,39 @@ func (u *UnlitMaterial) DrawSkinned(dc *vmodel.DrawContext, mesh vmodel.Mesh, wo
 	}
 }
 
+// DrawInstanced draws all instances of mesh with as few draw calls as possible
+func (u *UnlitMaterial) DrawInstanced(dc *vmodel.DrawContext, mesh vmodel.Mesh, instances []vmodel.Instance,
+	extra vmodel.ShaderExtra) {
+	scf := vscene.GetSimpleFrame(dc.Frame)
+	if scf == nil {
+		return // Simple frame not supported
+	}
+	rc := scf.GetCache()
+	key, _ := u.pipelineKeys()
+	gp := dc.Pass.Get(rc.Ctx, key, func(ctx vk.APIContext) interface{} {
+		return u.NewPipeline(ctx, dc, false)
+	}).(*vk.GraphicsPipeline)
+	uc := vscene.GetUniformCache(rc)
+	dsWorld := scf.BindFrame()
+	for len(instances) > 0 {
+		uli := rc.GetPerFrame(kUnlitInstances, func(ctx vk.APIContext) interface{} {
+			ds, sl := uc.Alloc(ctx)
+			return &unlitInstances{ds: ds, sl: sl}
+		}).(*unlitInstances)
+		first := uli.count
+		for len(instances) > 0 && uli.count < 200 {
+			uli.writeInstance(instances[0].World, instances[0].Color)
+			uli.count++
+			instances = instances[1:]
+		}
+		dc.DrawIndexed(gp, mesh.From, mesh.Count).AddInputs(mesh.Model.VertexBuffers(vmodel.MESHKindNormal)...).
+			AddDescriptors(dsWorld, uli.ds, u.dsMat).SetInstances(first, uli.count-first)
+		if uli.count >= 200 {
+			rc.SetPerFrame(kUnlitInstances, nil)
+		}
+	}
+}
+
 func (u *UnlitMaterial) NewPipeline(ctx vk.APIContext, dc *vmodel.DrawContext, skinned bool) *vk.GraphicsPipeline {
 	rc := dc.Frame.GetCache()
 	gp := vk.NewGraphicsPipeline(ctx, rc.Device)
@@ -103,7 +153,12 @@ func (u *UnlitMaterial) NewPipeline(ctx vk.APIContext, dc *vmodel.DrawContext, s
 		gp.AddShader(ctx, vk.SHADERStageVertexBit, unlit_vert_spv)
 	}
 	gp.AddShader(ctx, vk.SHADERStageFragmentBit, unlit_frag_spv)
-	gp.AddDepth(ctx, true, true)
+	if u.blend {
+		gp.AddAlphaBlend(ctx)
+		gp.AddDepth(ctx, false, true)
+	} else {
+		gp.AddDepth(ctx, true, true)
+	}
 	gp.Create(ctx, dc.Pass)
 	return gp
 }
@@ -119,9 +174,22 @@ type unlitInstances struct {
 	count uint32
 }
 
+// colorOffset is offset of instance colors after world matrices. See unlit.vert.glsl
+const colorOffset = 256 * 64
+
+// noTint is instance color of meshes drawn without instancing
+var noTint = mgl32.Vec4{1, 1, 1, 1}
+
+func (i *unlitInstances) writeInstance(world mgl32.Mat4, color mgl32.Vec4) {
+	copy(i.sl.Content[i.count*64:i.count*64+64], vk.Float32ToBytes(world[:]))
+	copy(i.sl.Content[colorOffset+i.count*16:colorOffset+i.count*16+16], vk.Float32ToBytes(color[:]))
+}
+
 var kUnlitLayout = vk.NewKey()
 var kUnlitPipeline = vk.NewKey()
 var kUnlitSkinnedPipeline = vk.NewKey()
+var kUnlitBlendPipeline = vk.NewKey()
+var kUnlitSkinnedBlendPipeline = vk.NewKey()
 var kUnlitWorld = vk.NewKey()
 var kUnlitInstances = vk.NewKey()
 
@@ -8,6 +8,7 @@
 #endif
 
 layout(location = 0) out vec2 o_uv;
+layout(location = 1) out vec4 o_color;
 
 layout(set = 0, binding = 0) uniform FRAME {
     mat4 projection;
@@ -16,6 +17,7 @@ layout(set = 0, binding = 0) uniform FRAME {
 
 layout(set = 1, binding = 0) uniform INSTANCE {
     mat4 world[256];
+    vec4 color[256];
 } instance;
 
 void main() {
@@ -26,4 +28,5 @@ void main() {
     vec4 pos = world * vec4(i_position, 1.0);
     gl_Position = frame.projection * frame.view * pos;
     o_uv = i_uv0;
+    o_color = instance.color[gl_InstanceIndex];
 }
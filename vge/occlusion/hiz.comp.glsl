@@ -0,0 +1,29 @@
+#version 450
+
+// Reduces depth buffer to grid where each cell holds farthest depth of pixels under it. See hiz.go
+
+layout (local_size_x = 8, local_size_y = 8, local_size_z = 1) in;
+
+#define GRID_WIDTH 128
+#define GRID_HEIGHT 64
+
+layout(set=0, binding=0) uniform sampler2D depthImage;
+
+layout(set=0, binding=1) buffer HIZ {
+    float depth[];
+} hiz;
+
+void main() {
+    ivec2 cell = ivec2(gl_GlobalInvocationID.xy);
+    ivec2 grid = ivec2(GRID_WIDTH, GRID_HEIGHT);
+    ivec2 size = textureSize(depthImage, 0);
+    ivec2 from = cell * size / grid;
+    ivec2 to = max((cell + ivec2(1)) * size / grid, from + ivec2(1));
+    float maxDepth = 0;
+    for (int y = from.y; y < to.y && y < size.y; y++) {
+        for (int x = from.x; x < to.x && x < size.x; x++) {
+            maxDepth = max(maxDepth, texelFetch(depthImage, ivec2(x, y), 0).r);
+        }
+    }
+    hiz.depth[cell.y * GRID_WIDTH + cell.x] = maxDepth;
+}
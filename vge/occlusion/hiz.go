@@ -0,0 +1,227 @@
+// Package occlusion implements hierarchical depth (Hi-Z) occlusion culling.
+//
+// Depth buffer of rendered frame is reduced on GPU to low resolution grid where each cell holds farthest depth of pixels
+// under it. Grid is read back after frame has been rendered and expanded to mip pyramid on CPU.
+// Next frame tests bounding boxes of objects against pyramid and objects that are behind all depth values they cover are
+// culled. Test uses projection and view of previous frame so objects that come to view may appear one frame late.
+package occlusion
+
+import (
+	"image"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+const (
+	// GridWidth is width of depth grid calculated from depth buffer
+	GridWidth = 128
+	// GridHeight is height of depth grid calculated from depth buffer
+	GridHeight = 64
+)
+
+// HiZ builds depth pyramid from depth buffer and tests bounding boxes against it. HiZ is used by renderers (see
+// forward.Renderer.AddOcclusionCulling). Renderer must wait rendering to complete before next frame
+type HiZ struct {
+	// Bias is subtracted from nearest depth of tested box. Use bias to reduce culling of objects just behind
+	// occluders
+	Bias float32
+
+	key     vk.Key
+	ctx     vk.APIContext
+	pool    *vk.MemoryPool
+	buffer  *vk.Buffer
+	pending bool
+	nextVP  mgl32.Mat4
+	vp      mgl32.Mat4
+	levels  []hizLevel
+}
+
+type hizLevel struct {
+	size  image.Point
+	depth []float32
+}
+
+// NewHiZ creates new Hi-Z occlusion test
+func NewHiZ() *HiZ {
+	return &HiZ{key: vk.NewKey(), Bias: 0.0001}
+}
+
+func (h *HiZ) Dispose() {
+	if h.pool != nil {
+		h.pool.Dispose()
+		h.pool, h.buffer = nil, nil
+	}
+	h.levels, h.pending = nil, false
+}
+
+type hizFrame struct {
+	dp *vk.DescriptorPool
+	ds *vk.DescriptorSet
+}
+
+func (f *hizFrame) Dispose() {
+	if f.dp != nil {
+		f.dp.Dispose()
+		f.dp, f.ds = nil, nil
+	}
+}
+
+var kHiZLayout = vk.NewKey()
+var kHiZPipeline = vk.NewKey()
+
+// Record reduces depth image to depth grid. Record must be called after depth image has been rendered and outside of
+// render pass. Projection and view are camera matrices used to render depth image
+func (h *HiZ) Record(cmd *vk.Command, rc *vk.RenderCache, depthView *vk.ImageView, projection, view mgl32.Mat4) {
+	ctx, dev := rc.Ctx, rc.Device
+	if h.pool == nil {
+		h.ctx = ctx
+		h.pool = vk.NewMemoryPool(dev)
+		h.buffer = h.pool.ReserveBuffer(ctx, GridWidth*GridHeight*4, true, vk.BUFFERUsageStorageBufferBit)
+		h.pool.Allocate(ctx)
+	}
+	la := getHiZLayout(ctx, dev)
+	hf := rc.Get(h.key, func(ctx vk.APIContext) interface{} {
+		hf := &hizFrame{dp: vk.NewDescriptorPool(ctx, la, 1)}
+		hf.ds = hf.dp.Alloc(ctx)
+		hf.ds.WriteImage(ctx, 0, 0, depthView, vmodel.GetDefaultSampler(ctx, dev))
+		hf.ds.WriteBuffer(ctx, 1, 0, h.buffer)
+		return hf
+	}).(*hizFrame)
+	pl := dev.Get(ctx, kHiZPipeline, func(ctx vk.APIContext) interface{} {
+		cp := vk.NewComputePipeline(ctx, dev)
+		cp.AddLayout(ctx, la)
+		cp.AddShader(ctx, hiz_comp_spv)
+		cp.Create(ctx)
+		return cp
+	}).(*vk.ComputePipeline)
+	cmd.Compute(pl, GridWidth/8, GridHeight/8, 1, hf.ds)
+	h.pending, h.nextVP = true, projection.Mul4(view)
+}
+
+// Occluder returns occlusion test using depth grid from last recorded frame. Occluder returns nil if no frame has been
+// recorded. Occluder must not be called before previously recorded frame has completed
+func (h *HiZ) Occluder() vscene.Occluder {
+	if h.pending {
+		h.pending = false
+		h.readBack()
+	}
+	if len(h.levels) == 0 {
+		return nil
+	}
+	return h
+}
+
+// readBack copies depth grid from GPU and builds depth pyramid from it
+func (h *HiZ) readBack() {
+	content := h.buffer.Bytes(h.ctx)
+	if len(content) < GridWidth*GridHeight*4 {
+		return
+	}
+	depth := make([]float32, GridWidth*GridHeight)
+	copy(depth, (*[GridWidth * GridHeight]float32)(unsafe.Pointer(&content[0]))[:])
+	h.vp = h.nextVP
+	h.buildLevels(depth)
+}
+
+// buildLevels builds depth pyramid. Each level has maximum depth of 2 x 2 cells of previous level
+func (h *HiZ) buildLevels(depth []float32) {
+	h.levels = []hizLevel{{size: image.Pt(GridWidth, GridHeight), depth: depth}}
+	for {
+		prev := h.levels[len(h.levels)-1]
+		if prev.size.X == 1 && prev.size.Y == 1 {
+			return
+		}
+		l := hizLevel{size: image.Pt((prev.size.X+1)/2, (prev.size.Y+1)/2)}
+		l.depth = make([]float32, l.size.X*l.size.Y)
+		for y := 0; y < prev.size.Y; y++ {
+			for x := 0; x < prev.size.X; x++ {
+				idx := (y/2)*l.size.X + x/2
+				d := prev.depth[y*prev.size.X+x]
+				if d > l.depth[idx] {
+					l.depth[idx] = d
+				}
+			}
+		}
+		h.levels = append(h.levels, l)
+	}
+}
+
+// Occluded returns true if bounding box is behind all depth values in area that box covers in depth grid
+func (h *HiZ) Occluded(aabb vmodel.AABB) bool {
+	var minP, maxP mgl32.Vec3
+	for idx := 0; idx < 8; idx++ {
+		v := aabb.Min
+		if idx&1 == 1 {
+			v[0] = aabb.Max[0]
+		}
+		if idx&2 == 2 {
+			v[1] = aabb.Max[1]
+		}
+		if idx&4 == 4 {
+			v[2] = aabb.Max[2]
+		}
+		clip := h.vp.Mul4x1(v.Vec4(1))
+		if clip[3] <= 0.0001 {
+			// Box crosses camera plane
+			return false
+		}
+		p := clip.Vec3().Mul(1 / clip[3])
+		if idx == 0 {
+			minP, maxP = p, p
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			if p[i] < minP[i] {
+				minP[i] = p[i]
+			}
+			if p[i] > maxP[i] {
+				maxP[i] = p[i]
+			}
+		}
+	}
+	if maxP[0] < -1 || minP[0] > 1 || maxP[1] < -1 || minP[1] > 1 {
+		// Outside of previous view, we don't know
+		return false
+	}
+	x0, y0 := gridPos(minP[0], GridWidth), gridPos(minP[1], GridHeight)
+	x1, y1 := gridPos(maxP[0], GridWidth), gridPos(maxP[1], GridHeight)
+	// Select level where box covers at most 2 x 2 cells
+	level := 0
+	for (x1-x0 > 1 || y1-y0 > 1) && level < len(h.levels)-1 {
+		x0, y0, x1, y1 = x0/2, y0/2, x1/2, y1/2
+		level++
+	}
+	l := h.levels[level]
+	var maxDepth float32
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			if d := l.depth[y*l.size.X+x]; d > maxDepth {
+				maxDepth = d
+			}
+		}
+	}
+	return minP[2]-h.Bias > maxDepth
+}
+
+// gridPos converts normalized device coordinate to grid cell
+func gridPos(ndc float32, size int) int {
+	p := int((ndc*0.5 + 0.5) * float32(size))
+	if p < 0 {
+		return 0
+	}
+	if p >= size {
+		return size - 1
+	}
+	return p
+}
+
+func getHiZLayout(ctx vk.APIContext, dev *vk.Device) *vk.DescriptorLayout {
+	return dev.Get(ctx, kHiZLayout, func(ctx vk.APIContext) interface{} {
+		la := vk.NewDescriptorLayout(ctx, dev, vk.DESCRIPTORTypeCombinedImageSampler, vk.SHADERStageComputeBit, 1)
+		return la.AddBinding(ctx, vk.DESCRIPTORTypeStorageBuffer, vk.SHADERStageComputeBit, 1)
+	}).(*vk.DescriptorLayout)
+}
@@ -0,0 +1,46 @@
+package occlusion
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+func TestHiZ_Occluded(t *testing.T) {
+	h := NewHiZ()
+	projection := mgl32.Perspective(1, 2, 0.1, 100)
+	view := mgl32.LookAtV(mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{0, 1, 0})
+	h.vp = projection.Mul4(view)
+	// Wall at distance 5 covering left half of view
+	wall := h.vp.Mul4x1(mgl32.Vec4{0, 0, -5, 1})
+	depth := make([]float32, GridWidth*GridHeight)
+	for y := 0; y < GridHeight; y++ {
+		for x := 0; x < GridWidth; x++ {
+			depth[y*GridWidth+x] = 1
+			if x < GridWidth/2 {
+				depth[y*GridWidth+x] = wall[2] / wall[3]
+			}
+		}
+	}
+	h.buildLevels(depth)
+	if len(h.levels) != 8 {
+		t.Fatal("Expected 8 levels, got ", len(h.levels))
+	}
+	tests := []struct {
+		name     string
+		aabb     vmodel.AABB
+		occluded bool
+	}{
+		{"behind wall", vmodel.AABB{Min: mgl32.Vec3{-3, -1, -11}, Max: mgl32.Vec3{-1, 1, -10}}, true},
+		{"before wall", vmodel.AABB{Min: mgl32.Vec3{-2, -1, -4}, Max: mgl32.Vec3{-1, 1, -3}}, false},
+		{"beside wall", vmodel.AABB{Min: mgl32.Vec3{1, -1, -11}, Max: mgl32.Vec3{3, 1, -10}}, false},
+		{"partially behind wall", vmodel.AABB{Min: mgl32.Vec3{-3, -1, -11}, Max: mgl32.Vec3{3, 1, -10}}, false},
+		{"crossing camera", vmodel.AABB{Min: mgl32.Vec3{-3, -1, -11}, Max: mgl32.Vec3{-1, 1, 1}}, false},
+	}
+	for _, tt := range tests {
+		if h.Occluded(tt.aabb) != tt.occluded {
+			t.Error("Invalid occlusion for ", tt.name, ", expected ", tt.occluded)
+		}
+	}
+}
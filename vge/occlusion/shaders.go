@@ -0,0 +1,8 @@
+package occlusion
+
+import _ "embed"
+
+//go:generate glslangValidator -V hiz.comp.glsl -o hiz.comp.spv
+
+//go:embed hiz.comp.spv
+var hiz_comp_spv []byte
@@ -0,0 +1,50 @@
+#version 450
+#extension GL_GOOGLE_include_directive: require
+
+#include "post.glsl"
+
+#define MODE_EXTRACT 0
+#define MODE_BLUR 1
+#define MODE_COMBINE 2
+
+// 9 tap gaussian using linear sampling
+const float offsets[3] = float[](0.0, 1.3846153846, 3.2307692308);
+const float weights[3] = float[](0.2270270270, 0.3162162162, 0.0702702703);
+
+vec3 extract(vec2 uv, float threshold) {
+    // Average 4 texels of full size image to half size image
+    vec2 texel = settings.inputSize.zw * 0.5;
+    vec3 color = texture(images[0], uv + vec2(-texel.x, -texel.y)).rgb;
+    color += texture(images[0], uv + vec2(texel.x, -texel.y)).rgb;
+    color += texture(images[0], uv + vec2(-texel.x, texel.y)).rgb;
+    color += texture(images[0], uv + vec2(texel.x, texel.y)).rgb;
+    color *= 0.25;
+    float l = luminance(color);
+    if (l <= threshold) {
+        return vec3(0);
+    }
+    return color * ((l - threshold) / l);
+}
+
+vec3 blur(vec2 uv, vec2 direction) {
+    vec2 step = direction * settings.inputSize.zw;
+    vec3 color = texture(images[0], uv).rgb * weights[0];
+    for (int idx = 1; idx < 3; idx++) {
+        color += texture(images[0], uv + step * offsets[idx]).rgb * weights[idx];
+        color += texture(images[0], uv - step * offsets[idx]).rgb * weights[idx];
+    }
+    return color;
+}
+
+void main() {
+    vec4 b = settings.values[0]; // x = mode, y = threshold, z = intensity or horizontal spread, w = vertical spread
+    int mode = int(b.x);
+    if (mode == MODE_EXTRACT) {
+        o_color = vec4(extract(i_uv, b.y), 1);
+    } else if (mode == MODE_BLUR) {
+        o_color = vec4(blur(i_uv, b.zw), 1);
+    } else {
+        vec3 color = texture(images[0], i_uv).rgb + texture(images[1], i_uv).rgb * b.z;
+        o_color = vec4(color, 1);
+    }
+}
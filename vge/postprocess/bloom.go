@@ -0,0 +1,49 @@
+package postprocess
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+)
+
+// Bloom makes bright areas of HDR image glow. Bloom is threshold bloom: it doesn't read emissive color of materials but
+// luminance of HDR image. Colors with luminance above Threshold are extracted to half size image, blurred and added back
+// to image. With default threshold 1, emissive materials bloom when their emissive intensity is above 1.
+// Bloom should be before ToneMap
+type Bloom struct {
+	// Threshold is luminance where bloom starts
+	Threshold float32
+	// Intensity of blurred image added back to image
+	Intensity float32
+	// Spread scales blur radius
+	Spread float32
+	// Passes is number of horizontal and vertical blur pass pairs
+	Passes int
+}
+
+// NewBloom creates bloom effect with default settings
+func NewBloom() *Bloom {
+	return &Bloom{Threshold: 1, Intensity: 0.5, Spread: 1, Passes: 2}
+}
+
+const (
+	bloomExtract = 0
+	bloomBlur    = 1
+	bloomCombine = 2
+)
+
+var kBloomPipeline = vk.NewKey()
+var kBloomImages = vk.NewKeys(2)
+
+func (b *Bloom) Apply(pc *PassContext) {
+	if b.Intensity <= 0 {
+		return
+	}
+	t1, t2 := pc.Temp(kBloomImages, 2), pc.Temp(kBloomImages+1, 2)
+	pc.Draw(t1, kBloomPipeline, bloom_frag_spv, []mgl32.Vec4{{bloomExtract, b.Threshold}}, pc.Input)
+	for idx := 0; idx < b.Passes; idx++ {
+		pc.Draw(t2, kBloomPipeline, bloom_frag_spv, []mgl32.Vec4{{bloomBlur, 0, b.Spread, 0}}, t1.View)
+		pc.Draw(t1, kBloomPipeline, bloom_frag_spv, []mgl32.Vec4{{bloomBlur, 0, 0, b.Spread}}, t2.View)
+	}
+	pc.Draw(pc.Output, kBloomPipeline, bloom_frag_spv, []mgl32.Vec4{{bloomCombine, b.Threshold, b.Intensity}},
+		pc.Input, t1.View)
+}
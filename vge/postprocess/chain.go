@@ -0,0 +1,256 @@
+// Package postprocess implements post processing effects for forward and deferred renderers.
+//
+// Renderer with post processing draws scene to HDR image. Chain applies effects in order they were added,
+// each effect reading output of previous one. Last effect writes final image to main view (swapchain image).
+// UI layer is drawn after post processing so it will not be tone mapped.
+package postprocess
+
+import (
+	"errors"
+	"image"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+)
+
+// HDRFormat is format of HDR image and intermediate images of post processing
+const HDRFormat = vk.FORMATR16g16b16a16Sfloat
+
+// MaxPasses is maximum number of post processing passes per frame
+const MaxPasses = 32
+
+// MaxImages is maximum number of images that can be bound to single post processing pass
+const MaxImages = 4
+
+// Effect is single step in post processing chain
+type Effect interface {
+	// Apply renders effect from pc.Input to pc.Output using PassContext.Draw. Effect may also use temporary targets.
+	// If effect don't draw anything to pc.Output (effect is disabled), input is passed to next effect as is
+	Apply(pc *PassContext)
+}
+
+// Settings are uniform values of post processing pass, see post.glsl
+type Settings struct {
+	// TargetSize is width, height, 1 / width and 1 / height of target image
+	TargetSize mgl32.Vec4
+	// InputSize is width, height, 1 / width and 1 / height of input image
+	InputSize     mgl32.Vec4
+	InvProjection mgl32.Mat4
+	// Values are effect specific settings
+	Values [8]mgl32.Vec4
+}
+
+// Target is image that post processing pass renders to
+type Target struct {
+	View  *vk.ImageView
+	Size  image.Point
+	rp    *vk.GeneralRenderPass
+	fb    *vk.Framebuffer
+	final bool
+}
+
+// Chain is ordered list of post processing effects. Add chain to renderer before renderer is set up.
+// Renderer will dispose chain when it is disposed
+type Chain struct {
+	effects []Effect
+	desc    vk.ImageDescription
+	dev     *vk.Device
+	rpHDR   *vk.GeneralRenderPass
+	rpFinal *vk.GeneralRenderPass
+	key     vk.Key
+}
+
+// NewChain creates new post processing chain with given effects
+func NewChain(effects ...Effect) *Chain {
+	return &Chain{effects: effects, key: vk.NewKey()}
+}
+
+// Add appends effect to end of chain
+func (c *Chain) Add(effect Effect) *Chain {
+	c.effects = append(c.effects, effect)
+	return c
+}
+
+func (c *Chain) Dispose() {
+	if c.rpHDR != nil {
+		c.rpHDR.Dispose()
+		c.rpHDR = nil
+	}
+	if c.rpFinal != nil {
+		c.rpFinal.Dispose()
+		c.rpFinal = nil
+	}
+}
+
+// Setup is called from renderers Setup. MainImage is description of final image.
+func (c *Chain) Setup(ctx vk.APIContext, dev *vk.Device, mainImage vk.ImageDescription) {
+	c.desc = mainImage
+	if c.rpHDR != nil {
+		return
+	}
+	c.dev = dev
+	c.rpHDR = vk.NewGeneralRenderPass(ctx, dev, false, []vk.AttachmentInfo{
+		{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: vk.IMAGELayoutShaderReadOnlyOptimal, Format: HDRFormat},
+	})
+	c.rpFinal = vk.NewGeneralRenderPass(ctx, dev, false, []vk.AttachmentInfo{
+		{InitialLayout: vk.IMAGELayoutUndefined, FinalLayout: vk.IMAGELayoutPresentSrcKhr, Format: mainImage.Format},
+	})
+}
+
+// HDRDescription is description of HDR image where renderer should draw scene
+func (c *Chain) HDRDescription() vk.ImageDescription {
+	desc := c.desc
+	desc.Format, desc.MipLevels, desc.Layers, desc.Depth = HDRFormat, 1, 1, 1
+	return desc
+}
+
+// HDRView returns view of HDR image where renderer should draw scene. At end of scene render pass HDR image
+// must be in IMAGELayoutShaderReadOnlyOptimal layout. Each render cache has its own HDR image
+func (c *Chain) HDRView(rc *vk.RenderCache) *vk.ImageView {
+	return c.getResources(rc).hdr.View
+}
+
+// FinalPass returns render pass of final image. Renderer can use it to draw UI after all effects
+func (c *Chain) FinalPass() *vk.GeneralRenderPass {
+	return c.rpFinal
+}
+
+// Render applies all effects to HDR image and writes result to mainView. Render pass of final image is left open
+// so that renderer can draw UI over final image. Renderer must end render pass.
+// Depth view can be nil if renderer has no depth buffer
+func (c *Chain) Render(cmd *vk.Command, rc *vk.RenderCache, mainView *vk.ImageView, depthView *vk.ImageView,
+	projection mgl32.Mat4, view mgl32.Mat4) {
+	rsr := c.getResources(rc)
+	rsr.passes = 0
+	final := rsr.getFinal(rc.Ctx, c, mainView)
+	pc := &PassContext{Cmd: cmd, Cache: rc, Input: rsr.hdr.View, Depth: depthView, Projection: projection, View: view,
+		Size: rsr.hdr.Size, rsr: rsr, chain: c}
+	next := 0
+	for idx, ef := range c.effects {
+		if idx == len(c.effects)-1 {
+			pc.Output = final
+		} else {
+			pc.Output = rsr.pingPong[next]
+		}
+		pc.outputDrawn = false
+		ef.Apply(pc)
+		if pc.outputDrawn {
+			pc.Input = pc.Output.View
+			next = 1 - next
+		}
+	}
+	if pc.Output != final || !pc.outputDrawn {
+		pc.Draw(final, kCopyPipeline, copy_frag_spv, nil, pc.Input)
+	}
+}
+
+var kCopyPipeline = vk.NewKey()
+var kPostLayout = vk.NewKey()
+var kPostSampler = vk.NewKey()
+
+func getLayout(ctx vk.APIContext, dev *vk.Device) *vk.DescriptorLayout {
+	return dev.Get(ctx, kPostLayout, func(ctx vk.APIContext) interface{} {
+		la := vk.NewDescriptorLayout(ctx, dev, vk.DESCRIPTORTypeUniformBuffer, vk.SHADERStageFragmentBit, 1)
+		return la.AddBinding(ctx, vk.DESCRIPTORTypeCombinedImageSampler, vk.SHADERStageFragmentBit, MaxImages)
+	}).(*vk.DescriptorLayout)
+}
+
+func getSampler(ctx vk.APIContext, dev *vk.Device) *vk.Sampler {
+	return dev.Get(ctx, kPostSampler, func(ctx vk.APIContext) interface{} {
+		return vk.NewSampler(ctx, dev, vk.SAMPLERAddressModeClampToEdge)
+	}).(*vk.Sampler)
+}
+
+// PassContext is given to effects when chain is rendered
+type PassContext struct {
+	Cmd   *vk.Command
+	Cache *vk.RenderCache
+	// Input is output of previous effect or HDR image of scene
+	Input *vk.ImageView
+	// Depth is depth image of scene. Depth is nil if renderer has no depth buffer
+	Depth      *vk.ImageView
+	Projection mgl32.Mat4
+	View       mgl32.Mat4
+	// Size of final image
+	Size image.Point
+	// Output is target where effect must render its result
+	Output *Target
+
+	outputDrawn bool
+	rsr         *resources
+	chain       *Chain
+}
+
+// Temp returns temporary target for effect. Size of temporary image is size of final image divided with divider.
+// Temporary target with same key is reused in all frames
+func (pc *PassContext) Temp(key vk.Key, divider int) *Target {
+	return pc.rsr.getTemp(pc.Cache.Ctx, pc.chain, key, divider)
+}
+
+// Draw renders full screen pass to target using given fragment shader. Fragment shader should include post.glsl.
+// Images are bound to images array in post.glsl, unused image slots are filled with first image.
+// Key is used to cache pipeline
+func (pc *PassContext) Draw(target *Target, key vk.Key, fragment []byte, values []mgl32.Vec4, images ...*vk.ImageView) {
+	ctx := pc.Cache.Ctx
+	if pc.rsr.passes >= MaxPasses {
+		ctx.SetError(errors.New("Too many post processing passes"))
+		return
+	}
+	if len(images) == 0 || len(images) > MaxImages {
+		ctx.SetError(errors.New("Post processing pass must have 1 - 4 images"))
+		return
+	}
+	pl := target.rp.Get(ctx, key, func(ctx vk.APIContext) interface{} {
+		return newPipeline(ctx, pc.chain.dev, target.rp, fragment)
+	}).(*vk.GraphicsPipeline)
+	ds, sl := pc.rsr.dss[pc.rsr.passes], pc.rsr.ubfs[pc.rsr.passes]
+	pc.rsr.passes++
+	s := Settings{TargetSize: sizeOf(target.Size), InputSize: sizeOf(pc.imageSize(images[0])),
+		InvProjection: pc.Projection.Inv()}
+	copy(s.Values[:], values)
+	b := *(*[unsafe.Sizeof(Settings{})]byte)(unsafe.Pointer(&s))
+	copy(sl.Content, b[:])
+	ds.WriteSlice(ctx, 0, 0, sl)
+	sampler := getSampler(ctx, pc.chain.dev)
+	for idx := 0; idx < MaxImages; idx++ {
+		if idx < len(images) && images[idx] != nil {
+			ds.WriteImage(ctx, 1, uint32(idx), images[idx], sampler)
+		} else {
+			ds.WriteImage(ctx, 1, uint32(idx), images[0], sampler)
+		}
+	}
+	dl := &vk.DrawList{}
+	dl.Draw(pl, 0, 6).AddDescriptor(0, ds)
+	pc.Cmd.BeginRenderPass(target.rp, target.fb)
+	pc.Cmd.Draw(dl)
+	if !target.final {
+		pc.Cmd.EndRenderPass()
+	}
+	if target == pc.Output {
+		pc.outputDrawn = true
+	}
+}
+
+// imageSize returns size of temporary image or size of final image for all other images
+func (pc *PassContext) imageSize(view *vk.ImageView) image.Point {
+	for _, t := range pc.rsr.temps {
+		if t.View == view {
+			return t.Size
+		}
+	}
+	return pc.rsr.hdr.Size
+}
+
+func sizeOf(size image.Point) mgl32.Vec4 {
+	return mgl32.Vec4{float32(size.X), float32(size.Y), 1 / float32(size.X), 1 / float32(size.Y)}
+}
+
+func newPipeline(ctx vk.APIContext, dev *vk.Device, rp *vk.GeneralRenderPass, fragment []byte) *vk.GraphicsPipeline {
+	gp := vk.NewGraphicsPipeline(ctx, dev)
+	gp.AddLayout(ctx, getLayout(ctx, dev))
+	gp.AddShader(ctx, vk.SHADERStageVertexBit, post_vert_spv)
+	gp.AddShader(ctx, vk.SHADERStageFragmentBit, fragment)
+	gp.Create(ctx, rp)
+	return gp
+}
@@ -0,0 +1,8 @@
+#version 450
+#extension GL_GOOGLE_include_directive: require
+
+#include "post.glsl"
+
+void main() {
+    o_color = vec4(texture(images[0], i_uv).rgb, 1);
+}
@@ -0,0 +1,20 @@
+#version 450
+#extension GL_GOOGLE_include_directive: require
+
+#include "post.glsl"
+
+void main() {
+    vec4 fogColor = settings.values[0]; // w = density
+    vec4 fog = settings.values[1];      // x = start, y = max fog, z = fog background
+    vec3 color = texture(images[0], i_uv).rgb;
+    float depth = texture(images[1], i_uv).r;
+    float factor;
+    if (depth >= 1.0) {
+        factor = fog.z > 0 ? fog.y : 0.0;
+    } else {
+        vec4 viewPos = settings.invProjection * vec4(i_uv * 2.0 - 1.0, depth, 1.0);
+        float distance = length(viewPos.xyz / viewPos.w);
+        factor = min(1.0 - exp(-fogColor.w * max(distance - fog.x, 0.0)), fog.y);
+    }
+    o_color = vec4(mix(color, fogColor.rgb, factor), 1);
+}
@@ -0,0 +1,39 @@
+package postprocess
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+)
+
+// Fog blends scene towards fog color based on distance from camera. Fog uses exponential falloff starting from Start
+// distance. Fog requires depth buffer and it should be before ToneMap
+type Fog struct {
+	Color mgl32.Vec3
+	// Density of fog. Fog factor is 1 - exp(-Density * (distance - Start))
+	Density float32
+	// Start is distance from camera where fog starts
+	Start float32
+	// MaxFog limits fog factor. Use value below 1 to keep distant objects visible
+	MaxFog float32
+	// Background applies full fog also to background (pixels where depth buffer is empty)
+	Background bool
+}
+
+// NewFog creates fog effect with given color and density
+func NewFog(color mgl32.Vec3, density float32) *Fog {
+	return &Fog{Color: color, Density: density, MaxFog: 1}
+}
+
+var kFogPipeline = vk.NewKey()
+
+func (f *Fog) Apply(pc *PassContext) {
+	if pc.Depth == nil || f.Density <= 0 {
+		return
+	}
+	var bg float32
+	if f.Background {
+		bg = 1
+	}
+	values := []mgl32.Vec4{f.Color.Vec4(f.Density), {f.Start, f.MaxFog, bg}}
+	pc.Draw(pc.Output, kFogPipeline, fog_frag_spv, values, pc.Input, pc.Depth)
+}
@@ -0,0 +1,106 @@
+#version 450
+#extension GL_GOOGLE_include_directive: require
+
+#include "post.glsl"
+
+// Simplified FXAA 3.11 (quality preset 12)
+
+#define STEPS 5
+const float stepSizes[STEPS] = float[](1.0, 1.5, 2.0, 4.0, 12.0);
+
+float lumaAt(vec2 uv) {
+    return luminance(texture(images[0], uv).rgb);
+}
+
+void main() {
+    vec4 fx = settings.values[0]; // x = edge threshold, y = edge threshold min, z = subpix
+    vec2 texel = settings.inputSize.zw;
+    vec4 colorM = texture(images[0], i_uv);
+    float lumaM = luminance(colorM.rgb);
+    float lumaN = lumaAt(i_uv + vec2(0, -texel.y));
+    float lumaS = lumaAt(i_uv + vec2(0, texel.y));
+    float lumaE = lumaAt(i_uv + vec2(texel.x, 0));
+    float lumaW = lumaAt(i_uv + vec2(-texel.x, 0));
+    float lumaMax = max(lumaM, max(max(lumaN, lumaS), max(lumaE, lumaW)));
+    float lumaMin = min(lumaM, min(min(lumaN, lumaS), min(lumaE, lumaW)));
+    float range = lumaMax - lumaMin;
+    if (range < max(fx.y, lumaMax * fx.x)) {
+        o_color = vec4(colorM.rgb, 1);
+        return;
+    }
+    float lumaNW = lumaAt(i_uv + vec2(-texel.x, -texel.y));
+    float lumaNE = lumaAt(i_uv + vec2(texel.x, -texel.y));
+    float lumaSW = lumaAt(i_uv + vec2(-texel.x, texel.y));
+    float lumaSE = lumaAt(i_uv + vec2(texel.x, texel.y));
+
+    // Sub pixel aliasing
+    float lumaL = (2.0 * (lumaN + lumaS + lumaE + lumaW) + lumaNW + lumaNE + lumaSW + lumaSE) / 12.0;
+    float subpix = clamp(abs(lumaL - lumaM) / range, 0.0, 1.0);
+    subpix = smoothstep(0.0, 1.0, subpix);
+    subpix = subpix * subpix * fx.z;
+
+    // Edge direction
+    float edgeH = abs(lumaNW + lumaNE - 2.0 * lumaN) + 2.0 * abs(lumaW + lumaE - 2.0 * lumaM) + abs(lumaSW + lumaSE - 2.0 * lumaS);
+    float edgeV = abs(lumaNW + lumaSW - 2.0 * lumaW) + 2.0 * abs(lumaN + lumaS - 2.0 * lumaM) + abs(lumaNE + lumaSE - 2.0 * lumaE);
+    bool horizontal = edgeH >= edgeV;
+    float stepLength = horizontal ? texel.y : texel.x;
+    float luma1 = horizontal ? lumaN : lumaW;
+    float luma2 = horizontal ? lumaS : lumaE;
+    float gradient1 = abs(luma1 - lumaM);
+    float gradient2 = abs(luma2 - lumaM);
+    float lumaEdge;
+    if (gradient1 >= gradient2) {
+        stepLength = -stepLength;
+        lumaEdge = 0.5 * (luma1 + lumaM);
+    } else {
+        lumaEdge = 0.5 * (luma2 + lumaM);
+    }
+    float gradient = 0.25 * max(gradient1, gradient2);
+
+    // Search end of edge in both directions
+    vec2 uvEdge = i_uv;
+    vec2 edgeStep;
+    if (horizontal) {
+        uvEdge.y += stepLength * 0.5;
+        edgeStep = vec2(texel.x, 0);
+    } else {
+        uvEdge.x += stepLength * 0.5;
+        edgeStep = vec2(0, texel.y);
+    }
+    vec2 uvP = uvEdge + edgeStep;
+    vec2 uvN = uvEdge - edgeStep;
+    float endP = lumaAt(uvP) - lumaEdge;
+    float endN = lumaAt(uvN) - lumaEdge;
+    bool doneP = abs(endP) >= gradient;
+    bool doneN = abs(endN) >= gradient;
+    for (int idx = 1; idx < STEPS && !(doneP && doneN); idx++) {
+        if (!doneP) {
+            uvP += edgeStep * stepSizes[idx];
+            endP = lumaAt(uvP) - lumaEdge;
+            doneP = abs(endP) >= gradient;
+        }
+        if (!doneN) {
+            uvN -= edgeStep * stepSizes[idx];
+            endN = lumaAt(uvN) - lumaEdge;
+            doneN = abs(endN) >= gradient;
+        }
+    }
+    float distP = horizontal ? uvP.x - i_uv.x : uvP.y - i_uv.y;
+    float distN = horizontal ? i_uv.x - uvN.x : i_uv.y - uvN.y;
+    bool closerP = distP < distN;
+    float dist = min(distP, distN);
+    float endLuma = closerP ? endP : endN;
+    float edgeOffset = 0.0;
+    // Blend only if luma at end of edge changes in correct direction
+    if ((lumaM - lumaEdge < 0.0) != (endLuma < 0.0)) {
+        edgeOffset = 0.5 - dist / (distP + distN);
+    }
+    float offset = max(edgeOffset, subpix);
+    vec2 uv = i_uv;
+    if (horizontal) {
+        uv.y += offset * stepLength;
+    } else {
+        uv.x += offset * stepLength;
+    }
+    o_color = vec4(texture(images[0], uv).rgb, 1);
+}
@@ -0,0 +1,29 @@
+package postprocess
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+)
+
+// FXAA is fast approximate anti aliasing. FXAA expects tone mapped colors so it should be after ToneMap,
+// usually as last effect
+type FXAA struct {
+	// EdgeThreshold is minimum local contrast that is anti aliased
+	EdgeThreshold float32
+	// EdgeThresholdMin skips dark areas with lower contrast
+	EdgeThresholdMin float32
+	// Subpix is amount of sub pixel aliasing removal. 0 is off and 1 is softest
+	Subpix float32
+}
+
+// NewFXAA creates FXAA effect with default (quality) settings
+func NewFXAA() *FXAA {
+	return &FXAA{EdgeThreshold: 0.166, EdgeThresholdMin: 0.0833, Subpix: 0.75}
+}
+
+var kFXAAPipeline = vk.NewKey()
+
+func (f *FXAA) Apply(pc *PassContext) {
+	values := []mgl32.Vec4{{f.EdgeThreshold, f.EdgeThresholdMin, f.Subpix}}
+	pc.Draw(pc.Output, kFXAAPipeline, fxaa_frag_spv, values, pc.Input)
+}
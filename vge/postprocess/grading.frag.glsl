@@ -0,0 +1,23 @@
+#version 450
+#extension GL_GOOGLE_include_directive: require
+
+#include "post.glsl"
+
+// Sample LUT strip (see postprocess.ColorGrading) as 3D texture
+vec3 lookup(vec3 color, float size) {
+    color = clamp(color, 0.0, 1.0);
+    float slice = color.b * (size - 1.0);
+    float slice0 = floor(slice);
+    float slice1 = min(slice0 + 1.0, size - 1.0);
+    float y = (color.g * (size - 1.0) + 0.5) / size;
+    float x = color.r * (size - 1.0) + 0.5;
+    vec3 c0 = texture(images[1], vec2((slice0 * size + x) / (size * size), y)).rgb;
+    vec3 c1 = texture(images[1], vec2((slice1 * size + x) / (size * size), y)).rgb;
+    return mix(c0, c1, slice - slice0);
+}
+
+void main() {
+    vec4 g = settings.values[0]; // x = LUT size, y = strength
+    vec3 color = texture(images[0], i_uv).rgb;
+    o_color = vec4(mix(color, lookup(color, g.x), g.y), 1);
+}
@@ -0,0 +1,61 @@
+package postprocess
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vasset"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// ColorGrading maps colors using 3D lookup table (LUT). LUT is stored as horizontal strip of N slices where each slice is
+// N x N image (width N * N and height N). Red grows along x axis of slice, green along y axis and blue selects slice.
+// Color grading expects tone mapped colors so it should be after ToneMap.
+// Use Dispose to release LUT image
+type ColorGrading struct {
+	// Strength blends between original (0) and graded (1) color
+	Strength float32
+
+	pool    *vk.MemoryPool
+	lut     *vk.Image
+	lutSize uint32
+}
+
+// NewColorGrading loads LUT from image content. Kind is image kind like png or dds
+func NewColorGrading(ctx vk.APIContext, dev *vk.Device, kind string, content []byte) *ColorGrading {
+	var desc vk.ImageDescription
+	vasset.DescribeImage(ctx, kind, &desc, content)
+	if !ctx.IsValid() {
+		return nil
+	}
+	if desc.Height < 2 || desc.Width != desc.Height*desc.Height {
+		ctx.SetError(fmt.Errorf("Invalid LUT size %dx%d, width must be height * height", desc.Width, desc.Height))
+		return nil
+	}
+	cg := &ColorGrading{Strength: 1, lutSize: desc.Height}
+	cg.pool = vk.NewMemoryPool(dev)
+	cg.lut = cg.pool.ReserveImage(ctx, desc, vk.IMAGEUsageTransferDstBit|vk.IMAGEUsageSampledBit)
+	cg.pool.Allocate(ctx)
+	cp := vmodel.NewCopier(ctx, dev)
+	defer cp.Dispose()
+	cp.CopyToImage(cg.lut, kind, content, desc.FullRange(), vk.IMAGELayoutShaderReadOnlyOptimal)
+	return cg
+}
+
+func (cg *ColorGrading) Dispose() {
+	if cg.pool != nil {
+		cg.pool.Dispose()
+		cg.pool, cg.lut = nil, nil
+	}
+}
+
+var kGradingPipeline = vk.NewKey()
+
+func (cg *ColorGrading) Apply(pc *PassContext) {
+	if cg.lut == nil || cg.Strength <= 0 {
+		return
+	}
+	values := []mgl32.Vec4{{float32(cg.lutSize), cg.Strength}}
+	pc.Draw(pc.Output, kGradingPipeline, grading_frag_spv, values, pc.Input, cg.lut.DefaultView(pc.Cache.Ctx))
+}
@@ -0,0 +1,18 @@
+// Common declarations of post processing fragment shaders, see postprocess.Settings
+
+layout(location = 0) in vec2 i_uv;
+
+layout(location = 0) out vec4 o_color;
+
+layout(set=0, binding=0) uniform SETTINGS {
+    vec4 targetSize;    // width, height, 1 / width, 1 / height
+    vec4 inputSize;     // size of images[0]
+    mat4 invProjection;
+    vec4 values[8];     // effect specific values
+} settings;
+
+layout(set=0, binding=1) uniform sampler2D images[4];
+
+float luminance(vec3 color) {
+    return dot(color, vec3(0.2126, 0.7152, 0.0722));
+}
@@ -0,0 +1,130 @@
+package postprocess
+
+import (
+	"image"
+
+	"github.com/lakal3/vge/vge/vk"
+)
+
+// resources are images and descriptors of chain bound to single render cache (swapchain image)
+type resources struct {
+	pool     *vk.MemoryPool
+	hdr      *Target
+	pingPong [2]*Target
+	temps    map[vk.Key]*tempTarget
+	final    *Target
+	dsPool   *vk.DescriptorPool
+	dss      []*vk.DescriptorSet
+	ubfs     []*vk.Slice
+	passes   int
+}
+
+type tempTarget struct {
+	Target
+	pool *vk.MemoryPool
+}
+
+func (t *tempTarget) Dispose() {
+	if t.fb != nil {
+		t.fb.Dispose()
+		t.fb = nil
+	}
+	if t.pool != nil {
+		t.pool.Dispose()
+		t.pool = nil
+	}
+}
+
+func (r *resources) Dispose() {
+	for _, t := range r.temps {
+		t.Dispose()
+	}
+	r.temps = nil
+	for _, t := range []*Target{r.hdr, r.pingPong[0], r.pingPong[1], r.final} {
+		if t != nil && t.fb != nil {
+			t.fb.Dispose()
+			t.fb = nil
+		}
+	}
+	if r.dsPool != nil {
+		r.dsPool.Dispose()
+		r.dsPool, r.dss = nil, nil
+	}
+	if r.pool != nil {
+		r.pool.Dispose()
+		r.pool = nil
+	}
+}
+
+func (c *Chain) getResources(rc *vk.RenderCache) *resources {
+	return rc.Get(c.key, func(ctx vk.APIContext) interface{} {
+		return c.newResources(ctx)
+	}).(*resources)
+}
+
+func (c *Chain) newResources(ctx vk.APIContext) *resources {
+	r := &resources{temps: make(map[vk.Key]*tempTarget)}
+	desc := c.HDRDescription()
+	size := image.Pt(int(desc.Width), int(desc.Height))
+	r.pool = vk.NewMemoryPool(c.dev)
+	var images [3]*vk.Image
+	for idx := range images {
+		images[idx] = r.pool.ReserveImage(ctx, desc, vk.IMAGEUsageColorAttachmentBit|vk.IMAGEUsageSampledBit|
+			vk.IMAGEUsageTransferSrcBit)
+	}
+	ubf := r.pool.ReserveBuffer(ctx, vk.MinUniformBufferOffsetAlignment*MaxPasses, true, vk.BUFFERUsageUniformBufferBit)
+	r.pool.Allocate(ctx)
+	r.hdr = &Target{View: images[0].DefaultView(ctx), Size: size, rp: c.rpHDR}
+	for idx := 0; idx < 2; idx++ {
+		view := images[idx+1].DefaultView(ctx)
+		r.pingPong[idx] = &Target{View: view, Size: size, rp: c.rpHDR,
+			fb: vk.NewFramebuffer(ctx, c.rpHDR, []*vk.ImageView{view})}
+	}
+	r.dsPool = vk.NewDescriptorPool(ctx, getLayout(ctx, c.dev), MaxPasses)
+	for idx := uint64(0); idx < MaxPasses; idx++ {
+		r.dss = append(r.dss, r.dsPool.Alloc(ctx))
+		r.ubfs = append(r.ubfs, ubf.Slice(ctx, idx*vk.MinUniformBufferOffsetAlignment,
+			(idx+1)*vk.MinUniformBufferOffsetAlignment))
+	}
+	return r
+}
+
+// getFinal returns target for main view. Main view of render cache should not change but if it does,
+// framebuffer is recreated
+func (r *resources) getFinal(ctx vk.APIContext, c *Chain, mainView *vk.ImageView) *Target {
+	if r.final != nil && r.final.View == mainView {
+		return r.final
+	}
+	if r.final != nil {
+		r.final.fb.Dispose()
+	}
+	r.final = &Target{View: mainView, Size: r.hdr.Size, rp: c.rpFinal, final: true,
+		fb: vk.NewFramebuffer(ctx, c.rpFinal, []*vk.ImageView{mainView})}
+	return r.final
+}
+
+func (r *resources) getTemp(ctx vk.APIContext, c *Chain, key vk.Key, divider int) *Target {
+	t, ok := r.temps[key]
+	if ok {
+		return &t.Target
+	}
+	if divider < 1 {
+		divider = 1
+	}
+	desc := c.HDRDescription()
+	desc.Width, desc.Height = max1(desc.Width/uint32(divider)), max1(desc.Height/uint32(divider))
+	t = &tempTarget{pool: vk.NewMemoryPool(c.dev)}
+	img := t.pool.ReserveImage(ctx, desc, vk.IMAGEUsageColorAttachmentBit|vk.IMAGEUsageSampledBit)
+	t.pool.Allocate(ctx)
+	t.View, t.Size, t.rp = img.DefaultView(ctx), image.Pt(int(desc.Width), int(desc.Height)), c.rpHDR
+	t.fb = vk.NewFramebuffer(ctx, c.rpHDR, []*vk.ImageView{t.View})
+	r.temps[key] = t
+	return &t.Target
+}
+
+func max1(v uint32) uint32 {
+	if v < 1 {
+		return 1
+	}
+	return v
+}
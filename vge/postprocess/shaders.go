@@ -0,0 +1,32 @@
+package postprocess
+
+import _ "embed"
+
+//go:generate glslangValidator -V post.vert.glsl -o post.vert.spv
+//go:generate glslangValidator -V copy.frag.glsl -o copy.frag.spv
+//go:generate glslangValidator -V tonemap.frag.glsl -o tonemap.frag.spv
+//go:generate glslangValidator -V bloom.frag.glsl -o bloom.frag.spv
+//go:generate glslangValidator -V fog.frag.glsl -o fog.frag.spv
+//go:generate glslangValidator -V fxaa.frag.glsl -o fxaa.frag.spv
+//go:generate glslangValidator -V grading.frag.glsl -o grading.frag.spv
+
+//go:embed post.vert.spv
+var post_vert_spv []byte
+
+//go:embed copy.frag.spv
+var copy_frag_spv []byte
+
+//go:embed tonemap.frag.spv
+var tonemap_frag_spv []byte
+
+//go:embed bloom.frag.spv
+var bloom_frag_spv []byte
+
+//go:embed fog.frag.spv
+var fog_frag_spv []byte
+
+//go:embed fxaa.frag.spv
+var fxaa_frag_spv []byte
+
+//go:embed grading.frag.spv
+var grading_frag_spv []byte
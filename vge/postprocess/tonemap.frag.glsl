@@ -0,0 +1,38 @@
+#version 450
+#extension GL_GOOGLE_include_directive: require
+
+#include "post.glsl"
+
+#define MODE_ACES 0
+#define MODE_REINHARD 1
+
+// Narkowicz 2015, ACES Filmic Tone Mapping Curve
+vec3 aces(vec3 x) {
+    const float a = 2.51;
+    const float b = 0.03;
+    const float c = 2.43;
+    const float d = 0.59;
+    const float e = 0.14;
+    return clamp((x * (a * x + b)) / (x * (c * x + d) + e), 0.0, 1.0);
+}
+
+// Extended Reinhard applied to luminance
+vec3 reinhard(vec3 color, float white) {
+    float l = luminance(color);
+    if (l <= 0) {
+        return vec3(0);
+    }
+    float lMapped = l * (1 + l / (white * white)) / (1 + l);
+    return clamp(color * (lMapped / l), 0.0, 1.0);
+}
+
+void main() {
+    vec4 tm = settings.values[0]; // x = mode, y = exposure, z = white point, w = 1 / gamma
+    vec3 color = texture(images[0], i_uv).rgb * tm.y;
+    if (int(tm.x) == MODE_REINHARD) {
+        color = reinhard(color, tm.z);
+    } else {
+        color = aces(color);
+    }
+    o_color = vec4(pow(color, vec3(tm.w)), 1);
+}
@@ -0,0 +1,43 @@
+package postprocess
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+)
+
+type ToneMapMode int
+
+const (
+	// ToneMapACES uses filmic ACES curve (Narkowicz fit)
+	ToneMapACES = ToneMapMode(0)
+	// ToneMapReinhard uses extended Reinhard operator with white point
+	ToneMapReinhard = ToneMapMode(1)
+)
+
+// ToneMap maps HDR colors to displayable range. Tone mapping should be before effects that expect colors
+// in range 0 - 1 like FXAA and ColorGrading
+type ToneMap struct {
+	Mode ToneMapMode
+	// Exposure multiplies colors before tone mapping
+	Exposure float32
+	// White is smallest color value mapped to pure white in Reinhard operator
+	White float32
+	// Gamma correction applied after tone mapping. Use 1 (or 0) if main image has SRGB format
+	Gamma float32
+}
+
+// NewToneMap creates tone mapping effect with default settings
+func NewToneMap(mode ToneMapMode) *ToneMap {
+	return &ToneMap{Mode: mode, Exposure: 1, White: 4, Gamma: 1}
+}
+
+var kToneMapPipeline = vk.NewKey()
+
+func (t *ToneMap) Apply(pc *PassContext) {
+	gamma := t.Gamma
+	if gamma <= 0 {
+		gamma = 1
+	}
+	values := []mgl32.Vec4{{float32(t.Mode), t.Exposure, t.White, 1 / gamma}}
+	pc.Draw(pc.Output, kToneMapPipeline, tonemap_frag_spv, values, pc.Input)
+}
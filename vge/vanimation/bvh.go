@@ -85,6 +85,7 @@ func LoadBVH(l vasset.Loader, filename string) (*BVHAnimation, error) {
 //
 // However, currently this will only works if original skin don't have any rotation applied in bone direction
 // and root bone (typically Hips) should have no rotation in pose position. If this not true you will see odd twists at some bones in animated model!
+// Use ToSkin and Retarget if skin rest pose differs from BVH rest pose.
 func (bvh *BVHAnimation) BuildAnimation(sk *vmodel.Skin, mj MapJoint) vmodel.Animation {
 	ba := &buildAnimation{sk: sk}
 	ft, ok := bvh.Values["FrameTime"]
@@ -128,6 +129,61 @@ func (bvh *BVHAnimation) BuildAnimation(sk *vmodel.Skin, mj MapJoint) vmodel.Ani
 	return ba.an
 }
 
+// ToSkin converts BVH hierarchy to skin and BVH motion to animation of that skin. Skin joints are in same order as BVH joints
+// and rest pose of skin is BVH hierarchy without any rotations.
+// Use Retarget to convert animation to another skin.
+func (bvh *BVHAnimation) ToSkin() (*vmodel.Skin, vmodel.Animation) {
+	ft, ok := bvh.Values["FrameTime"]
+	if !ok {
+		ft = float32(1.0 / 60.0)
+	}
+	inp := make([]float32, len(bvh.frames))
+	for idx := 0; idx < len(inp); idx++ {
+		inp[idx] = ft * float32(idx)
+	}
+	sk := &vmodel.Skin{Joints: make([]vmodel.Joint, len(bvh.Joints))}
+	an := vmodel.Animation{}
+	world := make([]mgl32.Mat4, len(bvh.Joints))
+	for idx, j := range bvh.Joints {
+		sk.Joints[idx] = vmodel.Joint{Name: j.Name, Translate: j.Offset, Scale: mgl32.Vec3{1, 1, 1}, Rotate: mgl32.QuatIdent(),
+			Root: j.parent < 0}
+		world[idx] = mgl32.Translate3D(j.Offset[0], j.Offset[1], j.Offset[2])
+		if j.parent >= 0 {
+			sk.Joints[j.parent].Children = append(sk.Joints[j.parent].Children, idx)
+			world[idx] = world[j.parent].Mul4(world[idx])
+		}
+		sk.Joints[idx].InverseMatrix = world[idx].Inv()
+		chRot := vmodel.Channel{Joint: idx, Input: inp, Target: vmodel.TRotation}
+		chMove := vmodel.Channel{Joint: idx, Input: inp, Target: vmodel.TTranslation}
+		for frame := 0; frame < len(bvh.frames); frame++ {
+			if j.hasChannel("rotation") {
+				q := mgl32.Mat4ToQuat(j.GetRotation(frame))
+				chRot.Output = append(chRot.Output, q.V[0], q.V[1], q.V[2], q.W)
+			}
+			if j.hasChannel("position") {
+				pos := j.Offset.Add(j.GetTranslation(frame).Col(3).Vec3())
+				chMove.Output = append(chMove.Output, pos[0], pos[1], pos[2])
+			}
+		}
+		if len(chRot.Output) > 0 {
+			an.Channels = append(an.Channels, chRot)
+		}
+		if len(chMove.Output) > 0 {
+			an.Channels = append(an.Channels, chMove)
+		}
+	}
+	return sk, an
+}
+
+func (j *BvhJoint) hasChannel(suffix string) bool {
+	for _, ch := range j.channels {
+		if strings.HasSuffix(ch, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (bvh *BVHAnimation) read(sc *scanner.Scanner) error {
 	err := bvh.assume(sc, "HIERARCHY")
 	if err != nil {
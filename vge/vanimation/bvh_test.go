@@ -1,9 +1,15 @@
 package vanimation
 
 import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lakal3/vge/vge/vasset"
+	"github.com/lakal3/vge/vge/vmodel"
 )
 
 func TestLoadBVH(t *testing.T) {
@@ -17,3 +23,91 @@ func TestLoadBVH(t *testing.T) {
 		t.Error("Invalid number of frames, assumed 1413 got ", len(bvh.frames))
 	}
 }
+
+func TestWriteBVH(t *testing.T) {
+	l := vasset.DirectoryLoader{Directory: "../../assets/bvh/tests"}
+	bvh, err := LoadBVH(l, "simple1.bvh")
+	if err != nil {
+		t.Fatal("Load BVH failed, ", err)
+	}
+	sk, an := bvh.ToSkin()
+	dir := t.TempDir()
+	fOut, err := os.Create(filepath.Join(dir, "out.bvh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteBVH(fOut, sk, an, 1.0/30)
+	_ = fOut.Close()
+	if err != nil {
+		t.Fatal("Write BVH failed, ", err)
+	}
+	bvh2, err := LoadBVH(vasset.DirectoryLoader{Directory: dir}, "out.bvh")
+	if err != nil {
+		t.Fatal("Reload BVH failed, ", err)
+	}
+	sk2, an2 := bvh2.ToSkin()
+	if len(sk2.Joints) != len(sk.Joints) {
+		t.Fatalf("Joint count mismatch %d != %d", len(sk2.Joints), len(sk.Joints))
+	}
+	at := an.Length() / 2
+	j1 := append([]vmodel.Joint{}, sk.Joints...)
+	j2 := append([]vmodel.Joint{}, sk2.Joints...)
+	an.Apply(at, j1)
+	an2.Apply(at, j2)
+	for idx := range j1 {
+		if !sameRotation(j1[idx].Rotate, j2[idx].Rotate) {
+			t.Errorf("Joint %s rotation %v != %v", j1[idx].Name, j1[idx].Rotate, j2[idx].Rotate)
+		}
+	}
+	if !j1[0].Translate.ApproxEqualThreshold(j2[0].Translate, 0.01) {
+		t.Errorf("Root translation %v != %v", j1[0].Translate, j2[0].Translate)
+	}
+}
+
+func TestRetarget(t *testing.T) {
+	l := vasset.DirectoryLoader{Directory: "../../assets/bvh/tests"}
+	bvh, err := LoadBVH(l, "simple1.bvh")
+	if err != nil {
+		t.Fatal("Load BVH failed, ", err)
+	}
+	src, an := bvh.ToSkin()
+	// Target has double sized bones, prefixed names and different rest rotation in spine
+	tgt := &vmodel.Skin{}
+	names := make(map[string]string)
+	for _, j := range src.Joints {
+		j.Name = "mixamorig:" + j.Name
+		j.Translate = j.Translate.Mul(2)
+		names[strings.TrimPrefix(j.Name, "mixamorig:")] = j.Name
+		tgt.Joints = append(tgt.Joints, j)
+	}
+	spine := DefaultMapJointfunc(src, "Spine")
+	tgt.Joints[spine].Rotate = mgl32.QuatRotate(math.Pi/2, mgl32.Vec3{0, 1, 0})
+	rt := NewRetarget(src, tgt, NameTable(names))
+	tAn := rt.Retarget(an)
+
+	at := float32(1)
+	srcOrder, srcParents := jointOrder(src)
+	srcJoints := append([]vmodel.Joint{}, src.Joints...)
+	tgtJoints := append([]vmodel.Joint{}, tgt.Joints...)
+	an.Apply(at, srcJoints)
+	tAn.Apply(at, tgtJoints)
+	srcWorld := worldRotations(srcJoints, srcOrder, srcParents, nil)
+	tgtWorld := worldRotations(tgtJoints, srcOrder, srcParents, nil)
+	// Source rest pose has no rotations, so spine and its children should be rotated by spine rest rotation
+	tgtRestWorld := worldRotations(tgt.Joints, srcOrder, srcParents, nil)
+	for idx := range srcWorld {
+		expected := srcWorld[idx].Mul(tgtRestWorld[idx])
+		if !sameRotation(expected, tgtWorld[idx]) {
+			t.Errorf("Joint %s world rotation %v != %v", src.Joints[idx].Name, tgtWorld[idx], expected)
+		}
+	}
+	srcMove := srcJoints[0].Translate.Sub(src.Joints[0].Translate).Mul(2)
+	tgtMove := tgtJoints[0].Translate.Sub(tgt.Joints[0].Translate)
+	if !srcMove.ApproxEqualThreshold(tgtMove, 0.01) {
+		t.Errorf("Root move %v != %v", tgtMove, srcMove)
+	}
+}
+
+func sameRotation(q1 mgl32.Quat, q2 mgl32.Quat) bool {
+	return math.Abs(float64(q1.Dot(q2))) > 0.9999
+}
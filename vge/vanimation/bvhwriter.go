@@ -0,0 +1,143 @@
+package vanimation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// WriteBVH writes skin hierarchy and animation in BVH format. Animation is sampled at frameTime intervals (default 1/30 seconds).
+// Joints rest translations are used as BVH offsets. Root joint has position and rotation channels, other joints only rotation channels.
+// Rest rotations of joints are baked into rotation channels and scale is ignored.
+//
+// Skin must have exactly one root joint. Characters that are not valid in BVH names are replaced with underscore.
+func WriteBVH(w io.Writer, sk *vmodel.Skin, an vmodel.Animation, frameTime float32) error {
+	order, parents := jointOrder(sk)
+	root := -1
+	for idx, p := range parents {
+		if p < 0 {
+			if root >= 0 {
+				return errors.New("BVH supports only one root joint")
+			}
+			root = idx
+		}
+	}
+	if root < 0 {
+		return errors.New("Skin has no joints")
+	}
+	if frameTime <= 0 {
+		frameTime = 1.0 / 30.0
+	}
+	bw := &bvhWriter{w: bufio.NewWriter(w), sk: sk}
+	bw.printf("HIERARCHY\n")
+	bw.writeJoint(root, 0)
+	frames := int(math.Ceil(float64(an.Length()/frameTime))) + 1
+	bw.printf("MOTION\nFrames: %d\nFrame Time: %f\n", frames, frameTime)
+	joints := make([]vmodel.Joint, len(sk.Joints))
+	for frame := 0; frame < frames; frame++ {
+		copy(joints, sk.Joints)
+		an.Apply(float32(frame)*frameTime, joints)
+		for _, idx := range order {
+			if idx == root {
+				pos := joints[idx].Translate.Sub(sk.Joints[idx].Translate)
+				bw.printf("%f %f %f ", pos[0], pos[1], pos[2])
+			}
+			z, x, y := toZXY(joints[idx].Rotate.Mat4())
+			bw.printf("%f %f %f ", toDeg(z), toDeg(x), toDeg(y))
+		}
+		bw.printf("\n")
+	}
+	if bw.err != nil {
+		return bw.err
+	}
+	return bw.w.Flush()
+}
+
+type bvhWriter struct {
+	w   *bufio.Writer
+	sk  *vmodel.Skin
+	err error
+}
+
+func (bw *bvhWriter) printf(format string, args ...interface{}) {
+	if bw.err != nil {
+		return
+	}
+	_, bw.err = fmt.Fprintf(bw.w, format, args...)
+}
+
+func (bw *bvhWriter) writeJoint(idx int, level int) {
+	j := bw.sk.Joints[idx]
+	ind := strings.Repeat("\t", level)
+	if level == 0 {
+		bw.printf("ROOT %s\n{\n", bvhName(j.Name, idx))
+	} else {
+		bw.printf("%sJOINT %s\n%s{\n", ind, bvhName(j.Name, idx), ind)
+	}
+	bw.printf("%s\tOFFSET %f %f %f\n", ind, j.Translate[0], j.Translate[1], j.Translate[2])
+	if level == 0 {
+		bw.printf("%s\tCHANNELS 6 Xposition Yposition Zposition Zrotation Xrotation Yrotation\n", ind)
+	} else {
+		bw.printf("%s\tCHANNELS 3 Zrotation Xrotation Yrotation\n", ind)
+	}
+	for _, ch := range j.Children {
+		bw.writeJoint(ch, level+1)
+	}
+	if len(j.Children) == 0 {
+		bw.printf("%s\tEnd Site\n%s\t{\n%s\t\tOFFSET 0 0 0\n%s\t}\n", ind, ind, ind, ind)
+	}
+	bw.printf("%s}\n", ind)
+}
+
+// bvhName replaces characters that BVH reader would not accept
+func bvhName(name string, idx int) string {
+	if len(name) == 0 {
+		return fmt.Sprintf("Joint%d", idx)
+	}
+	sb := strings.Builder{}
+	for pos, r := range name {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if pos == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// toZXY decomposes rotation matrix to euler angles so that m = Rz(z) * Rx(x) * Ry(y)
+func toZXY(m mgl32.Mat4) (z, x, y float32) {
+	sx := m.At(2, 1)
+	if sx > 1 {
+		sx = 1
+	}
+	if sx < -1 {
+		sx = -1
+	}
+	x = float32(math.Asin(float64(sx)))
+	if sx > 0.9999 || sx < -0.9999 {
+		// Gimbal lock, put all rotation to z
+		return atan2(m.At(1, 0), m.At(0, 0)), x, 0
+	}
+	return atan2(-m.At(0, 1), m.At(1, 1)), x, atan2(-m.At(2, 0), m.At(2, 2))
+}
+
+func atan2(y, x float32) float32 {
+	return float32(math.Atan2(float64(y), float64(x)))
+}
+
+func toDeg(f float32) float32 {
+	return f * 180.0 / math.Pi
+}
@@ -0,0 +1,223 @@
+package vanimation
+
+import (
+	"math"
+
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// Clip is an animation played in a mixer layer
+type Clip struct {
+	Animation vmodel.Animation
+	// StartTime is scene time when clip was started
+	StartTime float64
+	// Speed is playback speed multiplier. Zero speed is handled as normal speed
+	Speed float32
+	// Loop animation. If Loop is false, last key of animation is held after animation has ended
+	Loop bool
+
+	fadeDuration float64
+}
+
+// AnimTime calculates time within animation at given scene time
+func (c *Clip) AnimTime(time float64) float32 {
+	speed := float64(c.Speed)
+	if speed == 0 {
+		speed = 1
+	}
+	at := (time - c.StartTime) * speed
+	l := float64(c.Animation.Length())
+	if l <= 0 || at <= 0 {
+		return 0
+	}
+	if c.Loop {
+		return float32(math.Mod(at, l))
+	}
+	if at > l {
+		return float32(l)
+	}
+	return float32(at)
+}
+
+// Ended checks if non looping clip has played to end
+func (c *Clip) Ended(time float64) bool {
+	if c.Loop {
+		return false
+	}
+	return c.AnimTime(time) >= c.Animation.Length()
+}
+
+// fade returns how much of clip is faded in (0 - 1)
+func (c *Clip) fade(time float64) float32 {
+	if c.fadeDuration <= 0 || time >= c.StartTime+c.fadeDuration {
+		return 1
+	}
+	if time <= c.StartTime {
+		return 0
+	}
+	return float32((time - c.StartTime) / c.fadeDuration)
+}
+
+// Layer blends its clips on top of layers before it in mixer. Layer will have multiple clips while cross fading from one clip to another.
+type Layer struct {
+	// Weight of layer. Weight 1 will fully replace pose from previous layers
+	Weight float32
+	// Mask has weight multiplier for each joint of skin. If mask is nil, layer affects all joints. See MaskFrom
+	Mask []float32
+
+	clips      []*Clip
+	pose       []vmodel.Joint
+	tmp        []vmodel.Joint
+	weights    []float32
+	tmpWeights []float32
+}
+
+// Play starts new animation in layer. If fade > 0, previous animation(s) are cross faded to new animation during fade seconds
+func (l *Layer) Play(time float64, an vmodel.Animation, loop bool, fade float64) *Clip {
+	c := &Clip{Animation: an, StartTime: time, Loop: loop, fadeDuration: fade}
+	if fade <= 0 {
+		l.clips = l.clips[:0]
+	}
+	l.clips = append(l.clips, c)
+	return c
+}
+
+// Current returns last started clip. Current is nil if nothing has been played on layer
+func (l *Layer) Current() *Clip {
+	if len(l.clips) == 0 {
+		return nil
+	}
+	return l.clips[len(l.clips)-1]
+}
+
+// Fading checks if layer is still cross fading to current clip
+func (l *Layer) Fading(time float64) bool {
+	c := l.Current()
+	return c != nil && len(l.clips) > 1 && c.fade(time) < 1
+}
+
+func (l *Layer) calcPose(time float64, rest []vmodel.Joint) {
+	// Drop clips that are completely hidden by fully faded in later clip
+	for idx := len(l.clips) - 1; idx > 0; idx-- {
+		if l.clips[idx].fade(time) >= 1 {
+			l.clips = append(l.clips[:0], l.clips[idx:]...)
+			break
+		}
+	}
+	l.pose = append(l.pose[:0], rest...)
+	for idx, c := range l.clips {
+		if idx == 0 {
+			c.Animation.Apply(c.AnimTime(time), l.pose)
+			continue
+		}
+		l.tmp = append(l.tmp[:0], rest...)
+		c.Animation.Apply(c.AnimTime(time), l.tmp)
+		f := c.fade(time)
+		for jIdx := range l.pose {
+			l.pose[jIdx] = l.pose[jIdx].Blend(l.tmp[jIdx], f)
+		}
+	}
+}
+
+func (l *Layer) calcWeights(time float64, rest []float32, weights []float32) {
+	copy(weights, rest)
+	for idx, c := range l.clips {
+		if idx == 0 {
+			c.Animation.ApplyWeights(c.AnimTime(time), weights)
+			continue
+		}
+		l.tmpWeights = append(l.tmpWeights[:0], rest...)
+		c.Animation.ApplyWeights(c.AnimTime(time), l.tmpWeights)
+		f := c.fade(time)
+		for wIdx := range weights {
+			weights[wIdx] = weights[wIdx]*(1-f) + l.tmpWeights[wIdx]*f
+		}
+	}
+}
+
+// Mixer blends animations from multiple layers. Layers are blended in order so that later layers will override earlier ones
+// based on layers weight and mask. Mixer implements vscene.PoseSource and vscene.MorphSource and can be assigned to vscene.AnimatedNodeControl
+//
+// Mixer should only be modified in vscene.Scene.Update if scene is live.
+type Mixer struct {
+	Layers      []*Layer
+	rest        []vmodel.Joint
+	restWeights []float32
+}
+
+// AddLayer adds new layer on top of existing layers
+func (m *Mixer) AddLayer(weight float32, mask []float32) *Layer {
+	l := &Layer{Weight: weight, Mask: mask}
+	m.Layers = append(m.Layers, l)
+	return l
+}
+
+// Pose calculates blended pose from all layers. Joints must be initialized to rest pose of skin
+func (m *Mixer) Pose(time float64, joints []vmodel.Joint) {
+	m.rest = append(m.rest[:0], joints...)
+	for _, l := range m.Layers {
+		if l.Weight <= 0 || len(l.clips) == 0 {
+			continue
+		}
+		l.calcPose(time, m.rest)
+		for jIdx := range joints {
+			w := l.Weight
+			if l.Mask != nil {
+				if jIdx >= len(l.Mask) {
+					continue
+				}
+				w *= l.Mask[jIdx]
+			}
+			if w >= 1 {
+				joints[jIdx] = l.pose[jIdx]
+			} else if w > 0 {
+				joints[jIdx] = joints[jIdx].Blend(l.pose[jIdx], w)
+			}
+		}
+	}
+}
+
+// MorphWeights calculates blended morph target weights from all layers. Weights must be initialized to default weights of mesh.
+// Layer masks don't affect morph weights.
+func (m *Mixer) MorphWeights(time float64, weights []float32) {
+	m.restWeights = append(m.restWeights[:0], weights...)
+	for _, l := range m.Layers {
+		if l.Weight <= 0 || len(l.clips) == 0 {
+			continue
+		}
+		if len(l.weights) != len(weights) {
+			l.weights = make([]float32, len(weights))
+		}
+		l.calcWeights(time, m.restWeights, l.weights)
+		w := l.Weight
+		if w > 1 {
+			w = 1
+		}
+		for wIdx := range weights {
+			weights[wIdx] = weights[wIdx]*(1-w) + l.weights[wIdx]*w
+		}
+	}
+}
+
+// MaskFrom builds layer mask where named joint and all its child joints have given weight. Other joints will have zero weight.
+// Masks can be combined with AddMask
+func MaskFrom(sk *vmodel.Skin, jointName string, weight float32) []float32 {
+	mask := make([]float32, len(sk.Joints))
+	AddMask(sk, mask, jointName, weight)
+	return mask
+}
+
+// AddMask sets weight to named joint and all its child joints in mask
+func AddMask(sk *vmodel.Skin, mask []float32, jointName string, weight float32) {
+	jIdx := DefaultMapJointfunc(sk, jointName)
+	if jIdx >= 0 {
+		addMask(sk, mask, jIdx, weight)
+	}
+}
+
+func addMask(sk *vmodel.Skin, mask []float32, jIdx int, weight float32) {
+	mask[jIdx] = weight
+	for _, ch := range sk.Joints[jIdx].Children {
+		addMask(sk, mask, ch, weight)
+	}
+}
@@ -0,0 +1,72 @@
+package vanimation
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+func testSkin() *vmodel.Skin {
+	sk := &vmodel.Skin{Joints: []vmodel.Joint{
+		{Name: "root", Root: true, Children: []int{1}, Rotate: mgl32.QuatIdent(), Scale: mgl32.Vec3{1, 1, 1}},
+		{Name: "arm", Rotate: mgl32.QuatIdent(), Scale: mgl32.Vec3{1, 1, 1}},
+	}}
+	return sk
+}
+
+func moveAnim(x float32) vmodel.Animation {
+	return vmodel.Animation{Name: "move", Channels: []vmodel.Channel{
+		{Joint: 0, Target: vmodel.TTranslation, Input: []float32{0, 1}, Output: []float32{x, 0, 0, x, 0, 0}},
+		{Joint: 1, Target: vmodel.TTranslation, Input: []float32{0, 1}, Output: []float32{0, x, 0, 0, x, 0}},
+	}}
+}
+
+func restPose(sk *vmodel.Skin) []vmodel.Joint {
+	return append([]vmodel.Joint{}, sk.Joints...)
+}
+
+func TestStateMachine_CrossFade(t *testing.T) {
+	sk := testSkin()
+	sm := NewStateMachine(1)
+	sm.AddState("idle", vmodel.Animation{}, true)
+	sm.AddState("walk", moveAnim(2), true)
+	sm.AddTransition("walk", "idle", 2)
+	if err := sm.SetState(10, "walk"); err != nil {
+		t.Fatal(err)
+	}
+	joints := restPose(sk)
+	sm.Pose(10.5, joints)
+	if joints[0].Translate.X() != 2 {
+		t.Error("First state should not fade, got ", joints[0].Translate)
+	}
+	_ = sm.SetState(11, "idle")
+	joints = restPose(sk)
+	sm.Pose(12, joints)
+	if !joints[0].Translate.ApproxEqual(mgl32.Vec3{1, 0, 0}) {
+		t.Error("Expected half way fade to idle, got ", joints[0].Translate)
+	}
+	joints = restPose(sk)
+	sm.Pose(14, joints)
+	if !joints[0].Translate.ApproxEqual(mgl32.Vec3{0, 0, 0}) {
+		t.Error("Expected rest pose after fade, got ", joints[0].Translate)
+	}
+	if sm.SetState(14, "run") == nil {
+		t.Error("Unknown state should fail")
+	}
+}
+
+func TestMixer_Mask(t *testing.T) {
+	sk := testSkin()
+	m := &Mixer{}
+	m.AddLayer(1, nil).Play(0, moveAnim(1), true, 0)
+	m.AddLayer(0.5, MaskFrom(sk, "arm", 1)).Play(0, moveAnim(3), true, 0)
+	joints := restPose(sk)
+	m.Pose(0.5, joints)
+	if !joints[0].Translate.ApproxEqual(mgl32.Vec3{1, 0, 0}) {
+		t.Error("Masked joint should not change, got ", joints[0].Translate)
+	}
+	if !joints[1].Translate.ApproxEqual(mgl32.Vec3{0, 2, 0}) {
+		t.Error("Expected blended arm, got ", joints[1].Translate)
+	}
+}
@@ -0,0 +1,206 @@
+package vanimation
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// Retarget converts animations made for source skeleton to target skeleton.
+//
+// Retargeting is done in world space. For each mapped joint, world rotation difference from source rest pose is applied to
+// target joint in target rest pose. Source and target rest poses should therefore be similar (for example both in T-pose) but
+// bone orientations and bone rolls may differ. If skin joints are not in similar pose you can give alternative rest poses in
+// SourcePose and TargetPose.
+//
+// Translations are scaled with ratio of target and source bone lengths.
+type Retarget struct {
+	Source *vmodel.Skin
+	Target *vmodel.Skin
+	// Map maps source joint name to target skin joint. See NameTable
+	Map MapJoint
+	// SourcePose is optional rest pose of source skeleton. If nil, source skin joints are used
+	SourcePose []vmodel.Joint
+	// TargetPose is optional rest pose of target skeleton. If nil, target skin joints are used
+	TargetPose []vmodel.Joint
+	// FrameTime is interval at which source animation is sampled. Default is 1/30 seconds
+	FrameTime float32
+	// Scale for root translations. If Scale is 0, scale is calculated from bone lengths of mapped joints
+	Scale float32
+}
+
+// NewRetarget creates new retargeting from source to target skin. If mj is nil, joints are mapped by name
+func NewRetarget(source *vmodel.Skin, target *vmodel.Skin, mj MapJoint) *Retarget {
+	if mj == nil {
+		mj = DefaultMapJointfunc
+	}
+	return &Retarget{Source: source, Target: target, Map: mj, FrameTime: 1.0 / 30.0}
+}
+
+// NameTable returns joint mapping that maps source joint names to target joint names using given table.
+// Joints not in table are not mapped
+func NameTable(names map[string]string) MapJoint {
+	return func(sk *vmodel.Skin, name string) (jIndex int) {
+		tn, ok := names[name]
+		if !ok {
+			return -1
+		}
+		return DefaultMapJointfunc(sk, tn)
+	}
+}
+
+// Retarget converts source animation to target skin. Target animation will have rotation channel for each mapped joint and
+// translation channel for mapped joints that have translation channel in source animation.
+func (r *Retarget) Retarget(an vmodel.Animation) vmodel.Animation {
+	srcRest, tgtRest := r.SourcePose, r.TargetPose
+	if srcRest == nil {
+		srcRest = r.Source.Joints
+	}
+	if tgtRest == nil {
+		tgtRest = r.Target.Joints
+	}
+	srcOrder, srcParents := jointOrder(r.Source)
+	tgtOrder, tgtParents := jointOrder(r.Target)
+	srcOf := make([]int, len(r.Target.Joints))
+	for idx := range srcOf {
+		srcOf[idx] = -1
+	}
+	for idx, j := range r.Source.Joints {
+		tIdx := r.Map(r.Target, j.Name)
+		if tIdx >= 0 && tIdx < len(srcOf) && srcOf[tIdx] < 0 {
+			srcOf[tIdx] = idx
+		}
+	}
+	moves := make(map[int]bool)
+	for _, ch := range an.Channels {
+		if ch.Target == vmodel.TTranslation && len(ch.Input) > 0 {
+			moves[ch.Joint] = true
+		}
+	}
+	scale := r.Scale
+	if scale == 0 {
+		scale = r.boneScale(srcOf, srcParents, tgtParents, srcRest, tgtRest)
+	}
+
+	ft := r.FrameTime
+	if ft <= 0 {
+		ft = 1.0 / 30.0
+	}
+	frames := int(math.Ceil(float64(an.Length()/ft))) + 1
+	input := make([]float32, frames)
+	for idx := range input {
+		input[idx] = float32(idx) * ft
+	}
+	result := vmodel.Animation{Name: an.Name}
+	rotChannels := make([]int, len(srcOf))
+	moveChannels := make([]int, len(srcOf))
+	for tIdx, sIdx := range srcOf {
+		rotChannels[tIdx], moveChannels[tIdx] = -1, -1
+		if sIdx < 0 {
+			continue
+		}
+		rotChannels[tIdx] = len(result.Channels)
+		result.Channels = append(result.Channels, vmodel.Channel{Joint: tIdx, Input: input, Target: vmodel.TRotation})
+		if moves[sIdx] {
+			moveChannels[tIdx] = len(result.Channels)
+			result.Channels = append(result.Channels, vmodel.Channel{Joint: tIdx, Input: input, Target: vmodel.TTranslation})
+		}
+	}
+
+	srcRestWorld := worldRotations(srcRest, srcOrder, srcParents, nil)
+	tgtRestWorld := worldRotations(tgtRest, tgtOrder, tgtParents, nil)
+	srcJoints := make([]vmodel.Joint, len(srcRest))
+	srcWorld := make([]mgl32.Quat, len(srcRest))
+	tgtWorld := make([]mgl32.Quat, len(tgtRest))
+	for _, at := range input {
+		copy(srcJoints, srcRest)
+		an.Apply(at, srcJoints)
+		worldRotations(srcJoints, srcOrder, srcParents, srcWorld)
+		for _, tIdx := range tgtOrder {
+			parent := mgl32.QuatIdent()
+			if tgtParents[tIdx] >= 0 {
+				parent = tgtWorld[tgtParents[tIdx]]
+			}
+			sIdx := srcOf[tIdx]
+			if sIdx < 0 {
+				tgtWorld[tIdx] = parent.Mul(tgtRest[tIdx].Rotate)
+				continue
+			}
+			delta := srcWorld[sIdx].Mul(srcRestWorld[sIdx].Inverse())
+			tgtWorld[tIdx] = delta.Mul(tgtRestWorld[tIdx]).Normalize()
+			q := parent.Inverse().Mul(tgtWorld[tIdx]).Normalize()
+			ch := &result.Channels[rotChannels[tIdx]]
+			ch.Output = append(ch.Output, q.V[0], q.V[1], q.V[2], q.W)
+			if moveChannels[tIdx] < 0 {
+				continue
+			}
+			// Move translation delta from source parent space to target parent space
+			d := srcJoints[sIdx].Translate.Sub(srcRest[sIdx].Translate)
+			if srcParents[sIdx] >= 0 {
+				d = srcWorld[srcParents[sIdx]].Rotate(d)
+			}
+			d = parent.Inverse().Rotate(d).Mul(scale)
+			tr := tgtRest[tIdx].Translate.Add(d)
+			ch = &result.Channels[moveChannels[tIdx]]
+			ch.Output = append(ch.Output, tr[0], tr[1], tr[2])
+		}
+	}
+	return result
+}
+
+// boneScale calculates ratio between target and source bone lengths from joints where joint and its parent are mapped
+func (r *Retarget) boneScale(srcOf []int, srcParents []int, tgtParents []int, srcRest []vmodel.Joint, tgtRest []vmodel.Joint) float32 {
+	var srcLen, tgtLen float32
+	for tIdx, sIdx := range srcOf {
+		if sIdx < 0 || tgtParents[tIdx] < 0 || srcParents[sIdx] < 0 || srcOf[tgtParents[tIdx]] < 0 {
+			continue
+		}
+		srcLen += srcRest[sIdx].Translate.Len()
+		tgtLen += tgtRest[tIdx].Translate.Len()
+	}
+	if srcLen < 0.0001 || tgtLen < 0.0001 {
+		return 1
+	}
+	return tgtLen / srcLen
+}
+
+// jointOrder returns joint indexes so that parents are before children and parent index of each joint (-1 for roots)
+func jointOrder(sk *vmodel.Skin) (order []int, parents []int) {
+	parents = make([]int, len(sk.Joints))
+	for idx := range parents {
+		parents[idx] = -1
+	}
+	for idx, j := range sk.Joints {
+		for _, ch := range j.Children {
+			parents[ch] = idx
+		}
+	}
+	var add func(idx int)
+	add = func(idx int) {
+		order = append(order, idx)
+		for _, ch := range sk.Joints[idx].Children {
+			add(ch)
+		}
+	}
+	for idx, p := range parents {
+		if p < 0 {
+			add(idx)
+		}
+	}
+	return order, parents
+}
+
+func worldRotations(joints []vmodel.Joint, order []int, parents []int, world []mgl32.Quat) []mgl32.Quat {
+	if world == nil {
+		world = make([]mgl32.Quat, len(joints))
+	}
+	for _, idx := range order {
+		if parents[idx] < 0 {
+			world[idx] = joints[idx].Rotate
+		} else {
+			world[idx] = world[parents[idx]].Mul(joints[idx].Rotate)
+		}
+	}
+	return world
+}
@@ -0,0 +1,113 @@
+package vanimation
+
+import (
+	"fmt"
+
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// State is one animation state in state machine
+type State struct {
+	Name      string
+	Animation vmodel.Animation
+	Loop      bool
+	Speed     float32
+	// Next state is activated when non looping animation ends. If next is empty, last key of animation is held
+	Next string
+}
+
+type transitionKey struct {
+	from string
+	to   string
+}
+
+// StateMachine plays one animation state at time and cross fades between states. Game code changes states with SetState.
+// State machine plays animations in first layer of its mixer. You can add more layers to mixer for example to
+// play masked upper body animations on top of state animations.
+//
+// StateMachine implements vscene.PoseSource and vscene.MorphSource and can be assigned to vscene.AnimatedNodeControl.
+// State machine should only be modified in vscene.Scene.Update if scene is live.
+type StateMachine struct {
+	Mixer Mixer
+	// DefaultFade is cross fade duration in seconds used when there is no explicit transition between states
+	DefaultFade float64
+
+	states      map[string]*State
+	transitions map[transitionKey]float64
+	current     *State
+	layer       *Layer
+}
+
+// NewStateMachine creates new state machine with given default cross fade time
+func NewStateMachine(defaultFade float64) *StateMachine {
+	sm := &StateMachine{DefaultFade: defaultFade, states: make(map[string]*State),
+		transitions: make(map[transitionKey]float64)}
+	sm.layer = sm.Mixer.AddLayer(1, nil)
+	return sm
+}
+
+// AddState adds new animation state. Empty animation can be used as a state that returns skin to its rest pose
+func (sm *StateMachine) AddState(name string, an vmodel.Animation, loop bool) *State {
+	st := &State{Name: name, Animation: an, Loop: loop}
+	sm.states[name] = st
+	return st
+}
+
+// AddTransition sets cross fade duration when moving from one state to another. Empty from state matches all states.
+func (sm *StateMachine) AddTransition(from string, to string, duration float64) {
+	sm.transitions[transitionKey{from: from, to: to}] = duration
+}
+
+// Current returns name of active state. Current is empty until first SetState
+func (sm *StateMachine) Current() string {
+	if sm.current == nil {
+		return ""
+	}
+	return sm.current.Name
+}
+
+// SetState starts transition to a new state at given scene time. Setting current state again has no effect.
+// First state is activated without cross fade.
+func (sm *StateMachine) SetState(time float64, name string) error {
+	st, ok := sm.states[name]
+	if !ok {
+		return fmt.Errorf("Unknown animation state %s", name)
+	}
+	if st == sm.current {
+		return nil
+	}
+	fade := 0.0
+	if sm.current != nil {
+		fade = sm.fadeTime(sm.current.Name, name)
+	}
+	sm.current = st
+	c := sm.layer.Play(time, st.Animation, st.Loop, fade)
+	c.Speed = st.Speed
+	return nil
+}
+
+// Pose implements vscene.PoseSource
+func (sm *StateMachine) Pose(time float64, joints []vmodel.Joint) {
+	if sm.current != nil && sm.current.Next != "" {
+		c := sm.layer.Current()
+		if c.Ended(time) {
+			_ = sm.SetState(time, sm.current.Next)
+		}
+	}
+	sm.Mixer.Pose(time, joints)
+}
+
+// MorphWeights implements vscene.MorphSource
+func (sm *StateMachine) MorphWeights(time float64, weights []float32) {
+	sm.Mixer.MorphWeights(time, weights)
+}
+
+func (sm *StateMachine) fadeTime(from string, to string) float64 {
+	if d, ok := sm.transitions[transitionKey{from: from, to: to}]; ok {
+		return d
+	}
+	if d, ok := sm.transitions[transitionKey{to: to}]; ok {
+		return d
+	}
+	return sm.DefaultFade
+}
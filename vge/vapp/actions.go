@@ -0,0 +1,445 @@
+package vapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lakal3/vge/vge/vk"
+)
+
+// Binding binds one input to action or axis. Set one of Key, MouseButton, GamepadButton or GamepadAxis.
+// Inputs are named using keys of Keys, MouseButtons, GamepadButtons and GamepadAxes maps
+type Binding struct {
+	Key           string `json:"key,omitempty"`
+	MouseButton   string `json:"mouseButton,omitempty"`
+	GamepadButton string `json:"gamepadButton,omitempty"`
+	GamepadAxis   string `json:"gamepadAxis,omitempty"`
+	// Mods must also be down to activate binding, for example CTRL for Ctrl+S. Mods are ignored for gamepad inputs
+	Mods Mods `json:"mods,omitempty"`
+	// Scale is value of axis when key or button is down. Gamepad axis values are multiplied with scale. 0 is same as 1.
+	// Action is pressed when value of any of its bindings is at least 0.5
+	Scale float32 `json:"scale,omitempty"`
+	// DeadZone is ignored range of gamepad axis around 0
+	DeadZone float32 `json:"deadZone,omitempty"`
+}
+
+// MouseButtons maps mouse button names to button numbers of MouseDownEvent
+var MouseButtons = map[string]int{
+	"LEFT":   0,
+	"RIGHT":  1,
+	"MIDDLE": 2,
+}
+
+// ActionConfig is serializable set of action and axis bindings
+type ActionConfig struct {
+	Actions map[string][]Binding `json:"actions,omitempty"`
+	Axes    map[string][]Binding `json:"axes,omitempty"`
+}
+
+// ActionEvent is posted by ActionMap when action is pressed or released
+type ActionEvent struct {
+	Map     *ActionMap
+	Action  string
+	Pressed bool
+	handled bool
+}
+
+func (a *ActionEvent) Handled() bool {
+	return a.handled
+}
+
+func (a *ActionEvent) SetHandled() {
+	a.handled = true
+}
+
+// ActionMap maps keyboard, mouse and gamepad input to named actions and axes. Handlers can then check actions
+// (or handle ActionEvents) without knowing which keys or buttons are bound to them. Bindings can be loaded from
+// configuration file and changed while application is running
+type ActionMap struct {
+	// Window limits keyboard and mouse input to one window. If window is nil, input from all windows is accepted
+	Window *RenderWindow
+
+	mx      *sync.Mutex
+	config  ActionConfig
+	actions map[string][]boundInput
+	axes    map[string][]boundInput
+	inputs  map[inputID]float32
+	mods    Mods
+	pressed map[string]bool
+	stopped bool
+}
+
+type inputKind int
+
+const (
+	inputKey = inputKind(iota + 1)
+	inputMouse
+	inputGamepadButton
+	inputGamepadAxis
+)
+
+type inputID struct {
+	kind    inputKind
+	code    uint32
+	gamepad int
+}
+
+type boundInput struct {
+	kind     inputKind
+	code     uint32
+	mods     Mods
+	scale    float32
+	deadZone float32
+}
+
+// NewActionMap creates action map without bindings
+func NewActionMap(win *RenderWindow) *ActionMap {
+	return &ActionMap{Window: win, mx: &sync.Mutex{}, actions: make(map[string][]boundInput),
+		axes: make(map[string][]boundInput), inputs: make(map[inputID]float32), pressed: make(map[string]bool),
+		config: ActionConfig{Actions: make(map[string][]Binding), Axes: make(map[string][]Binding)}}
+}
+
+// LoadActionMap creates action map with bindings from JSON configuration (see ActionConfig)
+func LoadActionMap(win *RenderWindow, r io.Reader) (*ActionMap, error) {
+	var cfg ActionConfig
+	err := json.NewDecoder(r).Decode(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	am := NewActionMap(win)
+	err = am.SetConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return am, nil
+}
+
+// Save writes current bindings as JSON configuration
+func (am *ActionMap) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(am.Config())
+}
+
+// Config returns copy of current bindings
+func (am *ActionMap) Config() ActionConfig {
+	am.mx.Lock()
+	defer am.mx.Unlock()
+	cfg := ActionConfig{Actions: make(map[string][]Binding), Axes: make(map[string][]Binding)}
+	for k, v := range am.config.Actions {
+		cfg.Actions[k] = append([]Binding(nil), v...)
+	}
+	for k, v := range am.config.Axes {
+		cfg.Axes[k] = append([]Binding(nil), v...)
+	}
+	return cfg
+}
+
+// SetConfig replaces all bindings. If any binding is invalid, bindings are not changed
+func (am *ActionMap) SetConfig(cfg ActionConfig) error {
+	actions, err := resolveBindings(cfg.Actions)
+	if err != nil {
+		return err
+	}
+	axes, err := resolveBindings(cfg.Axes)
+	if err != nil {
+		return err
+	}
+	am.mx.Lock()
+	am.config = ActionConfig{Actions: make(map[string][]Binding), Axes: make(map[string][]Binding)}
+	for k, v := range cfg.Actions {
+		am.config.Actions[k] = append([]Binding(nil), v...)
+	}
+	for k, v := range cfg.Axes {
+		am.config.Axes[k] = append([]Binding(nil), v...)
+	}
+	am.actions, am.axes = actions, axes
+	am.mx.Unlock()
+	return nil
+}
+
+// BindAction replaces bindings of action. Action without bindings is removed
+func (am *ActionMap) BindAction(action string, bindings ...Binding) error {
+	return am.bind(action, bindings, false)
+}
+
+// BindAxis replaces bindings of axis. Axis without bindings is removed
+func (am *ActionMap) BindAxis(axis string, bindings ...Binding) error {
+	return am.bind(axis, bindings, true)
+}
+
+func (am *ActionMap) bind(name string, bindings []Binding, axis bool) error {
+	resolved, err := resolveBindings(map[string][]Binding{name: bindings})
+	if err != nil {
+		return err
+	}
+	am.mx.Lock()
+	defer am.mx.Unlock()
+	// Maps are replaced when config is loaded, select them while holding lock
+	config, bound := am.config.Actions, am.actions
+	if axis {
+		config, bound = am.config.Axes, am.axes
+	}
+	if len(bindings) == 0 {
+		delete(config, name)
+		delete(bound, name)
+		return nil
+	}
+	config[name] = append([]Binding(nil), bindings...)
+	bound[name] = resolved[name]
+	return nil
+}
+
+// Pressed returns true if any binding of action is active
+func (am *ActionMap) Pressed(action string) bool {
+	am.mx.Lock()
+	defer am.mx.Unlock()
+	return am.isPressed(action)
+}
+
+// Axis returns sum of values of all active axis bindings limited to range -1 to 1
+func (am *ActionMap) Axis(axis string) float32 {
+	am.mx.Lock()
+	defer am.mx.Unlock()
+	var v float32
+	for _, b := range am.axes[axis] {
+		v += am.value(b)
+	}
+	if v > 1 {
+		return 1
+	}
+	if v < -1 {
+		return -1
+	}
+	return v
+}
+
+// Start registers action map to event queue. Action map will post ActionEvent when state of action changes
+func (am *ActionMap) Start(priority float64) {
+	am.mx.Lock()
+	am.stopped = false
+	am.mx.Unlock()
+	RegisterHandler(priority, am.eventHandler)
+}
+
+// Stop unregisters action map from event queue
+func (am *ActionMap) Stop() {
+	am.mx.Lock()
+	am.stopped = true
+	am.mx.Unlock()
+}
+
+func (am *ActionMap) eventHandler(ctx vk.APIContext, ev Event) (unregister bool) {
+	changes, stopped := am.update(ev)
+	for _, ch := range changes {
+		Post(ch)
+	}
+	return stopped
+}
+
+// update updates input state from event and returns actions that changed state
+func (am *ActionMap) update(ev Event) (changes []*ActionEvent, stopped bool) {
+	am.mx.Lock()
+	defer am.mx.Unlock()
+	if am.stopped {
+		return nil, true
+	}
+	ui := uiEventOf(ev)
+	if ui != nil {
+		if am.Window != nil && !ui.IsWin(am.Window) {
+			return nil, false
+		}
+		am.mods = ui.CurrentMods
+	}
+	switch e := ev.(type) {
+	case *KeyDownEvent:
+		am.inputs[inputID{kind: inputKey, code: uint32(e.KeyCode)}] = 1
+	case *KeyUpEvent:
+		delete(am.inputs, inputID{kind: inputKey, code: uint32(e.KeyCode)})
+	case *MouseDownEvent:
+		am.inputs[inputID{kind: inputMouse, code: uint32(e.Button)}] = 1
+	case *MouseUpEvent:
+		delete(am.inputs, inputID{kind: inputMouse, code: uint32(e.Button)})
+	case *GamepadButtonDownEvent:
+		am.inputs[inputID{kind: inputGamepadButton, code: uint32(e.Button), gamepad: e.Gamepad}] = 1
+	case *GamepadButtonUpEvent:
+		delete(am.inputs, inputID{kind: inputGamepadButton, code: uint32(e.Button), gamepad: e.Gamepad})
+	case *GamepadAxisEvent:
+		am.inputs[inputID{kind: inputGamepadAxis, code: uint32(e.Axis), gamepad: e.Gamepad}] = e.Value
+	case *GamepadConnectEvent:
+		for id := range am.inputs {
+			if id.gamepad == e.Gamepad && (id.kind == inputGamepadButton || id.kind == inputGamepadAxis) {
+				delete(am.inputs, id)
+			}
+		}
+	default:
+		if ui == nil {
+			return nil, false
+		}
+	}
+	names := make([]string, 0, len(am.actions))
+	for name := range am.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := am.isPressed(name)
+		if p != am.pressed[name] {
+			am.pressed[name] = p
+			changes = append(changes, &ActionEvent{Map: am, Action: name, Pressed: p})
+		}
+	}
+	return changes, false
+}
+
+func (am *ActionMap) isPressed(action string) bool {
+	for _, b := range am.actions[action] {
+		if am.value(b) >= 0.5 {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns current value of bound input. Gamepad inputs use value of any gamepad that has largest magnitude
+func (am *ActionMap) value(b boundInput) float32 {
+	if b.kind != inputGamepadButton && b.kind != inputGamepadAxis {
+		ui := UIEvent{CurrentMods: am.mods}
+		if !ui.HasMods(b.mods) {
+			return 0
+		}
+	}
+	var v float32
+	for id, iv := range am.inputs {
+		if id.kind == b.kind && id.code == b.code && abs32(iv) > abs32(v) {
+			v = iv
+		}
+	}
+	if abs32(v) <= b.deadZone {
+		return 0
+	}
+	return v * b.scale
+}
+
+func resolveBindings(bindings map[string][]Binding) (resolved map[string][]boundInput, err error) {
+	resolved = make(map[string][]boundInput)
+	for name, bs := range bindings {
+		for _, b := range bs {
+			bi, err := b.resolve()
+			if err != nil {
+				return nil, fmt.Errorf("Binding of %s: %v", name, err)
+			}
+			resolved[name] = append(resolved[name], bi)
+		}
+	}
+	return resolved, nil
+}
+
+func (b Binding) resolve() (bi boundInput, err error) {
+	bi = boundInput{mods: b.Mods, scale: b.Scale, deadZone: b.DeadZone}
+	if bi.scale == 0 {
+		bi.scale = 1
+	}
+	var ok bool
+	switch {
+	case b.Key != "":
+		var kc GLFWKeyCode
+		kc, ok = Keys[strings.ToUpper(b.Key)]
+		bi.kind, bi.code = inputKey, uint32(kc)
+	case b.MouseButton != "":
+		var mb int
+		mb, ok = MouseButtons[strings.ToUpper(b.MouseButton)]
+		bi.kind, bi.code = inputMouse, uint32(mb)
+	case b.GamepadButton != "":
+		var gb GamepadButton
+		gb, ok = GamepadButtons[strings.ToUpper(b.GamepadButton)]
+		bi.kind, bi.code = inputGamepadButton, uint32(gb)
+	case b.GamepadAxis != "":
+		var ga GamepadAxis
+		ga, ok = GamepadAxes[strings.ToUpper(b.GamepadAxis)]
+		bi.kind, bi.code = inputGamepadAxis, uint32(ga)
+	default:
+		return bi, fmt.Errorf("no input in binding")
+	}
+	if !ok {
+		return bi, fmt.Errorf("unknown input %s%s%s%s", b.Key, b.MouseButton, b.GamepadButton, b.GamepadAxis)
+	}
+	return bi, nil
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+var modNames = []struct {
+	name string
+	mods Mods
+}{
+	{"SHIFT", MODShift}, {"LEFT_SHIFT", MODLeftShift}, {"RIGHT_SHIFT", MODRightShift},
+	{"CTRL", MODCtrl}, {"LEFT_CTRL", MODLeftCtrl}, {"RIGHT_CTRL", MODRightCtrl},
+	{"ALT", MODAlt}, {"LEFT_ALT", MODLeftAlt}, {"RIGHT_ALT", MODRightAlt},
+	{"MOUSE1", MODMouseButton1}, {"MOUSE2", MODMouseButton2}, {"MOUSE3", MODMouseButton3},
+}
+
+// UnmarshalJSON accepts mods both as text (see UnmarshalText) and as number. Older recordings store mods as numbers
+func (m *Mods) UnmarshalJSON(data []byte) error {
+	var n uint32
+	if err := json.Unmarshal(data, &n); err == nil {
+		*m = Mods(n)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return m.UnmarshalText([]byte(text))
+}
+
+// MarshalText writes mods as names joined with +, for example CTRL+SHIFT
+func (m Mods) MarshalText() ([]byte, error) {
+	var names []string
+	for _, mn := range modNames {
+		if m&mn.mods == mn.mods {
+			names = append(names, mn.name)
+			m &= ^mn.mods
+		}
+	}
+	if m != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint32(m)))
+	}
+	return []byte(strings.Join(names, "+")), nil
+}
+
+// UnmarshalText parses mod names joined with +. Unnamed mods are written as hex numbers
+func (m *Mods) UnmarshalText(text []byte) error {
+	*m = 0
+	if len(text) == 0 {
+		return nil
+	}
+	for _, name := range strings.Split(string(text), "+") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, mn := range modNames {
+			if strings.EqualFold(mn.name, name) {
+				*m |= mn.mods
+				found = true
+			}
+		}
+		if v, err := strconv.ParseUint(name, 0, 32); !found && err == nil {
+			*m |= Mods(v)
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("Unknown mod %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package vapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testActions = `{
+  "actions": {
+    "jump": [{"key": "SPACE"}, {"gamepadButton": "A"}],
+    "save": [{"key": "S", "mods": "CTRL"}]
+  },
+  "axes": {
+    "moveX": [{"key": "D"}, {"key": "A", "scale": -1}, {"gamepadAxis": "LEFT_X", "deadZone": 0.2}]
+  }
+}`
+
+func TestActionMap_Update(t *testing.T) {
+	win := &RenderWindow{}
+	am, err := LoadActionMap(win, strings.NewReader(testActions))
+	if err != nil {
+		t.Fatal("Load failed ", err)
+	}
+	ui := UIEvent{Window: win}
+	changes, _ := am.update(&KeyDownEvent{UIEvent: ui, KeyCode: Keys["S"]})
+	if len(changes) != 0 || am.Pressed("save") {
+		t.Error("Save without ctrl ", changes)
+	}
+	ui.CurrentMods = MODLeftCtrl
+	changes, _ = am.update(&KeyDownEvent{UIEvent: ui, KeyCode: Keys["LEFT_CONTROL"]})
+	if len(changes) != 1 || changes[0].Action != "save" || !changes[0].Pressed {
+		t.Error("Expected save to be pressed ", changes)
+	}
+	changes, _ = am.update(&KeyUpEvent{UIEvent: ui, KeyCode: Keys["S"]})
+	if len(changes) != 1 || changes[0].Pressed {
+		t.Error("Expected save to be released ", changes)
+	}
+	// Other windows are ignored
+	changes, _ = am.update(&KeyDownEvent{UIEvent: UIEvent{Window: &RenderWindow{}}, KeyCode: Keys["SPACE"]})
+	if len(changes) != 0 {
+		t.Error("Event from other window ", changes)
+	}
+	am.update(&GamepadButtonDownEvent{GamepadEvent: GamepadEvent{Gamepad: 1}, Button: GamepadButtons["A"]})
+	if !am.Pressed("jump") {
+		t.Error("Expected jump to be pressed")
+	}
+	am.update(&GamepadConnectEvent{GamepadEvent: GamepadEvent{Gamepad: 1}})
+	if am.Pressed("jump") {
+		t.Error("Expected jump to be released after disconnect")
+	}
+}
+
+func TestActionMap_Axis(t *testing.T) {
+	am, err := LoadActionMap(nil, strings.NewReader(testActions))
+	if err != nil {
+		t.Fatal("Load failed ", err)
+	}
+	am.update(&KeyDownEvent{KeyCode: Keys["A"]})
+	if v := am.Axis("moveX"); v != -1 {
+		t.Error("Expected -1, got ", v)
+	}
+	am.update(&GamepadAxisEvent{Axis: GamepadAxes["LEFT_X"], Value: 0.1})
+	if v := am.Axis("moveX"); v != -1 {
+		t.Error("Expected dead zone, got ", v)
+	}
+	am.update(&KeyUpEvent{KeyCode: Keys["A"]})
+	am.update(&GamepadAxisEvent{Axis: GamepadAxes["LEFT_X"], Value: 0.5})
+	if v := am.Axis("moveX"); v != 0.5 {
+		t.Error("Expected 0.5, got ", v)
+	}
+	// Rebind
+	err = am.BindAxis("moveX", Binding{Key: "LEFT", Scale: -0.5})
+	if err != nil {
+		t.Fatal("Bind failed ", err)
+	}
+	am.update(&KeyDownEvent{KeyCode: GLFWKeyLeft})
+	if v := am.Axis("moveX"); v != -0.5 {
+		t.Error("Expected -0.5, got ", v)
+	}
+	if err = am.BindAction("jump", Binding{Key: "NO_SUCH_KEY"}); err == nil {
+		t.Error("Expected unknown key error")
+	}
+	buf := &bytes.Buffer{}
+	_ = am.Save(buf)
+	am2, err := LoadActionMap(nil, buf)
+	if err != nil {
+		t.Fatal("Reload failed ", err)
+	}
+	cfg := am2.Config()
+	if len(cfg.Axes["moveX"]) != 1 || cfg.Actions["save"][0].Mods != MODCtrl {
+		t.Error("Invalid saved config ", cfg)
+	}
+}
+
+func TestMods_Text(t *testing.T) {
+	b, err := (MODCtrl | MODLeftShift).MarshalText()
+	if err != nil || string(b) != "LEFT_SHIFT+CTRL" {
+		t.Error("Invalid mods text ", string(b), err)
+	}
+	var m Mods
+	if err = m.UnmarshalText([]byte("ctrl+0x8000")); err != nil || m != MODCtrl|0x8000 {
+		t.Error("Invalid mods ", m, err)
+	}
+	var ev struct {
+		Mods Mods `json:"mods"`
+	}
+	if err = json.Unmarshal([]byte(`{"mods":4}`), &ev); err != nil || ev.Mods != 4 {
+		t.Error("Numeric mods should be accepted ", ev.Mods, err)
+	}
+	if err = json.Unmarshal([]byte(`{"mods":"CTRL"}`), &ev); err != nil || ev.Mods != MODCtrl {
+		t.Error("Invalid mods from JSON ", ev.Mods, err)
+	}
+}
@@ -0,0 +1,317 @@
+package vapp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/lakal3/vge/vge/vasset"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+// FrameOutput receives captured frames encoded in image kind of capture
+type FrameOutput interface {
+	WriteFrame(frame int, kind string, content []byte) error
+	// Close is called after last frame
+	Close() error
+}
+
+// Capture captures sequence of rendered frames. Scene time advances with fixed Step between captured frames
+// regardless of how long rendering and saving frames take.
+//
+// Frames are encoded using vasset.SaveImage so that image loader for Kind must be registered. For example use
+// pngloader.RegisterPngLoader to register PNG support
+type Capture struct {
+	// Frames is number of frames to capture. If Frames is 0, capture continues until Stop is called
+	Frames int
+	// Step is scene time between frames. Default step is 1/30 seconds
+	Step float64
+	// Kind is image kind of captured frames. Default kind is png
+	Kind string
+	// Output receives captured frames
+	Output FrameOutput
+	// OnEnd is called after capture has ended. Err is first error from writing frames
+	OnEnd func(err error)
+
+	frame   int
+	stopped bool
+	reader  *frameReader
+}
+
+// Stop stops capture after current frame
+func (c *Capture) Stop() {
+	c.stopped = true
+}
+
+// StartCapture starts capturing frames rendered to window. Window must have been created with image usage
+// IMAGEUsageTransferSrcBit, which is included in default image usage of Desktop.
+// Copy of each frame is recorded to frame command before frame is presented, so renderer must complete pending
+// functions of vscene.PredrawPhase after frame has been drawn (both forward and deferred renderers do).
+// Capture replaces previous capture of window. Safe for concurrent access.
+func (rw *RenderWindow) StartCapture(c *Capture) {
+	c.init()
+	rw.mxCapture.Lock()
+	rw.nextCapture = c
+	rw.mxCapture.Unlock()
+}
+
+// frameCapture returns capture of next frame. Only render loop may call frameCapture
+func (rw *RenderWindow) frameCapture() *Capture {
+	rw.mxCapture.Lock()
+	next := rw.nextCapture
+	rw.nextCapture = nil
+	rw.mxCapture.Unlock()
+	if next != nil {
+		rw.endCapture()
+		rw.capture = next
+	}
+	return rw.capture
+}
+
+func (rw *RenderWindow) endCapture() {
+	if rw.capture != nil {
+		_ = rw.capture.end()
+		rw.capture = nil
+	}
+}
+
+// captureControl records copy of captured frame to frame command in predraw phase
+type captureControl struct {
+	rw *RenderWindow
+}
+
+func (cc captureControl) Process(pi *vscene.ProcessInfo) {
+	pd, ok := pi.Phase.(*vscene.PredrawPhase)
+	c, img := cc.rw.capture, cc.rw.captureImage
+	if !ok || c == nil || img == nil || c.stopped {
+		return
+	}
+	pd.Pending = append(pd.Pending, func() {
+		c.record(pd.Cmd, img)
+	})
+}
+
+// Capture renders and captures frames from render target. Capture returns after last frame has been captured
+func (rt *RenderTarget) Capture(c *Capture) error {
+	c.init()
+	for {
+		rt.Render()
+		rt.mx.Lock()
+		done := rt.disposed || c.write(rt.image)
+		rt.mx.Unlock()
+		if done {
+			return c.end()
+		}
+		rt.Advance(c.Step)
+	}
+}
+
+func (c *Capture) init() {
+	if c.Step <= 0 {
+		c.Step = 1.0 / 30
+	}
+	if len(c.Kind) == 0 {
+		c.Kind = "png"
+	}
+	c.frame, c.stopped = 0, false
+}
+
+// write reads and writes one frame. Write returns true when capture is done
+func (c *Capture) write(img *vk.Image) (done bool) {
+	if c.stopped {
+		return true
+	}
+	if c.reader == nil {
+		c.reader = &frameReader{}
+	}
+	return c.output(c.reader.read(img, vk.IMAGELayoutPresentSrcKhr, c.Kind))
+}
+
+// record records copy of image to frame command. Copied frame is written with writeRecorded after command has completed
+func (c *Capture) record(cmd *vk.Command, img *vk.Image) {
+	if c.reader == nil {
+		c.reader = &frameReader{}
+	}
+	c.reader.reserve(img.Description)
+	c.reader.copyImage(cmd, img, vk.IMAGELayoutPresentSrcKhr)
+}
+
+// writeRecorded writes frame copied with record. WriteRecorded returns true when capture is done
+func (c *Capture) writeRecorded(desc vk.ImageDescription) (done bool) {
+	if c.stopped {
+		return true
+	}
+	if c.reader == nil || !c.reader.recorded {
+		if c.reader == nil {
+			c.reader = &frameReader{}
+		}
+		c.reader.err = errors.New("Renderer did not record captured frame")
+		return true
+	}
+	c.reader.recorded = false
+	return c.output(c.reader.encode(desc, c.Kind))
+}
+
+func (c *Capture) output(content []byte) (done bool) {
+	if content == nil {
+		return true
+	}
+	err := c.Output.WriteFrame(c.frame, c.Kind, content)
+	c.frame++
+	if err != nil {
+		c.reader.err = err
+		return true
+	}
+	return c.Frames > 0 && c.frame >= c.Frames
+}
+
+// end releases read back resources and closes output
+func (c *Capture) end() (err error) {
+	if c.reader != nil {
+		err = c.reader.err
+		c.reader.dispose()
+		c.reader = nil
+	}
+	cErr := c.Output.Close()
+	if err == nil {
+		err = cErr
+	}
+	if c.OnEnd != nil {
+		c.OnEnd(err)
+	}
+	return err
+}
+
+// frameReader copies images to host visible buffer and encodes them
+type frameReader struct {
+	pool     *vk.MemoryPool
+	buf      *vk.Buffer
+	cmd      *vk.Command
+	size     uint64
+	recorded bool
+	err      error
+}
+
+// read copies image using own command and encodes it
+func (fr *frameReader) read(img *vk.Image, layout vk.ImageLayout, kind string) []byte {
+	fr.reserve(img.Description)
+	if fr.cmd == nil {
+		fr.cmd = vk.NewCommand(Ctx, Dev, vk.QUEUETransferBit, false)
+	}
+	fr.cmd.Begin()
+	fr.copyImage(fr.cmd, img, layout)
+	fr.cmd.Submit()
+	fr.cmd.Wait()
+	return fr.encode(img.Description, kind)
+}
+
+// reserve allocates buffer for image
+func (fr *frameReader) reserve(desc vk.ImageDescription) {
+	size := desc.ImageSize()
+	if fr.buf == nil || fr.size != size {
+		fr.dispose()
+		fr.pool = vk.NewMemoryPool(Dev)
+		fr.buf = fr.pool.ReserveBuffer(Ctx, size, true, vk.BUFFERUsageTransferDstBit)
+		fr.pool.Allocate(Ctx)
+		fr.size = size
+	}
+}
+
+// copyImage records copy of image to buffer. Image is returned to original layout after copy
+func (fr *frameReader) copyImage(cmd *vk.Command, img *vk.Image, layout vk.ImageLayout) {
+	ir := img.FullRange()
+	ir.Layout = layout
+	cmd.SetLayout(img, &ir, vk.IMAGELayoutTransferSrcOptimal)
+	cmd.CopyImageToBuffer(fr.buf, img, &ir)
+	cmd.SetLayout(img, &ir, layout)
+	fr.recorded = true
+}
+
+// encode encodes copied image. Command that copied image must have completed
+func (fr *frameReader) encode(desc vk.ImageDescription, kind string) []byte {
+	if kind != "dds" && kind != "raw" {
+		// Other image encoders expect RGBA order. Swapchain images are usually BGRA
+		switch desc.Format {
+		case vk.FORMATB8g8r8a8Unorm, vk.FORMATB8g8r8a8Srgb:
+			swapRB(fr.buf.Bytes(Ctx))
+			desc.Format = vk.FORMATR8g8b8a8Unorm
+		}
+	}
+	content := vasset.SaveImage(Ctx, kind, desc, fr.buf)
+	if content == nil && fr.err == nil {
+		fr.err = errors.New("Failed to save captured frame in format " + kind)
+	}
+	return content
+}
+
+func (fr *frameReader) dispose() {
+	if fr.cmd != nil {
+		fr.cmd.Dispose()
+		fr.cmd = nil
+	}
+	if fr.pool != nil {
+		fr.pool.Dispose()
+		fr.pool, fr.buf = nil, nil
+	}
+}
+
+func swapRB(pix []byte) {
+	for idx := 0; idx+3 < len(pix); idx += 4 {
+		pix[idx], pix[idx+2] = pix[idx+2], pix[idx]
+	}
+}
+
+// SequenceOutput writes each frame to own numbered file, for example frame00001.png
+type SequenceOutput struct {
+	Dir    string
+	Prefix string
+}
+
+func (s SequenceOutput) WriteFrame(frame int, kind string, content []byte) error {
+	return ioutil.WriteFile(filepath.Join(s.Dir, fmt.Sprintf("%s%05d.%s", s.Prefix, frame, kind)), content, 0660)
+}
+
+func (s SequenceOutput) Close() error {
+	return nil
+}
+
+// PipeOutput streams frames to writer, usually to standard input of video encoder
+type PipeOutput struct {
+	W   io.WriteCloser
+	cmd *exec.Cmd
+}
+
+// NewEncoderOutput starts external encoder and streams frames to encoders standard input.
+// For example, PNG frames can be encoded to video with ffmpeg using arguments "-f image2pipe -framerate 30 -i - out.mp4"
+func NewEncoderOutput(cmd *exec.Cmd) (*PipeOutput, error) {
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	return &PipeOutput{W: w, cmd: cmd}, nil
+}
+
+func (p *PipeOutput) WriteFrame(frame int, kind string, content []byte) error {
+	_, err := p.W.Write(content)
+	return err
+}
+
+// Close closes writer and waits encoder to complete
+func (p *PipeOutput) Close() error {
+	err := p.W.Close()
+	if p.cmd != nil {
+		wErr := p.cmd.Wait()
+		if err == nil {
+			err = wErr
+		}
+	}
+	return err
+}
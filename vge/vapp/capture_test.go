@@ -0,0 +1,42 @@
+package vapp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSequenceOutput_WriteFrame(t *testing.T) {
+	so := SequenceOutput{Dir: t.TempDir(), Prefix: "frame"}
+	err := so.WriteFrame(12, "png", []byte("test"))
+	if err != nil {
+		t.Fatal("Write failed ", err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(so.Dir, "frame00012.png"))
+	if err != nil || string(content) != "test" {
+		t.Error("Invalid frame file ", string(content), err)
+	}
+}
+
+func TestPipeOutput(t *testing.T) {
+	cat, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip("No cat command")
+	}
+	cmd := exec.Command(cat)
+	out := &bytes.Buffer{}
+	cmd.Stdout = out
+	po, err := NewEncoderOutput(cmd)
+	if err != nil {
+		t.Fatal("Start failed ", err)
+	}
+	for idx := 0; idx < 3; idx++ {
+		_ = po.WriteFrame(idx, "raw", []byte{byte('a' + idx)})
+	}
+	err = po.Close()
+	if err != nil || out.String() != "abc" {
+		t.Error("Invalid output ", out.String(), err)
+	}
+}
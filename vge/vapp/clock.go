@@ -0,0 +1,226 @@
+package vapp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+// TickFunc is called by Clock at fixed simulation rate. SceneTime is scene time at end of tick and step is length of tick.
+// Tick functions are run with Scene.Update so they can safely modify scene. Scene will run them before it is next processed
+type TickFunc func(sceneTime float64, step float64) (unregister bool)
+
+// Clock advances scene time of RenderWindow or RenderTarget and runs registered tick functions at fixed rate.
+// Each frame scene time advances by measured wall clock time or by fixed step (see SetFixedStep) multiplied with time scale.
+// Tick functions run as many times as there are full tick steps in advanced time. Remaining fraction of tick step is
+// available as TickAlpha so that rendering can interpolate between last two simulation states
+type Clock struct {
+	scene       *vscene.Scene
+	mx          *sync.Mutex
+	sceneTime   float64
+	timeScale   float64
+	fixedStep   float64
+	tickStep    float64
+	accumulated float64
+	ticks       []*tickEntry
+	pending     []pendingTick
+	queued      bool
+	paused      bool
+	steps       int
+	lastFrame   time.Time
+}
+
+type tickEntry struct {
+	tick    TickFunc
+	removed bool
+}
+
+type pendingTick struct {
+	sceneTime float64
+	step      float64
+}
+
+// DefaultTickStep is default length of simulation tick (60 ticks / second)
+const DefaultTickStep = 1.0 / 60
+
+// maxFrameTime limits wall clock time between frames so that stalls (debugger, window moves) don't run long burst of ticks
+const maxFrameTime = 0.25
+
+// NewClock creates new clock for scene. Scene may be nil if there is no scene to update
+func NewClock(sc *vscene.Scene) *Clock {
+	return &Clock{scene: sc, mx: &sync.Mutex{}, timeScale: 1, tickStep: DefaultTickStep}
+}
+
+// SceneTime returns current scene time
+func (c *Clock) SceneTime() float64 {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.sceneTime
+}
+
+// SetSceneTime moves scene time to given value without running tick functions
+func (c *Clock) SetSceneTime(sceneTime float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.sceneTime, c.accumulated = sceneTime, 0
+}
+
+// TickAlpha returns fraction (0 <= alpha < 1) of tick step that has elapsed after last tick.
+// Rendering can use alpha to interpolate between previous and current simulation state
+func (c *Clock) TickAlpha() float64 {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.accumulated / c.tickStep
+}
+
+// SetPaused stops advancing scene time. Paused clock can still be advanced with Step
+func (c *Clock) SetPaused(paused bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.paused = paused
+}
+
+func (c *Clock) Paused() bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.paused
+}
+
+// Step advances paused clock by one tick step on next frame
+func (c *Clock) Step() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.steps++
+}
+
+// SetTimeScale sets speed of scene time relative to wall clock time or fixed step. Default scale is 1
+func (c *Clock) SetTimeScale(scale float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.timeScale = scale
+}
+
+// SetFixedStep advances scene time by step seconds on each frame instead of measured wall clock time.
+// Fixed step makes clock deterministic. Step 0 restores wall clock time
+func (c *Clock) SetFixedStep(step float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.fixedStep = step
+}
+
+// SetTickStep sets length of simulation tick. Default is DefaultTickStep
+func (c *Clock) SetTickStep(step float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if step > 0 {
+		c.tickStep = step
+	}
+}
+
+// RegisterTick adds tick function to clock. Tick function is called until it returns true
+func (c *Clock) RegisterTick(tick TickFunc) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.ticks = append(c.ticks, &tickEntry{tick: tick})
+}
+
+// Advance advances scene time by delta seconds and runs all ticks that are due. Time scale and pause don't affect
+// Advance. Advance is mainly intended for offscreen rendering and tests
+func (c *Clock) Advance(delta float64) {
+	c.mx.Lock()
+	c.lastFrame = time.Time{}
+	c.mx.Unlock()
+	c.advance(delta)
+}
+
+// frame advances clock after rendered frame
+func (c *Clock) frame() {
+	c.mx.Lock()
+	var delta float64
+	switch {
+	case c.paused:
+		c.lastFrame = time.Time{}
+		delta = float64(c.steps) * c.tickStep
+		c.steps = 0
+	case c.fixedStep > 0:
+		c.lastFrame = time.Time{}
+		delta = c.fixedStep * c.timeScale
+	default:
+		t := time.Now()
+		if !c.lastFrame.IsZero() {
+			delta = t.Sub(c.lastFrame).Seconds()
+			if delta > maxFrameTime {
+				delta = maxFrameTime
+			}
+			delta *= c.timeScale
+		}
+		c.lastFrame = t
+	}
+	c.mx.Unlock()
+	c.advance(delta)
+}
+
+func (c *Clock) advance(delta float64) {
+	if delta <= 0 {
+		return
+	}
+	c.mx.Lock()
+	c.sceneTime += delta
+	c.accumulated += delta
+	// Small tolerance so that rounding errors won't delay tick to next frame
+	for c.accumulated >= c.tickStep*(1-1e-6) {
+		c.accumulated -= c.tickStep
+		if c.accumulated < 0 {
+			c.accumulated = 0
+		}
+		if len(c.ticks) > 0 {
+			c.pending = append(c.pending, pendingTick{sceneTime: c.sceneTime - c.accumulated, step: c.tickStep})
+		}
+	}
+	queue := len(c.pending) > 0 && !c.queued
+	if queue {
+		c.queued = true
+	}
+	c.mx.Unlock()
+	if !queue {
+		return
+	}
+	if c.scene != nil {
+		c.scene.Update(c.runTicks)
+	} else {
+		c.runTicks()
+	}
+}
+
+// runTicks runs all pending ticks. Ticks from several advances before scene is processed run in same update.
+// Each tick time runs against current tick list so that unregistered ticks are not called again
+func (c *Clock) runTicks() {
+	c.mx.Lock()
+	pending := c.pending
+	c.pending, c.queued = nil, false
+	c.mx.Unlock()
+	for _, pt := range pending {
+		c.mx.Lock()
+		ticks := append([]*tickEntry(nil), c.ticks...)
+		c.mx.Unlock()
+		for _, te := range ticks {
+			if !te.removed && te.tick(pt.sceneTime, pt.step) {
+				c.unregister(te)
+			}
+		}
+	}
+}
+
+// unregister removes tick from clock. Ticks may have been registered while ticks were running
+func (c *Clock) unregister(te *tickEntry) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	te.removed = true
+	for idx, t := range c.ticks {
+		if t == te {
+			c.ticks = append(c.ticks[:idx:idx], c.ticks[idx+1:]...)
+			return
+		}
+	}
+}
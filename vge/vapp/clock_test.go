@@ -0,0 +1,86 @@
+package vapp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+func TestClock_Ticks(t *testing.T) {
+	sc := &vscene.Scene{}
+	sc.Init()
+	c := NewClock(sc)
+	c.SetTickStep(0.1)
+	var times []float64
+	c.RegisterTick(func(sceneTime float64, step float64) (unregister bool) {
+		times = append(times, sceneTime)
+		return false
+	})
+	once := 0
+	c.RegisterTick(func(sceneTime float64, step float64) (unregister bool) {
+		once++
+		return true
+	})
+	c.Advance(0.25)
+	// Scene runs updates on next process
+	sc.Process(0, nil)
+	if len(times) != 2 || math.Abs(times[1]-0.2) > 1e-9 || once != 1 {
+		t.Fatal("Expected 2 ticks, got ", times, once)
+	}
+	if a := c.TickAlpha(); math.Abs(a-0.5) > 1e-9 {
+		t.Error("Expected alpha 0.5, got ", a)
+	}
+	c.Advance(0.05)
+	sc.Process(0, nil)
+	if len(times) != 3 || once != 1 {
+		t.Error("Expected 3 ticks, got ", times, once)
+	}
+	// Several advances before scene is processed
+	c.RegisterTick(func(sceneTime float64, step float64) (unregister bool) {
+		once++
+		return true
+	})
+	c.Advance(0.1)
+	c.Advance(0.1)
+	sc.Process(0, nil)
+	if len(times) != 5 || math.Abs(times[4]-0.5) > 1e-9 || once != 2 {
+		t.Error("Expected 5 ticks, got ", times, once)
+	}
+}
+
+func TestClock_Frame(t *testing.T) {
+	c := NewClock(nil)
+	c.SetTickStep(0.1)
+	ticks := 0
+	c.RegisterTick(func(sceneTime float64, step float64) (unregister bool) {
+		ticks++
+		return false
+	})
+	// Deterministic mode with time scale
+	c.SetFixedStep(0.1)
+	c.SetTimeScale(2)
+	c.frame()
+	if math.Abs(c.SceneTime()-0.2) > 1e-9 || ticks != 2 {
+		t.Error("Expected time 0.2, got ", c.SceneTime(), ticks)
+	}
+	// Single step while paused
+	c.SetPaused(true)
+	c.frame()
+	c.Step()
+	c.frame()
+	c.frame()
+	if math.Abs(c.SceneTime()-0.3) > 1e-9 || ticks != 3 {
+		t.Error("Expected time 0.3, got ", c.SceneTime(), ticks)
+	}
+	// Long stall of wall clock is limited to maxFrameTime
+	c.SetPaused(false)
+	c.SetFixedStep(0)
+	c.SetTimeScale(1)
+	c.lastFrame = time.Now().Add(-10 * time.Second)
+	c.frame()
+	if d := c.SceneTime() - 0.3; d < maxFrameTime-1e-9 || d > maxFrameTime+1e-9 {
+		t.Error("Expected frame time ", maxFrameTime, ", got ", d)
+	}
+}
@@ -67,6 +67,8 @@ type UIEvent struct {
 	MousePos    image.Point
 	handled     bool
 	Window      *RenderWindow
+	// replayed is set for events posted by Replayer
+	replayed bool
 }
 
 func (u *UIEvent) IsWin(win *RenderWindow) bool {
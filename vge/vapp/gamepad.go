@@ -0,0 +1,94 @@
+package vapp
+
+import "github.com/lakal3/vge/vge/vk"
+
+// GamepadButton is GLFW gamepad button number
+type GamepadButton uint32
+
+// GamepadAxis is GLFW gamepad axis number
+type GamepadAxis uint32
+
+// GamepadButtons maps button names to GLFW gamepad buttons. Buttons are named using Xbox controller layout
+var GamepadButtons = map[string]GamepadButton{
+	"A":            0,
+	"B":            1,
+	"X":            2,
+	"Y":            3,
+	"LEFT_BUMPER":  4,
+	"RIGHT_BUMPER": 5,
+	"BACK":         6,
+	"START":        7,
+	"GUIDE":        8,
+	"LEFT_THUMB":   9,
+	"RIGHT_THUMB":  10,
+	"DPAD_UP":      11,
+	"DPAD_RIGHT":   12,
+	"DPAD_DOWN":    13,
+	"DPAD_LEFT":    14,
+}
+
+// GamepadAxes maps axis names to GLFW gamepad axes. Triggers range from -1 (released) to 1 (fully pressed)
+var GamepadAxes = map[string]GamepadAxis{
+	"LEFT_X":        0,
+	"LEFT_Y":        1,
+	"RIGHT_X":       2,
+	"RIGHT_Y":       3,
+	"LEFT_TRIGGER":  4,
+	"RIGHT_TRIGGER": 5,
+}
+
+// GamepadEvent is common part of all gamepad events. Gamepad is GLFW joystick id of gamepad.
+// Gamepad events are not bound to any window
+type GamepadEvent struct {
+	Gamepad int
+	handled bool
+}
+
+func (g *GamepadEvent) Handled() bool {
+	return g.handled
+}
+
+func (g *GamepadEvent) SetHandled() {
+	g.handled = true
+}
+
+type GamepadButtonDownEvent struct {
+	GamepadEvent
+	Button GamepadButton
+}
+
+type GamepadButtonUpEvent struct {
+	GamepadEvent
+	Button GamepadButton
+}
+
+// GamepadAxisEvent is sent when value of axis changes. Value ranges from -1 to 1
+type GamepadAxisEvent struct {
+	GamepadEvent
+	Axis  GamepadAxis
+	Value float32
+}
+
+// GamepadConnectEvent is sent when gamepad is connected or disconnected
+type GamepadConnectEvent struct {
+	GamepadEvent
+	Connected bool
+}
+
+// gamepadEvent converts raw desktop event to gamepad event. Other events return nil
+func gamepadEvent(raw vk.RawEvent) Event {
+	ge := GamepadEvent{Gamepad: int(raw.Arg1)}
+	switch raw.EventType {
+	case evGamepadButtonUp:
+		return &GamepadButtonUpEvent{GamepadEvent: ge, Button: GamepadButton(raw.Arg2)}
+	case evGamepadButtonDown:
+		return &GamepadButtonDownEvent{GamepadEvent: ge, Button: GamepadButton(raw.Arg2)}
+	case evGamepadAxis:
+		return &GamepadAxisEvent{GamepadEvent: ge, Axis: GamepadAxis(raw.Arg2), Value: float32(raw.Arg3) / 32767}
+	case evGamepadConnected:
+		return &GamepadConnectEvent{GamepadEvent: ge, Connected: true}
+	case evGamepadDisconnected:
+		return &GamepadConnectEvent{GamepadEvent: ge}
+	}
+	return nil
+}
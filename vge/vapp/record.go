@@ -0,0 +1,275 @@
+package vapp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lakal3/vge/vge/vk"
+)
+
+// PRIRecorder is priority of input recorder and replayer. They must see UI events before any other handler
+const PRIRecorder = 1000
+
+// Kinds of recorded input events
+const (
+	RECKeyUp     = "keyUp"
+	RECKeyDown   = "keyDown"
+	RECChar      = "char"
+	RECScroll    = "scroll"
+	RECMouseMove = "mouseMove"
+	RECMouseUp   = "mouseUp"
+	RECMouseDown = "mouseDown"
+)
+
+// InputRecord is one recorded UI event. Records are stored as JSON, one record per line
+type InputRecord struct {
+	// Time is scene time of event relative to start of recording
+	Time     float64     `json:"time"`
+	Kind     string      `json:"kind"`
+	Mods     Mods        `json:"mods,omitempty"`
+	MouseX   int         `json:"mouseX"`
+	MouseY   int         `json:"mouseY"`
+	KeyCode  GLFWKeyCode `json:"keyCode,omitempty"`
+	ScanCode uint32      `json:"scanCode,omitempty"`
+	Char     rune        `json:"char,omitempty"`
+	Button   int         `json:"button,omitempty"`
+	RangeX   int         `json:"rangeX,omitempty"`
+	RangeY   int         `json:"rangeY,omitempty"`
+}
+
+// Recorder writes UI events of window with scene timestamps. Use Replay to feed recorded events back to event loop
+type Recorder struct {
+	win   *RenderWindow
+	start float64
+	mx    *sync.Mutex
+	w     *bufio.Writer
+	enc   *json.Encoder
+	err   error
+}
+
+// StartRecording starts recording UI events of window to writer. Recording continues until Stop is called
+func StartRecording(win *RenderWindow, w io.Writer) *Recorder {
+	r := &Recorder{win: win, start: win.GetSceneTime(), mx: &sync.Mutex{}, w: bufio.NewWriter(w)}
+	r.enc = json.NewEncoder(r.w)
+	RegisterHandler(PRIRecorder, r.eventHandler)
+	return r
+}
+
+// Stop stops recording and flushes recorded events to writer. Stop returns first error from writer
+func (r *Recorder) Stop() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.enc != nil {
+		r.enc = nil
+		err := r.w.Flush()
+		if r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+func (r *Recorder) eventHandler(ctx vk.APIContext, ev Event) (unregister bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.enc == nil {
+		return true
+	}
+	rec, ok := recordEvent(ev, r.win)
+	if ok && r.err == nil {
+		rec.Time = r.win.GetSceneTime() - r.start
+		r.err = r.enc.Encode(rec)
+	}
+	return false
+}
+
+// recordEvent converts UI event of window to record
+func recordEvent(ev Event, win *RenderWindow) (rec InputRecord, ok bool) {
+	ui := uiEventOf(ev)
+	if ui == nil || !ui.IsWin(win) {
+		return rec, false
+	}
+	switch e := ev.(type) {
+	case *KeyUpEvent:
+		rec = InputRecord{Kind: RECKeyUp, KeyCode: e.KeyCode, ScanCode: e.ScanCode}
+	case *KeyDownEvent:
+		rec = InputRecord{Kind: RECKeyDown, KeyCode: e.KeyCode, ScanCode: e.ScanCode}
+	case *CharEvent:
+		rec = InputRecord{Kind: RECChar, Char: e.Char}
+	case *ScrollEvent:
+		rec = InputRecord{Kind: RECScroll, RangeX: e.Range.X, RangeY: e.Range.Y}
+	case *MouseMoveEvent:
+		rec = InputRecord{Kind: RECMouseMove}
+	case *MouseUpEvent:
+		rec = InputRecord{Kind: RECMouseUp, Button: e.Button}
+	case *MouseDownEvent:
+		rec = InputRecord{Kind: RECMouseDown, Button: e.Button}
+	}
+	rec.Mods, rec.MouseX, rec.MouseY = ui.CurrentMods, ui.MousePos.X, ui.MousePos.Y
+	return rec, true
+}
+
+// event converts record back to UI event of window
+func (rec InputRecord) event(win *RenderWindow) (ev Event, err error) {
+	ui := UIEvent{Window: win, CurrentMods: rec.Mods, MousePos: image.Point{X: rec.MouseX, Y: rec.MouseY}, replayed: true}
+	switch rec.Kind {
+	case RECKeyUp:
+		return &KeyUpEvent{UIEvent: ui, KeyCode: rec.KeyCode, ScanCode: rec.ScanCode}, nil
+	case RECKeyDown:
+		return &KeyDownEvent{UIEvent: ui, KeyCode: rec.KeyCode, ScanCode: rec.ScanCode}, nil
+	case RECChar:
+		return &CharEvent{UIEvent: ui, Char: rec.Char}, nil
+	case RECScroll:
+		return &ScrollEvent{UIEvent: ui, Range: image.Point{X: rec.RangeX, Y: rec.RangeY}}, nil
+	case RECMouseMove:
+		return &MouseMoveEvent{UIEvent: ui}, nil
+	case RECMouseUp:
+		return &MouseUpEvent{UIEvent: ui, Button: rec.Button}, nil
+	case RECMouseDown:
+		return &MouseDownEvent{UIEvent: ui, Button: rec.Button}, nil
+	}
+	return nil, fmt.Errorf("Unknown input record kind %s", rec.Kind)
+}
+
+// ReadRecords reads all input records from reader
+func ReadRecords(r io.Reader) (records []InputRecord, err error) {
+	dec := json.NewDecoder(r)
+	for {
+		var rec InputRecord
+		err = dec.Decode(&rec)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// Replayer feeds recorded events to event loop when scene time of window reaches time of event
+type Replayer struct {
+	// OnEnd is called from render loop after last event has been handled
+	OnEnd func()
+
+	win     *RenderWindow
+	start   float64
+	events  []Event
+	times   []float64
+	next    int
+	stopped bool
+}
+
+// Replay starts replaying recorded events to window. Window will use fixed step clock (see SetFixedStep) so that replay
+// is deterministic. Live UI events of window are ignored while replay is running.
+// Events of frame are handled before frame is rendered
+func Replay(win *RenderWindow, records []InputRecord, step float64) (*Replayer, error) {
+	rp := &Replayer{win: win, start: win.GetSceneTime()}
+	for _, rec := range records {
+		ev, err := rec.event(win)
+		if err != nil {
+			return nil, err
+		}
+		rp.events, rp.times = append(rp.events, ev), append(rp.times, rec.Time)
+	}
+	RegisterHandler(PRIRecorder, rp.eventHandler)
+	win.SetFixedStep(step)
+	win.replay = rp
+	return rp, nil
+}
+
+// Done returns true when all events have been replayed
+func (rp *Replayer) Done() bool {
+	return rp.stopped
+}
+
+// post sends events that are due at scene time and waits until event loop has handled them
+func (rp *Replayer) post(sceneTime float64) {
+	if rp.stopped {
+		return
+	}
+	posted := false
+	for rp.next < len(rp.events) && rp.times[rp.next] <= sceneTime-rp.start {
+		ev := rp.events[rp.next]
+		ui := uiEventOf(ev)
+		rp.win.MousePos, rp.win.CurrentMods = ui.MousePos, ui.CurrentMods
+		Post(ev)
+		rp.next++
+		posted = true
+	}
+	if posted {
+		rp.wait()
+	}
+	if rp.next >= len(rp.events) {
+		rp.stopped = true
+		if rp.OnEnd != nil {
+			rp.OnEnd()
+		}
+	}
+}
+
+// wait waits until all posted events have been handled or window is closed
+func (rp *Replayer) wait() {
+	done := &replaySync{ch: make(chan struct{})}
+	Post(done)
+	for {
+		select {
+		case <-done.ch:
+			return
+		case <-time.After(10 * time.Millisecond):
+			if rp.win.state != 1 {
+				return
+			}
+		}
+	}
+}
+
+func (rp *Replayer) eventHandler(ctx vk.APIContext, ev Event) (unregister bool) {
+	if rp.stopped {
+		return true
+	}
+	ui := uiEventOf(ev)
+	if ui != nil && ui.IsWin(rp.win) && !ui.replayed {
+		// Live input is ignored during replay
+		ui.SetHandled()
+	}
+	return false
+}
+
+func uiEventOf(ev Event) *UIEvent {
+	switch e := ev.(type) {
+	case *KeyUpEvent:
+		return &e.UIEvent
+	case *KeyDownEvent:
+		return &e.UIEvent
+	case *CharEvent:
+		return &e.UIEvent
+	case *ScrollEvent:
+		return &e.UIEvent
+	case *MouseMoveEvent:
+		return &e.UIEvent
+	case *MouseUpEvent:
+		return &e.UIEvent
+	case *MouseDownEvent:
+		return &e.UIEvent
+	}
+	return nil
+}
+
+// replaySync is posted after replayed events. Event loop signals it after all previous events have been handled
+type replaySync struct {
+	ch chan struct{}
+}
+
+func (r *replaySync) Handled() bool {
+	return false
+}
+
+func (r *replaySync) Done() {
+	close(r.ch)
+}
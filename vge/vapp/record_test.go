@@ -0,0 +1,86 @@
+package vapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"testing"
+
+	"github.com/lakal3/vge/vge/vk"
+)
+
+func TestInputRecord_RoundTrip(t *testing.T) {
+	win := &RenderWindow{state: 1}
+	ui := UIEvent{Window: win, CurrentMods: MODLeftShift, MousePos: image.Point{X: 10, Y: 20}}
+	events := []Event{&KeyDownEvent{UIEvent: ui, KeyCode: GLFWKeyF1, ScanCode: 3}, &CharEvent{UIEvent: ui, Char: 'x'},
+		&ScrollEvent{UIEvent: ui, Range: image.Point{Y: -1}}, &MouseDownEvent{UIEvent: ui, Button: 1},
+		&MouseMoveEvent{UIEvent: UIEvent{Window: &RenderWindow{}}}}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for idx, ev := range events {
+		rec, ok := recordEvent(ev, win)
+		if !ok {
+			continue
+		}
+		rec.Time = float64(idx)
+		_ = enc.Encode(rec)
+	}
+	records, err := ReadRecords(buf)
+	if err != nil {
+		t.Fatal("Read failed ", err)
+	}
+	if len(records) != 4 {
+		t.Fatal("Expected 4 records, got ", len(records))
+	}
+	ev, err := records[0].event(win)
+	if err != nil {
+		t.Fatal("Event failed ", err)
+	}
+	kd, ok := ev.(*KeyDownEvent)
+	if !ok || kd.KeyCode != GLFWKeyF1 || kd.ScanCode != 3 || !kd.HasMods(MODLeftShift) || kd.MousePos.Y != 20 || !kd.IsWin(win) {
+		t.Error("Invalid key down event ", ev)
+	}
+	ev, _ = records[2].event(win)
+	if se, ok := ev.(*ScrollEvent); !ok || se.Range.Y != -1 {
+		t.Error("Invalid scroll event ", ev)
+	}
+}
+
+func TestReplayer_Post(t *testing.T) {
+	Ctx = testCtx{t: t}
+	startEventLoop()
+	defer func() {
+		stopEventLoop()
+		eventLoop.handlers = nil
+	}()
+	win := &RenderWindow{state: 1}
+	records := []InputRecord{{Time: 0, Kind: RECKeyDown, KeyCode: 65}, {Time: 0.1, Kind: RECKeyUp, KeyCode: 65},
+		{Time: 0.25, Kind: RECMouseMove, MouseX: 5}}
+	rp := &Replayer{win: win}
+	for _, rec := range records {
+		ev, _ := rec.event(win)
+		rp.events, rp.times = append(rp.events, ev), append(rp.times, rec.Time)
+	}
+	RegisterHandler(PRIRecorder, rp.eventHandler)
+	count := 0
+	RegisterHandler(0, func(ctx vk.APIContext, ev Event) (unregister bool) {
+		if uiEventOf(ev) != nil {
+			count++
+		}
+		return false
+	})
+	// Live events are ignored
+	Post(&KeyDownEvent{UIEvent: UIEvent{Window: win}})
+	rp.post(0.1)
+	if count != 2 {
+		t.Error("Expected 2 events, got ", count)
+	}
+	rp.post(0.2)
+	if count != 2 || rp.Done() {
+		t.Error("Expected no new events, got ", count)
+	}
+	rp.post(0.3)
+	if count != 3 || !rp.Done() || win.MousePos.X != 5 {
+		t.Error("Expected replay to end, got ", count)
+	}
+}
@@ -0,0 +1,181 @@
+package vapp
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"sync"
+
+	"github.com/lakal3/vge/vge/forward"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+	"github.com/lakal3/vge/vge/vscene"
+)
+
+// ErrNoImage is returned when render target has no rendered frame in supported format
+var ErrNoImage = errors.New("No rendered image")
+
+// RenderTarget renders scene to offscreen image. Render target has same scene layout (Env, Model and Ui nodes) as
+// RenderWindow but there is no render loop. Each call to Render renders one frame at current scene time.
+// Render target don't need Desktop option so it can be used for example to render thumbnails on server or
+// screenshots in CI
+type RenderTarget struct {
+	Scene  vscene.Scene
+	Camera vscene.Camera
+	Env    *vscene.Node
+	Model  *vscene.Node
+	Ui     *vscene.Node
+	// Clock controls scene time of render target. Clock is advanced only with Advance or SetSceneTime
+	Clock *Clock
+
+	// Desc is description of target image
+	Desc vk.ImageDescription
+
+	owner    vk.Owner
+	mx       *sync.Mutex
+	renderer vscene.Renderer
+	image    *vk.Image
+	cache    *vk.RenderCache
+	rendered bool
+	disposed bool
+}
+
+// NewRenderTarget creates new render target with R8G8B8A8 image of given size. If renderer is nil,
+// forward renderer with depth buffer is used
+func NewRenderTarget(size image.Point, renderer vscene.Renderer) *RenderTarget {
+	return NewRenderTargetDesc(vk.ImageDescription{Width: uint32(size.X), Height: uint32(size.Y), Depth: 1,
+		Format: vk.FORMATR8g8b8a8Unorm, MipLevels: 1, Layers: 1}, renderer)
+}
+
+// NewRenderTargetDesc creates new render target with given image description.
+// Render target is disposed when application terminates if not disposed before that
+func NewRenderTargetDesc(desc vk.ImageDescription, renderer vscene.Renderer) *RenderTarget {
+	if renderer == nil {
+		renderer = forward.NewRenderer(true)
+	}
+	rt := &RenderTarget{Desc: desc, renderer: renderer, mx: &sync.Mutex{}}
+	rt.owner = vk.NewOwner(true)
+	pool := vk.NewMemoryPool(Dev)
+	rt.owner.AddChild(pool)
+	rt.image = pool.ReserveImage(Ctx, desc, vk.IMAGEUsageColorAttachmentBit|vk.IMAGEUsageTransferSrcBit)
+	pool.Allocate(Ctx)
+	rt.owner.AddChild(renderer)
+	rt.cache = vk.NewRenderCache(Ctx, Dev)
+	rt.owner.AddChild(rt.cache)
+	renderer.Setup(Ctx, Dev, desc, 1)
+	rt.Scene.Init()
+	rt.Env = rt.Scene.AddNode(nil, nil)
+	rt.Model = rt.Scene.AddNode(nil, nil)
+	rt.Ui = rt.Scene.AddNode(nil, nil)
+	rt.Camera = vscene.NewPerspectiveCamera(1000)
+	rt.Clock = NewClock(&rt.Scene)
+	AddChild(rt)
+	return rt
+}
+
+// Dispose render target and all child objects added to it
+func (rt *RenderTarget) Dispose() {
+	rt.mx.Lock()
+	defer rt.mx.Unlock()
+	if !rt.disposed {
+		rt.disposed = true
+		rt.owner.Dispose()
+		rt.image = nil
+	}
+}
+
+// AddChild adds disposable object bound to render targets lifetime. Safe for concurrent access.
+func (rt *RenderTarget) AddChild(disp vk.Disposable) {
+	rt.owner.AddChild(disp)
+}
+
+func (rt *RenderTarget) GetRenderer() vscene.Renderer {
+	return rt.renderer
+}
+
+// GetImage returns target image. Image layout after Render is IMAGELayoutPresentSrcKhr
+func (rt *RenderTarget) GetImage() *vk.Image {
+	return rt.image
+}
+
+func (rt *RenderTarget) GetSceneTime() float64 {
+	return rt.Clock.SceneTime()
+}
+
+// SetSceneTime sets scene time of next rendered frame
+func (rt *RenderTarget) SetSceneTime(sceneTime float64) {
+	rt.Clock.SetSceneTime(sceneTime)
+}
+
+// Advance advances scene time by step seconds and runs tick functions of clock
+func (rt *RenderTarget) Advance(step float64) {
+	rt.Clock.Advance(step)
+}
+
+// Render renders one frame at current scene time. Render waits until frame is completed
+func (rt *RenderTarget) Render() {
+	rt.mx.Lock()
+	defer rt.mx.Unlock()
+	if rt.disposed {
+		return
+	}
+	rt.cache.NewFrame()
+	rt.Scene.Time = rt.Clock.SceneTime()
+	rt.renderer.Render(rt.Camera, &rt.Scene, rt.cache, rt.image, 0, nil)
+	rt.rendered = true
+}
+
+// Save copies last rendered frame from image and encodes it using image kind. Image loader for kind must be registered
+func (rt *RenderTarget) Save(kind string) []byte {
+	rt.mx.Lock()
+	defer rt.mx.Unlock()
+	if rt.disposed || !rt.rendered {
+		return nil
+	}
+	cp := vmodel.NewCopier(Ctx, Dev)
+	defer cp.Dispose()
+	ir := rt.image.FullRange()
+	ir.Layout = vk.IMAGELayoutPresentSrcKhr
+	return cp.CopyFromImage(rt.image, ir, kind, vk.IMAGELayoutPresentSrcKhr)
+}
+
+// ReadImage copies last rendered frame to Go image. Only 8 bit RGBA and BGRA target formats are supported.
+// Unsupported formats and render targets without rendered frames return nil
+func (rt *RenderTarget) ReadImage() image.Image {
+	if !isRGBAFormat(rt.Desc.Format) {
+		return nil
+	}
+	content := rt.Save("raw")
+	if content == nil {
+		return nil
+	}
+	return toRGBA(rt.Desc, content)
+}
+
+func isRGBAFormat(format vk.Format) bool {
+	switch format {
+	case vk.FORMATR8g8b8a8Unorm, vk.FORMATR8g8b8a8Srgb, vk.FORMATB8g8r8a8Unorm, vk.FORMATB8g8r8a8Srgb:
+		return true
+	}
+	return false
+}
+
+// toRGBA converts raw content of 8 bit RGBA or BGRA image to Go image
+func toRGBA(desc vk.ImageDescription, content []byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(desc.Width), int(desc.Height)))
+	copy(img.Pix, content)
+	if desc.Format == vk.FORMATB8g8r8a8Unorm || desc.Format == vk.FORMATB8g8r8a8Srgb {
+		swapRB(img.Pix)
+	}
+	return img
+}
+
+// SavePNG writes last rendered frame to writer in PNG format
+func (rt *RenderTarget) SavePNG(w io.Writer) error {
+	img := rt.ReadImage()
+	if img == nil {
+		return ErrNoImage
+	}
+	return png.Encode(w, img)
+}
@@ -0,0 +1,40 @@
+package vapp
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/lakal3/vge/vge/vk"
+)
+
+func TestToRGBA(t *testing.T) {
+	content := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	desc := vk.ImageDescription{Width: 2, Height: 1, Depth: 1, Format: vk.FORMATB8g8r8a8Unorm, MipLevels: 1, Layers: 1}
+	img := toRGBA(desc, content)
+	if !bytes.Equal(img.Pix, []byte{3, 2, 1, 4, 7, 6, 5, 8}) {
+		t.Error("BGRA image should be swizzled ", img.Pix)
+	}
+	if content[0] != 1 {
+		t.Error("Raw content should not change")
+	}
+	desc.Format = vk.FORMATR8g8b8a8Srgb
+	img = toRGBA(desc, content)
+	if !bytes.Equal(img.Pix, content) {
+		t.Error("RGBA image should be copied as is ", img.Pix)
+	}
+}
+
+func TestRenderTarget_SavePNG(t *testing.T) {
+	// Render target without rendered frame don't access device
+	rt := &RenderTarget{Desc: vk.ImageDescription{Width: 2, Height: 2, Depth: 1, Format: vk.FORMATR8g8b8a8Unorm,
+		MipLevels: 1, Layers: 1}, mx: &sync.Mutex{}}
+	var buf bytes.Buffer
+	if err := rt.SavePNG(&buf); err != ErrNoImage || buf.Len() != 0 {
+		t.Error("Expected ErrNoImage before render ", err)
+	}
+	rt.Desc.Format = vk.FORMATR16g16b16a16Sfloat
+	if rt.ReadImage() != nil {
+		t.Error("Unsupported format should not return image")
+	}
+}
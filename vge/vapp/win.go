@@ -23,6 +23,12 @@ const (
 	evMouseDown    = 301
 	evMouseMove    = 302
 	evMouseScroll  = 303
+	// Gamepad events are not bound to any window
+	evGamepadButtonUp     = 400
+	evGamepadButtonDown   = 401
+	evGamepadAxis         = 402
+	evGamepadConnected    = 403
+	evGamepadDisconnected = 404
 )
 
 type Desktop struct {
@@ -58,7 +64,9 @@ func NewRenderWindowAt(title string, wp vk.WindowPos, renderer vscene.Renderer)
 	rw.Env = rw.Scene.AddNode(nil, nil)
 	rw.Model = rw.Scene.AddNode(nil, nil)
 	rw.Ui = rw.Scene.AddNode(nil, nil)
+	rw.Scene.AddNode(nil, captureControl{rw: rw})
 	rw.Camera = vscene.NewPerspectiveCamera(1000)
+	rw.Clock = NewClock(&rw.Scene)
 	RegisterHandler(PRIWindow, rw.eventHandler)
 	go rw.renderLoop()
 	return rw
@@ -71,22 +79,28 @@ type RenderWindow struct {
 	Env         *vscene.Node
 	Model       *vscene.Node
 	Ui          *vscene.Node
+	// Clock controls scene time of window. Use clock to register fixed rate tick functions
+	Clock *Clock
 
 	MousePos   image.Point
 	WindowSize image.Point
 	// OnClose is called when user request closing windows. Default action disposes window
 	OnClose func()
 
-	owner      vk.Owner
-	renderer   vscene.Renderer
-	win        *vk.Window
-	caches     []*vk.RenderCache
-	wg         *sync.WaitGroup
-	lastRender time.Time
-	sceneTime  float64
-	paused     bool
-	state      int
-	setup      bool
+	owner    vk.Owner
+	renderer vscene.Renderer
+	win      *vk.Window
+	caches   []*vk.RenderCache
+	wg       *sync.WaitGroup
+	replay   *Replayer
+	state    int
+	setup    bool
+
+	mxCapture   sync.Mutex
+	nextCapture *Capture
+	// capture and captureImage are only accessed from render loop
+	capture      *Capture
+	captureImage *vk.Image
 }
 
 type rawWinEvent struct {
@@ -132,7 +146,9 @@ func (d Desktop) TerminateApp() {
 func (d *Desktop) pollDesktopEvents(wg *sync.WaitGroup, shutdown *bool) {
 	for !*shutdown {
 		ev, win := appStatic.desktop.PullEvent(Ctx)
-		if ev.EventType != 0 {
+		if ge := gamepadEvent(ev); ge != nil {
+			Post(ge)
+		} else if ev.EventType != 0 {
 			Post(&rawWinEvent{win: win, ev: ev})
 		} else {
 			<-time.After(1 * time.Millisecond)
@@ -146,11 +162,17 @@ func (rw *RenderWindow) Closed() bool {
 }
 
 func (rw *RenderWindow) SetPaused(paused bool) {
-	rw.paused = paused
+	rw.Clock.SetPaused(paused)
+}
+
+// SetFixedStep advances scene time by step seconds on each rendered frame instead of measured wall clock time.
+// Fixed step makes scene time deterministic, for example when replaying recorded input. Step 0 restores wall clock time
+func (rw *RenderWindow) SetFixedStep(step float64) {
+	rw.Clock.SetFixedStep(step)
 }
 
 func (rw *RenderWindow) GetSceneTime() float64 {
-	return rw.sceneTime
+	return rw.Clock.SceneTime()
 }
 
 func (rw *RenderWindow) GetRenderer() vscene.Renderer {
@@ -203,21 +225,29 @@ func (rw *RenderWindow) renderLoop() {
 		}
 		rc := rw.caches[imageIndex]
 		rc.NewFrame()
+		if rw.replay != nil {
+			rw.replay.post(rw.GetSceneTime())
+		}
 		rw.Scene.Time = rw.GetSceneTime()
+		capture := rw.frameCapture()
+		if capture != nil {
+			rw.captureImage = im
+		}
 		rw.renderer.Render(rw.Camera, &rw.Scene, rc, im, int(imageIndex), []vk.SubmitInfo{submitInfo})
+		rw.captureImage = nil
+		if capture != nil && capture.writeRecorded(im.Description) {
+			rw.endCapture()
+		}
 
 		// Adjust scene time
-		if rw.paused {
-			rw.lastRender = time.Time{}
+		if rw.capture != nil {
+			rw.Clock.Advance(rw.capture.Step)
 		} else {
-			t := time.Now()
-			if !rw.lastRender.IsZero() {
-				d := t.Sub(rw.lastRender).Seconds()
-				rw.sceneTime += d
-			}
-			rw.lastRender = t
+			rw.Clock.frame()
 		}
 	}
+	rw.frameCapture()
+	rw.endCapture()
 	rw.clearCaches()
 	rw.wg.Done()
 }
@@ -0,0 +1,111 @@
+package vasset
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// FSLoader loads assets from io/fs file system like embed.FS. Dir is optional directory prefix inside file system.
+//
+// File names are converted to io/fs format: backslashes are converted to forward slashes and leading slash is removed
+type FSLoader struct {
+	FS  fs.FS
+	Dir string
+}
+
+func (f FSLoader) Open(filename string) (io.ReadCloser, error) {
+	return f.FS.Open(FSName(path.Join(f.Dir, FSName(filename))))
+}
+
+// FSName converts file name to valid io/fs name
+func FSName(filename string) string {
+	name := path.Clean(strings.ReplaceAll(filename, "\\", "/"))
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// ZipLoader loads assets from zip archive
+type ZipLoader struct {
+	FSLoader
+	closer io.Closer
+}
+
+// NewZipLoader creates loader from zip archive content
+func NewZipLoader(r io.ReaderAt, size int64) (*ZipLoader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipLoader{FSLoader: FSLoader{FS: zr}}, nil
+}
+
+// OpenZipLoader opens zip file and creates loader from it. Close loader when assets are no longer loaded from it
+func OpenZipLoader(zipFile string) (*ZipLoader, error) {
+	zr, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipLoader{FSLoader: FSLoader{FS: zr}, closer: zr}, nil
+}
+
+// Close closes zip file if loader was opened with OpenZipLoader
+func (z *ZipLoader) Close() error {
+	if z.closer == nil {
+		return nil
+	}
+	err := z.closer.Close()
+	z.closer = nil
+	return err
+}
+
+// OverlayLoader searches assets from multiple loaders. First layer that has file will serve it, so layers for mods or
+// patches should be before base layers.
+//
+// Layer is skipped if open fails with error that matches fs.ErrNotExist. Other errors are returned immediately.
+type OverlayLoader struct {
+	Layers []Loader
+}
+
+// NewOverlayLoader creates overlay from loaders. Topmost layer is given first
+func NewOverlayLoader(layers ...Loader) *OverlayLoader {
+	return &OverlayLoader{Layers: layers}
+}
+
+func (o *OverlayLoader) Open(filename string) (io.ReadCloser, error) {
+	rd, _, err := o.OpenLayer(filename)
+	return rd, err
+}
+
+// OpenLayer opens file and reports index of layer that served it
+func (o *OverlayLoader) OpenLayer(filename string) (rd io.ReadCloser, layer int, err error) {
+	for idx, l := range o.Layers {
+		rd, err = l.Open(filename)
+		if err == nil {
+			return rd, idx, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, -1, err
+		}
+	}
+	return nil, -1, &os.PathError{Op: "open", Path: filename,
+		Err: fmt.Errorf("%w in any of %d layers", fs.ErrNotExist, len(o.Layers))}
+}
+
+// Layer reports index of layer that would serve file
+func (o *OverlayLoader) Layer(filename string) (int, error) {
+	rd, layer, err := o.OpenLayer(filename)
+	if err != nil {
+		return -1, err
+	}
+	_ = rd.Close()
+	return layer, nil
+}
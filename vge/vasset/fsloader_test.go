@@ -0,0 +1,52 @@
+package vasset
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayLoader(t *testing.T) {
+	base := FSLoader{FS: fstest.MapFS{
+		"assets/models/cube.obj": {Data: []byte("base cube")},
+		"assets/readme.txt":      {Data: []byte("base readme")},
+	}, Dir: "assets"}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, _ := zw.Create("models/cube.obj")
+	_, _ = w.Write([]byte("patched cube"))
+	_ = zw.Close()
+	patch, err := NewZipLoader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal("Zip ", err)
+	}
+
+	ol := NewOverlayLoader(patch, base)
+	testLayer(t, ol, "models\\cube.obj", "patched cube", 0)
+	testLayer(t, ol, "/readme.txt", "base readme", 1)
+	_, err = ol.Layer("missing.txt")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Expected not exists, got ", err)
+	}
+}
+
+func testLayer(t *testing.T, ol *OverlayLoader, name string, expected string, expectedLayer int) {
+	layer, err := ol.Layer(name)
+	if err != nil {
+		t.Fatal("Layer ", name, ": ", err)
+	}
+	if layer != expectedLayer {
+		t.Errorf("File %s served from layer %d, expected %d", name, layer, expectedLayer)
+	}
+	content, err := Load(name, ol)
+	if err != nil {
+		t.Fatal("Load ", name, ": ", err)
+	}
+	if string(content) != expected {
+		t.Errorf("File %s content %s, expected %s", name, string(content), expected)
+	}
+}
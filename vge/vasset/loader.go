@@ -42,7 +42,8 @@ func (d MultiDirectorLoader) Open(filename string) (io.ReadCloser, error) {
 		}
 		return os.Open(fullName)
 	}
-	return nil, fmt.Errorf("Can't locate file %s from any of directories %s", filename, strings.Join(d.Directories, ";"))
+	return nil, &os.PathError{Op: "open", Path: filename,
+		Err: fmt.Errorf("%w in any of directories %s", os.ErrNotExist, strings.Join(d.Directories, ";"))}
 }
 
 // Make load request relative to main component path.
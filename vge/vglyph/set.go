@@ -3,8 +3,10 @@ package vglyph
 import (
 	"image"
 	"math"
+	"sort"
 
 	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
 )
 
 type SetKind int
@@ -61,6 +63,35 @@ func (set *GlyphSet) Get(name string) Glyph {
 	return set.glyphs[name]
 }
 
+// Glyphs returns all glyphs of set sorted by name
+func (set *GlyphSet) Glyphs() []Glyph {
+	glyphs := make([]Glyph, 0, len(set.glyphs))
+	for _, gl := range set.glyphs {
+		glyphs = append(glyphs, gl)
+	}
+	sort.Slice(glyphs, func(i, j int) bool {
+		return glyphs[i].Name < glyphs[j].Name
+	})
+	return glyphs
+}
+
+// NewGlyphSet creates glyph set from prerendered glyphs. Content must contain whole image described by desc in raw format.
+// NewGlyphSet is used to restore glyph sets that have been read back from GPU (for example from asset packs).
+// Advance of set is DefaultAdvance
+func NewGlyphSet(ctx vk.APIContext, dev *vk.Device, desc vk.ImageDescription, kind SetKind, glyphs []Glyph, content []byte) *GlyphSet {
+	gs := &GlyphSet{glyphs: make(map[string]Glyph, len(glyphs)), kind: kind, Desc: desc, Advance: DefaultAdvance}
+	for _, gl := range glyphs {
+		gs.glyphs[gl.Name] = gl
+	}
+	gs.pool = vk.NewMemoryPool(dev)
+	gs.image = gs.pool.ReserveImage(ctx, gs.Desc, vk.IMAGEUsageSampledBit|vk.IMAGEUsageTransferDstBit|vk.IMAGEUsageTransferSrcBit)
+	gs.pool.Allocate(ctx)
+	cp := vmodel.NewCopier(ctx, dev)
+	defer cp.Dispose()
+	cp.CopyToImage(gs.image, "raw", content, gs.Desc.FullRange(), vk.IMAGELayoutShaderReadOnlyOptimal)
+	return gs
+}
+
 // If glyph set is made from font, measure string will calculate length of text using given font height
 func (gs *GlyphSet) MeasureString(text string, fontHeight int) int {
 	pos := float32(0)
@@ -1,6 +1,9 @@
 package vk
 
-import "runtime"
+import (
+	"runtime"
+	"sort"
+)
 
 type Command struct {
 	dev       *Device
@@ -11,6 +14,8 @@ type Command struct {
 
 type DrawList struct {
 	list []DrawItem
+	keys []float32
+	key  float32
 }
 
 type SubmitInfo struct {
@@ -108,6 +113,12 @@ func (c *Command) CopyBufferToImage(dst *Image, src *Buffer, imRange *ImageRange
 	}
 }
 
+func (c *Command) CopySliceToImage(dst *Image, sl *Slice, imRange *ImageRange) {
+	if c.IsValid(c.Ctx) && dst.IsValid(c.Ctx) && sl.IsValid(c.Ctx) {
+		call_Command_CopyBufferToImage(c.Ctx, c.hCmd, sl.buffer.hBuf, dst.hImage, imRange, sl.from)
+	}
+}
+
 func (c *Command) ClearImage(dst *Image, imRange *ImageRange, color float32, alpha float32) {
 	if c.IsValid(c.Ctx) && dst.IsValid(c.Ctx) {
 		call_Command_ClearImage(c.Ctx, c.hCmd, dst.hImage, imRange, imRange.Layout, color, alpha)
@@ -192,15 +203,50 @@ func NewTimerPool(ctx APIContext, dev *Device, size uint32) *TimerPool {
 func (dr *DrawList) Draw(pl Pipeline, from, count uint32) *DrawItem {
 	di := DrawItem{pipeline: pl.handle(), from: from, count: count, instances: 1}
 	dr.list = append(dr.list, di)
+	dr.keys = append(dr.keys, dr.key)
 	return &(dr.list[len(dr.list)-1])
 }
 
 func (dr *DrawList) DrawIndexed(pl Pipeline, from, count uint32) *DrawItem {
 	di := DrawItem{pipeline: pl.handle(), from: from, count: count, instances: 1, indexed: 1}
 	dr.list = append(dr.list, di)
+	dr.keys = append(dr.keys, dr.key)
 	return &(dr.list[len(dr.list)-1])
 }
 
+// SetSortKey sets sort key of all draw items added after this call. See SortByKey
+func (dr *DrawList) SetSortKey(key float32) {
+	dr.key = key
+}
+
+// SortByKey orders draw items by sort key. Sort is stable, draw items with same key retain their order.
+// If descending is set, items with highest key are drawn first. Draw items returned from Draw or DrawIndexed are not
+// valid after sort
+func (dr *DrawList) SortByKey(descending bool) {
+	sort.Stable(drawListSorter{dr: dr, descending: descending})
+}
+
+type drawListSorter struct {
+	dr         *DrawList
+	descending bool
+}
+
+func (d drawListSorter) Len() int {
+	return len(d.dr.list)
+}
+
+func (d drawListSorter) Less(i, j int) bool {
+	if d.descending {
+		return d.dr.keys[i] > d.dr.keys[j]
+	}
+	return d.dr.keys[i] < d.dr.keys[j]
+}
+
+func (d drawListSorter) Swap(i, j int) {
+	d.dr.list[i], d.dr.list[j] = d.dr.list[j], d.dr.list[i]
+	d.dr.keys[i], d.dr.keys[j] = d.dr.keys[j], d.dr.keys[i]
+}
+
 func (dr *DrawList) optimize() {
 	if len(dr.list) < 2 {
 		return
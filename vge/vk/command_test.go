@@ -0,0 +1,19 @@
+package vk
+
+import "testing"
+
+func TestDrawList_SortByKey(t *testing.T) {
+	dl := &DrawList{}
+	pl := &GraphicsPipeline{}
+	for idx, key := range []float32{2, 5, 1, 5} {
+		dl.SetSortKey(key)
+		dl.Draw(pl, uint32(idx), 1)
+	}
+	dl.SortByKey(true)
+	expected := []uint32{1, 3, 0, 2}
+	for idx, di := range dl.list {
+		if di.from != expected[idx] {
+			t.Error("Invalid order at ", idx, " expected ", expected[idx], " got ", di.from)
+		}
+	}
+}
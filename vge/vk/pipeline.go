@@ -115,6 +115,10 @@ func (cp *ComputePipeline) Create(ctx APIContext) {
 }
 
 func addShader(ctx APIContext, pl Pipeline, stage ShaderStageFlags, code []byte) {
+	if len(code) == 0 {
+		ctx.SetError(errors.New("Empty shader code. Compile shaders with go generate"))
+		return
+	}
 	call_Pipeline_AddShader(ctx, pl.handle(), stage, code)
 }
 
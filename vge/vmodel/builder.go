@@ -53,6 +53,12 @@ type NodeBuilder struct {
 	Mesh     MeshIndex
 	Material MaterialIndex
 	Skin     SkinIndex
+	// Animation contains transform animations of this node. Nil if node is not animated
+	Animation *NodeAnimation
+	// Light attached to node. Nil if node has no light
+	Light *Light
+	// Camera attached to node. Nil if node has no camera
+	Camera *Camera
 }
 
 // SetMesh assign a mesh with material to node
@@ -70,6 +76,12 @@ func (nb *NodeBuilder) SetSkinnedMesh(m MeshIndex, mat MaterialIndex, skin SkinI
 	return nb
 }
 
+// SetAnimation assign transform animations to node. Animations will replace location of node when animation is played
+func (nb *NodeBuilder) SetAnimation(na *NodeAnimation) *NodeBuilder {
+	nb.Animation = na
+	return nb
+}
+
 // Add childs adds child nodes to a node
 func (nb *NodeBuilder) AddChild(child ...*NodeBuilder) *NodeBuilder {
 	nb.Children = append(nb.Children, child...)
@@ -188,13 +200,10 @@ func (mb *ModelBuilder) ToModel(ctx vk.APIContext, dev *vk.Device) *Model {
 	}
 	var iLen [MESHMax]uint64
 	var vLen [MESHMax]uint64
+	var morphLen uint64
 
 	for _, ms := range mb.Meshes {
-		ms.buildUvs()
-		ms.buildNormals()
-		ms.buildTangets()
-		// mesh.buildQTangent()
-		hasWeights := ms.buildWeights()
+		hasWeights := ms.Complete()
 		var aabb AABB
 		for idx, vb := range ms.Vextexies {
 			aabb.Add(idx == 0, vb.Position)
@@ -212,6 +221,7 @@ func (mb *ModelBuilder) ToModel(ctx vk.APIContext, dev *vk.Device) *Model {
 		}
 		iLen[ms.kind] += uint64(len(ms.Incides)) * 4
 		vLen[ms.kind] += vertexSize * uint64(len(ms.Vextexies))
+		morphLen += ms.morphSize()
 	}
 	for idx := 0; idx < MESHMax; idx++ {
 		if iLen[idx] > 0 {
@@ -219,6 +229,13 @@ func (mb *ModelBuilder) ToModel(ctx vk.APIContext, dev *vk.Device) *Model {
 			m.vertexies[idx].bVertex = m.memPool.ReserveBuffer(ctx, vLen[idx], false, vk.BUFFERUsageTransferDstBit|vk.BUFFERUsageVertexBufferBit)
 		}
 	}
+	if vLen[MESHKindSkinned] > 0 {
+		// Skinned meshes always bind morph buffer so reserve some space even if there are no morph targets
+		if morphLen < 16 {
+			morphLen = 16
+		}
+		m.bMorph = m.memPool.ReserveBuffer(ctx, morphLen, false, vk.BUFFERUsageTransferDstBit|vk.BUFFERUsageStorageBufferBit)
+	}
 	ubfLen := mb.buildMaterials(ctx, dev, m)
 	if ubfLen > 0 {
 		m.bUbf = m.memPool.ReserveBuffer(ctx, ubfLen, false, vk.BUFFERUsageTransferDstBit|vk.BUFFERUsageUniformBufferBit)
@@ -227,6 +244,7 @@ func (mb *ModelBuilder) ToModel(ctx vk.APIContext, dev *vk.Device) *Model {
 	cp := NewCopier(ctx, dev)
 	defer cp.Dispose()
 
+	m.meshes = make([]Mesh, len(mb.Meshes))
 	mb.copyNormalVertex(m, cp)
 	mb.copySkinnedVertex(m, cp)
 	for _, ib := range mb.Images {
@@ -250,7 +268,7 @@ func (mb *ModelBuilder) ToModel(ctx vk.APIContext, dev *vk.Device) *Model {
 func (mb *ModelBuilder) copyNormalVertex(m *Model, cp *Copier) {
 	var indices []uint32
 	var vertexies []normalVertex
-	for _, mesh := range mb.Meshes {
+	for mIdx, mesh := range mb.Meshes {
 		offset := uint32(len(vertexies))
 		iOffset := uint32(len(indices))
 		if mesh.kind == MESHKindNormal {
@@ -260,8 +278,8 @@ func (mb *ModelBuilder) copyNormalVertex(m *Model, cp *Copier) {
 			for _, idx := range mesh.Incides {
 				indices = append(indices, idx+offset)
 			}
-			m.meshes = append(m.meshes, Mesh{Kind: MESHKindNormal, AABB: mesh.aabb,
-				Model: m, From: iOffset, Count: uint32(len(mesh.Incides))})
+			m.meshes[mIdx] = Mesh{Kind: MESHKindNormal, AABB: mesh.aabb,
+				Model: m, From: iOffset, Count: uint32(len(mesh.Incides))}
 		}
 	}
 	if len(indices) > 0 {
@@ -273,7 +291,8 @@ func (mb *ModelBuilder) copyNormalVertex(m *Model, cp *Copier) {
 func (mb *ModelBuilder) copySkinnedVertex(m *Model, cp *Copier) {
 	var indices []uint32
 	var vertexies []skinnedVertex
-	for _, mesh := range mb.Meshes {
+	var morphs []float32
+	for mIdx, mesh := range mb.Meshes {
 		offset := uint32(len(vertexies))
 		iOffset := uint32(len(indices))
 		if mesh.kind == MESHKindSkinned {
@@ -284,14 +303,25 @@ func (mb *ModelBuilder) copySkinnedVertex(m *Model, cp *Copier) {
 			for _, idx := range mesh.Incides {
 				indices = append(indices, idx+offset)
 			}
-			m.meshes = append(m.meshes, Mesh{Kind: MESHKindSkinned, AABB: mesh.aabb,
-				Model: m, From: iOffset, Count: uint32(len(mesh.Incides))})
+			var mi MorphInfo
+			morphs, mi = mesh.appendMorph(morphs, offset)
+			m.meshes[mIdx] = Mesh{Kind: MESHKindSkinned, AABB: mesh.aabb,
+				Model: m, From: iOffset, Count: uint32(len(mesh.Incides)), Morph: mi}
 		}
 	}
 	if len(indices) > 0 {
 		cp.CopyToBuffer(m.vertexies[MESHKindSkinned].bIndex, vk.UInt32ToBytes(indices))
 		cp.CopyToBuffer(m.vertexies[MESHKindSkinned].bVertex, skinnedVertexToBytes(vertexies))
 	}
+	if m.bMorph != nil {
+		if len(morphs) > 0 {
+			cp.CopyToBuffer(m.bMorph, vk.Float32ToBytes(morphs))
+		}
+		pool := vk.NewDescriptorPool(cp.ctx, GetMorphLayout(cp.ctx, cp.dev), 1)
+		m.owner.AddChild(pool)
+		m.dsMorph = pool.Alloc(cp.ctx)
+		m.dsMorph.WriteBuffer(cp.ctx, 0, 0, m.bMorph)
+	}
 }
 
 func (mb *ModelBuilder) copyImage(m *Model, ctx vk.APIContext, dev *vk.Device, ib *ImageBuilder) {
@@ -395,6 +425,9 @@ func (mb *ModelBuilder) addNodes(n *NodeBuilder, m *Model) NodeIndex {
 	node.Mesh = n.Mesh
 	node.Material = n.Material
 	node.Skin = n.Skin
+	node.Animation = n.Animation
+	node.Light = n.Light
+	node.Camera = n.Camera
 	m.nodes = append(m.nodes, node)
 	if len(n.Children) > 0 {
 		for _, ch := range n.Children {
@@ -433,8 +466,12 @@ type MaterialInfo struct {
 type MeshBuilder struct {
 	Vextexies []*VertexBuilder
 	Incides   []uint32
-	aabb      AABB
-	kind      MeshKind
+	// Morph targets of mesh. See AddMorphTarget
+	Targets []*MorphTarget
+	// Default weights of morph targets
+	Weights []float32
+	aabb    AABB
+	kind    MeshKind
 }
 
 type VertexFlags int
@@ -529,6 +566,17 @@ func (mb *MeshBuilder) addPos(tr mgl32.Mat4, v mgl32.Vec3, n mgl32.Vec3, uv mgl3
 	return vb
 }
 
+// Complete generates missing uvs, normals and tangents of vertices. Complete returns true if mesh has joint weights and
+// will be converted to skinned mesh. ToModel will complete all meshes, but you can call Complete for example before
+// exporting mesh content.
+func (mb *MeshBuilder) Complete() (skinned bool) {
+	mb.buildUvs()
+	mb.buildNormals()
+	mb.buildTangets()
+	// mesh.buildQTangent()
+	return mb.buildWeights()
+}
+
 func (mb *MeshBuilder) buildUvs() {
 	for _, vb := range mb.Vextexies {
 		if vb.flags&VFUV != 0 {
@@ -658,6 +706,13 @@ func (mb *MeshBuilder) buildWeights() (hasWeights bool) {
 			break
 		}
 	}
+	if !hasWeights && len(mb.Targets) > 0 {
+		// Morphed meshes are drawn as skinned meshes. Bind all vertices to first joint
+		for _, vb := range mb.Vextexies {
+			vb.AddWeights(mgl32.Vec4{1, 0, 0, 0}, 0, 0, 0, 0)
+		}
+		hasWeights = true
+	}
 	if hasWeights {
 		for idx, vb := range mb.Vextexies {
 			w1 := 1 - vb.Weights[1] + vb.Weights[2] + vb.Weights[3]
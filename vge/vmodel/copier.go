@@ -58,7 +58,24 @@ func (c *Copier) CopyToImage(dst *vk.Image, kind string, content []byte, imRange
 	c.cmd.Begin()
 	c.cmd.SetLayout(dst, &imRange, vk.IMAGELayoutTransferDstOptimal)
 	imRange.Layout = vk.IMAGELayoutTransferDstOptimal
-	c.cmd.CopyBufferToImage(dst, bTmp, &imRange)
+	if imRange.LevelCount > 1 {
+		// Content has all mip levels of first layer, then all mip levels of second layer ... (same as in CopyFromImage)
+		subRange := imRange
+		subRange.LevelCount = 1
+		subRange.LayerCount = 1
+		offset := uint64(0)
+		for layer := imRange.FirstLayer; layer < imRange.FirstLayer+imRange.LayerCount; layer++ {
+			for mip := imRange.FirstMipLevel; mip < imRange.FirstMipLevel+imRange.LevelCount; mip++ {
+				subRange.FirstMipLevel = mip
+				subRange.FirstLayer = layer
+				mipSize := dst.Description.ImageRangeSize(subRange)
+				c.cmd.CopySliceToImage(dst, bTmp.Slice(c.ctx, offset, offset+mipSize), &subRange)
+				offset += mipSize
+			}
+		}
+	} else {
+		c.cmd.CopyBufferToImage(dst, bTmp, &imRange)
+	}
 	c.cmd.SetLayout(dst, &imRange, finalLayout)
 	c.cmd.Submit()
 	c.cmd.Wait()
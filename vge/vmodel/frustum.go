@@ -0,0 +1,51 @@
+package vmodel
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Frustum is view volume bounded by six planes. Plane normals (x, y, z) point inside of frustum and w is distance
+// from origin
+type Frustum struct {
+	Planes [6]mgl32.Vec4
+}
+
+// NewFrustum extracts frustum planes from combined projection * view matrix. Near plane is extracted assuming
+// OpenGL depth range from -1 to 1 that also contains Vulkan depth range from 0 to 1
+func NewFrustum(viewProjection mgl32.Mat4) Frustum {
+	r0, r1, r2, r3 := viewProjection.Row(0), viewProjection.Row(1), viewProjection.Row(2), viewProjection.Row(3)
+	f := Frustum{Planes: [6]mgl32.Vec4{r3.Add(r0), r3.Sub(r0), r3.Add(r1), r3.Sub(r1), r3.Add(r2), r3.Sub(r2)}}
+	for idx, p := range f.Planes {
+		l := p.Vec3().Len()
+		if l > 0 {
+			f.Planes[idx] = p.Mul(1 / l)
+		}
+	}
+	return f
+}
+
+// IntersectsAABB returns false if box is completely outside of frustum. Test is conservative and some boxes near
+// corners of frustum are reported to intersect even if they are outside
+func (f *Frustum) IntersectsAABB(aabb AABB) bool {
+	for _, p := range f.Planes {
+		// Test corner that is farthest along plane normal
+		v := aabb.Min
+		for idx := 0; idx < 3; idx++ {
+			if p[idx] >= 0 {
+				v[idx] = aabb.Max[idx]
+			}
+		}
+		if p.Vec3().Dot(v)+p[3] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsSphere returns false if sphere is completely outside of frustum
+func (f *Frustum) IntersectsSphere(center mgl32.Vec3, radius float32) bool {
+	for _, p := range f.Planes {
+		if p.Vec3().Dot(center)+p[3] < -radius {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package vmodel
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestFrustum_IntersectsAABB(t *testing.T) {
+	projection := mgl32.Perspective(1, 1, 0.1, 100)
+	view := mgl32.LookAtV(mgl32.Vec3{0, 0, 10}, mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 1, 0})
+	f := NewFrustum(projection.Mul4(view))
+	tests := []struct {
+		name    string
+		aabb    AABB
+		visible bool
+	}{
+		{"center", AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}, true},
+		{"behind", AABB{Min: mgl32.Vec3{-1, -1, 11}, Max: mgl32.Vec3{1, 1, 12}}, false},
+		{"left", AABB{Min: mgl32.Vec3{-30, -1, -1}, Max: mgl32.Vec3{-20, 1, 1}}, false},
+		{"above", AABB{Min: mgl32.Vec3{-1, 20, -1}, Max: mgl32.Vec3{1, 30, 1}}, false},
+		{"far", AABB{Min: mgl32.Vec3{-1, -1, -200}, Max: mgl32.Vec3{1, 1, -100}}, false},
+		{"partial", AABB{Min: mgl32.Vec3{-30, -1, -1}, Max: mgl32.Vec3{0, 1, 1}}, true},
+	}
+	for _, tt := range tests {
+		if f.IntersectsAABB(tt.aabb) != tt.visible {
+			t.Error("Invalid visibility for ", tt.name, ", expected ", tt.visible)
+		}
+	}
+	if f.IntersectsSphere(mgl32.Vec3{0, 0, 20}, 5) {
+		t.Error("Sphere behind camera visible")
+	}
+}
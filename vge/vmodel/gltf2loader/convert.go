@@ -1,6 +1,7 @@
 package gltf2loader
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -22,8 +23,12 @@ type GLTF2Loader struct {
 	Loader     vasset.Loader
 	Model      *GLTF2
 	ImageUsage vk.ImageUsageFlags
+	// LightScale is multiplier for intensity of KHR_lights_punctual lights. glTF lights use physical units (candela and lux).
+	// If LightScale is 0, intensities are not scaled
+	LightScale float32
 
 	materials []vmodel.MaterialIndex
+	lights    []*vmodel.Light
 	meshes    [][]meshMaterial
 	skins     []vmodel.SkinIndex
 	streams   map[int][]float32
@@ -53,12 +58,19 @@ func (cc *GLTF2Loader) loadImage(tx *Texture) (vmodel.ImageIndex, error) {
 	if tx == nil {
 		return 0, nil
 	}
-	img := cc.Model.Images[tx.Index]
+	imgIndex, err := cc.Model.GetTextureImage(tx.Index)
+	if err != nil {
+		return 0, err
+	}
+	if imgIndex < 0 || imgIndex >= len(cc.Model.Images) {
+		return 0, fmt.Errorf("Invalid image index %d", imgIndex)
+	}
+	img := cc.Model.Images[imgIndex]
 	if img.index != 0 {
 		return img.index, nil
 	}
 	if img.content == nil {
-		return 0, fmt.Errorf("No content for image %d", tx.Index)
+		return 0, fmt.Errorf("No content for image %d", imgIndex)
 	}
 	img.index = cc.Builder.AddImage(img.kind, img.content, cc.ImageUsage)
 	return img.index, nil
@@ -71,8 +83,13 @@ func (cc *GLTF2Loader) toScene(sceneIndex int) (err error) {
 			return err
 		}
 	}
-	cc.convertScene(sceneIndex)
-	return
+	if cc.lights == nil {
+		err = cc.convertLights()
+		if err != nil {
+			return err
+		}
+	}
+	return cc.convertScene(sceneIndex)
 }
 
 func (cc *GLTF2Loader) convertMaterials() error {
@@ -133,6 +150,10 @@ func (cc *GLTF2Loader) buildMeshes(gltf *GLTF2) error {
 			if err != nil {
 				return err
 			}
+			err = cc.addMorphTargets(mb, len(vbs), p, m.Weights)
+			if err != nil {
+				return err
+			}
 
 			var incides []uint32
 			if p.Indices != nil {
@@ -158,10 +179,83 @@ func (cc *GLTF2Loader) buildMeshes(gltf *GLTF2) error {
 	return nil
 }
 
-func (cc *GLTF2Loader) convertScene(sceneIndex int) {
+func (cc *GLTF2Loader) convertScene(sceneIndex int) error {
 	gltf := cc.Model
 	nl := gltf.Scenes[sceneIndex].Nodes
-	cc.convertLevel(nl, cc.Parent)
+	return cc.convertLevel(nl, cc.Parent)
+}
+
+// convertLights converts KHR_lights_punctual lights
+func (cc *GLTF2Loader) convertLights() error {
+	cc.lights = []*vmodel.Light{}
+	if len(cc.Model.Extensions) == 0 {
+		return nil
+	}
+	var ext Extensions
+	err := json.Unmarshal(cc.Model.Extensions, &ext)
+	if err != nil {
+		return fmt.Errorf("Model extensions: %v", err)
+	}
+	if ext.LightsPunctual == nil {
+		return nil
+	}
+	for _, l := range ext.LightsPunctual.Lights {
+		vl := &vmodel.Light{Color: mgl32.Vec3{1, 1, 1}, Intensity: 1, Range: l.Range, OuterCone: math.Pi / 4}
+		switch l.Type {
+		case "directional":
+			vl.Kind = vmodel.LightDirectional
+		case "point":
+			vl.Kind = vmodel.LightPoint
+		case "spot":
+			vl.Kind = vmodel.LightSpot
+		default:
+			return fmt.Errorf("Unknown light type %s", l.Type)
+		}
+		if len(l.Color) == 3 {
+			vl.Color = mgl32.Vec3{l.Color[0], l.Color[1], l.Color[2]}
+		}
+		if l.Intensity != nil {
+			vl.Intensity = *l.Intensity
+		}
+		if cc.LightScale != 0 {
+			vl.Intensity *= cc.LightScale
+		}
+		if l.Spot != nil {
+			vl.InnerCone = l.Spot.InnerConeAngle
+			if l.Spot.OuterConeAngle != nil {
+				vl.OuterCone = *l.Spot.OuterConeAngle
+			}
+		}
+		cc.lights = append(cc.lights, vl)
+	}
+	return nil
+}
+
+// addCameraAndLight attaches perspective camera and KHR_lights_punctual light of glTF node to model node.
+// Orthographic cameras are not supported
+func (cc *GLTF2Loader) addCameraAndLight(newNode *vmodel.NodeBuilder, n *Node) error {
+	if n.Camera != nil {
+		c := cc.Model.Cameras[*n.Camera]
+		if c.Type == "perspective" && c.Perspective != nil {
+			newNode.SetCamera(&vmodel.Camera{YFov: c.Perspective.YFov, AspectRatio: c.Perspective.AspectRatio,
+				ZNear: c.Perspective.ZNear, ZFar: c.Perspective.ZFar})
+		}
+	}
+	if len(n.Extensions) == 0 {
+		return nil
+	}
+	var ext NodeExtensions
+	err := json.Unmarshal(n.Extensions, &ext)
+	if err != nil {
+		return fmt.Errorf("Node %s extensions: %v", n.Name, err)
+	}
+	if ext.LightsPunctual != nil {
+		if ext.LightsPunctual.Light < 0 || ext.LightsPunctual.Light >= len(cc.lights) {
+			return fmt.Errorf("Node %s has invalid light index %d", n.Name, ext.LightsPunctual.Light)
+		}
+		newNode.SetLight(cc.lights[ext.LightsPunctual.Light])
+	}
+	return nil
 }
 
 func (cc *GLTF2Loader) convertLevel(nodes []int, parent *vmodel.NodeBuilder) (err error) {
@@ -172,13 +266,29 @@ func (cc *GLTF2Loader) convertLevel(nodes []int, parent *vmodel.NodeBuilder) (er
 		n.ApplyTransform(&local)
 		sk := vmodel.SkinIndex(0)
 		if n.Skin != nil {
-			sk, err = cc.buildSkin(*n.Skin)
+			sk, err = cc.buildSkin(*n.Skin, nIdx)
+			if err != nil {
+				return err
+			}
+		} else if n.Mesh != nil && cc.hasMorphTargets(*n.Mesh) {
+			sk, err = cc.buildMorphSkin(nIdx)
 			if err != nil {
 				return err
 			}
 		}
 
 		newNode := cc.Builder.AddNode(n.Name, parent, local)
+		na, err := cc.buildNodeAnimation(nIdx, n)
+		if err != nil {
+			return err
+		}
+		if na != nil {
+			newNode.SetAnimation(na)
+		}
+		err = cc.addCameraAndLight(newNode, n)
+		if err != nil {
+			return err
+		}
 		if n.Mesh != nil {
 			mList := cc.meshes[*n.Mesh]
 			for chIdx, m := range mList {
@@ -209,6 +319,8 @@ func (cc *GLTF2Loader) mapMaterial(m *Material) (vmodel.MaterialIndex, error) {
 	switch m.AlphaMode {
 	case "MASK":
 		props.SetFactor(vmodel.FAlphaCutoff, m.AlphaCutoff)
+	case "BLEND":
+		props.SetFactor(vmodel.FAlphaBlend, 1)
 	}
 	if m.PbrMetallicRoughness != nil {
 		pbr := m.PbrMetallicRoughness
@@ -258,9 +370,86 @@ func (cc *GLTF2Loader) mapMaterial(m *Material) (vmodel.MaterialIndex, error) {
 	} else if txIndex != 0 {
 		props.SetColor(vmodel.CEmissive, mgl32.Vec4{1, 1, 1, 1})
 	}
+	txIndex, err = cc.loadImage(m.OcclusionTexture)
+	if err != nil {
+		return 0, err
+	}
+	if txIndex != 0 {
+		props.SetImage(vmodel.TxOcclusion, txIndex)
+		props.SetFactor(vmodel.FOcclusionStrength, 1)
+		if m.OcclusionTexture.Strength != nil {
+			props.SetFactor(vmodel.FOcclusionStrength, *m.OcclusionTexture.Strength)
+		}
+	}
+	err = mapMaterialExtensions(m, props)
+	if err != nil {
+		return 0, err
+	}
 	return cc.Builder.AddMaterial(m.Name, props), nil
 }
 
+// mapMaterialExtensions maps supported KHR_materials extensions and KHR_texture_transform to material properties
+func mapMaterialExtensions(m *Material, props vmodel.MaterialProperties) error {
+	if len(m.Extensions) > 0 {
+		var ext MaterialExtensions
+		err := json.Unmarshal(m.Extensions, &ext)
+		if err != nil {
+			return fmt.Errorf("Material %s extensions: %v", m.Name, err)
+		}
+		if ext.EmissiveStrength != nil {
+			props.SetFactor(vmodel.FEmissiveStrength, ext.EmissiveStrength.EmissiveStrength)
+		}
+		if ext.Unlit != nil {
+			props.SetFactor(vmodel.FUnlit, 1)
+		}
+		if ext.Clearcoat != nil {
+			props.SetFactor(vmodel.FClearcoat, ext.Clearcoat.ClearcoatFactor)
+			props.SetFactor(vmodel.FClearcoatRoughness, ext.Clearcoat.ClearcoatRoughnessFactor)
+		}
+	}
+	// Only one texture transform is supported for material. Base color transform is preferred
+	var textures []*Texture
+	if m.PbrMetallicRoughness != nil {
+		textures = append(textures, m.PbrMetallicRoughness.BaseColorTexture, m.PbrMetallicRoughness.MetallicRoughnessTexture)
+	}
+	textures = append(textures, m.NormalTexture, m.OcclusionTexture, m.EmissiveTexture)
+	for _, tx := range textures {
+		tr, ok, err := getTextureTransform(tx)
+		if err != nil {
+			return fmt.Errorf("Material %s texture transform: %v", m.Name, err)
+		}
+		if ok {
+			props.SetTransform(vmodel.XUVTransform, tr)
+			return nil
+		}
+	}
+	return nil
+}
+
+// getTextureTransform calculates texture coordinate transform from KHR_texture_transform extension
+func getTextureTransform(tx *Texture) (tr mgl32.Mat3, ok bool, err error) {
+	if tx == nil || len(tx.Extensions) == 0 {
+		return tr, false, nil
+	}
+	var ext TextureExtensions
+	err = json.Unmarshal(tx.Extensions, &ext)
+	if err != nil || ext.TextureTransform == nil {
+		return tr, false, err
+	}
+	tt := ext.TextureTransform
+	offset, scale := mgl32.Vec2{0, 0}, mgl32.Vec2{1, 1}
+	if len(tt.Offset) == 2 {
+		offset = mgl32.Vec2{tt.Offset[0], tt.Offset[1]}
+	}
+	if len(tt.Scale) == 2 {
+		scale = mgl32.Vec2{tt.Scale[0], tt.Scale[1]}
+	}
+	sin, cos := float32(math.Sin(float64(tt.Rotation))), float32(math.Cos(float64(tt.Rotation)))
+	translation := mgl32.Mat3FromRows(mgl32.Vec3{1, 0, offset[0]}, mgl32.Vec3{0, 1, offset[1]}, mgl32.Vec3{0, 0, 1})
+	rotation := mgl32.Mat3FromRows(mgl32.Vec3{cos, sin, 0}, mgl32.Vec3{-sin, cos, 0}, mgl32.Vec3{0, 0, 1})
+	return translation.Mul3(rotation).Mul3(mgl32.Scale2D(scale[0], scale[1])), true, nil
+}
+
 func (cc *GLTF2Loader) isRoot(sk *vmodel.Skin, joint int) bool {
 	for _, j := range sk.Joints {
 		for _, ch := range j.Children {
@@ -283,26 +472,81 @@ func (cc *GLTF2Loader) buildJoint(jNode int, joints map[int]int) vmodel.Joint {
 		j.Children = append(j.Children, chIdx)
 	}
 	j.Name = n.Name
+	j.Translate, j.Rotate, j.Scale = getTRS(n)
+	return j
+}
+
+func getTRS(n *Node) (translate mgl32.Vec3, rotate mgl32.Quat, scale mgl32.Vec3) {
 	if len(n.Translation) > 2 {
-		j.Translate = mgl32.Vec3{n.Translation[0], n.Translation[1], n.Translation[2]}
+		translate = mgl32.Vec3{n.Translation[0], n.Translation[1], n.Translation[2]}
 	}
 	if len(n.Rotation) > 3 {
-		j.Rotate = mgl32.Quat{V: mgl32.Vec3{n.Rotation[0], n.Rotation[1], n.Rotation[2]}, W: n.Rotation[3]}
+		rotate = mgl32.Quat{V: mgl32.Vec3{n.Rotation[0], n.Rotation[1], n.Rotation[2]}, W: n.Rotation[3]}
 	} else {
-		j.Rotate = mgl32.Quat{W: 1}
+		rotate = mgl32.Quat{W: 1}
 	}
 	if len(n.Scale) > 2 {
-		j.Scale = mgl32.Vec3{n.Scale[0], n.Scale[1], n.Scale[2]}
+		scale = mgl32.Vec3{n.Scale[0], n.Scale[1], n.Scale[2]}
 	} else {
-		j.Scale = mgl32.Vec3{1, 1, 1}
+		scale = mgl32.Vec3{1, 1, 1}
 	}
+	return
+}
 
-	return j
+func (cc *GLTF2Loader) isJoint(nIdx int) bool {
+	for _, sk := range cc.Model.Skins {
+		for _, j := range sk.Joints {
+			if j == nIdx {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildNodeAnimation collects all animation channels that target a non joint node. Node will have one track for each animation
+// in glTF file so that same animation index can be used on all nodes
+func (cc *GLTF2Loader) buildNodeAnimation(nIdx int, n *Node) (*vmodel.NodeAnimation, error) {
+	if len(n.Matrix) > 0 || cc.isJoint(nIdx) {
+		return nil, nil
+	}
+	var na *vmodel.NodeAnimation
+	for aIdx, animation := range cc.Model.Animations {
+		for _, ch := range animation.Channels {
+			if ch.Target.Node != nIdx {
+				continue
+			}
+			target, err := getTarget(ch.Target.Path)
+			if err != nil {
+				return nil, err
+			}
+			if target == 0 || target == vmodel.TWeights {
+				continue
+			}
+			chNew, err := cc.buildChannel(animation, ch, target)
+			if err != nil {
+				return nil, err
+			}
+			if len(chNew.Input) == 0 {
+				continue
+			}
+			if na == nil {
+				na = &vmodel.NodeAnimation{Tracks: make([]vmodel.Animation, len(cc.Model.Animations))}
+				na.Translate, na.Rotate, na.Scale = getTRS(n)
+				for idx, an := range cc.Model.Animations {
+					na.Tracks[idx].Name = an.Name
+				}
+			}
+			na.Tracks[aIdx].Channels = append(na.Tracks[aIdx].Channels, chNew)
+		}
+	}
+	return na, nil
 }
 
-func (cc *GLTF2Loader) addAnimations(sk *vmodel.Skin, jointMap map[int]int) error {
+// addAnimations adds all animations that target skin joints or morph weights of mesh node
+func (cc *GLTF2Loader) addAnimations(sk *vmodel.Skin, jointMap map[int]int, meshNode int) error {
 	for _, an := range cc.Model.Animations {
-		err := cc.addAnimation(an, sk, jointMap)
+		err := cc.addAnimation(an, sk, jointMap, meshNode)
 		if err != nil {
 			return err
 		}
@@ -310,31 +554,31 @@ func (cc *GLTF2Loader) addAnimations(sk *vmodel.Skin, jointMap map[int]int) erro
 	return nil
 }
 
-func (cc *GLTF2Loader) addAnimation(animation *Animation, sk *vmodel.Skin, jointMap map[int]int) error {
+func (cc *GLTF2Loader) addAnimation(animation *Animation, sk *vmodel.Skin, jointMap map[int]int, meshNode int) error {
 	an := vmodel.Animation{Name: animation.Name}
 
 	for _, ch := range animation.Channels {
+		target, err := getTarget(ch.Target.Path)
+		if err != nil {
+			return err
+		}
+		if target == 0 {
+			continue
+		}
 		jt, ok := jointMap[ch.Target.Node]
+		if target == vmodel.TWeights {
+			jt, ok = 0, ch.Target.Node == meshNode
+		}
 		if !ok {
 			continue
 		}
-		var target vmodel.ChannelTarget
-		switch ch.Target.Path {
-		case "translation":
-			target = vmodel.TTranslation
-		case "rotation":
-			target = vmodel.TRotation
-		case "scale":
-			target = vmodel.TScale
-		default:
-			return fmt.Errorf("Unknown channel target %s", ch.Target.Path)
-		}
-		input, output, err := cc.getSampler(target, animation.Samplers[ch.Sampler])
+		chNew, err := cc.buildChannel(animation, ch, target)
 		if err != nil {
 			return err
 		}
-		if len(input) > 1 {
-			an.Channels = append(an.Channels, vmodel.Channel{Target: target, Joint: jt, Input: input, Output: output})
+		chNew.Joint = jt
+		if len(chNew.Input) > 0 {
+			an.Channels = append(an.Channels, chNew)
 		}
 	}
 	if len(an.Channels) > 0 {
@@ -365,7 +609,12 @@ func (cc *GLTF2Loader) getSampler(target vmodel.ChannelTarget, sampler *Sampler)
 		if err != nil {
 			return nil, nil, err
 		}
-		if (el != 3 && target != vmodel.TRotation) || (el != 4 && target == vmodel.TRotation) {
+		switch {
+		case target == vmodel.TWeights:
+			if el != 1 {
+				return nil, nil, errors.New("Weights output sample should have element size 1")
+			}
+		case (el != 3 && target != vmodel.TRotation) || (el != 4 && target == vmodel.TRotation):
 			return nil, nil, errors.New("Output sample should have element size 3 or 4")
 		}
 		output = fl
@@ -374,7 +623,54 @@ func (cc *GLTF2Loader) getSampler(target vmodel.ChannelTarget, sampler *Sampler)
 	return
 }
 
-func (cc *GLTF2Loader) buildSkin(skinNro int) (vmodel.SkinIndex, error) {
+// getTarget maps glTF channel path to channel target. Target is 0 for supported paths that are not mapped to a transform
+func getTarget(path string) (vmodel.ChannelTarget, error) {
+	switch path {
+	case "translation":
+		return vmodel.TTranslation, nil
+	case "rotation":
+		return vmodel.TRotation, nil
+	case "scale":
+		return vmodel.TScale, nil
+	case "weights":
+		return vmodel.TWeights, nil
+	}
+	return 0, fmt.Errorf("Unknown channel target %s", path)
+}
+
+// buildChannel reads channels sampler. Returned channel has no input if channel has less than two keys
+func (cc *GLTF2Loader) buildChannel(animation *Animation, ch *Channels, target vmodel.ChannelTarget) (vmodel.Channel, error) {
+	sampler := animation.Samplers[ch.Sampler]
+	input, output, err := cc.getSampler(target, sampler)
+	if err != nil {
+		return vmodel.Channel{}, err
+	}
+	ip, err := getInterpolation(sampler)
+	if err != nil {
+		return vmodel.Channel{}, err
+	}
+	if len(input) > 0 && len(output)%len(input) != 0 {
+		return vmodel.Channel{}, fmt.Errorf("Sampler output length %d don't match input length %d", len(output), len(input))
+	}
+	if len(input) < 2 {
+		return vmodel.Channel{Target: target}, nil
+	}
+	return vmodel.Channel{Target: target, Input: input, Output: output, Interpolation: ip}, nil
+}
+
+func getInterpolation(sampler *Sampler) (vmodel.Interpolation, error) {
+	switch sampler.Interpolation {
+	case "", "LINEAR":
+		return vmodel.ILinear, nil
+	case "STEP":
+		return vmodel.IStep, nil
+	case "CUBICSPLINE":
+		return vmodel.ICubicSpline, nil
+	}
+	return 0, fmt.Errorf("Unknown sampler interpolation %s", sampler.Interpolation)
+}
+
+func (cc *GLTF2Loader) buildSkin(skinNro int, meshNode int) (vmodel.SkinIndex, error) {
 	gltf := cc.Model
 	rawSk := gltf.Skins[skinNro]
 	sk := vmodel.Skin{}
@@ -398,7 +694,7 @@ func (cc *GLTF2Loader) buildSkin(skinNro int) (vmodel.SkinIndex, error) {
 		sk.Joints[idx] = cc.buildJoint(jIdx, jointMap)
 		copy(sk.Joints[idx].InverseMatrix[:], mxFloats[16*idx:])
 	}
-	err = cc.addAnimations(&sk, jointMap)
+	err = cc.addAnimations(&sk, jointMap, meshNode)
 	if err != nil {
 		return 0, err
 	}
@@ -408,6 +704,75 @@ func (cc *GLTF2Loader) buildSkin(skinNro int) (vmodel.SkinIndex, error) {
 	return cc.Builder.AddSkin(sk), nil
 }
 
+// buildMorphSkin builds skin with single identity joint for mesh node that has morph targets but no skin.
+// Skin will have all morph weight animations of mesh node
+func (cc *GLTF2Loader) buildMorphSkin(meshNode int) (vmodel.SkinIndex, error) {
+	sk := vmodel.Skin{Joints: []vmodel.Joint{{Name: "morph", Root: true, Rotate: mgl32.QuatIdent(),
+		Scale: mgl32.Vec3{1, 1, 1}, InverseMatrix: mgl32.Ident4()}}}
+	err := cc.addAnimations(&sk, map[int]int{}, meshNode)
+	if err != nil {
+		return 0, err
+	}
+	return cc.Builder.AddSkin(sk), nil
+}
+
+func (cc *GLTF2Loader) hasMorphTargets(mesh int) bool {
+	for _, p := range cc.Model.Meshes[mesh].Primitives {
+		if len(p.Targets) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// addMorphTargets reads position, normal and tangent deltas of each primitive morph target
+func (cc *GLTF2Loader) addMorphTargets(mb *vmodel.MeshBuilder, vertices int, p Primitive, weights []float32) error {
+	for tIdx, t := range p.Targets {
+		mt := &vmodel.MorphTarget{}
+		var err error
+		mt.Positions, err = cc.getDeltas(t, APosition, vertices)
+		if err != nil {
+			return err
+		}
+		mt.Normals, err = cc.getDeltas(t, ANormal, vertices)
+		if err != nil {
+			return err
+		}
+		mt.Tangents, err = cc.getDeltas(t, ATangent, vertices)
+		if err != nil {
+			return err
+		}
+		var w float32
+		if tIdx < len(weights) {
+			w = weights[tIdx]
+		}
+		mb.AddMorphTarget(mt, w)
+	}
+	return nil
+}
+
+func (cc *GLTF2Loader) getDeltas(target map[string]int, attr string, vertices int) ([]mgl32.Vec3, error) {
+	ac, ok := target[attr]
+	if !ok {
+		return nil, nil
+	}
+	attrs, elements, err := cc.Model.GetFloats(ac)
+	if err != nil {
+		return nil, err
+	}
+	if elements != 3 {
+		return nil, fmt.Errorf("Morph target %s should have 3 elements, not %d", attr, elements)
+	}
+	if len(attrs) != 3*vertices {
+		return nil, fmt.Errorf("%d morph target deltas and %d vertexies", len(attrs)/3, vertices)
+	}
+	deltas := make([]mgl32.Vec3, vertices)
+	for idx := range deltas {
+		deltas[idx] = mgl32.Vec3{attrs[3*idx], attrs[3*idx+1], attrs[3*idx+2]}
+	}
+	return deltas, nil
+}
+
 func (cc *GLTF2Loader) addNormal(vbs []*vmodel.VertexBuilder, p Primitive) error {
 	ac, ok := p.Attributes[ANormal]
 	if !ok {
@@ -0,0 +1,781 @@
+package gltf2loader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// GLTF2Exporter converts content of model builder to glTF 2.0 model. Exported model will contain all materials of
+// model builder and all nodes under builders root node (root node itself is not exported) with their meshes, images,
+// skins, animations, cameras and lights.
+//
+// Geometry of built vmodel.Model resides only in GPU memory, so export must be done from ModelBuilder. Exporter will
+// complete copies of meshes (see MeshBuilder.Complete) so builder is not modified and it can be exported either before
+// or after ToModel.
+//
+// Joint nodes of skin are added as children of first node that uses skin. Node animations and skin animations with same
+// name are merged to one glTF animation. Unnamed animations are named by their index.
+//
+// Only png, jpg and dds images and raw images with R8 or R8G8B8A8 format are supported. Raw images are encoded to png and
+// dds images use MSFT_texture_dds extension. Image 0 (white image) is treated as no image.
+type GLTF2Exporter struct {
+	Builder *vmodel.ModelBuilder
+	// LightScale is divider for light intensities. Use same LightScale that was used in GLTF2Loader to get original
+	// intensities back. If LightScale is 0, intensities are not scaled
+	LightScale float32
+	// Model is exported glTF model. Model is set by Export
+	Model *GLTF2
+
+	data       []byte
+	textures   map[vmodel.ImageIndex]int
+	meshes     map[meshMaterial]int
+	skins      map[vmodel.SkinIndex]*exportSkin
+	skinOrder  []*exportSkin
+	lights     map[*vmodel.Light]int
+	animations map[string]int
+	used       map[string]bool
+	required   map[string]bool
+}
+
+type exportSkin struct {
+	skin      *vmodel.Skin
+	index     int
+	firstNode int
+	meshNodes []int
+}
+
+// Export converts content of model builder to glTF model
+func (ex *GLTF2Exporter) Export() error {
+	if ex.Builder == nil {
+		return errors.New("Set Builder")
+	}
+	ex.Model = &GLTF2{Asset: &Asset{Version: "2.0", Generator: "VGE"}, Scenes: []*Scene{{}}}
+	ex.data = nil
+	ex.textures = make(map[vmodel.ImageIndex]int)
+	ex.meshes = make(map[meshMaterial]int)
+	ex.skins = make(map[vmodel.SkinIndex]*exportSkin)
+	ex.skinOrder = nil
+	ex.lights = make(map[*vmodel.Light]int)
+	ex.animations = make(map[string]int)
+	ex.used, ex.required = make(map[string]bool), make(map[string]bool)
+	var lights []*Light
+	for _, mi := range ex.Builder.Materials {
+		m, err := ex.exportMaterial(mi)
+		if err != nil {
+			return err
+		}
+		ex.Model.Materials = append(ex.Model.Materials, m)
+	}
+	if ex.Builder.Root != nil {
+		for _, ch := range ex.Builder.Root.Children {
+			nIdx, err := ex.exportNode(ch, &lights)
+			if err != nil {
+				return err
+			}
+			ex.Model.Scenes[0].Nodes = append(ex.Model.Scenes[0].Nodes, nIdx)
+		}
+	}
+	for _, es := range ex.skinOrder {
+		ex.exportSkinAnimations(es)
+	}
+	if len(lights) > 0 {
+		ext, err := json.Marshal(Extensions{LightsPunctual: &LightsPunctual{Lights: lights}})
+		if err != nil {
+			return err
+		}
+		ex.Model.Extensions = ext
+		ex.used["KHR_lights_punctual"] = true
+	}
+	if len(ex.data) > 0 {
+		b := &Buffer{ByteLength: len(ex.data)}
+		b.SetContent(ex.data)
+		ex.Model.Buffers = append(ex.Model.Buffers, b)
+	}
+	ex.Model.ExtensionsUsed = sortedKeys(ex.used)
+	ex.Model.ExtensionsRequired = sortedKeys(ex.required)
+	ex.Model.Bind()
+	return nil
+}
+
+// WriteGltf writes model as glTF json. Binary content is embedded to json as base64 data uri. Model is exported if
+// Export has not been called
+func (ex *GLTF2Exporter) WriteGltf(w io.Writer) error {
+	if ex.Model == nil {
+		err := ex.Export()
+		if err != nil {
+			return err
+		}
+	}
+	m := *ex.Model
+	if len(m.Buffers) > 0 {
+		b := *m.Buffers[0]
+		b.URI = "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(b.data)
+		m.Buffers = []*Buffer{&b}
+	}
+	content, err := json.Marshal(&m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// WriteGLB writes model in binary glTF format. Model is exported if Export has not been called
+func (ex *GLTF2Exporter) WriteGLB(w io.Writer) error {
+	if ex.Model == nil {
+		err := ex.Export()
+		if err != nil {
+			return err
+		}
+	}
+	content, err := json.Marshal(ex.Model)
+	if err != nil {
+		return err
+	}
+	for len(content)%4 != 0 {
+		content = append(content, ' ')
+	}
+	bin := ex.data
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+	total := 12 + 8 + len(content)
+	if len(bin) > 0 {
+		total += 8 + len(bin)
+	}
+	buf := &bytes.Buffer{}
+	writeUint32s(buf, 0x46546C67, 2, uint32(total), uint32(len(content)), 0x4E4F534A)
+	buf.Write(content)
+	if len(bin) > 0 {
+		writeUint32s(buf, uint32(len(bin)), 0x004E4942)
+		buf.Write(bin)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeUint32s(buf *bytes.Buffer, values ...uint32) {
+	var b [4]byte
+	for _, v := range values {
+		binary.LittleEndian.PutUint32(b[:], v)
+		buf.Write(b[:])
+	}
+}
+
+func (ex *GLTF2Exporter) exportNode(nb *vmodel.NodeBuilder, lights *[]*Light) (int, error) {
+	n := &Node{GLTFBase: GLTFBase{Name: nb.Name}}
+	nIdx := len(ex.Model.Nodes)
+	ex.Model.Nodes = append(ex.Model.Nodes, n)
+	if nb.Animation != nil {
+		na := nb.Animation
+		n.Translation, n.Rotation, n.Scale = na.Translate[:], []float32{na.Rotate.V[0], na.Rotate.V[1], na.Rotate.V[2], na.Rotate.W}, na.Scale[:]
+		for tIdx, track := range na.Tracks {
+			for _, ch := range track.Channels {
+				if ch.Target == vmodel.TWeights || len(ch.Input) == 0 {
+					continue
+				}
+				an := ex.getAnimation(track.Name, tIdx)
+				ex.addChannel(an, ex.addSampler(an, ch), nIdx, ch.Target)
+			}
+		}
+	} else if nb.Location != mgl32.Ident4() {
+		loc := nb.Location
+		n.Matrix = loc[:]
+	}
+	if nb.Mesh >= 0 {
+		mIdx, err := ex.exportMesh(meshMaterial{mesh: nb.Mesh, mat: nb.Material})
+		if err != nil {
+			return 0, err
+		}
+		n.Mesh = &mIdx
+		if nb.Skin > 0 {
+			es, err := ex.exportSkin(nb.Skin, n)
+			if err != nil {
+				return 0, err
+			}
+			n.Skin = &es.index
+			if len(ex.Builder.Meshes[nb.Mesh].Targets) > 0 {
+				es.meshNodes = append(es.meshNodes, nIdx)
+			}
+		}
+	}
+	if nb.Camera != nil {
+		c := nb.Camera
+		n.Camera = new(int)
+		*n.Camera = len(ex.Model.Cameras)
+		ex.Model.Cameras = append(ex.Model.Cameras, &Camera{Type: "perspective", Perspective: &CameraPerspective{
+			AspectRatio: c.AspectRatio, YFov: c.YFov, ZFar: c.ZFar, ZNear: c.ZNear}})
+	}
+	if nb.Light != nil {
+		lIdx, ok := ex.lights[nb.Light]
+		if !ok {
+			l, err := ex.exportLight(nb.Light)
+			if err != nil {
+				return 0, err
+			}
+			lIdx = len(*lights)
+			*lights = append(*lights, l)
+			ex.lights[nb.Light] = lIdx
+		}
+		ext, err := json.Marshal(NodeExtensions{LightsPunctual: &NodeLight{Light: lIdx}})
+		if err != nil {
+			return 0, err
+		}
+		n.Extensions = ext
+	}
+	for _, ch := range nb.Children {
+		chIdx, err := ex.exportNode(ch, lights)
+		if err != nil {
+			return 0, err
+		}
+		n.Children = append(n.Children, chIdx)
+	}
+	return nIdx, nil
+}
+
+func (ex *GLTF2Exporter) exportLight(vl *vmodel.Light) (*Light, error) {
+	intensity := vl.Intensity
+	if ex.LightScale != 0 {
+		intensity /= ex.LightScale
+	}
+	l := &Light{Color: vl.Color[:], Intensity: &intensity, Range: vl.Range}
+	switch vl.Kind {
+	case vmodel.LightDirectional:
+		l.Type, l.Range = "directional", 0
+	case vmodel.LightPoint:
+		l.Type = "point"
+	case vmodel.LightSpot:
+		outer := vl.OuterCone
+		l.Type, l.Spot = "spot", &LightSpot{InnerConeAngle: vl.InnerCone, OuterConeAngle: &outer}
+	default:
+		return nil, fmt.Errorf("Unknown light kind %d", vl.Kind)
+	}
+	return l, nil
+}
+
+// completeCopy completes copy of mesh so that export will not modify vertices of model builder
+func completeCopy(mb *vmodel.MeshBuilder) (*vmodel.MeshBuilder, bool) {
+	cp := *mb
+	cp.Vextexies = make([]*vmodel.VertexBuilder, len(mb.Vextexies))
+	for idx, vb := range mb.Vextexies {
+		v := *vb
+		cp.Vextexies[idx] = &v
+	}
+	return &cp, cp.Complete()
+}
+
+// tangentSigns computes handedness (w of glTF tangent) of each vertex from UV mapping of triangles.
+// GlTF bitangent, cross(normal, tangent) * w, points towards decreasing v so w is -1 where UVs are mirrored
+func tangentSigns(mb *vmodel.MeshBuilder) []float32 {
+	vertex := func(idx int) *vmodel.VertexBuilder {
+		if len(mb.Incides) > 0 {
+			return mb.Vextexies[mb.Incides[idx]]
+		}
+		return mb.Vextexies[idx]
+	}
+	count := len(mb.Incides)
+	if count == 0 {
+		count = len(mb.Vextexies)
+	}
+	bitangents := make(map[*vmodel.VertexBuilder]mgl32.Vec3)
+	for idx := 0; idx+2 < count; idx += 3 {
+		vb1, vb2, vb3 := vertex(idx), vertex(idx+1), vertex(idx+2)
+		duv1, duv2 := vb2.Uv.Sub(vb1.Uv), vb3.Uv.Sub(vb1.Uv)
+		d := duv1.X()*duv2.Y() - duv1.Y()*duv2.X()
+		if d == 0 {
+			continue
+		}
+		dp1, dp2 := vb2.Position.Sub(vb1.Position), vb3.Position.Sub(vb1.Position)
+		// Direction of increasing v
+		bt := dp2.Mul(duv1.X()).Sub(dp1.Mul(duv2.X())).Mul(1 / d)
+		for _, vb := range []*vmodel.VertexBuilder{vb1, vb2, vb3} {
+			bitangents[vb] = bitangents[vb].Add(bt)
+		}
+	}
+	signs := make([]float32, len(mb.Vextexies))
+	for idx, vb := range mb.Vextexies {
+		signs[idx] = 1
+		if vb.Normal.Cross(vb.Tangent).Dot(bitangents[vb]) > 0 {
+			signs[idx] = -1
+		}
+	}
+	return signs
+}
+
+// exportMesh converts mesh with material to glTF mesh with one primitive. Same mesh will be exported multiple times
+// if it is used with different materials
+func (ex *GLTF2Exporter) exportMesh(mm meshMaterial) (int, error) {
+	mIdx, ok := ex.meshes[mm]
+	if ok {
+		return mIdx, nil
+	}
+	if int(mm.mesh) >= len(ex.Builder.Meshes) {
+		return 0, fmt.Errorf("Invalid mesh index %d", mm.mesh)
+	}
+	if int(mm.mat) >= len(ex.Builder.Materials) {
+		return 0, fmt.Errorf("Invalid material index %d", mm.mat)
+	}
+	mb, skinned := completeCopy(ex.Builder.Meshes[mm.mesh])
+	count := len(mb.Vextexies)
+	positions, normals, uvs := make([]float32, 0, count*3), make([]float32, 0, count*3), make([]float32, 0, count*2)
+	tangents, colors := make([]float32, 0, count*4), make([]float32, 0, count*4)
+	var weights []float32
+	var joints []uint16
+	hasColors := false
+	signs := tangentSigns(mb)
+	for idx, vb := range mb.Vextexies {
+		positions = append(positions, vb.Position[:]...)
+		normals = append(normals, vb.Normal[:]...)
+		uvs = append(uvs, vb.Uv[:]...)
+		tangents = append(tangents, vb.Tangent[0], vb.Tangent[1], vb.Tangent[2], signs[idx])
+		colors = append(colors, vb.Color[:]...)
+		hasColors = hasColors || vb.Color != mgl32.Vec4{}
+		if skinned {
+			weights = append(weights, vb.Weights[:]...)
+			joints = append(joints, vb.Joints[:]...)
+		}
+	}
+	p := Primitive{Attributes: make(map[string]int)}
+	p.Attributes[APosition] = ex.addFloats(positions, "VEC3", ARRAY_BUFFER, true)
+	p.Attributes[ANormal] = ex.addFloats(normals, "VEC3", ARRAY_BUFFER, false)
+	p.Attributes[ATangent] = ex.addFloats(tangents, "VEC4", ARRAY_BUFFER, false)
+	p.Attributes[AUV0] = ex.addFloats(uvs, "VEC2", ARRAY_BUFFER, false)
+	if hasColors {
+		p.Attributes["COLOR_0"] = ex.addFloats(colors, "VEC4", ARRAY_BUFFER, false)
+	}
+	if skinned {
+		p.Attributes[AJoints0] = ex.addJoints(joints)
+		p.Attributes[AWeights0] = ex.addFloats(weights, "VEC4", ARRAY_BUFFER, false)
+	}
+	if len(mb.Incides) > 0 {
+		p.Indices = new(int)
+		*p.Indices = ex.addIndices(mb.Incides)
+	}
+	if mm.mat >= 0 {
+		p.Material = new(int)
+		*p.Material = int(mm.mat)
+	}
+	for _, t := range mb.Targets {
+		target := map[string]int{APosition: ex.addFloats(flattenDeltas(t.Positions, count), "VEC3", ARRAY_BUFFER, true)}
+		if len(t.Normals) > 0 {
+			target[ANormal] = ex.addFloats(flattenDeltas(t.Normals, count), "VEC3", ARRAY_BUFFER, false)
+		}
+		if len(t.Tangents) > 0 {
+			target[ATangent] = ex.addFloats(flattenDeltas(t.Tangents, count), "VEC3", ARRAY_BUFFER, false)
+		}
+		p.Targets = append(p.Targets, target)
+	}
+	m := &Mesh{Primitives: []Primitive{p}}
+	if len(mb.Targets) > 0 {
+		m.Weights = mb.Weights
+	}
+	mIdx = len(ex.Model.Meshes)
+	ex.Model.Meshes = append(ex.Model.Meshes, m)
+	ex.meshes[mm] = mIdx
+	return mIdx, nil
+}
+
+func flattenDeltas(deltas []mgl32.Vec3, count int) []float32 {
+	result := make([]float32, 3*count)
+	for idx := 0; idx < count && idx < len(deltas); idx++ {
+		copy(result[3*idx:], deltas[idx][:])
+	}
+	return result
+}
+
+// exportSkin adds skin and joint nodes of skin when skin is first used. Root joints are added as children of node
+func (ex *GLTF2Exporter) exportSkin(si vmodel.SkinIndex, n *Node) (*exportSkin, error) {
+	es, ok := ex.skins[si]
+	if ok {
+		return es, nil
+	}
+	if int(si) > len(ex.Builder.Skins) {
+		return nil, fmt.Errorf("Invalid skin index %d", si)
+	}
+	sk := &ex.Builder.Skins[si-1]
+	es = &exportSkin{skin: sk, index: len(ex.Model.Skins), firstNode: len(ex.Model.Nodes)}
+	isChild := make([]bool, len(sk.Joints))
+	skin := &Skin{}
+	ibm := make([]float32, 0, 16*len(sk.Joints))
+	for idx := range sk.Joints {
+		j := &sk.Joints[idx]
+		jn := &Node{GLTFBase: GLTFBase{Name: j.Name}}
+		jn.Translation, jn.Rotation, jn.Scale = j.Translate[:], []float32{j.Rotate.V[0], j.Rotate.V[1], j.Rotate.V[2], j.Rotate.W}, j.Scale[:]
+		for _, ch := range j.Children {
+			if ch < 0 || ch >= len(sk.Joints) {
+				return nil, fmt.Errorf("Joint %s has invalid child %d", j.Name, ch)
+			}
+			jn.Children = append(jn.Children, es.firstNode+ch)
+			isChild[ch] = true
+		}
+		ex.Model.Nodes = append(ex.Model.Nodes, jn)
+		skin.Joints = append(skin.Joints, es.firstNode+idx)
+		ibm = append(ibm, j.InverseMatrix[:]...)
+	}
+	for idx, c := range isChild {
+		if !c {
+			n.Children = append(n.Children, es.firstNode+idx)
+		}
+	}
+	skin.InverseBindMatrices = ex.addFloats(ibm, "MAT4", 0, false)
+	ex.Model.Skins = append(ex.Model.Skins, skin)
+	ex.skins[si] = es
+	ex.skinOrder = append(ex.skinOrder, es)
+	return es, nil
+}
+
+// exportSkinAnimations adds skin animations. Morph weight channels will target all morphed mesh nodes that use skin
+func (ex *GLTF2Exporter) exportSkinAnimations(es *exportSkin) {
+	for aIdx, a := range es.skin.Animations {
+		for _, ch := range a.Channels {
+			if len(ch.Input) == 0 {
+				continue
+			}
+			if ch.Target == vmodel.TWeights {
+				if len(es.meshNodes) == 0 {
+					continue
+				}
+				an := ex.getAnimation(a.Name, aIdx)
+				sIdx := ex.addSampler(an, ch)
+				for _, nIdx := range es.meshNodes {
+					ex.addChannel(an, sIdx, nIdx, ch.Target)
+				}
+				continue
+			}
+			if ch.Joint < 0 || ch.Joint >= len(es.skin.Joints) {
+				continue
+			}
+			an := ex.getAnimation(a.Name, aIdx)
+			ex.addChannel(an, ex.addSampler(an, ch), es.firstNode+ch.Joint, ch.Target)
+		}
+	}
+}
+
+func (ex *GLTF2Exporter) getAnimation(name string, index int) *Animation {
+	if len(name) == 0 {
+		name = fmt.Sprintf("Animation%d", index)
+	}
+	aIdx, ok := ex.animations[name]
+	if ok {
+		return ex.Model.Animations[aIdx]
+	}
+	an := &Animation{Name: name}
+	ex.animations[name] = len(ex.Model.Animations)
+	ex.Model.Animations = append(ex.Model.Animations, an)
+	return an
+}
+
+func (ex *GLTF2Exporter) addSampler(an *Animation, ch vmodel.Channel) int {
+	tp := "VEC3"
+	switch ch.Target {
+	case vmodel.TRotation:
+		tp = "VEC4"
+	case vmodel.TWeights:
+		tp = "SCALAR"
+	}
+	s := &Sampler{Input: ex.addFloats(ch.Input, "SCALAR", 0, true), Output: ex.addFloats(ch.Output, tp, 0, false)}
+	switch ch.Interpolation {
+	case vmodel.IStep:
+		s.Interpolation = "STEP"
+	case vmodel.ICubicSpline:
+		s.Interpolation = "CUBICSPLINE"
+	default:
+		s.Interpolation = "LINEAR"
+	}
+	an.Samplers = append(an.Samplers, s)
+	return len(an.Samplers) - 1
+}
+
+func (ex *GLTF2Exporter) addChannel(an *Animation, sampler int, node int, target vmodel.ChannelTarget) {
+	ch := &Channels{Sampler: sampler}
+	ch.Target.Node = node
+	switch target {
+	case vmodel.TTranslation:
+		ch.Target.Path = "translation"
+	case vmodel.TRotation:
+		ch.Target.Path = "rotation"
+	case vmodel.TScale:
+		ch.Target.Path = "scale"
+	case vmodel.TWeights:
+		ch.Target.Path = "weights"
+	}
+	an.Channels = append(an.Channels, ch)
+}
+
+func (ex *GLTF2Exporter) exportMaterial(mi vmodel.MaterialInfo) (*Material, error) {
+	props := mi.Props
+	m := &Material{GLTFBase: GLTFBase{Name: mi.Name}}
+	pbr := &PbrMetallicRoughness{}
+	col := props.GetColor(vmodel.CAlbedo, mgl32.Vec4{1, 1, 1, 1})
+	if col != (mgl32.Vec4{1, 1, 1, 1}) {
+		pbr.BaseColorFactor = col[:]
+	}
+	pbr.MetallicFactor = getFactor(props, vmodel.FMetalness)
+	pbr.RoughnessFactor = getFactor(props, vmodel.FRoughness)
+	var err error
+	pbr.BaseColorTexture, err = ex.textureInfo(props, vmodel.TxAlbedo)
+	if err != nil {
+		return nil, err
+	}
+	pbr.MetallicRoughnessTexture, err = ex.textureInfo(props, vmodel.TxMetallicRoughness)
+	if err != nil {
+		return nil, err
+	}
+	m.PbrMetallicRoughness = pbr
+	m.NormalTexture, err = ex.textureInfo(props, vmodel.TxBump)
+	if err != nil {
+		return nil, err
+	}
+	m.EmissiveTexture, err = ex.textureInfo(props, vmodel.TxEmissive)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := props[vmodel.CEmissive]; ok {
+		em := props.GetColor(vmodel.CEmissive, mgl32.Vec4{})
+		m.EmissiveFactor = em[:3]
+	}
+	m.OcclusionTexture, err = ex.textureInfo(props, vmodel.TxOcclusion)
+	if err != nil {
+		return nil, err
+	}
+	if m.OcclusionTexture != nil {
+		m.OcclusionTexture.Strength = getFactor(props, vmodel.FOcclusionStrength)
+	}
+	if cutoff := getFactor(props, vmodel.FAlphaCutoff); cutoff != nil {
+		m.AlphaMode, m.AlphaCutoff = "MASK", *cutoff
+	} else if props.GetFactor(vmodel.FAlphaBlend, 0) > 0 {
+		m.AlphaMode = "BLEND"
+	}
+	ext := MaterialExtensions{}
+	hasExt := false
+	if f := getFactor(props, vmodel.FEmissiveStrength); f != nil {
+		ext.EmissiveStrength, hasExt = &EmissiveStrength{EmissiveStrength: *f}, true
+		ex.used["KHR_materials_emissive_strength"] = true
+	}
+	if props.GetFactor(vmodel.FUnlit, 0) > 0 {
+		ext.Unlit, hasExt = &struct{}{}, true
+		ex.used["KHR_materials_unlit"] = true
+	}
+	if props.GetFactor(vmodel.FClearcoat, 0) > 0 {
+		ext.Clearcoat = &Clearcoat{ClearcoatFactor: props.GetFactor(vmodel.FClearcoat, 0),
+			ClearcoatRoughnessFactor: props.GetFactor(vmodel.FClearcoatRoughness, 0)}
+		hasExt = true
+		ex.used["KHR_materials_clearcoat"] = true
+	}
+	if hasExt {
+		m.Extensions, err = json.Marshal(ext)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func getFactor(props vmodel.MaterialProperties, prop vmodel.Property) *float32 {
+	f, ok := props[prop].(float32)
+	if !ok {
+		return nil
+	}
+	return &f
+}
+
+// textureInfo exports image of texture property. Material uv transform is added to each texture with
+// KHR_texture_transform extension
+func (ex *GLTF2Exporter) textureInfo(props vmodel.MaterialProperties, prop vmodel.Property) (*Texture, error) {
+	imIndex := props.GetImage(prop)
+	if imIndex == 0 {
+		return nil, nil
+	}
+	txIndex, err := ex.exportTexture(imIndex)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Texture{Index: txIndex}
+	if _, ok := props[vmodel.XUVTransform]; ok {
+		tx.Extensions, err = json.Marshal(TextureExtensions{TextureTransform: toTextureTransform(props.GetTransform(vmodel.XUVTransform))})
+		if err != nil {
+			return nil, err
+		}
+		ex.used["KHR_texture_transform"] = true
+	}
+	return tx, nil
+}
+
+// toTextureTransform decomposes transform calculated by getTextureTransform back to offset, rotation and scale
+func toTextureTransform(tr mgl32.Mat3) *TextureTransform {
+	sx := mgl32.Vec2{tr.At(0, 0), tr.At(1, 0)}.Len()
+	sy := mgl32.Vec2{tr.At(0, 1), tr.At(1, 1)}.Len()
+	return &TextureTransform{Offset: []float32{tr.At(0, 2), tr.At(1, 2)}, Rotation: atan2(-tr.At(1, 0), tr.At(0, 0)),
+		Scale: []float32{sx, sy}}
+}
+
+func (ex *GLTF2Exporter) exportTexture(imIndex vmodel.ImageIndex) (int, error) {
+	txIndex, ok := ex.textures[imIndex]
+	if ok {
+		return txIndex, nil
+	}
+	if int(imIndex) >= len(ex.Builder.Images) {
+		return 0, fmt.Errorf("Invalid image index %d", imIndex)
+	}
+	ib := ex.Builder.Images[imIndex]
+	content, mimeType := ib.Content, ""
+	switch ib.Kind {
+	case "png":
+		mimeType = "image/png"
+	case "jpg", "jpeg":
+		mimeType = "image/jpeg"
+	case "dds":
+		mimeType = "image/vnd-ms.dds"
+	case "raw":
+		var err error
+		content, err = encodeRaw(ib)
+		if err != nil {
+			return 0, err
+		}
+		mimeType = "image/png"
+	default:
+		return 0, fmt.Errorf("Image kind %s can't be exported", ib.Kind)
+	}
+	bv := ex.addView(content, 0)
+	imgIndex := len(ex.Model.Images)
+	ex.Model.Images = append(ex.Model.Images, &Image{MimeType: mimeType, BufferView: &bv})
+	ts := &TextureSource{}
+	if ib.Kind == "dds" {
+		ext := TextureSourceExtensions{}
+		ext.DDS = &struct {
+			Source int `json:"source"`
+		}{Source: imgIndex}
+		var err error
+		ts.Extensions, err = json.Marshal(ext)
+		if err != nil {
+			return 0, err
+		}
+		ex.used["MSFT_texture_dds"], ex.required["MSFT_texture_dds"] = true, true
+	} else {
+		ts.Source = &imgIndex
+	}
+	txIndex = len(ex.Model.Textures)
+	ex.Model.Textures = append(ex.Model.Textures, ts)
+	ex.textures[imIndex] = txIndex
+	return txIndex, nil
+}
+
+// encodeRaw encodes first layer and mip level of raw image to png
+func encodeRaw(ib *vmodel.ImageBuilder) ([]byte, error) {
+	w, h := int(ib.Desc.Width), int(ib.Desc.Height)
+	var img image.Image
+	switch ib.Desc.Format {
+	case vk.FORMATR8Unorm:
+		if len(ib.Content) < w*h {
+			return nil, errors.New("Raw image content too short")
+		}
+		gray := image.NewGray(image.Rect(0, 0, w, h))
+		copy(gray.Pix, ib.Content)
+		img = gray
+	case vk.FORMATR8g8b8a8Unorm, vk.FORMATR8g8b8a8Srgb:
+		if len(ib.Content) < 4*w*h {
+			return nil, errors.New("Raw image content too short")
+		}
+		rgba := image.NewNRGBA(image.Rect(0, 0, w, h))
+		copy(rgba.Pix, ib.Content)
+		img = rgba
+	default:
+		return nil, fmt.Errorf("Raw image format %d can't be exported", ib.Desc.Format)
+	}
+	buf := &bytes.Buffer{}
+	err := png.Encode(buf, img)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// addView appends content to buffer. Buffer views are aligned to 4 bytes
+func (ex *GLTF2Exporter) addView(content []byte, target Target) int {
+	for len(ex.data)%4 != 0 {
+		ex.data = append(ex.data, 0)
+	}
+	bv := &BufferView{Buffer: 0, ByteOffset: len(ex.data), ByteLength: len(content), Target: target}
+	ex.data = append(ex.data, content...)
+	ex.Model.BufferViews = append(ex.Model.BufferViews, bv)
+	return len(ex.Model.BufferViews) - 1
+}
+
+func (ex *GLTF2Exporter) addAccessor(ac *Accessor) int {
+	ex.Model.Accessors = append(ex.Model.Accessors, ac)
+	return len(ex.Model.Accessors) - 1
+}
+
+func (ex *GLTF2Exporter) addFloats(values []float32, accType string, target Target, minMax bool) int {
+	elements := 1
+	switch accType {
+	case "VEC2":
+		elements = 2
+	case "VEC3":
+		elements = 3
+	case "VEC4":
+		elements = 4
+	case "MAT4":
+		elements = 16
+	}
+	content := make([]byte, 4*len(values))
+	for idx, v := range values {
+		binary.LittleEndian.PutUint32(content[4*idx:], math.Float32bits(v))
+	}
+	ac := &Accessor{BufferView: ex.addView(content, target), ComponentType: FLOAT, Count: len(values) / elements, Type: accType}
+	if minMax && len(values) >= elements {
+		ac.Min, ac.Max = make([]float32, elements), make([]float32, elements)
+		copy(ac.Min, values)
+		copy(ac.Max, values)
+		for idx, v := range values {
+			el := idx % elements
+			if v < ac.Min[el] {
+				ac.Min[el] = v
+			}
+			if v > ac.Max[el] {
+				ac.Max[el] = v
+			}
+		}
+	}
+	return ex.addAccessor(ac)
+}
+
+func (ex *GLTF2Exporter) addIndices(incides []uint32) int {
+	content := make([]byte, 4*len(incides))
+	for idx, v := range incides {
+		binary.LittleEndian.PutUint32(content[4*idx:], v)
+	}
+	return ex.addAccessor(&Accessor{BufferView: ex.addView(content, ELEMENT_ARRAY_BUFFER), ComponentType: UNSIGNED_INT,
+		Count: len(incides), Type: "SCALAR"})
+}
+
+func (ex *GLTF2Exporter) addJoints(joints []uint16) int {
+	content := make([]byte, 2*len(joints))
+	for idx, v := range joints {
+		binary.LittleEndian.PutUint16(content[2*idx:], v)
+	}
+	return ex.addAccessor(&Accessor{BufferView: ex.addView(content, ARRAY_BUFFER), ComponentType: UNSIGNED_SHORT,
+		Count: len(joints) / 4, Type: "VEC4"})
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,136 @@
+package gltf2loader
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+type memLoader map[string][]byte
+
+func (m memLoader) Open(filename string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(m[filename])), nil
+}
+
+func TestExportGLB(t *testing.T) {
+	mb := &vmodel.ModelBuilder{}
+	im := mb.AddImage("raw", []byte{0, 64, 128, 255}, vk.IMAGEUsageSampledBit)
+	mb.Images[im].Desc = vk.ImageDescription{Width: 2, Height: 2, Depth: 1, Format: vk.FORMATR8Unorm, Layers: 1, MipLevels: 1}
+	props := vmodel.NewMaterialProperties().SetColor(vmodel.CAlbedo, mgl32.Vec4{1, 0, 0, 1}).
+		SetFactor(vmodel.FRoughness, 0.4).SetFactor(vmodel.FAlphaCutoff, 0.3).SetImage(vmodel.TxAlbedo, im).
+		SetTransform(vmodel.XUVTransform, mgl32.Mat3FromRows(mgl32.Vec3{0, 2, 0.5}, mgl32.Vec3{-2, 0, 0}, mgl32.Vec3{0, 0, 1}))
+	mat := mb.AddMaterial("red", props)
+	cube := &vmodel.MeshBuilder{}
+	cube.AddCube(mgl32.Ident4())
+	mCube := mb.AddMesh(cube)
+	na := &vmodel.NodeAnimation{Translate: mgl32.Vec3{1, 0, 0}, Rotate: mgl32.QuatIdent(), Scale: mgl32.Vec3{1, 1, 1},
+		Tracks: []vmodel.Animation{{Name: "move", Channels: []vmodel.Channel{
+			{Input: []float32{0, 1}, Output: []float32{1, 0, 0, 2, 0, 0}, Target: vmodel.TTranslation}}}}}
+	mb.AddNode("cube", nil, mgl32.Translate3D(1, 0, 0)).SetMesh(mCube, mat).SetAnimation(na)
+
+	skinned := &vmodel.MeshBuilder{}
+	skinned.AddCube(mgl32.Ident4())
+	for _, vb := range skinned.Vextexies {
+		vb.AddWeights(mgl32.Vec4{0.5, 0.5, 0, 0}, 0, 1, 0, 0)
+	}
+	mSkinned := mb.AddMesh(skinned)
+	sk := mb.AddSkin(vmodel.Skin{Joints: []vmodel.Joint{
+		{Name: "hip", Rotate: mgl32.QuatIdent(), Scale: mgl32.Vec3{1, 1, 1}, InverseMatrix: mgl32.Ident4(), Root: true, Children: []int{1}},
+		{Name: "knee", Translate: mgl32.Vec3{0, -1, 0}, Rotate: mgl32.QuatIdent(), Scale: mgl32.Vec3{1, 1, 1}, InverseMatrix: mgl32.Translate3D(0, 1, 0)},
+	}, Animations: []vmodel.Animation{{Name: "move", Channels: []vmodel.Channel{
+		{Joint: 1, Input: []float32{0, 1}, Output: []float32{0, 0, 0, 1, 0, 0, 0.7071, 0.7071}, Target: vmodel.TRotation}}}}})
+	mb.AddNode("legs", nil, mgl32.Ident4()).SetSkinnedMesh(mSkinned, mat, sk)
+	light := mb.AddNode("light", nil, mgl32.Translate3D(0, 3, 0))
+	light.SetLight(&vmodel.Light{Kind: vmodel.LightPoint, Color: mgl32.Vec3{1, 1, 1}, Intensity: 5})
+
+	ex := &GLTF2Exporter{Builder: mb}
+	buf := &bytes.Buffer{}
+	err := ex.WriteGLB(buf)
+	if err != nil {
+		t.Fatal("Export ", err)
+	}
+	if cube.Vextexies[0].Tangent != (mgl32.Vec3{}) {
+		t.Error("Export should not modify model builder")
+	}
+
+	mb2 := &vmodel.ModelBuilder{}
+	ld := &GLTF2Loader{Loader: memLoader{"test.glb": buf.Bytes()}, Builder: mb2}
+	err = ld.LoadGLB("test.glb")
+	if err != nil {
+		t.Fatal("Load ", err)
+	}
+	err = ld.Convert(0)
+	if err != nil {
+		t.Fatal("Convert ", err)
+	}
+	mi := mb2.Materials[mb2.FindMaterial("red")]
+	if mi.Props.GetFactor(vmodel.FRoughness, 0) != 0.4 || mi.Props.GetFactor(vmodel.FAlphaCutoff, 0) != 0.3 {
+		t.Error("Invalid material factors ", mi.Props)
+	}
+	tr, orig := mi.Props.GetTransform(vmodel.XUVTransform), props.GetTransform(vmodel.XUVTransform)
+	for idx := range tr {
+		if math.Abs(float64(tr[idx]-orig[idx])) > 0.0001 {
+			t.Error("Invalid uv transform ", tr)
+			break
+		}
+	}
+	txIndex := mi.Props.GetImage(vmodel.TxAlbedo)
+	if txIndex == 0 || mb2.Images[txIndex].Kind != "png" {
+		t.Error("Albedo texture not exported")
+	}
+	if len(mb2.Meshes) != 2 || len(mb2.Meshes[0].Vextexies) != len(cube.Vextexies) {
+		t.Error("Invalid meshes ", len(mb2.Meshes))
+	}
+	if len(mb2.Skins) != 1 || len(mb2.Skins[0].Joints) != 2 || len(mb2.Skins[0].Animations) != 1 {
+		t.Fatal("Invalid skins ", mb2.Skins)
+	}
+	knee := mb2.Skins[0].Joints[1]
+	if knee.Name != "knee" || knee.Translate != (mgl32.Vec3{0, -1, 0}) || knee.InverseMatrix != mgl32.Translate3D(0, 1, 0) {
+		t.Error("Invalid joint ", knee)
+	}
+	var animated, lights int
+	mb2.Root.ForNodes(func(n *vmodel.NodeBuilder, parent *vmodel.NodeBuilder, index int) {
+		if n.Animation != nil {
+			animated++
+			if len(n.Animation.Tracks) != 1 || n.Animation.Tracks[0].Name != "move" {
+				t.Error("Invalid node animation ", n.Animation.Tracks)
+			}
+		}
+		if n.Light != nil {
+			lights++
+			if math.Abs(float64(n.Light.Intensity-5)) > 0.0001 {
+				t.Error("Invalid light intensity ", n.Light.Intensity)
+			}
+		}
+	})
+	if animated != 1 || lights != 1 {
+		t.Errorf("Animated nodes %d, lights %d", animated, lights)
+	}
+}
+
+func TestTangentSigns(t *testing.T) {
+	mb := &vmodel.MeshBuilder{}
+	// Two triangles facing +z, v grows down. Second triangle has mirrored u and tangent
+	positions := []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	for idx, uv := range []mgl32.Vec2{{0, 1}, {1, 1}, {0, 0}, {1, 1}, {0, 1}, {1, 0}} {
+		tangent := mgl32.Vec3{1, 0, 0}
+		if idx >= 3 {
+			tangent = mgl32.Vec3{-1, 0, 0}
+		}
+		mb.AddVertex(positions[idx%3]).AddUV(uv).AddNormal(mgl32.Vec3{0, 0, 1}).AddTangent(tangent)
+	}
+	mb.AddIndex(0, 1, 2, 3, 4, 5)
+	signs := tangentSigns(mb)
+	for idx, expected := range []float32{1, 1, 1, -1, -1, -1} {
+		if signs[idx] != expected {
+			t.Error("Invalid tangent signs ", signs)
+			break
+		}
+	}
+}
@@ -5,9 +5,10 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"path/filepath"
 	"strings"
+
+	"github.com/lakal3/vge/vge/vasset"
 )
 
 func (l *GLTF2Loader) LoadGLB(name string) (err error) {
@@ -69,12 +70,7 @@ func (l *GLTF2Loader) LoadGltf(mainUri string) (err error) {
 	for _, img := range l.Model.Images {
 		if len(img.URI) > 0 {
 
-			rd, err := l.Loader.Open(img.URI)
-			if err != nil {
-				return err
-			}
-			defer rd.Close()
-			content, err := ioutil.ReadAll(rd)
+			content, err := l.loadContent(img.URI)
 			if err != nil {
 				return err
 			}
@@ -97,27 +93,16 @@ func (l *GLTF2Loader) loadContent(uri string) ([]byte, error) {
 	if l.Loader == nil {
 		return nil, errors.New("Set loader")
 	}
-	r, err := l.Loader.Open(uri)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Close()
-	glb, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-	return glb, nil
+	return vasset.Load(uri, l.Loader)
 }
 
+// DefaultLoader returns URI resolver that loads data URIs and files relative to dir
 func DefaultLoader(dir string) func(uri string) (content []byte, err error) {
-	return func(uri string) (content []byte, err error) {
-		if strings.HasPrefix(uri, "data:") {
-			idx := strings.Index(uri, "base64,")
-			if idx < 0 {
-				return nil, errors.New("Can't locate base64, from data uri")
-			}
-			return base64.StdEncoding.DecodeString(uri[idx+7:])
-		}
-		return ioutil.ReadFile(filepath.Join(dir, uri))
-	}
+	return URILoader(vasset.DirectoryLoader{Directory: dir})
+}
+
+// URILoader returns URI resolver that loads data URIs and other URIs using given loader
+func URILoader(ld vasset.Loader) func(uri string) (content []byte, err error) {
+	l := &GLTF2Loader{Loader: ld}
+	return l.loadContent
 }
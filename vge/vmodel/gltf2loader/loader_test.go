@@ -0,0 +1,48 @@
+package gltf2loader
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const textureModel = `{
+	"asset": {"version": "2.0"},
+	"buffers": [{"byteLength": 8}],
+	"bufferViews": [{"buffer": 0, "byteOffset": 4, "byteLength": 4}],
+	"images": [{"uri": "first.png"}, {"bufferView": 0, "mimeType": "image/png"}, {"uri": "third.dds"}],
+	"textures": [{"source": 1}, {"source": 0, "extensions": {"MSFT_texture_dds": {"source": 2}}}, {}]
+}`
+
+func TestGLTF2_GetTextureImage(t *testing.T) {
+	gltf := &GLTF2{}
+	err := json.Unmarshal([]byte(textureModel), gltf)
+	if err != nil {
+		t.Fatal("Unmarshal ", err)
+	}
+	gltf.Buffers[0].SetContent([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	gltf.Bind()
+	for tx, expected := range []int{1, 2} {
+		img, err := gltf.GetTextureImage(tx)
+		if err != nil || img != expected {
+			t.Error("Texture ", tx, " resolved to ", img, err)
+		}
+	}
+	if _, err = gltf.GetTextureImage(2); err == nil {
+		t.Error("Texture without source should fail")
+	}
+	if _, err = gltf.GetTextureImage(3); err == nil {
+		t.Error("Invalid texture index should fail")
+	}
+	img := gltf.Images[1]
+	if img.kind != "png" || string(img.content) != string([]byte{5, 6, 7, 8}) {
+		t.Error("Invalid buffer view image ", img.kind, img.content)
+	}
+}
+
+func TestGLTF2_GetTextureImageNoTextures(t *testing.T) {
+	gltf := &GLTF2{}
+	img, err := gltf.GetTextureImage(3)
+	if err != nil || img != 3 {
+		t.Error("Model without textures should use texture index as image index ", img, err)
+	}
+}
@@ -23,46 +23,99 @@ const (
 )
 
 type GLTF2 struct {
-	ExtensionsUsed     []string      `json:"extensionsUsed"`
-	ExtensionsRequired []string      `json:"extensionsRequired"`
-	Accessors          []*Accessor   `json:"accessors"`
-	Animations         []*Animation  `json:"animations"`
-	Buffers            []*Buffer     `json:"buffers"`
-	BufferViews        []*BufferView `json:"bufferViews"`
-	Meshes             []*Mesh       `json:"meshes"`
-	Materials          []*Material   `json:"materials"`
-	Nodes              []*Node       `json:"nodes"`
-	Images             []*Image      `json:"images"`
-	Skins              []*Skin       `json:"skins"`
-	Scene              int           `json:"scene"`
-	Scenes             []*Scene      `json:"scenes"`
+	Asset              *Asset           `json:"asset,omitempty"`
+	ExtensionsUsed     []string         `json:"extensionsUsed,omitempty"`
+	ExtensionsRequired []string         `json:"extensionsRequired,omitempty"`
+	Accessors          []*Accessor      `json:"accessors,omitempty"`
+	Animations         []*Animation     `json:"animations,omitempty"`
+	Buffers            []*Buffer        `json:"buffers,omitempty"`
+	BufferViews        []*BufferView    `json:"bufferViews,omitempty"`
+	Cameras            []*Camera        `json:"cameras,omitempty"`
+	Meshes             []*Mesh          `json:"meshes,omitempty"`
+	Materials          []*Material      `json:"materials,omitempty"`
+	Nodes              []*Node          `json:"nodes,omitempty"`
+	Images             []*Image         `json:"images,omitempty"`
+	Textures           []*TextureSource `json:"textures,omitempty"`
+	Skins              []*Skin          `json:"skins,omitempty"`
+	Scene              int              `json:"scene"`
+	Scenes             []*Scene         `json:"scenes,omitempty"`
+	Extensions         json.RawMessage  `json:"extensions,omitempty"`
+}
+
+type Asset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type Extensions struct {
+	LightsPunctual *LightsPunctual `json:"KHR_lights_punctual"`
+}
+
+// LightsPunctual is KHR_lights_punctual extension of glTF model
+type LightsPunctual struct {
+	Lights []*Light `json:"lights"`
+}
+
+type Light struct {
+	Type      string     `json:"type"`
+	Color     []float32  `json:"color,omitempty"`
+	Intensity *float32   `json:"intensity,omitempty"`
+	Range     float32    `json:"range,omitempty"`
+	Spot      *LightSpot `json:"spot,omitempty"`
+	Name      string     `json:"name,omitempty"`
+}
+
+type LightSpot struct {
+	InnerConeAngle float32  `json:"innerConeAngle"`
+	OuterConeAngle *float32 `json:"outerConeAngle,omitempty"`
+}
+
+type Camera struct {
+	Type        string             `json:"type"`
+	Perspective *CameraPerspective `json:"perspective,omitempty"`
+	GLTFBase
+}
+
+type CameraPerspective struct {
+	AspectRatio float32 `json:"aspectRatio,omitempty"`
+	YFov        float32 `json:"yfov"`
+	ZFar        float32 `json:"zfar,omitempty"`
+	ZNear       float32 `json:"znear"`
+}
+
+type NodeExtensions struct {
+	LightsPunctual *NodeLight `json:"KHR_lights_punctual,omitempty"`
+}
+
+type NodeLight struct {
+	Light int `json:"light"`
 }
 
 type ComponentType uint32
 type Target uint32
 
 type GLTFBase struct {
-	Name       string          `json:"name"`
-	Extensions json.RawMessage `json:"extensions"`
-	Extras     json.RawMessage `json:"extras"`
+	Name       string          `json:"name,omitempty"`
+	Extensions json.RawMessage `json:"extensions,omitempty"`
+	Extras     json.RawMessage `json:"extras,omitempty"`
 	gltf       *GLTF2
 }
 
 type Accessor struct {
 	BufferView    int           `json:"bufferView"`
 	ComponentType ComponentType `json:"componentType"`
-	Normalized    bool          `json:"normalized"`
-	ByteOffset    int           `json:"byteOffset"`
+	Normalized    bool          `json:"normalized,omitempty"`
+	ByteOffset    int           `json:"byteOffset,omitempty"`
 	Count         int           `json:"count"`
 	Type          string        `json:"type"`
-	Min           []float32     `json:"min"`
-	Max           []float32     `json:"max"`
+	Min           []float32     `json:"min,omitempty"`
+	Max           []float32     `json:"max,omitempty"`
 	GLTFBase
 }
 
 type Buffer struct {
 	ByteLength int    `json:"byteLength"`
-	URI        string `json:"uri"`
+	URI        string `json:"uri,omitempty"`
 	GLTFBase
 	data []byte
 }
@@ -70,15 +123,15 @@ type Buffer struct {
 type BufferView struct {
 	Buffer     int    `json:"buffer"`
 	ByteLength int    `json:"byteLength"`
-	ByteOffset int    `json:"byteOffset"`
-	ByteStride int    `json:"byteStride"`
-	Target     Target `json:"target"`
+	ByteOffset int    `json:"byteOffset,omitempty"`
+	ByteStride int    `json:"byteStride,omitempty"`
+	Target     Target `json:"target,omitempty"`
 	GLTFBase
 }
 
 type Image struct {
 	URI        string `json:"uri,omitempty"`
-	MimeType   string `json:"mimeType"`
+	MimeType   string `json:"mimeType,omitempty"`
 	BufferView *int   `json:"bufferView,omitempty"`
 	GLTFBase
 	index   vmodel.ImageIndex
@@ -93,10 +146,11 @@ type Mesh struct {
 }
 
 type Primitive struct {
-	Attributes map[string]int `json:"attributes"`
-	Indices    *int           `json:"indices,omitempty"`
-	Material   *int           `json:"material,omitempty"`
-	Mode       *int           `json:"mode,omitempty"`
+	Attributes map[string]int   `json:"attributes"`
+	Indices    *int             `json:"indices,omitempty"`
+	Material   *int             `json:"material,omitempty"`
+	Mode       *int             `json:"mode,omitempty"`
+	Targets    []map[string]int `json:"targets,omitempty"`
 	GLTFBase
 }
 
@@ -115,30 +169,76 @@ type Node struct {
 type Skin struct {
 	InverseBindMatrices int    `json:"inverseBindMatrices"`
 	Joints              []int  `json:"joints"`
-	Name                string `json:"name"`
+	Name                string `json:"name,omitempty"`
 	GLTFBase
 }
 
+// Texture is texture info of material. Index refers to textures of glTF model
 type Texture struct {
-	Index int `json:"index"`
+	Index    int      `json:"index"`
+	TexCoord int      `json:"texCoord,omitempty"`
+	Scale    *float32 `json:"scale,omitempty"`
+	Strength *float32 `json:"strength,omitempty"`
+	GLTFBase
+}
+
+// TextureSource is entry in textures of glTF model. Texture source refers to an image directly or with MSFT_texture_dds extension
+type TextureSource struct {
+	Source  *int `json:"source,omitempty"`
+	Sampler *int `json:"sampler,omitempty"`
+	GLTFBase
+}
+
+type TextureSourceExtensions struct {
+	DDS *struct {
+		Source int `json:"source"`
+	} `json:"MSFT_texture_dds,omitempty"`
 }
+
+type TextureExtensions struct {
+	TextureTransform *TextureTransform `json:"KHR_texture_transform,omitempty"`
+}
+
+// TextureTransform is KHR_texture_transform extension of texture info
+type TextureTransform struct {
+	Offset   []float32 `json:"offset,omitempty"`
+	Rotation float32   `json:"rotation,omitempty"`
+	Scale    []float32 `json:"scale,omitempty"`
+	TexCoord *int      `json:"texCoord,omitempty"`
+}
+
 type PbrMetallicRoughness struct {
-	BaseColorFactor          []float32 `json:"baseColorFactor"`
-	RoughnessFactor          *float32  `json:"roughnessFactor"`
-	MetallicFactor           *float32  `json:"metallicFactor"`
-	BaseColorTexture         *Texture  `json:"baseColorTexture"`
-	MetallicRoughnessTexture *Texture  `json:"metallicRoughnessTexture"`
+	BaseColorFactor          []float32 `json:"baseColorFactor,omitempty"`
+	RoughnessFactor          *float32  `json:"roughnessFactor,omitempty"`
+	MetallicFactor           *float32  `json:"metallicFactor,omitempty"`
+	BaseColorTexture         *Texture  `json:"baseColorTexture,omitempty"`
+	MetallicRoughnessTexture *Texture  `json:"metallicRoughnessTexture,omitempty"`
 }
 
 type Material struct {
-	PbrMetallicRoughness *PbrMetallicRoughness `json:"pbrMetallicRoughness"`
-	NormalTexture        *Texture              `json:"normalTexture"`
-	OcclusionTexture     *Texture              `json:"occlusionTexture"`
-	EmissiveFactor       []float32             `json:"emissiveFactor"`
-	EmissiveTexture      *Texture              `json:"emissiveTexture"`
+	PbrMetallicRoughness *PbrMetallicRoughness `json:"pbrMetallicRoughness,omitempty"`
+	NormalTexture        *Texture              `json:"normalTexture,omitempty"`
+	OcclusionTexture     *Texture              `json:"occlusionTexture,omitempty"`
+	EmissiveFactor       []float32             `json:"emissiveFactor,omitempty"`
+	EmissiveTexture      *Texture              `json:"emissiveTexture,omitempty"`
 	AlphaMode            string                `json:"alphaMode,omitempty"`
-	AlphaCutoff          float32               `json:"alphaCutoff"`
-	Name                 string                `json:"name"`
+	AlphaCutoff          float32               `json:"alphaCutoff,omitempty"`
+	GLTFBase
+}
+
+type MaterialExtensions struct {
+	EmissiveStrength *EmissiveStrength `json:"KHR_materials_emissive_strength,omitempty"`
+	Unlit            *struct{}         `json:"KHR_materials_unlit,omitempty"`
+	Clearcoat        *Clearcoat        `json:"KHR_materials_clearcoat,omitempty"`
+}
+
+type EmissiveStrength struct {
+	EmissiveStrength float32 `json:"emissiveStrength"`
+}
+
+type Clearcoat struct {
+	ClearcoatFactor          float32 `json:"clearcoatFactor"`
+	ClearcoatRoughnessFactor float32 `json:"clearcoatRoughnessFactor"`
 }
 
 type Channels struct {
@@ -151,13 +251,13 @@ type Channels struct {
 
 type Sampler struct {
 	Input         int    `json:"input"`
-	Interpolation string `json:"interpolation"`
+	Interpolation string `json:"interpolation,omitempty"`
 	Output        int    `json:"output"`
 }
 
 type Animation struct {
 	Channels []*Channels `json:"channels"`
-	Name     string      `json:"name"`
+	Name     string      `json:"name,omitempty"`
 	Samplers []*Sampler  `json:"samplers"`
 	GLTFBase
 }
@@ -189,6 +289,7 @@ func (gltf *GLTF2) Bind() {
 	}
 	for _, i := range gltf.Images {
 		i.gltf = gltf
+		i.bindContent()
 	}
 	for _, n := range gltf.Nodes {
 		n.gltf = gltf
@@ -198,6 +299,52 @@ func (gltf *GLTF2) Bind() {
 	}
 }
 
+// bindContent sets content of image that is stored in buffer view
+func (img *Image) bindContent() {
+	if img.BufferView == nil || img.content != nil || *img.BufferView >= len(img.gltf.BufferViews) {
+		return
+	}
+	bv := img.gltf.BufferViews[*img.BufferView]
+	if bv.Buffer >= len(img.gltf.Buffers) || len(img.gltf.Buffers[bv.Buffer].data) < bv.ByteOffset+bv.ByteLength {
+		return
+	}
+	img.content = img.gltf.GetContent(bv)
+	switch img.MimeType {
+	case "image/png":
+		img.kind = "png"
+	case "image/jpeg":
+		img.kind = "jpg"
+	case "image/vnd-ms.dds":
+		img.kind = "dds"
+	}
+}
+
+// GetTextureImage resolves image index of texture. Textures using MSFT_texture_dds extension are supported. If model has
+// no textures, texture index is used as image index
+func (gltf *GLTF2) GetTextureImage(texture int) (int, error) {
+	if len(gltf.Textures) == 0 {
+		return texture, nil
+	}
+	if texture < 0 || texture >= len(gltf.Textures) {
+		return 0, fmt.Errorf("Invalid texture index %d", texture)
+	}
+	tx := gltf.Textures[texture]
+	if len(tx.Extensions) > 0 {
+		var ext TextureSourceExtensions
+		err := json.Unmarshal(tx.Extensions, &ext)
+		if err != nil {
+			return 0, fmt.Errorf("Texture %d extensions: %v", texture, err)
+		}
+		if ext.DDS != nil {
+			return ext.DDS.Source, nil
+		}
+	}
+	if tx.Source == nil {
+		return 0, fmt.Errorf("Texture %d has no supported source", texture)
+	}
+	return *tx.Source, nil
+}
+
 func (gltf *GLTF2) GetIncides(accessor int) ([]uint32, error) {
 	ac := gltf.Accessors[accessor]
 	bv := gltf.BufferViews[ac.BufferView]
@@ -0,0 +1,49 @@
+package vmodel
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// LightKind is type of punctual light
+type LightKind uint32
+
+const (
+	LightDirectional = LightKind(1)
+	LightPoint       = LightKind(2)
+	LightSpot        = LightKind(3)
+)
+
+// Light is punctual light attached to model node. Directional and spot lights shine to node's local -Z direction.
+// Lights are converted to scene lights when scene nodes are created from model (see vscene.NodeFromModel)
+type Light struct {
+	Kind      LightKind
+	Color     mgl32.Vec3
+	Intensity float32
+	// Range of point or spot light. 0 if light has no range
+	Range float32
+	// InnerCone of spot light in radians
+	InnerCone float32
+	// OuterCone of spot light in radians
+	OuterCone float32
+}
+
+// Camera is perspective camera attached to model node. Camera looks to node's local -Z direction and Y axis is up.
+type Camera struct {
+	// Vertical field of view in radians
+	YFov float32
+	// AspectRatio of camera. 0 if aspect ratio should be calculated from size of view
+	AspectRatio float32
+	ZNear       float32
+	// Far clipping plane. 0 if camera has no far plane
+	ZFar float32
+}
+
+// SetLight attaches light to node
+func (nb *NodeBuilder) SetLight(l *Light) *NodeBuilder {
+	nb.Light = l
+	return nb
+}
+
+// SetCamera attaches camera to node
+func (nb *NodeBuilder) SetCamera(c *Camera) *NodeBuilder {
+	nb.Camera = c
+	return nb
+}
@@ -18,6 +18,7 @@ const (
 	TxSpecular          = Texture + 3
 	TxBump              = Texture + 4
 	TxMetallicRoughness = Texture + 5
+	TxOcclusion         = Texture + 6 // Ambient occlusion in red channel
 	Factor              = Property(0x03000000)
 	FSpeculaPower       = Factor + 1
 	FMetalness          = Factor + 2
@@ -27,6 +28,21 @@ const (
 	FNormalAttenuation = Factor + 4
 	// Max level of alpha to discard pixel.
 	FAlphaCutoff = Factor + 5
+	// Strength of occlusion texture. 0 - no occlusion, 1 - full occlusion
+	FOcclusionStrength = Factor + 6
+	// Multiplier for emissive color
+	FEmissiveStrength = Factor + 7
+	// Material without lighting. Factories that support unlit materials will use unlit shader if FUnlit > 0
+	FUnlit = Factor + 8
+	// Intensity of clearcoat layer. 0 - no clearcoat. Clearcoat is only supported by forward renderer
+	FClearcoat = Factor + 9
+	// Roughness of clearcoat layer
+	FClearcoatRoughness = Factor + 10
+	// Material is blended with colors behind it if FAlphaBlend > 0 (glTF alpha mode BLEND)
+	FAlphaBlend = Factor + 11
+	// Transform (mgl32.Mat3) applied to texture coordinates
+	Transform    = Property(0x04000000)
+	XUVTransform = Transform + 1
 	Special      = Property(0xFF000000)
 	SMaxIndex    = Special + 1
 	PropertyKind = Property(0xFF000000)
@@ -53,6 +69,11 @@ func (mp MaterialProperties) SetImage(prop Property, im ImageIndex) MaterialProp
 	return mp
 }
 
+func (mp MaterialProperties) SetTransform(prop Property, val mgl32.Mat3) MaterialProperties {
+	mp[prop] = val
+	return mp
+}
+
 func (mp MaterialProperties) GetColor(prop Property, defaultValue mgl32.Vec4) mgl32.Vec4 {
 	v, ok := mp[prop]
 	if ok {
@@ -69,6 +90,15 @@ func (mp MaterialProperties) GetFactor(prop Property, defaultValue float32) floa
 	return defaultValue
 }
 
+// GetTransform returns transform property or identity matrix if transform is not set
+func (mp MaterialProperties) GetTransform(prop Property) mgl32.Mat3 {
+	v, ok := mp[prop]
+	if ok {
+		return v.(mgl32.Mat3)
+	}
+	return mgl32.Ident3()
+}
+
 func (mp MaterialProperties) GetImage(prop Property) ImageIndex {
 	v, ok := mp[prop]
 	if ok {
@@ -121,6 +151,19 @@ type Shader interface {
 	DrawSkinned(ctx *DrawContext, mesh Mesh, world mgl32.Mat4, aniMatrix []mgl32.Mat4, extra ShaderExtra)
 }
 
+// Instance is one instance of mesh drawn with InstancedShader
+type Instance struct {
+	World mgl32.Mat4
+	// Color is multiplied with base color of material. Use white color to keep material color unchanged
+	Color mgl32.Vec4
+}
+
+// InstancedShader is implemented by shaders that can draw multiple instances of same mesh with one draw call
+type InstancedShader interface {
+	Shader
+	DrawInstanced(ctx *DrawContext, mesh Mesh, instances []Instance, extra ShaderExtra)
+}
+
 type BoundShader interface {
 	Shader
 	SetModel(model *Model)
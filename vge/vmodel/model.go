@@ -39,6 +39,8 @@ type Model struct {
 	bUbf      *vk.Buffer
 	memPool   *vk.MemoryPool
 	skins     []Skin
+	bMorph    *vk.Buffer
+	dsMorph   *vk.DescriptorSet
 
 	// joints         []MJoint
 	// skins          []MSkin
@@ -166,6 +168,8 @@ type Mesh struct {
 	Model *Model
 	From  uint32
 	Count uint32
+	// Morph targets of skinned mesh. Morph.Targets is 0 if mesh has no morph targets
+	Morph MorphInfo
 }
 
 type Material struct {
@@ -183,4 +187,10 @@ type Node struct {
 	Mesh      MeshIndex
 	Skin      SkinIndex
 	Parent    NodeIndex
+	// Animation contains transform animations of non skinned node. Nil if node is not animated
+	Animation *NodeAnimation
+	// Light attached to node. Nil if node has no light
+	Light *Light
+	// Camera attached to node. Nil if node has no camera
+	Camera *Camera
 }
@@ -0,0 +1,124 @@
+package vmodel
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+)
+
+// MaxMorphTargets is maximum number of morph targets in one mesh. Must match shader value
+const MaxMorphTargets = 256
+
+// MorphUniformOffset is offset of morph info after joint matrixes in skin uniform buffer. Must match shader value (768 joints)
+const MorphUniformOffset = 768 * 64
+
+// KMorphWeights is key for current morph weights ([]float32) in ShaderExtra. See GetMorphWeights
+var KMorphWeights = vk.NewKey()
+
+var kMorphLayout = vk.NewKey()
+
+// MorphTarget contains position, normal and tangent differences for each vertex of a mesh. Normals and tangents are optional.
+type MorphTarget struct {
+	Positions []mgl32.Vec3
+	Normals   []mgl32.Vec3
+	Tangents  []mgl32.Vec3
+}
+
+// MorphInfo locates mesh morph targets from model's morph buffer.
+type MorphInfo struct {
+	// Offset is index of first vec4 of mesh deltas in morph buffer
+	Offset uint32
+	// Number of morph targets. For each target and vertex there are 3 vec4 deltas: position, normal and tangent
+	Targets uint32
+	// First vertex of mesh in model's vertex buffer
+	FirstVertex uint32
+	// Number of vertices in mesh
+	VertexCount uint32
+	// Default weights of morph targets
+	Weights []float32
+}
+
+// AddMorphTarget adds new morph target (blend shape) to mesh. Each array in morph target should have same length as there are vertices in mesh.
+// Mesh with morph targets is always converted to skinned mesh. If vertices don't have joint weights, all vertices will be bound to first joint.
+func (mb *MeshBuilder) AddMorphTarget(target *MorphTarget, defaultWeight float32) (index int) {
+	index = len(mb.Targets)
+	mb.Targets = append(mb.Targets, target)
+	mb.Weights = append(mb.Weights, defaultWeight)
+	return index
+}
+
+// GetMorphLayout returns descriptor layout for model's morph buffer
+func GetMorphLayout(ctx vk.APIContext, dev *vk.Device) *vk.DescriptorLayout {
+	return dev.Get(ctx, kMorphLayout, func(ctx vk.APIContext) interface{} {
+		return vk.NewDescriptorLayout(ctx, dev, vk.DESCRIPTORTypeStorageBuffer, vk.SHADERStageVertexBit, 1)
+	}).(*vk.DescriptorLayout)
+}
+
+// GetMorphWeights retrieves current morph weights from shader extras. Weights are nil if there is no morph animation.
+func GetMorphWeights(extra ShaderExtra) []float32 {
+	if extra == nil {
+		return nil
+	}
+	w, _ := extra.Get(KMorphWeights).([]float32)
+	return w
+}
+
+// WriteMorph writes mesh morph info and weights after joint matrixes in skin uniform. Default weights of mesh are used if
+// weights are nil
+func WriteMorph(content []byte, mesh Mesh, weights []float32) {
+	if len(content) < MorphUniformOffset+16+MaxMorphTargets*4 {
+		return
+	}
+	mi := mesh.Morph
+	if weights == nil {
+		weights = mi.Weights
+	}
+	targets := mi.Targets
+	if targets > MaxMorphTargets {
+		targets = MaxMorphTargets
+	}
+	if uint32(len(weights)) < targets {
+		targets = uint32(len(weights))
+	}
+	b := content[MorphUniformOffset:]
+	binary.LittleEndian.PutUint32(b[0:], mi.Offset)
+	binary.LittleEndian.PutUint32(b[4:], targets)
+	binary.LittleEndian.PutUint32(b[8:], mi.FirstVertex)
+	binary.LittleEndian.PutUint32(b[12:], mi.VertexCount)
+	for idx := uint32(0); idx < targets; idx++ {
+		binary.LittleEndian.PutUint32(b[16+idx*4:], math.Float32bits(weights[idx]))
+	}
+}
+
+// MorphDescriptor returns descriptor set that binds model's morph buffer. All models with skinned meshes will have morph descriptor
+// even if they don't have any morph targets.
+func (m *Model) MorphDescriptor() *vk.DescriptorSet {
+	return m.dsMorph
+}
+
+func (ms *MeshBuilder) morphSize() uint64 {
+	return uint64(len(ms.Targets)) * uint64(len(ms.Vextexies)) * 3 * 16
+}
+
+// appendMorph appends morph deltas of mesh to morph data
+func (ms *MeshBuilder) appendMorph(morphs []float32, firstVertex uint32) ([]float32, MorphInfo) {
+	mi := MorphInfo{Offset: uint32(len(morphs) / 4), Targets: uint32(len(ms.Targets)), FirstVertex: firstVertex,
+		VertexCount: uint32(len(ms.Vextexies)), Weights: ms.Weights}
+	for _, t := range ms.Targets {
+		for idx := range ms.Vextexies {
+			morphs = appendDelta(morphs, t.Positions, idx)
+			morphs = appendDelta(morphs, t.Normals, idx)
+			morphs = appendDelta(morphs, t.Tangents, idx)
+		}
+	}
+	return morphs, mi
+}
+
+func appendDelta(morphs []float32, deltas []mgl32.Vec3, idx int) []float32 {
+	if idx < len(deltas) {
+		return append(morphs, deltas[idx][0], deltas[idx][1], deltas[idx][2], 0)
+	}
+	return append(morphs, 0, 0, 0, 0)
+}
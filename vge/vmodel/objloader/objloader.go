@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -17,7 +16,8 @@ import (
 )
 
 type ObjLoader struct {
-	Builder   *vmodel.ModelBuilder
+	Builder *vmodel.ModelBuilder
+	// Loader used to load obj files, material libraries and images. If nil, vasset.DefaultLoader is used
 	Loader    vasset.Loader
 	Parent    *vmodel.NodeBuilder
 	materials map[string]vmodel.MaterialIndex
@@ -38,10 +38,7 @@ type vertex struct {
 }
 
 func (ol *ObjLoader) LoadFile(filename string) error {
-	if ol.Loader == nil {
-		return errors.New("Set loader")
-	}
-	rd, err := ol.Loader.Open(filename)
+	rd, err := ol.loader().Open(filename)
 	if err != nil {
 		return err
 	}
@@ -232,10 +229,7 @@ func (ol *ObjLoader) buildObject(newName string) (err error) {
 }
 
 func (ol *ObjLoader) loadMatLib(lib string) error {
-	if ol.Loader == nil {
-		return errors.New("Set Loader for material library")
-	}
-	r, err := ol.Loader.Open(lib)
+	r, err := ol.loader().Open(lib)
 	if err != nil {
 		return err
 	}
@@ -259,12 +253,7 @@ func (ol *ObjLoader) LoadImage(path string) (vmodel.ImageIndex, error) {
 	if ok {
 		return im, nil
 	}
-	rd, err := ol.Loader.Open(path)
-	if err != nil {
-		return 0, err
-	}
-	defer rd.Close()
-	content, err := ioutil.ReadAll(rd)
+	content, err := vasset.Load(path, ol.loader())
 	if err != nil {
 		return 0, err
 	}
@@ -272,6 +261,13 @@ func (ol *ObjLoader) LoadImage(path string) (vmodel.ImageIndex, error) {
 	return ol.Builder.AddImage(kind, content, vk.IMAGEUsageSampledBit|vk.IMAGEUsageTransferDstBit), nil
 }
 
+func (ol *ObjLoader) loader() vasset.Loader {
+	if ol.Loader == nil {
+		return vasset.DefaultLoader
+	}
+	return ol.Loader
+}
+
 type matLoader struct {
 	parent  *ObjLoader
 	matName string
@@ -1,6 +1,8 @@
 package vmodel
 
 import (
+	"sort"
+
 	"github.com/go-gl/mathgl/mgl32"
 )
 
@@ -10,6 +12,20 @@ const (
 	TTranslation = ChannelTarget(1)
 	TScale       = ChannelTarget(2)
 	TRotation    = ChannelTarget(3)
+	// TWeights animates morph target weights. Each key has one weight per morph target. Joint index of channel is not used.
+	TWeights = ChannelTarget(4)
+)
+
+// Interpolation tells how values between channel keyframes are calculated. Values match glTF sampler interpolation modes
+type Interpolation uint32
+
+const (
+	// ILinear interpolates linearly between keys. Rotations are interpolated using spherical linear interpolation (slerp)
+	ILinear = Interpolation(0)
+	// IStep holds value of previous key until next key
+	IStep = Interpolation(1)
+	// ICubicSpline uses cubic hermite spline. Each key in output will have in-tangent, value and out-tangent
+	ICubicSpline = Interpolation(2)
 )
 
 type Joint struct {
@@ -23,10 +39,11 @@ type Joint struct {
 }
 
 type Channel struct {
-	Joint  int
-	Input  []float32
-	Output []float32
-	Target ChannelTarget
+	Joint         int
+	Input         []float32
+	Output        []float32
+	Target        ChannelTarget
+	Interpolation Interpolation
 }
 
 type Animation struct {
@@ -39,3 +56,194 @@ type Skin struct {
 	Matrix     []mgl32.Mat4
 	Animations []Animation
 }
+
+// NodeAnimation contains transform animations of a single non skinned model node. Node is in Translate, Rotate and Scale pose when no animation
+// channel overrides these values. Each track animates translation, rotation or scale of node. Channel Joint index is not used.
+// Tracks from same source (like glTF file) will be in same order in all nodes so that same track index can be used to select animation for all nodes.
+type NodeAnimation struct {
+	Translate mgl32.Vec3
+	Scale     mgl32.Vec3
+	Rotate    mgl32.Quat
+	Tracks    []Animation
+}
+
+// Transform calculates local transform of node at given track and animation time
+func (na *NodeAnimation) Transform(track int, at float32) mgl32.Mat4 {
+	tr, rot, sc := na.Translate, na.Rotate, na.Scale
+	if track >= 0 && track < len(na.Tracks) {
+		for _, ch := range na.Tracks[track].Channels {
+			if len(ch.Input) == 0 {
+				continue
+			}
+			switch ch.Target {
+			case TTranslation:
+				tr = ch.SampleVec3(at)
+			case TRotation:
+				rot = ch.SampleQuat(at)
+			case TScale:
+				sc = ch.SampleVec3(at)
+			}
+		}
+	}
+	return mgl32.Translate3D(tr[0], tr[1], tr[2]).Mul4(rot.Mat4()).Mul4(mgl32.Scale3D(sc[0], sc[1], sc[2]))
+}
+
+// Blend interpolates translation, rotation and scale of joint towards other joint. Other properties are kept from original joint.
+func (j Joint) Blend(other Joint, f float32) Joint {
+	j.Translate = j.Translate.Mul(1 - f).Add(other.Translate.Mul(f))
+	j.Scale = j.Scale.Mul(1 - f).Add(other.Scale.Mul(f))
+	j.Rotate = slerp(j.Rotate, other.Rotate, f)
+	return j
+}
+
+// Apply samples all channels of animation at given time and updates animated properties of joints
+func (a Animation) Apply(at float32, joints []Joint) {
+	for _, ch := range a.Channels {
+		if len(ch.Input) == 0 || ch.Joint < 0 || ch.Joint >= len(joints) {
+			continue
+		}
+		switch ch.Target {
+		case TTranslation:
+			joints[ch.Joint].Translate = ch.SampleVec3(at)
+		case TRotation:
+			joints[ch.Joint].Rotate = ch.SampleQuat(at)
+		case TScale:
+			joints[ch.Joint].Scale = ch.SampleVec3(at)
+		}
+	}
+}
+
+// ApplyWeights samples all morph weight channels of animation at given time and updates weights
+func (a Animation) ApplyWeights(at float32, weights []float32) {
+	for _, ch := range a.Channels {
+		if ch.Target != TWeights || len(ch.Input) == 0 || ch.Elements() != len(weights) {
+			continue
+		}
+		ch.Sample(at, weights)
+	}
+}
+
+// Length is time of last key in animation
+func (a Animation) Length() float32 {
+	var l float32
+	for _, ch := range a.Channels {
+		if len(ch.Input) > 0 && ch.Input[len(ch.Input)-1] > l {
+			l = ch.Input[len(ch.Input)-1]
+		}
+	}
+	return l
+}
+
+// Elements returns number of float values in one key of channel
+func (ch Channel) Elements() int {
+	if len(ch.Input) == 0 {
+		return 0
+	}
+	if ch.Interpolation == ICubicSpline {
+		return len(ch.Output) / len(ch.Input) / 3
+	}
+	return len(ch.Output) / len(ch.Input)
+}
+
+// Sample calculates value of channel at given time. Time is clamped to channels input range. Values must have room for
+// at least Elements() values. Rotations are not normalized, use SampleQuat to get rotation.
+func (ch Channel) Sample(at float32, values []float32) {
+	el := ch.Elements()
+	if el == 0 {
+		return
+	}
+	idx, f := ch.findKey(at)
+	switch {
+	case f <= 0 || idx+1 >= len(ch.Input):
+		ch.copyKey(idx, el, values)
+	case ch.Interpolation == IStep:
+		ch.copyKey(idx, el, values)
+	case ch.Interpolation == ICubicSpline:
+		ch.cubic(idx, el, f, values)
+	case ch.Target == TRotation:
+		q := slerp(ch.quatKey(idx), ch.quatKey(idx+1), f)
+		values[0], values[1], values[2], values[3] = q.V[0], q.V[1], q.V[2], q.W
+	default:
+		for i := 0; i < el; i++ {
+			values[i] = ch.Output[idx*el+i]*(1-f) + ch.Output[(idx+1)*el+i]*f
+		}
+	}
+}
+
+// SampleVec3 samples translation or scale channel
+func (ch Channel) SampleVec3(at float32) (v mgl32.Vec3) {
+	var values [3]float32
+	ch.Sample(at, values[:])
+	return mgl32.Vec3(values)
+}
+
+// SampleQuat samples rotation channel. Returned rotation is normalized
+func (ch Channel) SampleQuat(at float32) mgl32.Quat {
+	var values [4]float32
+	ch.Sample(at, values[:])
+	q := mgl32.Quat{V: mgl32.Vec3{values[0], values[1], values[2]}, W: values[3]}
+	if q.Len() < 1e-6 {
+		return mgl32.QuatIdent()
+	}
+	return q.Normalize()
+}
+
+// findKey locates key before time and relative position (0 - 1) between key and next key
+func (ch Channel) findKey(at float32) (idx int, f float32) {
+	if at <= ch.Input[0] {
+		return 0, 0
+	}
+	idx = sort.Search(len(ch.Input), func(i int) bool {
+		return ch.Input[i] > at
+	}) - 1
+	if idx+1 >= len(ch.Input) {
+		return len(ch.Input) - 1, 0
+	}
+	delta := ch.Input[idx+1] - ch.Input[idx]
+	if delta <= 0 {
+		return idx, 0
+	}
+	return idx, (at - ch.Input[idx]) / delta
+}
+
+func (ch Channel) copyKey(idx int, el int, values []float32) {
+	if ch.Interpolation == ICubicSpline {
+		// Skip in-tangent
+		copy(values[:el], ch.Output[idx*el*3+el:])
+		return
+	}
+	copy(values[:el], ch.Output[idx*el:])
+}
+
+func (ch Channel) quatKey(idx int) mgl32.Quat {
+	return mgl32.Quat{V: mgl32.Vec3{ch.Output[idx*4], ch.Output[idx*4+1], ch.Output[idx*4+2]}, W: ch.Output[idx*4+3]}
+}
+
+// cubic calculates Hermite spline as specified in glTF 2.0 Appendix C
+func (ch Channel) cubic(idx int, el int, t float32, values []float32) {
+	td := ch.Input[idx+1] - ch.Input[idx]
+	t2 := t * t
+	t3 := t2 * t
+	h00 := 2*t3 - 3*t2 + 1
+	h10 := (t3 - 2*t2 + t) * td
+	h01 := -2*t3 + 3*t2
+	h11 := (t3 - t2) * td
+	k0 := idx * el * 3
+	k1 := (idx + 1) * el * 3
+	for i := 0; i < el; i++ {
+		v0 := ch.Output[k0+el+i]
+		b0 := ch.Output[k0+2*el+i]
+		a1 := ch.Output[k1+i]
+		v1 := ch.Output[k1+el+i]
+		values[i] = h00*v0 + h10*b0 + h01*v1 + h11*a1
+	}
+}
+
+// slerp interpolates along shortest path between two rotations
+func slerp(q1 mgl32.Quat, q2 mgl32.Quat, f float32) mgl32.Quat {
+	q1, q2 = q1.Normalize(), q2.Normalize()
+	if q1.Dot(q2) < 0 {
+		q2 = q2.Scale(-1)
+	}
+	return mgl32.QuatSlerp(q1, q2, f).Normalize()
+}
@@ -0,0 +1,43 @@
+package vmodel
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestChannel_Sample(t *testing.T) {
+	ch := Channel{Target: TTranslation, Input: []float32{0, 1, 2}, Output: []float32{0, 0, 0, 2, 0, 0, 2, 4, 0}}
+	if v := ch.SampleVec3(0.5); !v.ApproxEqual(mgl32.Vec3{1, 0, 0}) {
+		t.Error("Linear sample failed, got ", v)
+	}
+	if v := ch.SampleVec3(5); !v.ApproxEqual(mgl32.Vec3{2, 4, 0}) {
+		t.Error("Clamp after last key failed, got ", v)
+	}
+	ch.Interpolation = IStep
+	if v := ch.SampleVec3(1.5); !v.ApproxEqual(mgl32.Vec3{2, 0, 0}) {
+		t.Error("Step sample failed, got ", v)
+	}
+	// Cubic spline with zero tangents is smoothstep between keys
+	ch = Channel{Target: TScale, Interpolation: ICubicSpline, Input: []float32{0, 1},
+		Output: []float32{0, 0, 0, 1, 1, 1, 0, 0, 0, 0, 0, 0, 3, 3, 3, 0, 0, 0}}
+	if v := ch.SampleVec3(0.5); !v.ApproxEqual(mgl32.Vec3{2, 2, 2}) {
+		t.Error("Cubic sample failed, got ", v)
+	}
+}
+
+func TestChannel_SampleQuat(t *testing.T) {
+	q1 := mgl32.QuatIdent()
+	q2 := mgl32.QuatRotate(math.Pi/2, mgl32.Vec3{0, 1, 0}).Scale(-1)
+	ch := Channel{Target: TRotation, Input: []float32{0, 1},
+		Output: []float32{q1.V[0], q1.V[1], q1.V[2], q1.W, q2.V[0], q2.V[1], q2.V[2], q2.W}}
+	q := ch.SampleQuat(0.5)
+	expected := mgl32.QuatRotate(math.Pi/4, mgl32.Vec3{0, 1, 0})
+	if !q.ApproxEqualThreshold(expected, 1e-4) && !q.Scale(-1).ApproxEqualThreshold(expected, 1e-4) {
+		t.Error("Slerp should take shortest path, got ", q)
+	}
+	if math.Abs(float64(q.Len()-1)) > 1e-5 {
+		t.Error("Rotation not normalized ", q.Len())
+	}
+}
@@ -0,0 +1,195 @@
+package vpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lakal3/vge/vge/vasset"
+	"github.com/lakal3/vge/vge/vglyph"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// Builder collects assets and writes them to asset pack. Images are decoded and mip levels are generated using GPU
+// so most of add methods need a device.
+type Builder struct {
+	entries []*buildEntry
+	names   map[string]bool
+}
+
+type buildEntry struct {
+	name    string
+	kind    EntryKind
+	content []byte
+}
+
+// AddFile adds plain file to pack. Files can be opened from loaded pack like from any vasset.Loader
+func (b *Builder) AddFile(ctx vk.APIContext, name string, content []byte) {
+	b.add(ctx, name, KindFile, content)
+}
+
+// AddImage decodes image and adds it to pack with given number of mip levels. Image kind must be supported by some of
+// registered image loaders. Mip levels are not generated if image already has mip levels or image is too small
+func (b *Builder) AddImage(ctx vk.APIContext, dev *vk.Device, name string, kind string, content []byte, mipLevels uint32) {
+	im := BakeImage(ctx, dev, kind, content, vk.ImageDescription{}, mipLevels)
+	if im == nil {
+		return
+	}
+	im.Usage = vk.IMAGEUsageSampledBit | vk.IMAGEUsageTransferDstBit
+	b.addGob(ctx, name, KindImage, im)
+}
+
+// AddModel adds content of model builder to pack. All images of model are decoded and mip levels up to
+// ModelBuilder.MipLevels are generated. Meshes are completed (see MeshBuilder.Complete).
+//
+// White image must be first image of model builder. This is always true if all images are added with ModelBuilder.AddImage.
+func (b *Builder) AddModel(ctx vk.APIContext, dev *vk.Device, name string, mb *vmodel.ModelBuilder) {
+	if mb.AddWhite() != 0 {
+		ctx.SetError(errors.New("White image must be first image of model builder"))
+		return
+	}
+	pm := &packModel{MipLevels: mb.MipLevels, Root: mb.Root, Skins: mb.Skins}
+	for _, ib := range mb.Images[1:] {
+		im := BakeImage(ctx, dev, ib.Kind, ib.Content, ib.Desc, mb.MipLevels)
+		if im == nil {
+			return
+		}
+		im.Usage = ib.Usage
+		pm.Images = append(pm.Images, *im)
+	}
+	for _, mi := range mb.Materials {
+		pm.Materials = append(pm.Materials, packMaterial{Name: mi.Name, Decal: mi.Decal, Props: mi.Props})
+	}
+	for _, ms := range mb.Meshes {
+		pm.Meshes = append(pm.Meshes, fromMesh(ms))
+	}
+	b.addGob(ctx, name, KindModel, pm)
+}
+
+// AddGlyphSet reads glyph set image back from GPU and adds it to pack. Advance function of glyph set is not stored
+func (b *Builder) AddGlyphSet(ctx vk.APIContext, dev *vk.Device, name string, gs *vglyph.GlyphSet) {
+	cp := vmodel.NewCopier(ctx, dev)
+	defer cp.Dispose()
+	r := gs.Desc.FullRange()
+	r.Layout = vk.IMAGELayoutShaderReadOnlyOptimal
+	content := cp.CopyFromImage(gs.GetImage(), r, "raw", vk.IMAGELayoutShaderReadOnlyOptimal)
+	if !ctx.IsValid() {
+		return
+	}
+	pg := &packGlyphSet{Image: Image{Desc: gs.Desc, Content: content}, Kind: gs.Kind(), Glyphs: gs.Glyphs()}
+	b.addGob(ctx, name, KindGlyphSet, pg)
+}
+
+// Write writes asset pack
+func (b *Builder) Write(w io.Writer) error {
+	buf := &bytes.Buffer{}
+	buf.WriteString(Magic)
+	dirLen := 16
+	for _, e := range b.entries {
+		if len(e.name) > 0xFFFF {
+			return fmt.Errorf("Asset name %s too long", e.name)
+		}
+		dirLen += 2 + len(e.name) + 20
+	}
+	writeLE(buf, uint32(Version), uint32(len(b.entries)))
+	offset := uint64(dirLen)
+	for _, e := range b.entries {
+		writeLE(buf, uint16(len(e.name)))
+		buf.WriteString(e.name)
+		writeLE(buf, uint32(e.kind), offset, uint64(len(e.content)))
+		offset += uint64(len(e.content))
+	}
+	_, err := w.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	for _, e := range b.entries {
+		_, err = w.Write(e.content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLE(buf *bytes.Buffer, values ...interface{}) {
+	for _, v := range values {
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+}
+
+func (b *Builder) add(ctx vk.APIContext, name string, kind EntryKind, content []byte) {
+	name = CleanName(name)
+	if b.names == nil {
+		b.names = make(map[string]bool)
+	}
+	if b.names[name] {
+		ctx.SetError(fmt.Errorf("Asset %s already in pack", name))
+		return
+	}
+	b.names[name] = true
+	b.entries = append(b.entries, &buildEntry{name: name, kind: kind, content: content})
+}
+
+func (b *Builder) addGob(ctx vk.APIContext, name string, kind EntryKind, value interface{}) {
+	if !ctx.IsValid() {
+		return
+	}
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(value)
+	if err != nil {
+		ctx.SetError(fmt.Errorf("Asset %s: %v", name, err))
+		return
+	}
+	b.add(ctx, name, kind, buf.Bytes())
+}
+
+// BakeImage decodes image and generates missing mip levels. For raw images, desc must describe content.
+// Returned image content contains all layers and mip levels in raw format.
+func BakeImage(ctx vk.APIContext, dev *vk.Device, kind string, content []byte, desc vk.ImageDescription, mipLevels uint32) *Image {
+	if kind != "raw" {
+		desc = vk.ImageDescription{}
+		vasset.DescribeImage(ctx, kind, &desc, content)
+	}
+	if !ctx.IsValid() {
+		return nil
+	}
+	origMips := desc.MipLevels
+	usage := vk.IMAGEUsageSampledBit | vk.IMAGEUsageTransferDstBit | vk.IMAGEUsageTransferSrcBit
+	w, h := desc.Width, desc.Height
+	if desc.MipLevels < mipLevels && (w>>mipLevels) > 1 && (h>>mipLevels) > 1 {
+		desc.MipLevels = mipLevels
+		usage |= vk.IMAGEUsageStorageBit
+	}
+	pool := vk.NewMemoryPool(dev)
+	defer pool.Dispose()
+	img := pool.ReserveImage(ctx, desc, usage)
+	pool.Allocate(ctx)
+	cp := vmodel.NewCopier(ctx, dev)
+	defer cp.Dispose()
+	r := desc.FullRange()
+	if desc.MipLevels > origMips {
+		cp.SetLayout(img, r, vk.IMAGELayoutGeneral)
+		cp.CopyToImage(img, kind, content, vk.ImageRange{LayerCount: desc.Layers, LevelCount: 1}, vk.IMAGELayoutGeneral)
+		comp := vmodel.NewCompute(ctx, dev)
+		defer comp.Dispose()
+		for mip := origMips; mip < desc.MipLevels; mip++ {
+			for l := uint32(0); l < desc.Layers; l++ {
+				comp.MipImage(img, l, mip)
+			}
+		}
+		r.Layout = vk.IMAGELayoutGeneral
+	} else {
+		cp.CopyToImage(img, kind, content, r, vk.IMAGELayoutTransferSrcOptimal)
+		r.Layout = vk.IMAGELayoutTransferSrcOptimal
+	}
+	baked := cp.CopyFromImage(img, r, "raw", vk.IMAGELayoutUndefined)
+	if !ctx.IsValid() {
+		return nil
+	}
+	return &Image{Desc: desc, Content: baked}
+}
@@ -0,0 +1,338 @@
+// Package vpack implements binary asset packs. Asset pack stores preprocessed assets so that they only need to be
+// copied to GPU when application starts. Pack can contain models (vmodel.ModelBuilder with decoded images and mip levels),
+// glyph sets rendered from fonts or vector glyphs, GPU ready images and plain files.
+//
+// Packs are made with Builder or with vpack command line tool (tools/vpack).
+// Pack implements vasset.Loader so that plain files in pack can be loaded like any other assets.
+package vpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vasset"
+	"github.com/lakal3/vge/vge/vglyph"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// Magic is file signature of asset pack
+const Magic = "VGEPACK\x00"
+
+// Version is current version of asset pack format. Packs with newer version can't be read
+const Version = 1
+
+type EntryKind uint32
+
+const (
+	// KindFile is plain file
+	KindFile = EntryKind(1)
+	// KindImage is GPU ready image with all mip levels. See Image
+	KindImage = EntryKind(2)
+	// KindModel is model builder with GPU ready images. See Pack.LoadModel
+	KindModel = EntryKind(3)
+	// KindGlyphSet is prerendered glyph set. See Pack.LoadGlyphSet
+	KindGlyphSet = EntryKind(4)
+)
+
+// Entry describes one asset in pack
+type Entry struct {
+	Name   string
+	Kind   EntryKind
+	Size   uint64
+	offset uint64
+}
+
+// Image is GPU ready image. Content contains all layers and mip levels of image in raw format
+type Image struct {
+	Desc    vk.ImageDescription
+	Usage   vk.ImageUsageFlags
+	Content []byte
+}
+
+type packModel struct {
+	MipLevels uint32
+	// Images of model except white image (image 0)
+	Images    []Image
+	Materials []packMaterial
+	Meshes    []packMesh
+	Root      *vmodel.NodeBuilder
+	Skins     []vmodel.Skin
+}
+
+type packMaterial struct {
+	Name  string
+	Decal bool
+	Props vmodel.MaterialProperties
+}
+
+type packMesh struct {
+	Positions []mgl32.Vec3
+	Uvs       []mgl32.Vec2
+	Normals   []mgl32.Vec3
+	Tangents  []mgl32.Vec3
+	Colors    []mgl32.Vec4
+	Weights   []mgl32.Vec4
+	Joints    [][4]uint16
+	Indices   []uint32
+	Targets   []*vmodel.MorphTarget
+	// Default weights of morph targets
+	MorphWeights []float32
+}
+
+type packGlyphSet struct {
+	Image  Image
+	Kind   vglyph.SetKind
+	Glyphs []vglyph.Glyph
+}
+
+func init() {
+	// Value types of material properties
+	gob.Register(mgl32.Vec4{})
+	gob.Register(mgl32.Mat3{})
+	gob.Register(vmodel.ImageIndex(0))
+}
+
+// Pack is loaded asset pack
+type Pack struct {
+	content []byte
+	entries map[string]Entry
+}
+
+// Load loads asset pack using given loader. If loader is nil, vasset.DefaultLoader is used
+func Load(l vasset.Loader, name string) (*Pack, error) {
+	content, err := vasset.Load(name, l)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Parse parses asset pack from content
+func Parse(content []byte) (*Pack, error) {
+	if len(content) < 16 || string(content[:8]) != Magic {
+		return nil, errors.New("Not a VGE asset pack")
+	}
+	version := binary.LittleEndian.Uint32(content[8:])
+	if version > Version {
+		return nil, fmt.Errorf("Unsupported asset pack version %d", version)
+	}
+	count := int(binary.LittleEndian.Uint32(content[12:]))
+	p := &Pack{content: content, entries: make(map[string]Entry, count)}
+	pos := 16
+	for idx := 0; idx < count; idx++ {
+		if pos+2 > len(content) {
+			return nil, errors.New("Truncated asset pack directory")
+		}
+		nameLen := int(binary.LittleEndian.Uint16(content[pos:]))
+		pos += 2
+		if pos+nameLen+20 > len(content) {
+			return nil, errors.New("Truncated asset pack directory")
+		}
+		e := Entry{Name: string(content[pos : pos+nameLen])}
+		pos += nameLen
+		e.Kind = EntryKind(binary.LittleEndian.Uint32(content[pos:]))
+		e.offset = binary.LittleEndian.Uint64(content[pos+4:])
+		e.Size = binary.LittleEndian.Uint64(content[pos+12:])
+		pos += 20
+		if e.offset+e.Size > uint64(len(content)) {
+			return nil, fmt.Errorf("Asset %s is outside of pack", e.Name)
+		}
+		p.entries[e.Name] = e
+	}
+	return p, nil
+}
+
+// Entries returns all entries of pack sorted by name
+func (p *Pack) Entries() []Entry {
+	entries := make([]Entry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Open opens file entry from pack. Pack implements vasset.Loader
+func (p *Pack) Open(filename string) (io.ReadCloser, error) {
+	content, err := p.get(filename, KindFile)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Image retrieves GPU ready image from pack
+func (p *Pack) Image(name string) (*Image, error) {
+	im := &Image{}
+	err := p.decode(name, KindImage, im)
+	if err != nil {
+		return nil, err
+	}
+	return im, nil
+}
+
+// LoadImage reserves image from memory pool and copies image content to it. LoadImage will allocate memory pool
+// so all resources reserved earlier from pool will also be allocated.
+func (p *Pack) LoadImage(ctx vk.APIContext, dev *vk.Device, pool *vk.MemoryPool, name string) *vk.Image {
+	im, err := p.Image(name)
+	if err != nil {
+		ctx.SetError(err)
+		return nil
+	}
+	return im.Load(ctx, dev, pool)
+}
+
+// Load reserves image from memory pool and copies image content to it. Load will allocate memory pool
+// so all resources reserved earlier from pool will also be allocated.
+func (im *Image) Load(ctx vk.APIContext, dev *vk.Device, pool *vk.MemoryPool) *vk.Image {
+	img := pool.ReserveImage(ctx, im.Desc, im.Usage|vk.IMAGEUsageTransferDstBit)
+	pool.Allocate(ctx)
+	cp := vmodel.NewCopier(ctx, dev)
+	defer cp.Dispose()
+	cp.CopyToImage(img, "raw", im.Content, im.Desc.FullRange(), vk.IMAGELayoutShaderReadOnlyOptimal)
+	return img
+}
+
+// ModelBuilder restores model builder from pack. Set ShaderFactory of model builder before converting it to model
+func (p *Pack) ModelBuilder(name string) (*vmodel.ModelBuilder, error) {
+	pm := &packModel{}
+	err := p.decode(name, KindModel, pm)
+	if err != nil {
+		return nil, err
+	}
+	mb := &vmodel.ModelBuilder{MipLevels: pm.MipLevels, Root: pm.Root, Skins: pm.Skins}
+	mb.AddWhite()
+	for _, im := range pm.Images {
+		idx := mb.AddImage("raw", im.Content, im.Usage)
+		mb.Images[idx].Desc = im.Desc
+	}
+	for _, mi := range pm.Materials {
+		if mi.Props == nil {
+			mi.Props = vmodel.NewMaterialProperties()
+		}
+		if mi.Decal {
+			mb.AddDecalMaterial(mi.Name, mi.Props)
+		} else {
+			mb.AddMaterial(mi.Name, mi.Props)
+		}
+	}
+	for _, ms := range pm.Meshes {
+		mb.AddMesh(ms.toMesh())
+	}
+	return mb, nil
+}
+
+// LoadModel loads model from pack and converts it to model using given shader factory. Model builders images have already
+// all mip levels so ToModel only needs to copy model content to GPU.
+func (p *Pack) LoadModel(ctx vk.APIContext, dev *vk.Device, name string, factory vmodel.ShaderFactory) *vmodel.Model {
+	mb, err := p.ModelBuilder(name)
+	if err != nil {
+		ctx.SetError(err)
+		return nil
+	}
+	mb.ShaderFactory = factory
+	return mb.ToModel(ctx, dev)
+}
+
+// LoadGlyphSet loads prerendered glyph set from pack. Advance of glyph set will be vglyph.DefaultAdvance
+func (p *Pack) LoadGlyphSet(ctx vk.APIContext, dev *vk.Device, name string) *vglyph.GlyphSet {
+	pg := &packGlyphSet{}
+	err := p.decode(name, KindGlyphSet, pg)
+	if err != nil {
+		ctx.SetError(err)
+		return nil
+	}
+	return vglyph.NewGlyphSet(ctx, dev, pg.Image.Desc, pg.Kind, pg.Glyphs, pg.Image.Content)
+}
+
+func (p *Pack) get(name string, kind EntryKind) ([]byte, error) {
+	e, ok := p.entries[CleanName(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if e.Kind != kind {
+		return nil, fmt.Errorf("Asset %s is kind %d, not %d", name, e.Kind, kind)
+	}
+	return p.content[e.offset : e.offset+e.Size], nil
+}
+
+func (p *Pack) decode(name string, kind EntryKind, value interface{}) error {
+	content, err := p.get(name, kind)
+	if err != nil {
+		return err
+	}
+	err = gob.NewDecoder(bytes.NewReader(content)).Decode(value)
+	if err != nil {
+		return fmt.Errorf("Asset %s: %v", name, err)
+	}
+	return nil
+}
+
+// CleanName converts asset name to format used in packs. Names use forward slashes and don't have leading slash
+func CleanName(name string) string {
+	name = path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	return strings.TrimPrefix(name, "/")
+}
+
+func (pm *packMesh) toMesh() *vmodel.MeshBuilder {
+	mb := &vmodel.MeshBuilder{}
+	for idx, pos := range pm.Positions {
+		vb := mb.AddVertex(pos)
+		if idx < len(pm.Uvs) {
+			vb.AddUV(pm.Uvs[idx])
+		}
+		if idx < len(pm.Normals) {
+			vb.AddNormal(pm.Normals[idx])
+		}
+		if idx < len(pm.Tangents) {
+			vb.AddTangent(pm.Tangents[idx])
+		}
+		if idx < len(pm.Colors) {
+			vb.AddColor(pm.Colors[idx])
+		}
+		if idx < len(pm.Weights) && idx < len(pm.Joints) {
+			vb.AddWeights(pm.Weights[idx], pm.Joints[idx][:]...)
+		}
+	}
+	if len(pm.Indices) > 0 {
+		mb.AddIndex(pm.Indices...)
+	}
+	for idx, t := range pm.Targets {
+		var w float32
+		if idx < len(pm.MorphWeights) {
+			w = pm.MorphWeights[idx]
+		}
+		mb.AddMorphTarget(t, w)
+	}
+	return mb
+}
+
+func fromMesh(mb *vmodel.MeshBuilder) packMesh {
+	skinned := mb.Complete()
+	pm := packMesh{Indices: mb.Incides, Targets: mb.Targets, MorphWeights: mb.Weights}
+	for _, vb := range mb.Vextexies {
+		pm.Positions = append(pm.Positions, vb.Position)
+		pm.Uvs = append(pm.Uvs, vb.Uv)
+		pm.Normals = append(pm.Normals, vb.Normal)
+		pm.Tangents = append(pm.Tangents, vb.Tangent)
+		pm.Colors = append(pm.Colors, vb.Color)
+		if skinned {
+			pm.Weights = append(pm.Weights, vb.Weights)
+			pm.Joints = append(pm.Joints, vb.Joints)
+		}
+	}
+	return pm
+}
@@ -0,0 +1,91 @@
+package vpack
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+type testContext struct {
+	t *testing.T
+}
+
+func (tc testContext) SetError(err error) {
+	tc.t.Fatal("API call failed: ", err)
+}
+
+func (tc testContext) IsValid() bool {
+	return true
+}
+
+func (tc testContext) Begin(callName string) (atEnd func()) {
+	return nil
+}
+
+func TestPack(t *testing.T) {
+	ctx := testContext{t: t}
+	mb := &vmodel.ModelBuilder{MipLevels: 4}
+	mat := mb.AddMaterial("red", vmodel.NewMaterialProperties().SetColor(vmodel.CAlbedo, mgl32.Vec4{1, 0, 0, 1}).
+		SetFactor(vmodel.FRoughness, 0.4))
+	cube := &vmodel.MeshBuilder{}
+	cube.AddCube(mgl32.Ident4())
+	cube.AddMorphTarget(&vmodel.MorphTarget{Positions: make([]mgl32.Vec3, 24)}, 0.5)
+	m := mb.AddMesh(cube)
+	sk := mb.AddSkin(vmodel.Skin{Joints: []vmodel.Joint{{Name: "root", Root: true, Rotate: mgl32.QuatIdent(), InverseMatrix: mgl32.Ident4()}}})
+	mb.AddNode("cube", nil, mgl32.Translate3D(0, 1, 0)).SetSkinnedMesh(m, mat, sk).
+		SetLight(&vmodel.Light{Kind: vmodel.LightPoint, Intensity: 3})
+
+	b := &Builder{}
+	b.AddFile(ctx, "dir\\readme.txt", []byte("hello"))
+	b.AddModel(ctx, nil, "models/cube", mb)
+	buf := &bytes.Buffer{}
+	err := b.Write(buf)
+	if err != nil {
+		t.Fatal("Write ", err)
+	}
+
+	p, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal("Parse ", err)
+	}
+	if len(p.Entries()) != 2 {
+		t.Error("Expected 2 entries, got ", p.Entries())
+	}
+	rd, err := p.Open("/dir/readme.txt")
+	if err != nil {
+		t.Fatal("Open ", err)
+	}
+	content, _ := ioutil.ReadAll(rd)
+	if string(content) != "hello" {
+		t.Error("Invalid file content ", string(content))
+	}
+	_, err = p.Open("models/cube")
+	if err == nil {
+		t.Error("Model should not open as file")
+	}
+	mb2, err := p.ModelBuilder("models/cube")
+	if err != nil {
+		t.Fatal("Model ", err)
+	}
+	if mb2.MipLevels != 4 || len(mb2.Images) != 1 || len(mb2.Skins) != 1 {
+		t.Error("Invalid model builder ", mb2)
+	}
+	mi := mb2.Materials[mb2.FindMaterial("red")]
+	if mi.Props.GetFactor(vmodel.FRoughness, 0) != 0.4 || mi.Props.GetColor(vmodel.CAlbedo, mgl32.Vec4{}) != (mgl32.Vec4{1, 0, 0, 1}) {
+		t.Error("Invalid material ", mi.Props)
+	}
+	ms := mb2.Meshes[0]
+	if len(ms.Vextexies) != 24 || len(ms.Incides) != len(cube.Incides) || len(ms.Targets) != 1 || ms.Weights[0] != 0.5 {
+		t.Error("Invalid mesh")
+	}
+	if ms.Vextexies[5].Uv != cube.Vextexies[5].Uv || ms.Vextexies[5].Tangent != cube.Vextexies[5].Tangent {
+		t.Error("Vertex mismatch ", ms.Vextexies[5], cube.Vextexies[5])
+	}
+	n := mb2.Root.Children[0]
+	if n.Name != "cube" || n.Location != mgl32.Translate3D(0, 1, 0) || n.Skin != sk || n.Light == nil || n.Light.Intensity != 3 {
+		t.Error("Invalid node ", n)
+	}
+}
@@ -2,7 +2,6 @@ package vscene
 
 import (
 	"math"
-	"sort"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lakal3/vge/vge/vmodel"
@@ -18,6 +17,18 @@ func (r *RotateAnimate) Process(pi *ProcessInfo) {
 	pi.World = pi.World.Mul4(rot)
 }
 
+// PoseSource calculates pose of skin joints. Joints are initialized to skins rest pose before each call
+// (see vanimation.Mixer and vanimation.StateMachine)
+type PoseSource interface {
+	Pose(time float64, joints []vmodel.Joint)
+}
+
+// MorphSource calculates morph target weights. Weights are initialized to mesh default weights before each call.
+// PoseSource assigned to AnimatedNodeControl may also implement MorphSource.
+type MorphSource interface {
+	MorphWeights(time float64, weights []float32)
+}
+
 type AnimatedNodeControl struct {
 	Mat       vmodel.Shader
 	Mesh      vmodel.Mesh
@@ -25,6 +36,10 @@ type AnimatedNodeControl struct {
 	Skin      *vmodel.Skin
 	Animation vmodel.Animation
 	Joints    []vmodel.Joint
+	// Source will override Animation if set. Source will receive scene time (ProcessInfo.Time), not time since StartTime
+	Source PoseSource
+	// Weights are current morph target weights. Weights are nil if mesh has no morph targets
+	Weights []float32
 
 	calcTime float64
 	mxAnims  []mgl32.Mat4
@@ -40,13 +55,16 @@ func (a *AnimatedNodeControl) Process(pi *ProcessInfo) {
 		}
 		if time-a.calcTime > 0.01 || len(a.mxAnims) == 0 {
 			a.calcTime = time
-			a.recalc(time - a.StartTime)
+			a.recalc(time)
 		}
 	}
 	dr, ok := phase.(DrawPhase)
 	if ok {
-		dc := dr.GetDC(LAYER3D)
+		dc := getMeshDC(dr, pi.Frame, a.Mat, a.Mesh, pi.World)
 		if dc != nil {
+			if a.Weights != nil {
+				pi.Set(vmodel.KMorphWeights, a.Weights)
+			}
 			a.Mat.DrawSkinned(dc, a.Mesh, pi.World, a.mxAnims, pi)
 		}
 	}
@@ -57,12 +75,59 @@ func (a *AnimatedNodeControl) Process(pi *ProcessInfo) {
 		bb.Add(aabb)
 	}
 
+	ms, ok := phase.(MorphShadowPhase)
+	if ok {
+		ms.DrawMorphedShadow(a.Mesh, pi.World, a.Mat, a.mxAnims, a.Weights)
+		return
+	}
 	sd, ok := phase.(ShadowPhase)
 	if ok {
 		sd.DrawSkinnedShadow(a.Mesh, pi.World, a.Mat, a.mxAnims)
 	}
 }
 
+// NodeAnimationControl animates transform of non skinned node. NodeFromModel will use NodeAnimationControl instead of
+// TransformControl for all nodes that have animations.
+type NodeAnimationControl struct {
+	Animation *vmodel.NodeAnimation
+	// Track is index of played animation track. Negative track will keep node in rest pose
+	Track     int
+	StartTime float64
+
+	calcTime float64
+	local    mgl32.Mat4
+	valid    bool
+}
+
+func (na *NodeAnimationControl) Process(pi *ProcessInfo) {
+	if na.StartTime == 0 {
+		na.StartTime = pi.Time
+	}
+	if !na.valid || na.calcTime != pi.Time {
+		na.calcTime, na.valid = pi.Time, true
+		na.local = na.recalc(pi.Time - na.StartTime)
+	}
+	pi.World = pi.World.Mul4(na.local)
+}
+
+// SetAnimationIndex pick new animation track
+func (na *NodeAnimationControl) SetAnimationIndex(fromTime float64, animIndex int) {
+	na.Track = animIndex
+	na.StartTime = fromTime
+	na.valid = false
+}
+
+func (na *NodeAnimationControl) recalc(animTime float64) mgl32.Mat4 {
+	if na.Track < 0 || na.Track >= len(na.Animation.Tracks) {
+		return na.Animation.Transform(-1, 0)
+	}
+	at := float32(0)
+	if l := na.Animation.Tracks[na.Track].Length(); l > 0 {
+		at = float32(math.Mod(animTime, float64(l)))
+	}
+	return na.Animation.Transform(na.Track, at)
+}
+
 // SetAnimationIndex pick new animation from Skin
 func (a *AnimatedNodeControl) SetAnimationIndex(fromTime float64, animIndex int) {
 	if len(a.Skin.Animations) > animIndex {
@@ -72,7 +137,7 @@ func (a *AnimatedNodeControl) SetAnimationIndex(fromTime float64, animIndex int)
 	}
 }
 
-func (a *AnimatedNodeControl) recalc(animTime float64) {
+func (a *AnimatedNodeControl) recalc(time float64) {
 	if len(a.mxAnims) != len(a.Skin.Joints) {
 		a.mxAnims = make([]mgl32.Mat4, len(a.Skin.Joints))
 		a.Joints = make([]vmodel.Joint, len(a.Skin.Joints))
@@ -80,8 +145,24 @@ func (a *AnimatedNodeControl) recalc(animTime float64) {
 			a.Joints[idx] = j
 		}
 	}
-	for _, ch := range a.Animation.Channels {
-		a.applyChannel(ch, animTime)
+	if len(a.Weights) != int(a.Mesh.Morph.Targets) {
+		a.Weights = append([]float32{}, a.Mesh.Morph.Weights...)
+	}
+	if a.Source != nil {
+		copy(a.Joints, a.Skin.Joints)
+		a.Source.Pose(time, a.Joints)
+		ms, ok := a.Source.(MorphSource)
+		if ok && len(a.Weights) > 0 {
+			copy(a.Weights, a.Mesh.Morph.Weights)
+			ms.MorphWeights(time, a.Weights)
+		}
+	} else {
+		at := float32(0)
+		if l := a.Animation.Length(); l > 0 {
+			at = float32(math.Mod(time-a.StartTime, float64(l)))
+		}
+		a.Animation.Apply(at, a.Joints)
+		a.Animation.ApplyWeights(at, a.Weights)
 	}
 	for jIdx, j := range a.Joints {
 		if j.Root {
@@ -99,42 +180,3 @@ func (a *AnimatedNodeControl) recalcJoint(jNro int, joint vmodel.Joint, local mg
 		a.recalcJoint(chJ, a.Joints[chJ], local)
 	}
 }
-
-func (a *AnimatedNodeControl) applyChannel(ch vmodel.Channel, animTime float64) {
-	chLen := ch.Input[len(ch.Input)-1]
-	f := float32(math.Mod(animTime, float64(chLen)))
-	idx := sort.Search(len(ch.Input), func(i int) bool {
-		return ch.Input[i] >= f
-	})
-	idx--
-	var lin float32
-	if idx < 0 {
-		idx = 0
-		lin = 0
-	} else if idx-1 >= len(ch.Input) {
-		idx--
-		lin = 1
-	} else {
-		lin = (f - ch.Input[idx]) / (ch.Input[idx+1] - ch.Input[idx])
-	}
-	switch ch.Target {
-	case vmodel.TTranslation:
-		a.translate(ch, lin, idx)
-	case vmodel.TRotation:
-		a.rotate(ch, lin, idx)
-	}
-}
-
-func (a *AnimatedNodeControl) translate(ch vmodel.Channel, f float32, idx int) {
-	min := mgl32.Vec3{ch.Output[idx*3], ch.Output[idx*3+1], ch.Output[idx*3+2]}
-	max := mgl32.Vec3{ch.Output[idx*3+3], ch.Output[idx*3+4], ch.Output[idx*3+5]}
-	a.Joints[ch.Joint].Translate = min.Mul(1 - f).Add(max.Mul(f))
-}
-
-func (a *AnimatedNodeControl) rotate(ch vmodel.Channel, f float32, idx int) {
-	qMin := mgl32.Quat{V: mgl32.Vec3{ch.Output[idx*4], ch.Output[idx*4+1], ch.Output[idx*4+2]},
-		W: ch.Output[idx*4+3]}
-	qMax := mgl32.Quat{V: mgl32.Vec3{ch.Output[idx*4+4], ch.Output[idx*4+5], ch.Output[idx*4+6]},
-		W: ch.Output[idx*4+7]}
-	a.Joints[ch.Joint].Rotate = mgl32.QuatLerp(qMin, qMax, f)
-}
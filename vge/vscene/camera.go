@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
 )
 
 var kView = vk.NewKey()
@@ -51,6 +52,47 @@ func NewPerspectiveCamera(far float32) *PerspectiveCamera {
 	return pc
 }
 
+// CameraFromModel creates perspective camera from model camera. World is world transform of camera node.
+// Cameras without far plane will have far plane at 1000
+func CameraFromModel(c *vmodel.Camera, world mgl32.Mat4) *PerspectiveCamera {
+	far := c.ZFar
+	if far == 0 {
+		far = 1000
+	}
+	pc := NewPerspectiveCamera(far)
+	if c.ZNear > 0 {
+		pc.Near = c.ZNear
+	}
+	if c.YFov > 0 {
+		pc.FoV = c.YFov
+	}
+	pc.Position = world.Mul4x1(mgl32.Vec4{0, 0, 0, 1}).Vec3()
+	pc.Target = pc.Position.Add(world.Mul4x1(mgl32.Vec4{0, 0, -1, 0}).Vec3().Normalize())
+	pc.Up = world.Mul4x1(mgl32.Vec4{0, 1, 0, 0}).Vec3().Normalize()
+	return pc
+}
+
+// CamerasFromModel creates perspective cameras from all camera nodes of model. World is transform of model root node.
+// Cameras are in same order as nodes in model.
+func CamerasFromModel(m *vmodel.Model, world mgl32.Mat4) []*PerspectiveCamera {
+	var cameras []*PerspectiveCamera
+	var addCameras func(node vmodel.NodeIndex, world mgl32.Mat4)
+	addCameras = func(node vmodel.NodeIndex, world mgl32.Mat4) {
+		mn := m.GetNode(node)
+		world = world.Mul4(mn.Transform)
+		if mn.Camera != nil {
+			cameras = append(cameras, CameraFromModel(mn.Camera, world))
+		}
+		for _, ch := range mn.Children {
+			addCameras(ch, world)
+		}
+	}
+	if m.NodeCount() > 0 {
+		addCameras(0, world)
+	}
+	return cameras
+}
+
 func (pc *PerspectiveCamera) GetViewMatrix() mgl32.Mat4 {
 	return mgl32.LookAtV(pc.Position, pc.Target, pc.Up)
 }
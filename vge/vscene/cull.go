@@ -0,0 +1,148 @@
+package vscene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// CullPhase is implemented by phases that skip objects outside of their view volume. Mesh nodes are not drawn if their
+// world space bounding box is not visible. Static subtrees (see Static) are skipped as whole when bounds of subtree are
+// not visible
+type CullPhase interface {
+	Phase
+	// Visible returns false if world space bounding box is completely outside of view volume
+	Visible(aabb vmodel.AABB) bool
+}
+
+// ViewFrame is implemented by frames that know camera projection and view. Draw phases cull objects outside of
+// camera view using ViewProjection of frame
+type ViewFrame interface {
+	ViewProjection() (projection, view mgl32.Mat4)
+}
+
+// Occluder tests if world space bounding box is hidden behind other objects. See BasicDrawPhase.Occluder
+type Occluder interface {
+	Occluded(aabb vmodel.AABB) bool
+}
+
+// CullableControl is implemented by node controls from other packages that either don't draw anything or add bounds of
+// everything they draw to BoudingBox phase. Static subtrees are culled as whole only if all controls in subtree are cullable
+type CullableControl interface {
+	NodeControl
+	Cullable() bool
+}
+
+// IsVisible returns false if phase culls given world space bounding box
+func IsVisible(ph Phase, aabb vmodel.AABB) bool {
+	cp, ok := ph.(CullPhase)
+	return !ok || cp.Visible(aabb)
+}
+
+// ViewCuller culls objects outside of camera view of frame. Frustum is calculated on first test so camera projection
+// and view of frame must be set before frame is processed
+type ViewCuller struct {
+	frustum *vmodel.Frustum
+	noView  bool
+}
+
+// Visible returns false if bounding box is outside of camera view. If frame is not ViewFrame all objects are visible
+func (vc *ViewCuller) Visible(frame vmodel.Frame, aabb vmodel.AABB) bool {
+	if vc.noView {
+		return true
+	}
+	if vc.frustum == nil {
+		vf, ok := frame.(ViewFrame)
+		if !ok {
+			vc.noView = true
+			return true
+		}
+		projection, view := vf.ViewProjection()
+		f := vmodel.NewFrustum(projection.Mul4(view))
+		vc.frustum = &f
+	}
+	return vc.frustum.IntersectsAABB(aabb)
+}
+
+// subtreeBounds are cached world space bounds of node and its children
+type subtreeBounds struct {
+	world mgl32.Mat4
+	aabb  vmodel.AABB
+	valid bool
+}
+
+// subtreeVisible returns false if node and all its children are outside of view volume of phase. Bounds are calculated only for
+// static subtrees and cached until static version of scene changes
+func (sc *Scene) subtreeVisible(n *Node, pi *ProcessInfo) bool {
+	cp, ok := pi.Phase.(CullPhase)
+	if !ok || len(n.Children) == 0 {
+		return true
+	}
+	sc.mxBounds.Lock()
+	version := sc.StaticVersion()
+	if sc.bounds == nil || sc.boundsVersion != version {
+		sc.bounds, sc.boundsVersion = make(map[*Node]subtreeBounds), version
+	}
+	sb, ok := sc.bounds[n]
+	if !ok || sb.world != pi.World {
+		if !isStatic(n.Ctrl) {
+			sc.mxBounds.Unlock()
+			return true
+		}
+		sb = sc.calcBounds(n, pi.World)
+	}
+	sc.mxBounds.Unlock()
+	if !sb.valid {
+		return true
+	}
+	// Subtree without meshes draws nothing
+	return !sb.empty() && cp.Visible(sb.aabb)
+}
+
+// calcBounds calculates and caches bounds of node and all its children
+func (sc *Scene) calcBounds(n *Node, world mgl32.Mat4) subtreeBounds {
+	sb := subtreeBounds{world: world, valid: isCullable(n.Ctrl)}
+	bb := &BoudingBox{first: true}
+	pi := ProcessInfo{Phase: bb, World: world, Frame: NullFrame{}, Visible: true}
+	if n.Ctrl != nil {
+		n.Ctrl.Process(&pi)
+	}
+	for _, ch := range n.Children {
+		chb := sc.calcBounds(ch, pi.World)
+		if !chb.valid {
+			sb.valid = false
+		} else if !chb.empty() {
+			bb.Add(chb.aabb)
+		}
+	}
+	var empty bool
+	sb.aabb, empty = bb.Get()
+	if empty {
+		// Valid subtree without any meshes
+		sb.aabb = vmodel.AABB{Min: mgl32.Vec3{1, 1, 1}, Max: mgl32.Vec3{-1, -1, -1}}
+	}
+	if len(n.Children) > 0 {
+		sc.bounds[n] = sb
+	}
+	return sb
+}
+
+func (sb subtreeBounds) empty() bool {
+	return sb.aabb.Min[0] > sb.aabb.Max[0]
+}
+
+func isCullable(ctrl NodeControl) bool {
+	switch c := ctrl.(type) {
+	case nil, Static, *Static, *TransformControl, *MeshNodeControl, *InstancedNodeControl, *DirectionalLight, *PointLight, *SpotLight:
+		return true
+	case *MultiControl:
+		for _, ctrl := range c.Controls {
+			if !isCullable(ctrl) {
+				return false
+			}
+		}
+		return true
+	case CullableControl:
+		return c.Cullable()
+	}
+	return false
+}
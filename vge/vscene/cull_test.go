@@ -0,0 +1,67 @@
+package vscene
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+type testCullPhase struct {
+	maxX float32
+}
+
+func (t testCullPhase) Begin() (atEnd func()) {
+	return nil
+}
+
+func (t testCullPhase) Visible(aabb vmodel.AABB) bool {
+	return aabb.Min[0] <= t.maxX
+}
+
+type testBounded struct {
+	aabb    vmodel.AABB
+	visited int
+}
+
+func (t *testBounded) Process(pi *ProcessInfo) {
+	bb, ok := pi.Phase.(*BoudingBox)
+	if ok {
+		bb.Add(t.aabb.Translate(pi.World))
+		return
+	}
+	t.visited++
+}
+
+func (t *testBounded) Cullable() bool {
+	return true
+}
+
+func TestScene_Cull(t *testing.T) {
+	sc := &Scene{}
+	sc.Init()
+	unit := vmodel.AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}
+	near, far, dynamic := &testBounded{aabb: unit}, &testBounded{aabb: unit}, &testBounded{aabb: unit}
+	sc.Update(func() {
+		sc.AddNode(nil, NewMultiControl(&TransformControl{Transform: mgl32.Translate3D(100, 0, 0)}, Static{}),
+			NewNode(far))
+		sc.AddNode(nil, Static{}, NewNode(near))
+		sc.AddNode(nil, &TransformControl{Transform: mgl32.Translate3D(100, 0, 0)}, NewNode(dynamic))
+	})
+	sc.Process(0, nil, testCullPhase{maxX: 50})
+	if near.visited != 1 || far.visited != 0 || dynamic.visited != 1 {
+		t.Error("Invalid visits near ", near.visited, ", far ", far.visited, ", dynamic ", dynamic.visited)
+	}
+	// Cached bounds must be invalidated when static content changes
+	sc.Process(0, nil, testCullPhase{maxX: 200})
+	if far.visited != 1 {
+		t.Error("Far node not visible")
+	}
+	// Move near node out of view
+	sc.Root.Children[1].Children[0].Ctrl = NewMultiControl(&TransformControl{Transform: mgl32.Translate3D(100, 0, 0)}, near)
+	sc.InvalidateStatic()
+	sc.Process(0, nil, testCullPhase{maxX: 50})
+	if near.visited != 2 {
+		t.Error("Moved node still visible ", near.visited)
+	}
+}
@@ -12,9 +12,13 @@ layout (location = 6) in uvec4 i_joints0;
 
 #endif
 
-mat3 calcNormalSpace(mat4 world) {
-    vec3 normal = normalize(vec3(world * vec4(i_normal,0)));
-    vec3 tangent = normalize(vec3(world * vec4(i_tangent,0)));
+mat3 calcNormalSpaceOf(mat4 world, vec3 localNormal, vec3 localTangent) {
+    vec3 normal = normalize(vec3(world * vec4(localNormal,0)));
+    vec3 tangent = normalize(vec3(world * vec4(localTangent,0)));
     vec3 biTangent = -normalize(cross(tangent, normal));
     return mat3(tangent, biTangent, normal);
 }
+
+mat3 calcNormalSpace(mat4 world) {
+    return calcNormalSpaceOf(world, i_normal, i_tangent);
+}
@@ -0,0 +1,103 @@
+package vscene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// InstancedNodeControl draws same mesh with same material multiple times. Instances are placed relative to node and
+// each instance is culled separately.
+//
+// Shaders implementing vmodel.InstancedShader draw all visible instances with one draw call (or few if instances
+// don't fit into one uniform buffer). Other shaders draw each instance separately. Transparent instances are sorted
+// as one group using center of visible instances.
+//
+// Instances can be modified in Scene.Update
+type InstancedNodeControl struct {
+	Mat       vmodel.Shader
+	Mesh      vmodel.Mesh
+	Instances []vmodel.Instance
+}
+
+// NewInstancedNodeControl creates new instanced node control. More instances can be added with Add
+func NewInstancedNodeControl(mat vmodel.Shader, mesh vmodel.Mesh, instances ...vmodel.Instance) *InstancedNodeControl {
+	return &InstancedNodeControl{Mat: mat, Mesh: mesh, Instances: instances}
+}
+
+// Add adds new instance with given transform and color. Use white color to keep material color unchanged
+func (ic *InstancedNodeControl) Add(transform mgl32.Mat4, color mgl32.Vec4) {
+	ic.Instances = append(ic.Instances, vmodel.Instance{World: transform, Color: color})
+}
+
+func (ic *InstancedNodeControl) Process(pi *ProcessInfo) {
+	phase := pi.Phase
+	bb, ok := phase.(*BoudingBox)
+	if ok {
+		for _, inst := range ic.Instances {
+			bb.Add(ic.Mesh.AABB.Translate(pi.World.Mul4(inst.World)))
+		}
+	}
+	dr, ok := phase.(DrawPhase)
+	if ok {
+		ic.draw(dr, pi)
+	}
+	sd, ok := phase.(ShadowPhase)
+	if ok {
+		ic.drawShadow(sd, pi)
+	}
+}
+
+func (ic *InstancedNodeControl) draw(dr DrawPhase, pi *ProcessInfo) {
+	instances, aabb := ic.visible(pi)
+	if len(instances) == 0 {
+		return
+	}
+	var dc *vmodel.DrawContext
+	if isTransparent(ic.Mat, pi.Frame) {
+		dc = GetDrawContext(dr, LAYERTransparent, aabb.Center())
+	} else {
+		dc = dr.GetDC(LAYER3D)
+	}
+	if dc == nil {
+		return
+	}
+	is, ok := ic.Mat.(vmodel.InstancedShader)
+	if ok {
+		is.DrawInstanced(dc, ic.Mesh, instances, pi)
+		return
+	}
+	for _, inst := range instances {
+		ic.Mat.Draw(dc, ic.Mesh, inst.World, pi)
+	}
+}
+
+func (ic *InstancedNodeControl) drawShadow(sd ShadowPhase, pi *ProcessInfo) {
+	instances, _ := ic.visible(pi)
+	if len(instances) == 0 {
+		return
+	}
+	isd, ok := sd.(InstancedShadowPhase)
+	if ok {
+		isd.DrawShadowInstanced(ic.Mesh, instances, ic.Mat)
+		return
+	}
+	for _, inst := range instances {
+		sd.DrawShadow(ic.Mesh, inst.World, ic.Mat)
+	}
+}
+
+// visible returns world space instances that are not culled by phase and their combined bounds
+func (ic *InstancedNodeControl) visible(pi *ProcessInfo) (instances []vmodel.Instance, aabb vmodel.AABB) {
+	bb := BoudingBox{first: true}
+	instances = make([]vmodel.Instance, 0, len(ic.Instances))
+	for _, inst := range ic.Instances {
+		world := pi.World.Mul4(inst.World)
+		instAABB := ic.Mesh.AABB.Translate(world)
+		if IsVisible(pi.Phase, instAABB) {
+			instances = append(instances, vmodel.Instance{World: world, Color: inst.Color})
+			bb.Add(instAABB)
+		}
+	}
+	aabb, _ = bb.Get()
+	return instances, aabb
+}
@@ -0,0 +1,55 @@
+package vscene
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+type testShadowPhase struct {
+	testCullPhase
+	draws     int
+	instances []vmodel.Instance
+}
+
+func (t *testShadowPhase) DrawShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader) {
+	t.draws++
+	t.instances = append(t.instances, vmodel.Instance{World: world})
+}
+
+func (t *testShadowPhase) DrawSkinnedShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader, aniMatrix []mgl32.Mat4) {
+}
+
+func (t *testShadowPhase) DrawShadowInstanced(mesh vmodel.Mesh, instances []vmodel.Instance, material vmodel.Shader) {
+	t.draws++
+	t.instances = append(t.instances, instances...)
+}
+
+func TestInstancedNodeControl_Process(t *testing.T) {
+	sc := &Scene{}
+	sc.Init()
+	mesh := vmodel.Mesh{AABB: vmodel.AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}}
+	ic := NewInstancedNodeControl(nil, mesh)
+	white := mgl32.Vec4{1, 1, 1, 1}
+	for idx := 0; idx < 10; idx++ {
+		ic.Add(mgl32.Translate3D(float32(idx*10), 0, 0), white)
+	}
+	sc.Update(func() {
+		sc.AddNode(nil, &TransformControl{Transform: mgl32.Translate3D(5, 0, 0)}, NewNode(ic))
+	})
+	sp := &testShadowPhase{testCullPhase: testCullPhase{maxX: 50}}
+	sc.Process(0, nil, sp)
+	if sp.draws != 1 || len(sp.instances) != 5 {
+		t.Fatal("Expected 5 instances in one draw, got ", len(sp.instances), " in ", sp.draws)
+	}
+	if sp.instances[4].World.Col(3) != (mgl32.Vec4{45, 0, 0, 1}) {
+		t.Error("Invalid world of instance ", sp.instances[4].World.Col(3))
+	}
+	bb := &BoudingBox{first: true}
+	sc.Process(0, nil, bb)
+	aabb, _ := bb.Get()
+	if aabb.Min[0] != 4 || aabb.Max[0] != 96 {
+		t.Error("Invalid bounds ", aabb)
+	}
+}
@@ -1,29 +1,101 @@
 package vscene
 
-import "github.com/go-gl/mathgl/mgl32"
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
 
 type Light struct {
 	Intensity   mgl32.Vec4
 	Position    mgl32.Vec4 // w = 0 for directional light, 1 = point light, 2 = spot light
-	Direction   mgl32.Vec4 // depending on light type
-	Attenuation mgl32.Vec4 // 0, 1st and 2nd order, w is shadowmap index
+	Direction   mgl32.Vec4 // depending on light type, w is static shadow map index + 1 for shadowed lights
+	Attenuation mgl32.Vec4 // 0, 1st and 2nd order, w is max shadow distance
 	ShadowPlane mgl32.Vec4 // Shadow plane (quoternion)
 	// InnerAngle for spotlight
 	InnerAngle float32
 	// OuterAngle for spotlight
 	OuterAngle float32
-	// Shadow mapping method. 0 - No map, 1 - Point line cube shadow map
+	// Shadow mapping method. 0 - No map, 1 - Point line cube shadow map, 3 - Point light parabloid maps, 4 - Spot light,
+	// 5 - Directional light, 6 - One cascade of cascaded directional light.
+	// Shadow filter and filter size are encoded with method, use SetShadowMethod to set all of them
 	ShadowMapMethod float32
-	// Index for shadow map
+	// Index for shadow map. Index of optional static shadow map is stored in Direction.w, use SetShadowMaps to set both
 	ShadowMapIndex float32
 }
 
+// ShadowFilter selects how shadow map is sampled
+type ShadowFilter int
+
+const (
+	// ShadowFilterHard uses single sample from shadow map. Shadows will have hard edges
+	ShadowFilterHard = ShadowFilter(0)
+	// ShadowFilterPCF uses percentage closer filtering with Poisson disk kernel. Filter size is kernel radius in shadow map texels
+	ShadowFilterPCF = ShadowFilter(1)
+	// ShadowFilterPCSS uses percentage closer soft shadows. Shadow edges get softer the further receiver is from occluder.
+	// Filter size is size of light: angular diameter (in radians) for directional lights and radius of light
+	// (in world units) for point and spot lights
+	ShadowFilterPCSS = ShadowFilter(2)
+)
+
+// Filter size is stored in 1/shadowFilterScale units
+const shadowFilterScale = 1024
+
+// SetShadowMethod encodes shadow mapping method, filter and filter size to ShadowMapMethod.
+// Filter size must be less than 512
+func (l *Light) SetShadowMethod(method int, filter ShadowFilter, filterSize float32) {
+	size := math.Round(float64(filterSize) * shadowFilterScale)
+	if size < 0 {
+		size = 0
+	}
+	l.ShadowMapMethod = float32(method&7 + int(filter&3)<<3 + int(size)<<5)
+}
+
+// GetShadowMethod decodes shadow mapping method, filter and filter size from ShadowMapMethod
+func (l *Light) GetShadowMethod() (method int, filter ShadowFilter, filterSize float32) {
+	m := int(l.ShadowMapMethod)
+	return m & 7, ShadowFilter(m>>3) & 3, float32(m>>5) / shadowFilterScale
+}
+
+// SetShadowMaps sets shadow map index and static shadow map index. Static shadow map contains only static shadow
+// casters (see Static) and shadow map only dynamic ones. Shaders will combine both maps.
+// Static index + 1 is stored in Direction.w so that ShadowMapIndex is always plain index of shadow map.
+// Use staticIndex < 0 if light has no static shadow map. SetShadowMaps returns false if index is invalid
+func (l *Light) SetShadowMaps(index vmodel.ImageIndex, staticIndex vmodel.ImageIndex) bool {
+	if index < 0 {
+		return false
+	}
+	if staticIndex < 0 {
+		staticIndex = -1
+	}
+	l.ShadowMapIndex = float32(index)
+	l.Direction[3] = float32(staticIndex + 1)
+	return true
+}
+
+// GetShadowMaps returns shadow map indexes set with SetShadowMaps. Static index is -1 if light has no static shadow map
+func (l *Light) GetShadowMaps() (index vmodel.ImageIndex, staticIndex vmodel.ImageIndex) {
+	return vmodel.ImageIndex(l.ShadowMapIndex), vmodel.ImageIndex(l.Direction[3]) - 1
+}
+
 type DirectionalLight struct {
 	Intensity mgl32.Vec3
 	Direction mgl32.Vec3
+	// LocalDirection is optional direction of light in local space of node. If set, Direction is calculated from
+	// LocalDirection and world transform of node each time light is processed
+	LocalDirection mgl32.Vec3
+}
+
+// UpdateDirection recalculates Direction from LocalDirection if it is set
+func (d *DirectionalLight) UpdateDirection(world mgl32.Mat4) {
+	if d.LocalDirection.Len() > 0 {
+		d.Direction = world.Mul4x1(d.LocalDirection.Vec4(0)).Vec3().Normalize()
+	}
 }
 
 func (d *DirectionalLight) Process(pi *ProcessInfo) {
+	d.UpdateDirection(pi.World)
 	bf, ok := pi.Phase.(LightPhase)
 	if ok {
 		bf.AddLight(Light{Intensity: d.Intensity.Vec4(1),
@@ -52,3 +124,30 @@ func (p *PointLight) Process(pi *ProcessInfo) {
 			Position: pos, Attenuation: p.Attenuation.Vec4(p.MaxDistance)}, p)
 	}
 }
+
+// Minimum inner angle (in degrees) of spot lights converted from model. SpotLight uses outer angle if inner angle is 0
+const minInnerAngle = 0.01
+
+// LightFromModel converts model light to DirectionalLight, PointLight or SpotLight. Lights use world transform of scene node they
+// are attached to. Lights without range will have MaxDistance where light intensity drops below 1%
+func LightFromModel(l *vmodel.Light) NodeControl {
+	intensity := l.Color.Mul(l.Intensity)
+	maxDistance := l.Range
+	if maxDistance == 0 {
+		maxIntensity := math.Max(float64(intensity[0]), math.Max(float64(intensity[1]), float64(intensity[2])))
+		maxDistance = float32(math.Max(1, math.Sqrt(maxIntensity*100)))
+	}
+	switch l.Kind {
+	case vmodel.LightDirectional:
+		return &DirectionalLight{Intensity: intensity, Direction: mgl32.Vec3{0, 0, -1}, LocalDirection: mgl32.Vec3{0, 0, -1}}
+	case vmodel.LightSpot:
+		// glTF inner cone angle 0 means that light starts to fall off from center of cone
+		innerAngle := mgl32.RadToDeg(l.InnerCone)
+		if innerAngle < minInnerAngle {
+			innerAngle = minInnerAngle
+		}
+		return &SpotLight{Intensity: intensity, Direction: mgl32.Vec3{0, 0, -1}, Attenuation: mgl32.Vec3{0, 0, 1},
+			OuterAngle: mgl32.RadToDeg(l.OuterCone), InnerAngle: innerAngle, MaxDistance: maxDistance}
+	}
+	return &PointLight{Intensity: intensity, Attenuation: mgl32.Vec3{0, 0, 1}, MaxDistance: maxDistance}
+}
@@ -0,0 +1,71 @@
+package vscene
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+func TestShadowMethod(t *testing.T) {
+	l := Light{}
+	l.SetShadowMethod(6, ShadowFilterPCSS, 0.02)
+	method, filter, size := l.GetShadowMethod()
+	if method != 6 || filter != ShadowFilterPCSS || size < 0.019 || size > 0.021 {
+		t.Error("Invalid decode ", method, filter, size)
+	}
+	l.SetShadowMethod(4, ShadowFilterHard, 0)
+	if l.ShadowMapMethod != 4 {
+		t.Error("Hard filter should not change method ", l.ShadowMapMethod)
+	}
+	l.SetShadowMethod(3, ShadowFilterPCF, 511.5)
+	method, filter, size = l.GetShadowMethod()
+	if method != 3 || filter != ShadowFilterPCF || size != 511.5 {
+		t.Error("Invalid decode ", method, filter, size)
+	}
+}
+
+func TestShadowMaps(t *testing.T) {
+	l := Light{}
+	l.SetShadowMaps(12, -1)
+	if l.ShadowMapIndex != 12 {
+		t.Error("Shadow map without static map should not change index ", l.ShadowMapIndex)
+	}
+	l.SetShadowMaps(7, 0)
+	idx, staticIdx := l.GetShadowMaps()
+	if idx != 7 || staticIdx != 0 || l.ShadowMapIndex != 7 {
+		t.Error("Invalid decode ", idx, staticIdx)
+	}
+	if l.SetShadowMaps(-1, 2) {
+		t.Error("Invalid index should fail")
+	}
+}
+
+type testLightPhase struct {
+	lights []Light
+}
+
+func (t *testLightPhase) Begin() (atEnd func()) {
+	return nil
+}
+
+func (t *testLightPhase) AddLight(standard Light, special interface{}) {
+	t.lights = append(t.lights, standard)
+}
+
+func TestLightFromModel(t *testing.T) {
+	sl, ok := LightFromModel(&vmodel.Light{Kind: vmodel.LightSpot, OuterCone: math.Pi / 4}).(*SpotLight)
+	if !ok || sl.InnerAngle <= 0 {
+		t.Fatal("Inner cone 0 should not be mapped to hard edge ", sl)
+	}
+	dl, ok := LightFromModel(&vmodel.Light{Kind: vmodel.LightDirectional}).(*DirectionalLight)
+	if !ok {
+		t.Fatal("Expected directional light")
+	}
+	lp := &testLightPhase{}
+	dl.Process(&ProcessInfo{Phase: lp, World: mgl32.HomogRotate3DY(math.Pi / 2)})
+	if len(lp.lights) != 1 || lp.lights[0].Direction.Vec3().Sub(mgl32.Vec3{-1, 0, 0}).Len() > 0.0001 {
+		t.Error("Direction should follow node world ", lp.lights)
+	}
+}
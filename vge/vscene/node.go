@@ -84,6 +84,29 @@ func (t *TransformControl) Process(pi *ProcessInfo) {
 	pi.World = pi.World.Mul4(t.Transform)
 }
 
+// TransparentShader is implemented by shaders that blend with colors behind them. Mesh nodes draw transparent shaders
+// to LAYERTransparent instead of LAYER3D. Shader may support blending only in some renderers, so Transparent
+// receives frame that mesh is drawn to
+type TransparentShader interface {
+	vmodel.Shader
+	Transparent(frame vmodel.Frame) bool
+}
+
+// isTransparent returns true if material blends with colors behind it when drawn to frame
+func isTransparent(mat vmodel.Shader, frame vmodel.Frame) bool {
+	ts, ok := mat.(TransparentShader)
+	return ok && ts.Transparent(frame)
+}
+
+// getMeshDC returns draw context for mesh drawn with material
+func getMeshDC(dr DrawPhase, frame vmodel.Frame, mat vmodel.Shader, mesh vmodel.Mesh, world mgl32.Mat4) *vmodel.DrawContext {
+	if isTransparent(mat, frame) {
+		aabb := mesh.AABB
+		return GetDrawContext(dr, LAYERTransparent, aabb.Translate(world).Center())
+	}
+	return dr.GetDC(LAYER3D)
+}
+
 type MeshNodeControl struct {
 	Mat  vmodel.Shader
 	Mesh vmodel.Mesh
@@ -93,8 +116,8 @@ func (m *MeshNodeControl) Process(pi *ProcessInfo) {
 	phase := pi.Phase
 	dr, ok := phase.(DrawPhase)
 	if ok {
-		dc := dr.GetDC(LAYER3D)
-		if dc != nil {
+		dc := getMeshDC(dr, pi.Frame, m.Mat, m.Mesh, pi.World)
+		if dc != nil && IsVisible(phase, m.Mesh.AABB.Translate(pi.World)) {
 			m.Mat.Draw(dc, m.Mesh, pi.World, pi)
 		}
 	}
@@ -105,14 +128,22 @@ func (m *MeshNodeControl) Process(pi *ProcessInfo) {
 		bb.Add(aabb)
 	}
 	sd, ok := phase.(ShadowPhase)
-	if ok {
+	if ok && IsVisible(phase, m.Mesh.AABB.Translate(pi.World)) {
 		sd.DrawShadow(m.Mesh, pi.World, m.Mat)
 	}
 }
 
+// NodeFromModel creates scene node from model node. If recursive is set, also all child nodes are converted.
+// Model lights are converted to scene lights (see LightFromModel).
 func NodeFromModel(m *vmodel.Model, node vmodel.NodeIndex, recursive bool) *Node {
 	n := &Node{}
 	mn := m.GetNode(node)
+	var tc NodeControl
+	if mn.Animation != nil {
+		tc = &NodeAnimationControl{Animation: mn.Animation}
+	} else if !mn.Transform.ApproxEqual(mgl32.Ident4()) {
+		tc = &TransformControl{Transform: mn.Transform}
+	}
 	if mn.Material >= 0 && mn.Skin > 0 {
 		an := &AnimatedNodeControl{Mat: m.GetMaterial(mn.Material).Shader, Mesh: m.GetMesh(mn.Mesh), Skin: m.GetSkin(mn.Skin)}
 		n.Ctrl = an
@@ -120,17 +151,23 @@ func NodeFromModel(m *vmodel.Model, node vmodel.NodeIndex, recursive bool) *Node
 			// Initialize first animation if available
 			an.Animation = an.Skin.Animations[0]
 		}
-		if !mn.Transform.ApproxEqual(mgl32.Ident4()) {
-			n.Ctrl = NewMultiControl(&TransformControl{Transform: mn.Transform}, n.Ctrl)
+		if tc != nil {
+			n.Ctrl = NewMultiControl(tc, n.Ctrl)
 		}
 	} else if mn.Material >= 0 {
 		n.Ctrl = &MeshNodeControl{Mat: m.GetMaterial(mn.Material).Shader, Mesh: m.GetMesh(mn.Mesh)}
-		if !mn.Transform.ApproxEqual(mgl32.Ident4()) {
-			n.Ctrl = NewMultiControl(&TransformControl{Transform: mn.Transform}, n.Ctrl)
+		if tc != nil {
+			n.Ctrl = NewMultiControl(tc, n.Ctrl)
 		}
 	} else {
-		if !mn.Transform.ApproxEqual(mgl32.Ident4()) {
-			n.Ctrl = &TransformControl{Transform: mn.Transform}
+		n.Ctrl = tc
+	}
+	if mn.Light != nil {
+		lc := LightFromModel(mn.Light)
+		if n.Ctrl != nil {
+			n.Ctrl = NewMultiControl(n.Ctrl, lc)
+		} else {
+			n.Ctrl = lc
 		}
 	}
 
@@ -0,0 +1,50 @@
+package vscene
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vk"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+type testBlendShader struct {
+	blend bool
+	drawn []*vmodel.DrawContext
+}
+
+func (t *testBlendShader) SetDescriptor(dsMat *vk.DescriptorSet) {
+}
+
+func (t *testBlendShader) Draw(ctx *vmodel.DrawContext, mesh vmodel.Mesh, world mgl32.Mat4, extra vmodel.ShaderExtra) {
+	t.drawn = append(t.drawn, ctx)
+}
+
+func (t *testBlendShader) DrawSkinned(ctx *vmodel.DrawContext, mesh vmodel.Mesh, world mgl32.Mat4, aniMatrix []mgl32.Mat4,
+	extra vmodel.ShaderExtra) {
+	t.drawn = append(t.drawn, ctx)
+}
+
+func (t *testBlendShader) Transparent(frame vmodel.Frame) bool {
+	return t.blend
+}
+
+func TestMeshNodeControl_Transparent(t *testing.T) {
+	mesh := vmodel.Mesh{AABB: vmodel.AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}}
+	blended, opaque := &testBlendShader{blend: true}, &testBlendShader{}
+	p3D := &BasicDrawPhase{Layer: LAYER3D, NoCulling: true}
+	pSorted := NewTransparentPhase(nil, nil, nil, TRANSPARENCYSorted, mgl32.Ident4(), nil, nil).(*SortedDrawPhase)
+	pSorted.NoCulling = true
+	for _, ph := range []Phase{p3D, pSorted} {
+		for _, mat := range []vmodel.Shader{blended, opaque} {
+			mc := &MeshNodeControl{Mat: mat, Mesh: mesh}
+			mc.Process(&ProcessInfo{Phase: ph, World: mgl32.Translate3D(0, 0, -5)})
+		}
+	}
+	if len(blended.drawn) != 1 || blended.drawn[0] != &pSorted.DrawContext || pSorted.List == nil {
+		t.Error("Blended mesh should be drawn only in sorted phase ", blended.drawn)
+	}
+	if len(opaque.drawn) != 1 || opaque.drawn[0] != &p3D.DrawContext {
+		t.Error("Opaque mesh should be drawn only in 3D phase ", opaque.drawn)
+	}
+}
@@ -1,6 +1,8 @@
 package vscene
 
 import (
+	"math"
+
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/lakal3/vge/vge/vk"
 	"github.com/lakal3/vge/vge/vmodel"
@@ -17,6 +19,16 @@ const (
 	LAYERUI          Layer = 4000
 )
 
+// TransparencyMode selects how renderer orders draw items of LAYERTransparent
+type TransparencyMode int
+
+const (
+	// TRANSPARENCYUnsorted draws transparent items in scene traversal order
+	TRANSPARENCYUnsorted TransparencyMode = 0
+	// TRANSPARENCYSorted sorts transparent items back to front using view depth of drawn object
+	TRANSPARENCYSorted TransparencyMode = 1
+)
+
 type Phase interface {
 	Begin() (atEnd func())
 }
@@ -26,22 +38,66 @@ type DrawPhase interface {
 	GetDC(layer Layer) *vmodel.DrawContext
 }
 
+// DepthSortedPhase is implemented by draw phases that sort draw items by view depth. Use GetDrawContext to get draw context
+// that records position of drawn object
+type DepthSortedPhase interface {
+	DrawPhase
+	GetSortedDC(layer Layer, center mgl32.Vec3) *vmodel.DrawContext
+}
+
+// GetDrawContext returns draw context of layer from draw phase. Center is world position of drawn object and it is used to
+// order draw items if phase sorts them
+func GetDrawContext(dp DrawPhase, layer Layer, center mgl32.Vec3) *vmodel.DrawContext {
+	sp, ok := dp.(DepthSortedPhase)
+	if ok {
+		return sp.GetSortedDC(layer, center)
+	}
+	return dp.GetDC(layer)
+}
+
 type ShadowPhase interface {
 	Phase
 	DrawShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader)
 	DrawSkinnedShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader, aniMatrix []mgl32.Mat4)
 }
 
+// InstancedShadowPhase is implemented by shadow phases that can draw multiple instances of same mesh with one draw call.
+// Color of instances is ignored
+type InstancedShadowPhase interface {
+	ShadowPhase
+	DrawShadowInstanced(mesh vmodel.Mesh, instances []vmodel.Instance, material vmodel.Shader)
+}
+
+// MorphShadowPhase is implemented by shadow phases that apply morph target weights to skinned meshes.
+// DrawSkinnedShadow uses default weights of mesh
+type MorphShadowPhase interface {
+	ShadowPhase
+	DrawMorphedShadow(mesh vmodel.Mesh, world mgl32.Mat4, material vmodel.Shader, aniMatrix []mgl32.Mat4, weights []float32)
+}
+
+// StaticPhase is implemented by phases that process static nodes (see Static) differently from dynamic ones.
+// Static control replaces phase with StaticPhase() while processing static nodes. If StaticPhase returns nil,
+// static nodes are skipped
+type StaticPhase interface {
+	Phase
+	StaticPhase() Phase
+}
+
 func NewDrawPhase(frame vmodel.Frame, pass vk.RenderPass, layer Layer, cmd *vk.Command, begin func(), commit func()) DrawPhase {
 	return &BasicDrawPhase{DrawContext: vmodel.DrawContext{Frame: frame, Pass: pass}, Layer: layer, Cmd: cmd, begin: begin, commit: commit}
 }
 
 type BasicDrawPhase struct {
 	vmodel.DrawContext
-	Cmd    *vk.Command
-	Layer  Layer
-	begin  func()
-	commit func()
+	Cmd   *vk.Command
+	Layer Layer
+	// NoCulling disables culling of objects outside of camera view
+	NoCulling bool
+	// Occluder is optional occlusion test for objects inside of camera view
+	Occluder Occluder
+	begin    func()
+	commit   func()
+	culler   ViewCuller
 	// FP  *vk.Framebuffer
 }
 
@@ -52,6 +108,17 @@ func (d *BasicDrawPhase) GetDC(layer Layer) *vmodel.DrawContext {
 	return nil
 }
 
+// Visible returns false if bounding box is outside of camera view of frame or if occluder hides it
+func (d *BasicDrawPhase) Visible(aabb vmodel.AABB) bool {
+	if d.NoCulling {
+		return true
+	}
+	if !d.culler.Visible(d.Frame, aabb) {
+		return false
+	}
+	return d.Occluder == nil || !d.Occluder.Occluded(aabb)
+}
+
 func (d *BasicDrawPhase) Begin() (atEnd func()) {
 
 	if d.begin != nil {
@@ -67,6 +134,58 @@ func (d *BasicDrawPhase) Begin() (atEnd func()) {
 	}
 }
 
+// NewTransparentPhase creates draw phase for LAYERTransparent. With TRANSPARENCYSorted draw items are sorted back to front
+// before they are recorded to command. View is view matrix of camera
+func NewTransparentPhase(frame vmodel.Frame, pass vk.RenderPass, cmd *vk.Command, mode TransparencyMode, view mgl32.Mat4,
+	begin func(), commit func()) DrawPhase {
+	if mode != TRANSPARENCYSorted {
+		return NewDrawPhase(frame, pass, LAYERTransparent, cmd, begin, commit)
+	}
+	return &SortedDrawPhase{BasicDrawPhase: BasicDrawPhase{DrawContext: vmodel.DrawContext{Frame: frame, Pass: pass},
+		Layer: LAYERTransparent, Cmd: cmd, begin: begin, commit: commit}, View: view}
+}
+
+// SortedDrawPhase draws items from farthest to nearest. Items drawn without position (using GetDC) are drawn last
+type SortedDrawPhase struct {
+	BasicDrawPhase
+	View mgl32.Mat4
+}
+
+func (d *SortedDrawPhase) GetDC(layer Layer) *vmodel.DrawContext {
+	return d.getDC(layer, -math.MaxFloat32)
+}
+
+func (d *SortedDrawPhase) GetSortedDC(layer Layer, center mgl32.Vec3) *vmodel.DrawContext {
+	// View space looks towards negative z axis
+	return d.getDC(layer, -d.View.Mul4x1(center.Vec4(1)).Z())
+}
+
+func (d *SortedDrawPhase) getDC(layer Layer, depth float32) *vmodel.DrawContext {
+	if d.Layer != layer {
+		return nil
+	}
+	if d.List == nil {
+		d.List = new(vk.DrawList)
+	}
+	d.List.SetSortKey(depth)
+	return &d.DrawContext
+}
+
+func (d *SortedDrawPhase) Begin() (atEnd func()) {
+	if d.begin != nil {
+		d.begin()
+	}
+	return func() {
+		if d.List != nil {
+			d.List.SortByKey(true)
+			d.Cmd.Draw(d.List)
+		}
+		if d.commit != nil {
+			d.commit()
+		}
+	}
+}
+
 type PredrawPhase struct {
 	Scene   *Scene
 	Cmd     *vk.Command
@@ -0,0 +1,170 @@
+package vscene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+// Weight of probes without influence volume. Global probes are only used where no other probe reaches
+const globalProbeWeight = 0.001
+
+// ProbeVolume is influence volume of environment probe. Objects inside volume use probe for ambient light and reflections.
+// Near the border of volume probe is blended with next best probe.
+//
+// Volume without box and radius is global and it is used only where no other probe reaches
+type ProbeVolume struct {
+	// Box is influence box. Box is given in probe nodes local coordinates
+	Box vmodel.AABB
+	// Radius of influence sphere. Radius is used if Box is empty
+	Radius float32
+	// BlendDistance is width of border where probe fades out
+	BlendDistance float32
+	// BoxProjection corrects reflections using influence box as proxy geometry (parallax correction).
+	// Box projection works best when influence box matches walls of room
+	BoxProjection bool
+}
+
+// IsGlobal reports if volume covers whole scene
+func (v ProbeVolume) IsGlobal() bool {
+	return v.Radius <= 0 && !v.hasBox()
+}
+
+func (v ProbeVolume) hasBox() bool {
+	d := v.Box.Max.Sub(v.Box.Min)
+	return d[0] > 0 && d[1] > 0 && d[2] > 0
+}
+
+// EnvProbe is environment probe with influence volume. Frames convert probe to shader layout with ShaderLayout
+type EnvProbe struct {
+	SPH [9]mgl32.Vec4
+	// Capture position of probe, w is index of environment cube image
+	Position mgl32.Vec4
+	// Min corner of influence box, w is radius of influence sphere
+	BoxMin mgl32.Vec4
+	// Max corner of influence box, w is blend distance
+	BoxMax mgl32.Vec4
+	// x is kind of influence volume (probeGlobal, probeSphere or probeBox), y is 1 if box projection is used
+	Flags mgl32.Vec4
+}
+
+const (
+	probeGlobal = 0
+	probeSphere = 1
+	probeBox    = 2
+)
+
+// NewEnvProbe converts probe volume to shader layout. World is world transformation of probe
+func NewEnvProbe(SPH [9]mgl32.Vec4, envImage vmodel.ImageIndex, world mgl32.Mat4, volume ProbeVolume) EnvProbe {
+	ep := EnvProbe{SPH: SPH, Position: world.Col(3).Vec3().Vec4(float32(envImage))}
+	if volume.hasBox() {
+		box := volume.Box.Translate(world)
+		ep.BoxMin, ep.BoxMax = box.Min.Vec4(0), box.Max.Vec4(volume.BlendDistance)
+		ep.Flags[0] = probeBox
+		if volume.BoxProjection {
+			ep.Flags[1] = 1
+		}
+	} else if volume.Radius > 0 {
+		ep.BoxMin[3], ep.BoxMax[3] = volume.Radius, volume.BlendDistance
+		ep.Flags[0] = probeSphere
+	}
+	return ep
+}
+
+// ShaderProbe is lighting part of environment probe in shader layout (PROBE in frame layouts). See probes.glsl
+type ShaderProbe struct {
+	SPH      [9]mgl32.Vec4
+	EnvImage float32
+	Filler1  float32
+	Filler2  float32
+	Filler3  float32
+}
+
+// ShaderProbeVolume is influence volume of environment probe in shader layout (PROBEVOLUME in frame layouts).
+// Volumes are in separate array after other frame content so that PROBE and rest of frame keep their original layout
+type ShaderProbeVolume struct {
+	Position mgl32.Vec4
+	BoxMin   mgl32.Vec4
+	BoxMax   mgl32.Vec4
+	Flags    mgl32.Vec4
+}
+
+// ShaderLayout splits probe to lighting part and influence volume in shader layout
+func (ep EnvProbe) ShaderLayout() (ShaderProbe, ShaderProbeVolume) {
+	return ShaderProbe{SPH: ep.SPH, EnvImage: ep.Position[3]},
+		ShaderProbeVolume{Position: ep.Position.Vec3().Vec4(0), BoxMin: ep.BoxMin, BoxMax: ep.BoxMax, Flags: ep.Flags}
+}
+
+// IsGlobal reports if probe covers whole scene
+func (ep EnvProbe) IsGlobal() bool {
+	return ep.Flags[0] == probeGlobal
+}
+
+// Weight of probe at given world position. Weight is 1 inside influence volume, 0 outside of it and fades
+// from 1 to 0 over blend distance. Global probes have a small constant weight
+func (ep EnvProbe) Weight(pos mgl32.Vec3) float32 {
+	var d float32
+	switch ep.Flags[0] {
+	case probeGlobal:
+		return globalProbeWeight
+	case probeSphere:
+		d = ep.BoxMin[3] - pos.Sub(ep.Position.Vec3()).Len()
+	default:
+		// Distance to closest side of box
+		d = ep.BoxMax[0] - pos[0]
+		for idx := 0; idx < 3; idx++ {
+			d = min32(d, min32(pos[idx]-ep.BoxMin[idx], ep.BoxMax[idx]-pos[idx]))
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+	if ep.BoxMax[3] <= 0 || d >= ep.BoxMax[3] {
+		return 1
+	}
+	return d / ep.BoxMax[3]
+}
+
+// SelectProbes selects two best probes for given position and weight (blend) of first one. Second is -1 if only one probe
+// is used and first is -1 if no probe reaches position. Shaders use same selection, see probes.glsl.
+//
+// If only one local probe reaches position, it is blended with global probe
+func SelectProbes(probes []EnvProbe, pos mgl32.Vec3) (first int, second int, blend float32) {
+	first, second = -1, -1
+	global := -1
+	var w1, w2 float32
+	for idx, ep := range probes {
+		if ep.IsGlobal() {
+			if global < 0 {
+				global = idx
+			}
+			continue
+		}
+		w := ep.Weight(pos)
+		if w <= 0 {
+			continue
+		}
+		if w > w1 {
+			second, w2 = first, w1
+			first, w1 = idx, w
+		} else if w > w2 {
+			second, w2 = idx, w
+		}
+	}
+	if first < 0 {
+		return global, -1, 1
+	}
+	if second < 0 && global >= 0 && w1 < 1 {
+		second, w2 = global, 1-w1
+	}
+	if second < 0 {
+		return first, -1, 1
+	}
+	return first, second, w1 / (w1 + w2)
+}
+
+func min32(a float32, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,47 @@
+package vscene
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/lakal3/vge/vge/vmodel"
+)
+
+func TestSelectProbes(t *testing.T) {
+	room := ProbeVolume{Box: vmodel.AABB{Min: mgl32.Vec3{-5, 0, -5}, Max: mgl32.Vec3{5, 3, 5}}, BlendDistance: 1}
+	probes := []EnvProbe{
+		NewEnvProbe([9]mgl32.Vec4{}, 1, mgl32.Ident4(), ProbeVolume{}),
+		NewEnvProbe([9]mgl32.Vec4{}, 2, mgl32.Translate3D(0, 1, 0), room),
+		NewEnvProbe([9]mgl32.Vec4{}, 3, mgl32.Translate3D(9.5, 1, 0), room),
+	}
+	if probes[1].BoxMin.Vec3() != (mgl32.Vec3{-5, 1, -5}) {
+		t.Error("Influence box not moved with probe ", probes[1].BoxMin)
+	}
+	testSelect(t, probes, mgl32.Vec3{0, 2, 0}, 1, -1, 1)
+	// Outside of rooms
+	testSelect(t, probes, mgl32.Vec3{0, 10, 0}, 0, -1, 1)
+	// Blend border between rooms
+	testSelect(t, probes, mgl32.Vec3{4.75, 2, 0}, 1, 2, 0.5)
+	// Blend border to outside
+	testSelect(t, probes, mgl32.Vec3{0, 2, -4.75}, 1, 0, 0.25)
+}
+
+func testSelect(t *testing.T, probes []EnvProbe, pos mgl32.Vec3, first int, second int, blend float32) {
+	f, s, b := SelectProbes(probes, pos)
+	if f != first || s != second || math.Abs(float64(b-blend)) > 0.001 {
+		t.Errorf("At %v expected %d, %d, %f got %d, %d, %f", pos, first, second, blend, f, s, b)
+	}
+}
+
+func TestProbeShaderLayout(t *testing.T) {
+	if unsafe.Sizeof(ShaderProbe{}) != 160 {
+		t.Error("ShaderProbe must keep 160 byte PROBE layout, size is ", unsafe.Sizeof(ShaderProbe{}))
+	}
+	ep := NewEnvProbe([9]mgl32.Vec4{}, 3, mgl32.Translate3D(1, 2, 3), ProbeVolume{Radius: 4})
+	sp, spv := ep.ShaderLayout()
+	if sp.EnvImage != 3 || spv.Position != (mgl32.Vec4{1, 2, 3, 0}) || spv.BoxMin[3] != 4 || spv.Flags[0] != probeSphere {
+		t.Error("Invalid shader layout ", sp.EnvImage, spv)
+	}
+}
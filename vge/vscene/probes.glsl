@@ -0,0 +1,151 @@
+// Environment probe selection and sampling. Frame layout must have noProbes, probes and probeVolumes
+// (see vscene.ShaderProbe and vscene.ShaderProbeVolume)
+// and frameImagesCube must be defined before including this file
+
+#define PROBE_GLOBAL 0
+#define PROBE_SPHERE 1
+#define PROBE_BOX 2
+
+// Two selected probes and weight of first one. Index is negative if probe is not used
+struct PROBESELECTION {
+    int first;
+    int second;
+    float blend;
+};
+
+// Weight of probe at position. 1 inside influence volume, 0 outside of it and fade over blend distance (boxMax.w)
+float probeWeight(PROBEVOLUME p, vec3 pos) {
+    int kind = int(p.flags.x);
+    if (kind == PROBE_GLOBAL) {
+        return 0.001;
+    }
+    float d;
+    if (kind == PROBE_SPHERE) {
+        d = p.boxMin.w - length(pos - p.position.xyz);
+    } else {
+        vec3 dBox = min(pos - p.boxMin.xyz, p.boxMax.xyz - pos);
+        d = min(min(dBox.x, dBox.y), dBox.z);
+    }
+    if (d <= 0) {
+        return 0;
+    }
+    if (p.boxMax.w <= 0) {
+        return 1;
+    }
+    return clamp(d / p.boxMax.w, 0, 1);
+}
+
+// Select two best probes for position. Same selection as vscene.SelectProbes
+PROBESELECTION selectProbes(vec3 pos) {
+    PROBESELECTION ps = PROBESELECTION(-1, -1, 1);
+    int global = -1;
+    float w1 = 0;
+    float w2 = 0;
+    int noProbes = int(frame.noProbes);
+    for (int idx = 0; idx < noProbes; idx++) {
+        if (int(frame.probeVolumes[idx].flags.x) == PROBE_GLOBAL) {
+            if (global < 0) {
+                global = idx;
+            }
+            continue;
+        }
+        float w = probeWeight(frame.probeVolumes[idx], pos);
+        if (w <= 0) {
+            continue;
+        }
+        if (w > w1) {
+            ps.second = ps.first;
+            w2 = w1;
+            ps.first = idx;
+            w1 = w;
+        } else if (w > w2) {
+            ps.second = idx;
+            w2 = w;
+        }
+    }
+    if (ps.first < 0) {
+        ps.first = global;
+        return ps;
+    }
+    if (ps.second < 0 && global >= 0 && w1 < 1) {
+        ps.second = global;
+        w2 = 1 - w1;
+    }
+    if (ps.second >= 0) {
+        ps.blend = w1 / (w1 + w2);
+    }
+    return ps;
+}
+
+// IBL color from spherical harmonics of probe
+vec3 shProbe(PROBE p, vec3 normal) {
+    float x = normal.x;
+    float y = normal.y;
+    float z = normal.z;
+    vec4 result = (
+        p.sph[0] +
+
+        p.sph[1] * -y +
+        p.sph[2] * z +
+        p.sph[3] * -x +
+
+        p.sph[4] * x * y +
+        p.sph[5] * -y * z +
+        p.sph[6] * (3.0 * z * z - 1.0) +
+        p.sph[7] * -x * z +
+        p.sph[8] * (x*x - y*y)
+    );
+
+    return max(vec3(result), vec3(0.0));
+}
+
+// Box projected (parallax corrected) reflection direction. Reflection ray is intersected with influence box
+// and direction is taken from capture position of probe to intersection
+vec3 boxProject(PROBEVOLUME p, vec3 pos, vec3 r) {
+    vec3 first = (p.boxMax.xyz - pos) / r;
+    vec3 second = (p.boxMin.xyz - pos) / r;
+    vec3 furthest = max(first, second);
+    float dist = min(min(furthest.x, furthest.y), furthest.z);
+    return pos + r * dist - p.position.xyz;
+}
+
+vec3 specularProbe(int idx, vec3 pos, vec3 r, float roughness) {
+    int tx_envMap = int(frame.probes[idx].envImage);
+    if (tx_envMap == 0) {
+        return vec3(0.5);
+    }
+    PROBEVOLUME v = frame.probeVolumes[idx];
+    if (int(v.flags.x) == PROBE_BOX && v.flags.y > 0.5) {
+        r = boxProject(v, pos, r);
+    }
+    int lods = textureQueryLevels(frameImagesCube[tx_envMap]);
+    float lod1 = floor(lods * roughness);
+    float lod2 = ceil(lods * roughness);
+    vec4 tx1 = textureLod(frameImagesCube[tx_envMap], r, lod1);
+    vec4 tx2 = textureLod(frameImagesCube[tx_envMap], r, lod2);
+    return mix(tx1.rgb, tx2.rgb, fract(roughness));
+}
+
+// Blended spherical harmonics of selected probes
+vec3 blendSH(PROBESELECTION ps, vec3 normal) {
+    if (ps.first < 0) {
+        return vec3(0);
+    }
+    vec3 c = shProbe(frame.probes[ps.first], normal);
+    if (ps.second >= 0) {
+        c = mix(shProbe(frame.probes[ps.second], normal), c, ps.blend);
+    }
+    return c;
+}
+
+// Blended specular IBL of selected probes
+vec3 blendSpecular(PROBESELECTION ps, vec3 pos, vec3 r, float roughness) {
+    if (ps.first < 0) {
+        return vec3(0.5);
+    }
+    vec3 c = specularProbe(ps.first, pos, r, roughness);
+    if (ps.second >= 0) {
+        c = mix(specularProbe(ps.second, pos, r, roughness), c, ps.blend);
+    }
+    return c;
+}
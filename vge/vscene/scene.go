@@ -30,6 +30,16 @@ type Scene struct {
 	lockCount int32
 	pending   []func()
 	Time      float64
+
+	staticVersion int32
+	staticNodes   int32
+	staticSet     map[*Node]struct{}
+	staticScanned bool
+	staticRescan  int32
+
+	mxBounds      sync.Mutex
+	bounds        map[*Node]subtreeBounds
+	boundsVersion int
 }
 
 // Init must be called before Process or Update
@@ -52,6 +62,7 @@ func (sc *Scene) Update(action func()) {
 		sc.pending = append(sc.pending, action)
 	} else {
 		action()
+		sc.rescanStatic()
 	}
 }
 
@@ -82,9 +93,26 @@ func (sc *Scene) AddNode(parent *Node, ctrl NodeControl, children ...*Node) *Nod
 		parent = &sc.Root
 	}
 	parent.Children = append(parent.Children, n)
+	sc.addStatic(parent, n)
 	return n
 }
 
+// RemoveNode removes child node from parent. Parent nil is root of scene. RemoveNode returns false if node was not
+// a child of parent. Like AddNode, this method should be called from scene.Update
+func (sc *Scene) RemoveNode(parent *Node, child *Node) bool {
+	if parent == nil {
+		parent = &sc.Root
+	}
+	for idx, ch := range parent.Children {
+		if ch == child {
+			parent.Children = append(parent.Children[:idx:idx], parent.Children[idx+1:]...)
+			sc.removeStatic(child)
+			return true
+		}
+	}
+	return false
+}
+
 // Check if scene is in readonly state. You should use Update method instead of relying on this to property update live scene
 func (sc *Scene) Locked() bool {
 	return atomic.LoadInt32(&sc.lockCount) > 0
@@ -100,9 +128,14 @@ func (sc *Scene) processPhase(time float64, frame vmodel.Frame, world mgl32.Mat4
 }
 
 func (sc *Scene) processNode(n *Node, piParent *ProcessInfo) {
+	if !sc.subtreeVisible(n, piParent) {
+		return
+	}
 	if n.Ctrl != nil {
 		pi := *piParent
 		pi.parent = piParent
+		// Extras set by this node must not leak to parent or sibling nodes. Parent values are found through parent chain
+		pi.extras = nil
 		n.Ctrl.Process(&pi)
 		if !pi.Visible {
 			return
@@ -124,4 +157,5 @@ func (sc *Scene) updatePending() {
 	for _, ac := range pending {
 		ac()
 	}
+	sc.rescanStatic()
 }
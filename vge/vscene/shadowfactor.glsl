@@ -7,14 +7,144 @@ vec2 sampleOffsetDirections[5] = vec2[] (
 vec2( 0, 0), vec2( 1,  1), vec2( 1, -1), vec2(-1, -1), vec2(-1,  1)
 );
 
+// Shadow filters. Filter and filter size are encoded to shadowMapMethod, see vscene.Light.SetShadowMethod
+#define FILTER_HARD 0
+#define FILTER_PCF 1
+#define FILTER_PCSS 2
+// Maximum filter radius in texels
+#define MAX_FILTER_TEXELS 32
+#define POISSON_SAMPLES 16
+
+const vec2 poissonDisk[16] = vec2[] (
+vec2(-0.94201624, -0.39906216), vec2(0.94558609, -0.76890725), vec2(-0.09418410, -0.92938870), vec2(0.34495938, 0.29387760),
+vec2(-0.91588581, 0.45771432), vec2(-0.81544232, -0.87912464), vec2(-0.38277543, 0.27676845), vec2(0.97484398, 0.75648379),
+vec2(0.44323325, -0.97511554), vec2(0.53742981, -0.47373420), vec2(-0.26496911, -0.41893023), vec2(0.79197514, 0.19090188),
+vec2(-0.24188840, 0.99706507), vec2(-0.81409955, 0.91437590), vec2(0.19984126, 0.78641367), vec2(0.14383161, -0.14100790)
+);
+
 // From: https://community.khronos.org/t/quaternion-functions-for-glsl/50140/2
 // Transform (rotate) vector by quoternion
 vec3 qtransform( vec4 q, vec3 v ){
     return v + 2.0*cross(cross(v, q.xyz ) + q.w*v, q.xyz);
 }
 
+// Shadow map description used by filters
+struct SHADOWMAP {
+    int index;
+    // Layer for array shadow maps (point lights). Negative for 2D shadow maps
+    float layer;
+    // Shadow map value * scale is distance from light
+    float scale;
+    // Uv units per world unit (orthographic maps) or per radian (perspective maps)
+    float uvScale;
+    bool perspective;
+    // Index of static shadow map. Negative if light has no static shadow map
+    int staticIndex;
+};
+
+float shadowMapDistance(SHADOWMAP sm, vec2 uv) {
+    float d;
+    if (sm.layer >= 0) {
+        d = texture(frameImagesArray[sm.index], vec3(uv, sm.layer)).x;
+        if (sm.staticIndex >= 0) {
+            d = min(d, texture(frameImagesArray[sm.staticIndex], vec3(uv, sm.layer)).x);
+        }
+    } else {
+        d = texture(frameImages2D[sm.index], uv).x;
+        if (sm.staticIndex >= 0) {
+            d = min(d, texture(frameImages2D[sm.staticIndex], uv).x);
+        }
+    }
+    return d * sm.scale;
+}
+
+vec2 shadowMapTexel(SHADOWMAP sm) {
+    if (sm.layer >= 0) {
+        return vec2(1.0) / vec2(textureSize(frameImagesArray[sm.index], 0).xy);
+    }
+    return vec2(1.0) / vec2(textureSize(frameImages2D[sm.index], 0));
+}
+
+// filterShadow returns fraction of filter kernel that is lit. Receiver and bias are in world units
+float filterShadow(SHADOWMAP sm, vec2 uv, float receiver, float bias, int filterMode, float filterSize) {
+    if (filterMode == FILTER_HARD) {
+        return shadowMapDistance(sm, uv) + bias > receiver ? 1 : 0;
+    }
+    vec2 texel = shadowMapTexel(sm);
+    vec2 radius = texel * clamp(filterSize, 0, MAX_FILTER_TEXELS);
+    if (filterMode == FILTER_PCSS) {
+        // Search blockers from area light would cover
+        float search = filterSize * (sm.perspective ? 1 / receiver : receiver) * sm.uvScale;
+        vec2 searchRadius = clamp(vec2(search), texel, texel * MAX_FILTER_TEXELS);
+        float blockers = 0;
+        float blockerSum = 0;
+        for (int idx = 0; idx < POISSON_SAMPLES; idx++) {
+            float d = shadowMapDistance(sm, uv + poissonDisk[idx] * searchRadius);
+            if (d + bias < receiver) {
+                blockerSum += d;
+                blockers++;
+            }
+        }
+        if (blockers == 0) {
+            return 1;
+        }
+        float blocker = max(blockerSum / blockers, 0.0001);
+        float penumbra = sm.perspective ? filterSize * (receiver - blocker) / (blocker * receiver) :
+            filterSize * (receiver - blocker);
+        radius = clamp(vec2(penumbra * sm.uvScale), texel, texel * MAX_FILTER_TEXELS);
+    }
+    float sum = 0;
+    for (int idx = 0; idx < POISSON_SAMPLES; idx++) {
+        sum += shadowMapDistance(sm, uv + poissonDisk[idx] * radius) + bias > receiver ? 1 : 0;
+    }
+    return sum / POISSON_SAMPLES;
+}
+
+// Position cascade split distances are measured from. Frame layouts without cameraPos must define SHADOW_EYE_POS
+#ifndef SHADOW_EYE_POS
+#define SHADOW_EYE_POS frame.cameraPos.xyz
+#endif
+
+// Method 6, cascaded directional light. Each cascade is a separate light that covers view distances
+// from innerAngle to outerAngle. Cascade fades in and out over blend band (|intensity.w| * split distance)
+// so that weights of overlapping cascades sum to 1. Last cascade (intensity.w < 0) fades to fully lit.
+// Attenuation.w is half size of cascade area and direction.w depth range of cascade projection
+float getCascadeFactor(LIGHT l, vec3 worldPos, int filterMode, float filterSize) {
+    float dist = length(worldPos - SHADOW_EYE_POS);
+    float blend = abs(l.intensity.w);
+    float fadeIn = l.innerAngle > 0 ? clamp((dist - l.innerAngle * (1 - blend)) / (l.innerAngle * blend), 0, 1) : 1;
+    float fadeOut = clamp((l.outerAngle - dist) / (l.outerAngle * blend), 0, 1);
+    float lit = l.intensity.w < 0 ? 1 - fadeOut : 0;
+    int shadowMapIdx = int(l.shadowMapIndex);
+    if (fadeIn * fadeOut <= 0) {
+        return fadeIn * lit;
+    }
+    #ifndef DYNAMIC_DESCRIPTORS
+    if (shadowMapIdx >= MAX_IMAGES) {
+        return fadeIn * (fadeOut + lit);
+    }
+    #endif
+    float area = l.attenuation.w;
+    vec3 samplePos = qtransform(l.shadowPlane, worldPos - l.position.xyz);
+    if (abs(samplePos.x) > area || abs(samplePos.z) > area) {
+        return fadeIn * (fadeOut + lit);
+    }
+    SHADOWMAP sm = SHADOWMAP(shadowMapIdx, -1, l.direction.w, 0.5 / area, false, -1);
+    // Bias 1.5 texels in world units
+    float bias = 3.0 * area * shadowMapTexel(sm).x;
+    vec2 pos = samplePos.xz / vec2(area) * vec2(0.5, 0.5) + vec2(0.5, 0.5);
+    float f = filterShadow(sm, pos, samplePos.y, bias, filterMode, filterSize);
+    return fadeIn * (f * fadeOut + lit);
+}
 
 float getShadowFactor(LIGHT l, vec3 worldPos) {
+    int shadowMethod = int(l.shadowMapMethod);
+    int filterMode = (shadowMethod >> 3) & 3;
+    float filterSize = float(shadowMethod >> 5) / 1024;
+    shadowMethod = shadowMethod & 7;
+    if (shadowMethod == 6) {
+        return getCascadeFactor(l, worldPos, filterMode, filterSize);
+    }
     vec4 lightPos = l.position;
     float maxDist = l.attenuation.w;
     vec3 samplePos = worldPos - lightPos.xyz;
@@ -34,10 +164,11 @@ float getShadowFactor(LIGHT l, vec3 worldPos) {
     if (fLength > maxDist) {
         return l.attenuation.x > 0 ? spFactor: 0;  // If we have constant attenuation, light is visible outside shadow range
     }
-    int shadowMethod = int(l.shadowMapMethod);
+    // Static shadow map index + 1 is stored in direction.w, see vscene.Light.SetShadowMaps
     int shadowMapIdx = int(l.shadowMapIndex);
+    int staticIdx = int(l.direction.w) - 1;
     #ifndef DYNAMIC_DESCRIPTORS
-    if (shadowMapIdx >= MAX_IMAGES) {
+    if (shadowMapIdx >= MAX_IMAGES || staticIdx >= MAX_IMAGES) {
         return spFactor;
     }
     #endif
@@ -73,68 +204,39 @@ float getShadowFactor(LIGHT l, vec3 worldPos) {
     // Point light (3) or Spot light 4.
     // Spot light have plane transform in quoternion l.shadowPlane.
     // Point lights have always 2 maps with 0,-1,0 and 0,1,0 axis
+    // Parabloid maps have about 0.25 uv units per radian near center of map
     if (shadowMethod == 3 || shadowMethod == 4) {
         if (shadowMethod == 4) {
             samplePos = qtransform(l.shadowPlane, samplePos);
         }
         samplePos = samplePos / vec3(fLength);
-        if (fLength > maxDist) {
-            return 0;
-        }
         // calc "normal" on intersection, by adding the
         // reflection-vector(0,0,1) and divide through
         // his z to get the texture coords
-        float d = 0;
-        float sum = 0;
-        #if PCF
-        for (int idx = 0; idx < SAMPLES; idx++) {
-            vec2 offset = sampleOffsetDirections[idx] * vec2(0.001);
-            #else
-            vec2 offset = vec2(0);
-            #endif
-
-            if (shadowMethod == 4) {
-                float n = samplePos.y + 1;
-                vec2 pos = vec2(samplePos.x / n, samplePos.z / n) * vec2(0.5, 0.5) + vec2(0.5, 0.5) + offset;
-                d = texture(frameImages2D[shadowMapIdx], pos).x;
-            } else if (samplePos.y < 0 ) {
-                float n = -samplePos.y + 1;
-                vec3 pos = vec3(samplePos.x / n, samplePos.z / n, 0) * vec3(0.5, 0.5, 1) + vec3(0.5, 0.5, 0) + vec3(offset,0);
-                // pos.y = 1 - pos.y;
-                d = texture(frameImagesArray[shadowMapIdx], pos).x;
-            } else {
-                float n = samplePos.y + 1;
-                vec3 pos = vec3(samplePos.x / n, samplePos.z / n, 1) * vec3(0.5, 0.5, 1) + vec3(0.5, 0.5, 0) + vec3(offset,0);
-                // pos.y = 1 - pos.y;
-                d = texture(frameImagesArray[shadowMapIdx + 1], pos).x;
-            }
-            sum += d + shadowBias > (fLength / maxDist) ? spFactor : 0;
-            #if PCF
+        SHADOWMAP sm = SHADOWMAP(shadowMapIdx, -1, maxDist, 0.25, true, staticIdx);
+        vec2 pos;
+        if (shadowMethod == 4) {
+            float n = samplePos.y + 1;
+            pos = vec2(samplePos.x / n, samplePos.z / n) * vec2(0.5, 0.5) + vec2(0.5, 0.5);
+        } else if (samplePos.y < 0 ) {
+            float n = -samplePos.y + 1;
+            pos = vec2(samplePos.x / n, samplePos.z / n) * vec2(0.5, 0.5) + vec2(0.5, 0.5);
+            sm.layer = 0;
+        } else {
+            float n = samplePos.y + 1;
+            pos = vec2(samplePos.x / n, samplePos.z / n) * vec2(0.5, 0.5) + vec2(0.5, 0.5);
+            sm.index = shadowMapIdx + 1;
+            sm.staticIndex = staticIdx >= 0 ? staticIdx + 1 : -1;
+            sm.layer = 1;
         }
-        return sum / SAMPLES * spFactor;
-        #else
-        returm sum * spFactor;
-        #endif
-
+        return filterShadow(sm, pos, fLength, shadowBias * maxDist, filterMode, filterSize) * spFactor;
     }
+    // Directional light (5)
     if (shadowMethod == 5) {
         samplePos = qtransform(l.shadowPlane, samplePos);
-        float sum = 0;
-        #if PCF
-        for (int idx = 0; idx < SAMPLES; idx++) {
-            vec2 offset = sampleOffsetDirections[idx] * vec2(0.001);
-            #else
-            vec2 offset = vec2(0);
-            #endif
-            vec2 pos = samplePos.xz / vec2(maxDist) * vec2(0.5, 0.5) + vec2(0.5, 0.5) + offset;
-            float d = texture(frameImages2D[shadowMapIdx], pos).x;
-            sum += d + shadowBias > (samplePos.y / maxDist) ? spFactor : 0;
-            #if PCF
-        }
-        return sum / SAMPLES * spFactor;
-        #else
-        returm sum * spFactor;
-        #endif
+        SHADOWMAP sm = SHADOWMAP(shadowMapIdx, -1, maxDist, 0.5 / maxDist, false, staticIdx);
+        vec2 pos = samplePos.xz / vec2(maxDist) * vec2(0.5, 0.5) + vec2(0.5, 0.5);
+        return filterShadow(sm, pos, samplePos.y, shadowBias * maxDist, filterMode, filterSize) * spFactor;
     }
     // TODO: Directional light
     return spFactor;
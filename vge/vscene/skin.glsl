@@ -3,8 +3,13 @@
 #define SKIN_SET 3
 #endif
 
+#define MAX_MORPH_TARGETS 256
+
 layout(set=SKIN_SET, binding=0) uniform JOINTS {
     mat4 jointMatrix[768];
+    // x - offset of mesh deltas, y - number of morph targets, z - first vertex of mesh, w - number of vertices in mesh
+    uvec4 morphInfo;
+    vec4 morphWeights[MAX_MORPH_TARGETS / 4];
 } joints;
 
 mat4 skinMatrix() {
@@ -13,4 +18,30 @@ mat4 skinMatrix() {
     i_weights0.y * joints.jointMatrix[i_joints0.y] +
     i_weights0.z * joints.jointMatrix[i_joints0.z] +
     i_weights0.w * joints.jointMatrix[i_joints0.w];
-}
\ No newline at end of file
+}
+
+#ifdef MORPH_SET
+layout(std430, set=MORPH_SET, binding=0) readonly buffer MORPHS {
+    // Position, normal and tangent delta for each morph target and vertex
+    vec4 deltas[];
+} morphs;
+
+// Add weighted morph target deltas to vertex position, normal and tangent
+void morphVertex(out vec3 position, out vec3 normal, out vec3 tangent) {
+    position = i_position;
+    normal = i_normal;
+    tangent = i_tangent;
+    uvec4 mi = joints.morphInfo;
+    uint vertex = uint(gl_VertexIndex) - mi.z;
+    for (uint t = 0; t < mi.y; t++) {
+        float w = joints.morphWeights[t / 4][t % 4];
+        if (w == 0) {
+            continue;
+        }
+        uint idx = mi.x + (t * mi.w + vertex) * 3;
+        position += w * morphs.deltas[idx].xyz;
+        normal += w * morphs.deltas[idx + 1].xyz;
+        tangent += w * morphs.deltas[idx + 2].xyz;
+    }
+}
+#endif
@@ -0,0 +1,120 @@
+package vscene
+
+import "sync/atomic"
+
+// Static marks node and all its children static. Static nodes don't move and they are not animated. Shadow lights
+// render static nodes once to separate static shadow map and only dynamic nodes are rendered each frame.
+//
+// Scene tracks static nodes added with Scene.AddNode or removed with Scene.RemoveNode. If you change static nodes
+// otherwise, for example move them or append them directly to Children of node, call Scene.InvalidateStatic to rebuild
+// cached static content
+type Static struct {
+}
+
+func (s Static) Process(pi *ProcessInfo) {
+	sp, ok := pi.Phase.(StaticPhase)
+	if !ok {
+		return
+	}
+	ph := sp.StaticPhase()
+	if ph == nil {
+		pi.Visible = false
+		return
+	}
+	pi.Phase = ph
+}
+
+// StaticVersion is changed each time static nodes are added to or removed from scene or when static content is
+// invalidated. Cached static content is valid as long as static version remains same
+func (sc *Scene) StaticVersion() int {
+	return int(atomic.LoadInt32(&sc.staticVersion))
+}
+
+// StaticNodes returns number of static nodes in scene. Children of static nodes are also included in count
+func (sc *Scene) StaticNodes() int {
+	return int(atomic.LoadInt32(&sc.staticNodes))
+}
+
+// InvalidateStatic forces rebuild of all cached static content like static shadow maps. Static nodes of scene are
+// rescanned on next Update or Process
+func (sc *Scene) InvalidateStatic() {
+	atomic.StoreInt32(&sc.staticRescan, 1)
+	atomic.AddInt32(&sc.staticVersion, 1)
+}
+
+// addStatic registers static nodes in subtree that was added to parent
+func (sc *Scene) addStatic(parent *Node, n *Node) {
+	_, static := sc.staticSet[parent]
+	if sc.registerStatic(n, static) {
+		sc.staticChanged()
+	}
+}
+
+// removeStatic unregisters static nodes in subtree that was removed from scene
+func (sc *Scene) removeStatic(n *Node) {
+	if sc.unregisterStatic(n) {
+		sc.staticChanged()
+	}
+}
+
+func (sc *Scene) registerStatic(n *Node, static bool) (found bool) {
+	if !static && isStatic(n.Ctrl) {
+		static = true
+	}
+	if static {
+		if sc.staticSet == nil {
+			sc.staticSet = make(map[*Node]struct{})
+		}
+		sc.staticSet[n] = struct{}{}
+	}
+	found = static
+	for _, ch := range n.Children {
+		if sc.registerStatic(ch, static) {
+			found = true
+		}
+	}
+	return found
+}
+
+func (sc *Scene) unregisterStatic(n *Node) (found bool) {
+	_, found = sc.staticSet[n]
+	delete(sc.staticSet, n)
+	for _, ch := range n.Children {
+		if sc.unregisterStatic(ch) {
+			found = true
+		}
+	}
+	return found
+}
+
+func (sc *Scene) staticChanged() {
+	atomic.StoreInt32(&sc.staticNodes, int32(len(sc.staticSet)))
+	atomic.AddInt32(&sc.staticVersion, 1)
+}
+
+// rescanStatic rebuilds set of static nodes from whole scene. Scene is rescanned only before first Process and
+// after InvalidateStatic, static nodes added with AddNode or removed with RemoveNode are tracked incrementally
+func (sc *Scene) rescanStatic() {
+	if !atomic.CompareAndSwapInt32(&sc.staticRescan, 1, 0) && sc.staticScanned {
+		return
+	}
+	sc.staticScanned = true
+	sc.staticSet = nil
+	if sc.registerStatic(&sc.Root, false) || atomic.LoadInt32(&sc.staticNodes) != 0 {
+		sc.staticChanged()
+	}
+}
+
+func isStatic(ctrl NodeControl) bool {
+	switch c := ctrl.(type) {
+	case Static, *Static:
+		return true
+	case *MultiControl:
+		for _, ctrl := range c.Controls {
+			if isStatic(ctrl) {
+				return true
+			}
+		}
+	}
+	return false
+}
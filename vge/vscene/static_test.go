@@ -0,0 +1,62 @@
+package vscene
+
+import "testing"
+
+func TestStaticVersion(t *testing.T) {
+	sc := &Scene{}
+	sc.Init()
+	sc.Update(func() {
+		sc.AddNode(nil, &TransformControl{})
+	})
+	sc.Process(0, nil)
+	v := sc.StaticVersion()
+	if sc.StaticNodes() != 0 {
+		t.Error("Expected no static nodes, got ", sc.StaticNodes())
+	}
+
+	var static *Node
+	sc.Update(func() {
+		static = sc.AddNode(nil, NewMultiControl(&TransformControl{}, Static{}), NewNode(nil))
+	})
+	sc.Process(0, nil)
+	if sc.StaticVersion() == v || sc.StaticNodes() != 2 {
+		t.Error("Static node add not detected ", sc.StaticVersion(), sc.StaticNodes())
+	}
+	v = sc.StaticVersion()
+
+	sc.Update(func() {
+		sc.AddNode(nil, &TransformControl{})
+	})
+	sc.Process(0, nil)
+	if sc.StaticVersion() != v {
+		t.Error("Dynamic node changed static version")
+	}
+
+	sc.Update(func() {
+		sc.AddNode(static.Children[0], nil)
+	})
+	sc.Process(0, nil)
+	if sc.StaticVersion() == v || sc.StaticNodes() != 3 {
+		t.Error("Child of static node not detected ", sc.StaticVersion(), sc.StaticNodes())
+	}
+	v = sc.StaticVersion()
+
+	sc.Update(func() {
+		sc.RemoveNode(nil, static)
+	})
+	sc.Process(0, nil)
+	if sc.StaticVersion() == v || sc.StaticNodes() != 0 || len(sc.Root.Children) != 2 {
+		t.Error("Static node remove not detected ", sc.StaticVersion(), sc.StaticNodes())
+	}
+	v = sc.StaticVersion()
+
+	// Direct changes are found after InvalidateStatic
+	sc.Update(func() {
+		sc.Root.Children = append(sc.Root.Children, NewNode(Static{}))
+		sc.InvalidateStatic()
+	})
+	sc.Process(0, nil)
+	if sc.StaticVersion() == v || sc.StaticNodes() != 1 {
+		t.Error("Static node not found after invalidate ", sc.StaticVersion(), sc.StaticNodes())
+	}
+}